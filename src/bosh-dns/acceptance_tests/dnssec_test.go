@@ -0,0 +1,120 @@
+package acceptance_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/cloudfoundry/bosh-utils/system"
+	"github.com/onsi/gomega/gexec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// the public key of test_yml_assets/enable-dnssec.yml
+const dnssecTestKey = "st6ygRkIw2aeOcp2rZ7G5+9gqXLUUbMnOnLrqswa90s9tF+I5nZKbg78AzWE2g6OVmtVHJ0HxB104EiJ8091IA=="
+
+var _ = Describe("DNSSEC", func() {
+	var (
+		firstInstance instanceInfo
+		anchorsFile   string
+	)
+
+	runCommand := func(name string, args ...string) string {
+		session, err := gexec.Start(exec.Command(name, args...), GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		<-session.Exited
+		Expect(session.ExitCode()).To(BeZero())
+
+		return string(session.Out.Contents())
+	}
+
+	// validate resolves name with delv, which validates the answer locally
+	// against the test key as the only trust anchor.
+	validate := func(qtype, name string) string {
+		return runCommand("delv",
+			fmt.Sprintf("@%s", firstInstance.IP),
+			"-a", anchorsFile, "+root=bosh.",
+			"-t", qtype, name,
+		)
+	}
+
+	BeforeEach(func() {
+		if testTargetOS == "windows" {
+			Skip("the local validator runs against linux deployments only")
+		}
+
+		cmdRunner = system.NewExecCmdRunner(boshlog.NewLogger(boshlog.LevelDebug))
+
+		manifestPath, err := filepath.Abs(fmt.Sprintf("../test_yml_assets/%s.yml", testManifestName()))
+		Expect(err).ToNot(HaveOccurred())
+		aliasProvidingPath, err := filepath.Abs("dns-acceptance-release")
+		Expect(err).ToNot(HaveOccurred())
+		enableDNSSECPath, err := filepath.Abs("../test_yml_assets/enable-dnssec.yml")
+		Expect(err).ToNot(HaveOccurred())
+
+		updateCloudConfigWithDefaultCloudConfig()
+
+		stdOut, stdErr, exitStatus, err := cmdRunner.RunCommand(boshBinaryPath,
+			"-n", "-d", boshDeployment, "deploy",
+			"-o", enableDNSSECPath,
+			"-v", fmt.Sprintf("name=%s", boshDeployment),
+			"-v", fmt.Sprintf("acceptance_release_path=%s", aliasProvidingPath),
+			manifestPath,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exitStatus).To(Equal(0), fmt.Sprintf("stdOut: %s \n stdErr: %s", stdOut, stdErr))
+		allDeployedInstances = getInstanceInfos(boshBinaryPath)
+		firstInstance = allDeployedInstances[0]
+
+		anchors, err := ioutil.TempFile("", "dnssec-anchors")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = fmt.Fprintf(anchors, "trusted-keys { \"bosh.\" 257 3 13 \"%s\"; };\n", dnssecTestKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(anchors.Close()).To(Succeed())
+		anchorsFile = anchors.Name()
+	})
+
+	AfterEach(func() {
+		os.Remove(anchorsFile)
+	})
+
+	It("returns signatures with answers when asked with the DO bit", func() {
+		output := runCommand("dig", strings.Split(fmt.Sprintf("+dnssec -t A q-s0.bosh-dns.default.bosh-dns.bosh @%s", firstInstance.IP), " ")...)
+
+		Expect(output).To(ContainSubstring("; EDNS: version: 0, flags: do;"))
+		Expect(output).To(MatchRegexp("q-s0\\.bosh-dns\\.default\\.bosh-dns\\.bosh\\.\\s+0\\s+IN\\s+RRSIG\\s+A 13 "))
+	})
+
+	It("does not sign answers without the DO bit", func() {
+		output := runCommand("dig", strings.Split(fmt.Sprintf("-t A q-s0.bosh-dns.default.bosh-dns.bosh @%s", firstInstance.IP), " ")...)
+
+		Expect(output).NotTo(ContainSubstring("RRSIG"))
+	})
+
+	It("publishes the key at the zone apex", func() {
+		output := runCommand("dig", strings.Split(fmt.Sprintf("+dnssec -t DNSKEY bosh. @%s", firstInstance.IP), " ")...)
+
+		Expect(output).To(ContainSubstring(dnssecTestKey))
+	})
+
+	It("validates answers for instances and groups", func() {
+		output := validate("A", fmt.Sprintf("%s.bosh-dns.default.bosh-dns.bosh.", firstInstance.InstanceID))
+		Expect(output).To(ContainSubstring("; fully validated"))
+		Expect(output).To(ContainSubstring(firstInstance.IP))
+
+		output = validate("A", "q-s0.bosh-dns.default.bosh-dns.bosh.")
+		Expect(output).To(ContainSubstring("; fully validated"))
+	})
+
+	It("validates the denial of missing records", func() {
+		output := validate("AAAA", fmt.Sprintf("%s.bosh-dns.default.bosh-dns.bosh.", firstInstance.InstanceID))
+		Expect(output).To(ContainSubstring("; negative response, fully validated"))
+	})
+})
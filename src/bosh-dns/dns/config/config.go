@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/miekg/dns"
 )
 
 type Config struct {
-	Address           string       `json:"address"`
+	Address           AddressList  `json:"address"`
 	Port              int          `json:"port"`
 	Timeout           DurationJSON `json:"timeout,omitempty"`
 	RecursorTimeout   DurationJSON `json:"recursor_timeout,omitempty"`
@@ -20,25 +24,673 @@ type Config struct {
 	AliasFilesGlob    string       `json:"alias_files_glob,omitempty"`
 	HandlersFilesGlob string       `json:"handlers_files_glob,omitempty"`
 	UpcheckDomains    []string     `json:"upcheck_domains,omitempty"`
+	MaxUDPSize        int          `json:"max_udp_size,omitempty"`
+
+	UpcheckRecoveryAttempts int    `json:"upcheck_recovery_attempts"`
+	UpcheckRecursionDomain  string `json:"upcheck_recursion_domain,omitempty"`
+
+	RecordsSource RecordsSource `json:"records_source"`
+	Health        HealthConfig  `json:"health"`
+	Cache         Cache         `json:"cache"`
+	ZoneTransfer  ZoneTransfer  `json:"zone_transfer"`
+
+	DynamicUpdates DynamicUpdates `json:"dynamic_updates"`
+	DNSSEC         DNSSEC         `json:"dnssec"`
+
+	DNSSECValidation DNSSECValidation `json:"dnssec_validation"`
+	RateLimit        RateLimit        `json:"rate_limit"`
+
+	ACL          ACL            `json:"acl"`
+	AddressACLs  map[string]ACL `json:"address_acls,omitempty"`
+	RecursionACL ACL            `json:"recursion_acl"`
 
-	Health HealthConfig `json:"health"`
-	Cache  Cache        `json:"cache"`
+	Handoff Handoff `json:"handoff"`
+
+	Resolver Resolver `json:"resolver"`
+	Search   Search   `json:"search"`
+
+	Metrics Metrics `json:"metrics"`
+}
+
+// RecordsSource configures an optional local endpoint streaming records
+// snapshots. RecordsFile is still read whenever the endpoint is unavailable.
+type RecordsSource struct {
+	URL           string       `json:"url,omitempty"`
+	PollWait      DurationJSON `json:"poll_wait,omitempty"`
+	RetryInterval DurationJSON `json:"retry_interval,omitempty"`
 }
 
 type HealthConfig struct {
+	Enabled             bool         `json:"enabled"`
+	Port                int          `json:"port"`
+	CertificateFile     string       `json:"certificate_file"`
+	PrivateKeyFile      string       `json:"private_key_file"`
+	CAFile              string       `json:"ca_file"`
+	CheckInterval       DurationJSON `json:"check_interval,omitempty"`
+	CertReloadInterval  DurationJSON `json:"cert_reload_interval,omitempty"`
+	MaxTrackedQueries   int          `json:"max_tracked_queries,omitempty"`
+	MaxConcurrentChecks int          `json:"max_concurrent_checks,omitempty"`
+
+	Checkers []HealthCheckerConfig `json:"checkers,omitempty"`
+}
+
+const (
+	HealthCheckerMTLS  = "mtls"
+	HealthCheckerTCP   = "tcp"
+	HealthCheckerHTTP  = "http"
+	HealthCheckerHTTPS = "https"
+	HealthCheckerDNS   = "dns"
+)
+
+// HealthCheckerConfig overrides how instances matching either an alias or an
+// instance group are health checked.
+type HealthCheckerConfig struct {
+	Alias         string `json:"alias,omitempty"`
+	InstanceGroup string `json:"instance_group,omitempty"`
+
+	Type           string       `json:"type"`
+	Port           int          `json:"port"`
+	Path           string       `json:"path,omitempty"`
+	ExpectedStatus int          `json:"expected_status,omitempty"`
+	Query          string       `json:"query,omitempty"`
+	Timeout        DurationJSON `json:"timeout,omitempty"`
+
+	// CAFile and ServerName verify https servers against a private CA and
+	// a name other than the IP being checked.
+	CAFile     string `json:"ca_file,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
+}
+
+func (c HealthCheckerConfig) Validate() error {
+	if (c.Alias == "") == (c.InstanceGroup == "") {
+		return errors.New("exactly one of alias or instance_group is required")
+	}
+
+	switch c.Type {
+	case HealthCheckerMTLS, HealthCheckerTCP, HealthCheckerHTTP, HealthCheckerHTTPS:
+	case HealthCheckerDNS:
+		if c.Query == "" {
+			return errors.New("query is required for dns health checkers")
+		}
+	default:
+		return fmt.Errorf("unknown type '%s'", c.Type)
+	}
+
+	if c.Port <= 0 {
+		return errors.New("port is required")
+	}
+
+	if (c.CAFile != "" || c.ServerName != "") && c.Type != HealthCheckerHTTPS {
+		return errors.New("ca_file and server_name are only supported by https health checkers")
+	}
+
+	return nil
+}
+
+// ZoneTransfer makes bosh-dns a primary for the BOSH domains. Transfers are
+// only served to Secondaries, an allow-list of IPs or CIDRs, which sign their
+// requests with one of TSIGKeys. Notify lists host:port pairs which are sent a
+// NOTIFY whenever a zone changes.
+type ZoneTransfer struct {
+	Enabled     bool         `json:"enabled"`
+	Nameservers []string     `json:"nameservers,omitempty"`
+	Hostmaster  string       `json:"hostmaster,omitempty"`
+	Secondaries []string     `json:"secondaries,omitempty"`
+	Notify      []string     `json:"notify,omitempty"`
+	TSIGKeys    []TSIGKey    `json:"tsig_keys,omitempty"`
+	TTL         DurationJSON `json:"ttl,omitempty"`
+	Refresh     DurationJSON `json:"refresh,omitempty"`
+	Retry       DurationJSON `json:"retry,omitempty"`
+	Expire      DurationJSON `json:"expire,omitempty"`
+}
+
+type TSIGKey struct {
+	Name      string `json:"name"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Secret    string `json:"secret"`
+}
+
+// SecondaryNetworks parses Secondaries, treating plain IPs as single hosts.
+func (z ZoneTransfer) SecondaryNetworks() ([]*net.IPNet, error) {
+	return parseNetworks("secondary", z.Secondaries)
+}
+
+func parseNetworks(kind string, entries []string) ([]*net.IPNet, error) {
+	networks := []*net.IPNet{}
+
+	for _, entry := range entries {
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s '%s': not an IP or CIDR", kind, entry)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+func (z ZoneTransfer) Validate() error {
+	if !z.Enabled {
+		return nil
+	}
+
+	if len(z.Nameservers) == 0 {
+		return errors.New("at least one nameserver is required")
+	}
+
+	if len(z.Secondaries) == 0 {
+		return errors.New("at least one secondary is required")
+	}
+
+	if _, err := z.SecondaryNetworks(); err != nil {
+		return err
+	}
+
+	if err := validateTSIGKeys(z.TSIGKeys); err != nil {
+		return err
+	}
+
+	for _, target := range z.Notify {
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return fmt.Errorf("invalid notify target '%s': %s", target, err)
+		}
+	}
+
+	return nil
+}
+
+// DynamicUpdates accepts RFC 2136 updates of A and AAAA records in Zones,
+// signed with one of TSIGKeys. Updated records expire after their TTL, capped
+// at MaxTTL, and are kept in PersistFile, when set, across restarts.
+type DynamicUpdates struct {
+	Enabled     bool         `json:"enabled"`
+	Zones       []string     `json:"zones,omitempty"`
+	TSIGKeys    []TSIGKey    `json:"tsig_keys,omitempty"`
+	PersistFile string       `json:"persist_file,omitempty"`
+	MaxTTL      DurationJSON `json:"max_ttl,omitempty"`
+}
+
+func (d DynamicUpdates) Validate() error {
+	if !d.Enabled {
+		return nil
+	}
+
+	if len(d.Zones) == 0 {
+		return errors.New("at least one zone is required")
+	}
+
+	if d.MaxTTL < DurationJSON(time.Second) {
+		return errors.New("max ttl must be at least 1s")
+	}
+
+	return validateTSIGKeys(d.TSIGKeys)
+}
+
+func validateTSIGKeys(keys []TSIGKey) error {
+	if len(keys) == 0 {
+		return errors.New("at least one tsig key is required")
+	}
+
+	for i, key := range keys {
+		if key.Name == "" || key.Secret == "" {
+			return fmt.Errorf("tsig key #%d requires a name and a secret", i)
+		}
+
+		switch dns.Fqdn(key.Algorithm) {
+		case dns.HmacMD5, dns.HmacSHA1, dns.HmacSHA256, dns.HmacSHA512:
+		default:
+			return fmt.Errorf("tsig key '%s' has unsupported algorithm '%s'", key.Name, key.Algorithm)
+		}
+	}
+
+	return nil
+}
+
+// TSIGKeyNames returns the fully qualified names of keys.
+func TSIGKeyNames(keys []TSIGKey) []string {
+	names := []string{}
+	for _, key := range keys {
+		names = append(names, dns.Fqdn(key.Name))
+	}
+
+	return names
+}
+
+// ListenAddresses joins every address with the port, or returns the port on
+// all interfaces when no address is configured.
+func (c Config) ListenAddresses() []string {
+	if len(c.Address) == 0 {
+		return []string{net.JoinHostPort("", strconv.Itoa(c.Port))}
+	}
+
+	listenAddresses := []string{}
+	for _, address := range c.Address {
+		listenAddresses = append(listenAddresses, net.JoinHostPort(address, strconv.Itoa(c.Port)))
+	}
+
+	return listenAddresses
+}
+
+// ACLFor returns the ACL of the queries received on address, which is its
+// entry in AddressACLs or ACL for addresses without one.
+func (c Config) ACLFor(address string) ACL {
+	if acl, ok := c.AddressACLs[address]; ok {
+		return acl
+	}
+
+	return c.ACL
+}
+
+// TSIGSecrets maps the fully qualified names of all enabled TSIG keys to
+// their base64 secrets, the form expected by dns.Server.
+func (c Config) TSIGSecrets() map[string]string {
+	secrets := map[string]string{}
+
+	if c.ZoneTransfer.Enabled {
+		for _, key := range c.ZoneTransfer.TSIGKeys {
+			secrets[dns.Fqdn(key.Name)] = key.Secret
+		}
+	}
+
+	if c.DynamicUpdates.Enabled {
+		for _, key := range c.DynamicUpdates.TSIGKeys {
+			secrets[dns.Fqdn(key.Name)] = key.Secret
+		}
+	}
+
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	return secrets
+}
+
+func (c Config) validateTSIGSecrets() error {
+	if !c.ZoneTransfer.Enabled || !c.DynamicUpdates.Enabled {
+		return nil
+	}
+
+	for _, transferKey := range c.ZoneTransfer.TSIGKeys {
+		for _, updateKey := range c.DynamicUpdates.TSIGKeys {
+			if dns.Fqdn(transferKey.Name) == dns.Fqdn(updateKey.Name) && transferKey.Secret != updateKey.Secret {
+				return fmt.Errorf("tsig key '%s' is configured with different secrets", transferKey.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DNSSEC signs the answers for the BOSH and alias zones online. Every key
+// which is not PublishOnly signs; publish only keys are merely listed in the
+// DNSKEY set so that they can be introduced ahead of a rollover.
+type DNSSEC struct {
 	Enabled           bool         `json:"enabled"`
-	Port              int          `json:"port"`
-	CertificateFile   string       `json:"certificate_file"`
-	PrivateKeyFile    string       `json:"private_key_file"`
-	CAFile            string       `json:"ca_file"`
-	CheckInterval     DurationJSON `json:"check_interval,omitempty"`
-	MaxTrackedQueries int          `json:"max_tracked_queries,omitempty"`
+	Keys              []DNSSECKey  `json:"keys,omitempty"`
+	SignatureValidity DurationJSON `json:"signature_validity,omitempty"`
+}
+
+// DNSSECKey points at a key pair in the format written by dnssec-keygen.
+type DNSSECKey struct {
+	PublicKeyFile  string `json:"public_key_file"`
+	PrivateKeyFile string `json:"private_key_file,omitempty"`
+	PublishOnly    bool   `json:"publish_only,omitempty"`
+}
+
+func (d DNSSEC) Validate() error {
+	if !d.Enabled {
+		return nil
+	}
+
+	signing := 0
+	for i, key := range d.Keys {
+		if key.PublicKeyFile == "" {
+			return fmt.Errorf("key #%d requires a public key file", i)
+		}
+
+		if key.PublishOnly {
+			continue
+		}
+
+		if key.PrivateKeyFile == "" {
+			return fmt.Errorf("key #%d requires a private key file unless it is publish only", i)
+		}
+
+		signing++
+	}
+
+	if signing == 0 {
+		return errors.New("at least one signing key is required")
+	}
+
+	if d.SignatureValidity < DurationJSON(time.Hour) {
+		return errors.New("signature validity must be at least 1h")
+	}
+
+	return nil
+}
+
+// DNSSECValidation validates the answers of the recursors against trust
+// anchors, given as DS or DNSKEY records in presentation format. Names under
+// a negative trust anchor are not validated.
+type DNSSECValidation struct {
+	Enabled              bool     `json:"enabled"`
+	TrustAnchors         []string `json:"trust_anchors,omitempty"`
+	NegativeTrustAnchors []string `json:"negative_trust_anchors,omitempty"`
+}
+
+func (d DNSSECValidation) Validate() error {
+	if !d.Enabled {
+		return nil
+	}
+
+	if len(d.TrustAnchors) == 0 {
+		return errors.New("at least one trust anchor is required")
+	}
+
+	for i, name := range d.NegativeTrustAnchors {
+		if _, ok := dns.IsDomainName(name); !ok || name == "" {
+			return fmt.Errorf("negative trust anchor #%d is not a domain name", i)
+		}
+	}
+
+	return nil
 }
 
 type Cache struct {
 	Enabled bool `json:"enabled"`
 }
 
+// ACL refuses clients within any of the Deny networks and, unless Allow is
+// empty, clients outside all of the Allow networks.
+type ACL struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+func (a ACL) Validate() error {
+	_, _, err := a.Networks()
+	return err
+}
+
+// Empty tells whether the ACL allows every client.
+func (a ACL) Empty() bool {
+	return len(a.Allow) == 0 && len(a.Deny) == 0
+}
+
+// Networks parses Allow and Deny, treating plain IPs as single hosts.
+func (a ACL) Networks() ([]*net.IPNet, []*net.IPNet, error) {
+	allow, err := parseNetworks("allowed network", a.Allow)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deny, err := parseNetworks("denied network", a.Deny)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return allow, deny, nil
+}
+
+// RateLimit limits the queries each client prefix sends per second, with a
+// lower limit for queries resolved by recursors or delegated handlers, and
+// the identical UDP responses it receives per second (RRL). Every Slip-th
+// response over the limit is sent truncated instead of being dropped. A rate
+// of 0 disables the limit. Exempt clients are never limited.
+type RateLimit struct {
+	Enabled                   bool     `json:"enabled"`
+	QueriesPerSecond          int      `json:"queries_per_second,omitempty"`
+	RecursiveQueriesPerSecond int      `json:"recursive_queries_per_second,omitempty"`
+	ResponsesPerSecond        int      `json:"responses_per_second,omitempty"`
+	Slip                      int      `json:"slip"`
+	IPv4PrefixLength          int      `json:"ipv4_prefix_length"`
+	IPv6PrefixLength          int      `json:"ipv6_prefix_length"`
+	Exempt                    []string `json:"exempt,omitempty"`
+}
+
+func (r RateLimit) Validate() error {
+	if !r.Enabled {
+		return nil
+	}
+
+	if r.QueriesPerSecond < 0 || r.RecursiveQueriesPerSecond < 0 || r.ResponsesPerSecond < 0 {
+		return errors.New("rates must not be negative")
+	}
+
+	if r.Slip < 0 || r.Slip > 10 {
+		return errors.New("slip must be between 0 and 10")
+	}
+
+	if r.IPv4PrefixLength < 1 || r.IPv4PrefixLength > 32 {
+		return errors.New("ipv4 prefix length must be between 1 and 32")
+	}
+
+	if r.IPv6PrefixLength < 1 || r.IPv6PrefixLength > 128 {
+		return errors.New("ipv6 prefix length must be between 1 and 128")
+	}
+
+	_, err := r.ExemptNetworks()
+	return err
+}
+
+// ExemptNetworks parses Exempt, treating plain IPs as single hosts.
+func (r RateLimit) ExemptNetworks() ([]*net.IPNet, error) {
+	return parseNetworks("exemption", r.Exempt)
+}
+
+// Handoff passes the listening sockets to the next bosh-dns process over the
+// unix socket at Socket, so that restarts do not drop queries. Once asked to
+// stop, the server keeps serving for up to Wait while no successor has taken
+// over. Windows cannot pass sockets between processes, so handoff is
+// rejected there.
+type Handoff struct {
+	Enabled bool         `json:"enabled"`
+	Socket  string       `json:"socket,omitempty"`
+	Wait    DurationJSON `json:"wait,omitempty"`
+}
+
+// Metrics serves the expvars of bosh-dns, such as the expiry of its health
+// client certificate, over plain HTTP at /debug/vars on Address and Port.
+type Metrics struct {
+	Enabled bool   `json:"enabled"`
+	Address string `json:"address,omitempty"`
+	Port    int    `json:"port,omitempty"`
+}
+
+func (m Metrics) Validate() error {
+	if !m.Enabled {
+		return nil
+	}
+
+	if net.ParseIP(m.Address) == nil {
+		return fmt.Errorf("address '%s' is not an IP", m.Address)
+	}
+
+	if m.Port <= 0 || m.Port > 65535 {
+		return fmt.Errorf("port %d is out of range", m.Port)
+	}
+
+	return nil
+}
+
+func (h Handoff) Validate() error {
+	if !h.Enabled {
+		return nil
+	}
+
+	if runtime.GOOS == "windows" {
+		return errors.New("socket handoff is not supported on windows")
+	}
+
+	if h.Socket == "" {
+		return errors.New("socket is required")
+	}
+
+	if h.Wait < 0 {
+		return errors.New("wait must not be negative")
+	}
+
+	return nil
+}
+
+// Resolver is the search list and options of the system resolver, which the
+// nameserver config monitor enforces along with our nameservers.
+type Resolver struct {
+	Search  []string `json:"search,omitempty"`
+	Options []string `json:"options,omitempty"`
+}
+
+func (r Resolver) Validate() error {
+	for _, domain := range r.Search {
+		if domain == "" || strings.ContainsAny(domain, " \t,;") {
+			return fmt.Errorf("invalid search domain '%s'", domain)
+		}
+	}
+
+	for _, option := range r.Options {
+		if option == "" || strings.ContainsAny(option, " \t,;") {
+			return fmt.Errorf("invalid option '%s'", option)
+		}
+	}
+
+	return nil
+}
+
+// Search expands the names of queries with fewer than NDots dots that do not
+// exist or have no answers with each suffix in turn. OwnNetwork puts the
+// <network>.<deployment>.<domain> names of the records of this VM before
+// Suffixes.
+type Search struct {
+	Suffixes   []string `json:"suffixes,omitempty"`
+	OwnNetwork bool     `json:"own_network,omitempty"`
+	NDots      int      `json:"ndots,omitempty"`
+}
+
+func (s Search) Validate() error {
+	for _, suffix := range s.Suffixes {
+		if _, ok := dns.IsDomainName(suffix); !ok || dns.Fqdn(suffix) == "." {
+			return fmt.Errorf("invalid suffix '%s'", suffix)
+		}
+	}
+
+	if s.NDots < 1 {
+		return errors.New("ndots must be positive")
+	}
+
+	return nil
+}
+
+// Enabled returns whether there is anything to search.
+func (s Search) Enabled() bool {
+	return s.OwnNetwork || len(s.Suffixes) > 0
+}
+
+const (
+	ClientSubnetStrip = "strip"
+	ClientSubnetAdd   = "add"
+)
+
+// EDNS rewrites the EDNS(0) options of the queries a handler forwards.
+// Padding (RFC 7830) only makes sense towards recursors queried over TLS.
+type EDNS struct {
+	ClientSubnet ClientSubnet `json:"client_subnet"`
+	Padding      bool         `json:"padding,omitempty"`
+}
+
+// ClientSubnet either strips the EDNS Client Subnet option (RFC 7871) from
+// forwarded queries or adds one for the client address. Subnets are
+// truncated to the prefix lengths, which default to 24 and 56 bits.
+type ClientSubnet struct {
+	Mode             string `json:"mode,omitempty"`
+	IPv4PrefixLength int    `json:"ipv4_prefix_length,omitempty"`
+	IPv6PrefixLength int    `json:"ipv6_prefix_length,omitempty"`
+}
+
+func (c ClientSubnet) Validate() error {
+	switch c.Mode {
+	case "", ClientSubnetStrip, ClientSubnetAdd:
+	default:
+		return fmt.Errorf("unknown client subnet mode '%s'", c.Mode)
+	}
+
+	if c.IPv4PrefixLength < 0 || c.IPv4PrefixLength > 32 {
+		return errors.New("client subnet ipv4 prefix length must be between 0 and 32")
+	}
+
+	if c.IPv6PrefixLength < 0 || c.IPv6PrefixLength > 128 {
+		return errors.New("client subnet ipv6 prefix length must be between 0 and 128")
+	}
+
+	return nil
+}
+
+// PrefixLengths returns the IPv4 and IPv6 prefix lengths, defaulted.
+func (c ClientSubnet) PrefixLengths() (int, int) {
+	ipv4, ipv6 := 24, 56
+	if c.IPv4PrefixLength != 0 {
+		ipv4 = c.IPv4PrefixLength
+	}
+	if c.IPv6PrefixLength != 0 {
+		ipv6 = c.IPv6PrefixLength
+	}
+
+	return ipv4, ipv6
+}
+
+// AddressList holds the IPs the server binds to. A single IP is accepted in
+// place of a list.
+type AddressList []string
+
+func (l *AddressList) UnmarshalJSON(b []byte) error {
+	var address string
+	if err := json.Unmarshal(b, &address); err == nil {
+		*l = AddressList{address}
+		return nil
+	}
+
+	var addresses []string
+	if err := json.Unmarshal(b, &addresses); err != nil {
+		return err
+	}
+
+	*l = AddressList(addresses)
+	return nil
+}
+
+func (l AddressList) Validate() error {
+	for _, address := range l {
+		// IPv6 link-local addresses need the zone of their interface
+		if net.ParseIP(strings.SplitN(address, "%", 2)[0]) == nil {
+			return fmt.Errorf("invalid address '%s': not an IP", address)
+		}
+	}
+
+	return nil
+}
+
+func (l AddressList) Contains(address string) bool {
+	for _, listed := range l {
+		if listed == address {
+			return true
+		}
+	}
+
+	return false
+}
+
 type DurationJSON time.Duration
 
 func (t *DurationJSON) UnmarshalJSON(b []byte) error {
@@ -72,8 +724,44 @@ func LoadFromFile(configFilePath string) (Config, error) {
 	c := Config{
 		Timeout:         DurationJSON(5 * time.Second),
 		RecursorTimeout: DurationJSON(2 * time.Second),
+		MaxUDPSize:      1232,
+
+		UpcheckRecoveryAttempts: 3,
+
+		RecordsSource: RecordsSource{
+			PollWait:      DurationJSON(30 * time.Second),
+			RetryInterval: DurationJSON(time.Second),
+		},
+		ZoneTransfer: ZoneTransfer{
+			Refresh: DurationJSON(time.Minute),
+			Retry:   DurationJSON(10 * time.Second),
+			Expire:  DurationJSON(time.Hour),
+		},
+		DynamicUpdates: DynamicUpdates{
+			MaxTTL: DurationJSON(time.Hour),
+		},
+		DNSSEC: DNSSEC{
+			SignatureValidity: DurationJSON(7 * 24 * time.Hour),
+		},
+		RateLimit: RateLimit{
+			Slip:             2,
+			IPv4PrefixLength: 24,
+			IPv6PrefixLength: 56,
+		},
+		Handoff: Handoff{
+			Wait: DurationJSON(30 * time.Second),
+		},
+		Search: Search{
+			NDots: 1,
+		},
+		Metrics: Metrics{
+			Address: "127.0.0.1",
+			Port:    53088,
+		},
 		Health: HealthConfig{
-			MaxTrackedQueries: 2000,
+			MaxTrackedQueries:   2000,
+			MaxConcurrentChecks: 100,
+			CertReloadInterval:  DurationJSON(10 * time.Second),
 		},
 	}
 
@@ -85,6 +773,97 @@ func LoadFromFile(configFilePath string) (Config, error) {
 		return Config{}, errors.New("port is required")
 	}
 
+	if err := c.Address.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	if c.UpcheckRecoveryAttempts < 0 {
+		return Config{}, errors.New("upcheck_recovery_attempts must not be negative")
+	}
+
+	if c.UpcheckRecursionDomain != "" {
+		c.UpcheckRecursionDomain = dns.Fqdn(c.UpcheckRecursionDomain)
+	}
+
+	if c.MaxUDPSize < dns.MinMsgSize || c.MaxUDPSize > dns.MaxMsgSize {
+		return Config{}, fmt.Errorf("max_udp_size must be between %d and %d", dns.MinMsgSize, dns.MaxMsgSize)
+	}
+
+	for i, checker := range c.Health.Checkers {
+		if err := checker.Validate(); err != nil {
+			return Config{}, fmt.Errorf("invalid health checker #%d: %s", i, err)
+		}
+
+		if checker.Type == HealthCheckerMTLS && !c.Health.Enabled {
+			return Config{}, fmt.Errorf("invalid health checker #%d: mtls health checkers require health.enabled", i)
+		}
+	}
+
+	defaultTSIGAlgorithms(c.ZoneTransfer.TSIGKeys)
+	defaultTSIGAlgorithms(c.DynamicUpdates.TSIGKeys)
+
+	if err := c.ZoneTransfer.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid zone_transfer: %s", err)
+	}
+
+	if err := c.DynamicUpdates.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid dynamic_updates: %s", err)
+	}
+
+	if err := c.DNSSEC.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid dnssec: %s", err)
+	}
+
+	if err := c.DNSSECValidation.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid dnssec_validation: %s", err)
+	}
+
+	if err := c.RateLimit.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid rate_limit: %s", err)
+	}
+
+	if err := c.ACL.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid acl: %s", err)
+	}
+
+	for address, acl := range c.AddressACLs {
+		if !c.Address.Contains(address) {
+			return Config{}, fmt.Errorf("invalid address_acls: '%s' is not a listen address", address)
+		}
+
+		if err := acl.Validate(); err != nil {
+			return Config{}, fmt.Errorf("invalid address_acls of '%s': %s", address, err)
+		}
+	}
+
+	if err := c.RecursionACL.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid recursion_acl: %s", err)
+	}
+
+	if err := c.Handoff.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid handoff: %s", err)
+	}
+
+	if err := c.Metrics.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid metrics: %s", err)
+	}
+
+	if err := c.Resolver.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid resolver: %s", err)
+	}
+
+	if err := c.Search.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid search: %s", err)
+	}
+
+	for i, suffix := range c.Search.Suffixes {
+		c.Search.Suffixes[i] = dns.Fqdn(suffix)
+	}
+
+	if err := c.validateTSIGSecrets(); err != nil {
+		return Config{}, err
+	}
+
 	c.Recursors, err = AppendDefaultDNSPortIfMissing(c.Recursors)
 	if err != nil {
 		return Config{}, err
@@ -93,13 +872,25 @@ func LoadFromFile(configFilePath string) (Config, error) {
 	return c, nil
 }
 
+func defaultTSIGAlgorithms(keys []TSIGKey) {
+	for i, key := range keys {
+		if key.Algorithm == "" {
+			keys[i].Algorithm = dns.HmacSHA256
+		}
+	}
+}
+
 func AppendDefaultDNSPortIfMissing(recursors []string) ([]string, error) {
+	return AppendDefaultPortIfMissing(recursors, "53")
+}
+
+func AppendDefaultPortIfMissing(recursors []string, port string) ([]string, error) {
 	recursorsWithPort := []string{}
 	for i := range recursors {
 		_, _, err := net.SplitHostPort(recursors[i])
 		if err != nil {
 			if strings.Contains(err.Error(), "missing port in address") {
-				recursorsWithPort = append(recursorsWithPort, net.JoinHostPort(recursors[i], "53"))
+				recursorsWithPort = append(recursorsWithPort, net.JoinHostPort(recursors[i], port))
 			} else {
 				return []string{}, err
 			}
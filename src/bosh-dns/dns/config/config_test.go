@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"runtime"
 	"time"
 
 	"os"
@@ -12,6 +13,7 @@ import (
 	"bosh-dns/dns/config"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -97,29 +99,125 @@ var _ = Describe("Config", func() {
 		dnsConfig, err := config.LoadFromFile(configFilePath)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(dnsConfig).To(Equal(config.Config{
-			Address:           listenAddress,
+			Address:           config.AddressList{listenAddress},
 			Port:              listenPort,
 			Timeout:           config.DurationJSON(timeoutDuration),
 			RecursorTimeout:   config.DurationJSON(recursorTimeoutDuration),
 			Recursors:         []string{},
 			UpcheckDomains:    []string{"upcheck.domain.", "health2.bosh."},
+			MaxUDPSize:        1232,
 			AliasFilesGlob:    aliasesFileGlob,
 			HandlersFilesGlob: handlersFileGlob,
+			RecordsSource: config.RecordsSource{
+				PollWait:      config.DurationJSON(30 * time.Second),
+				RetryInterval: config.DurationJSON(time.Second),
+			},
 			Health: config.HealthConfig{
-				Enabled:           true,
-				Port:              healthPort,
-				CertificateFile:   healthCertificateFile,
-				PrivateKeyFile:    healthPrivateKeyFile,
-				CAFile:            healthCAFile,
-				CheckInterval:     config.DurationJSON(upcheckIntervalDuration),
-				MaxTrackedQueries: healthMaxTrackedQueries,
+				Enabled:             true,
+				Port:                healthPort,
+				CertificateFile:     healthCertificateFile,
+				PrivateKeyFile:      healthPrivateKeyFile,
+				CAFile:              healthCAFile,
+				CheckInterval:       config.DurationJSON(upcheckIntervalDuration),
+				CertReloadInterval:  config.DurationJSON(10 * time.Second),
+				MaxTrackedQueries:   healthMaxTrackedQueries,
+				MaxConcurrentChecks: 100,
 			},
 			Cache: config.Cache{
 				Enabled: true,
 			},
+			ZoneTransfer: config.ZoneTransfer{
+				Refresh: config.DurationJSON(time.Minute),
+				Retry:   config.DurationJSON(10 * time.Second),
+				Expire:  config.DurationJSON(time.Hour),
+			},
+			DynamicUpdates: config.DynamicUpdates{
+				MaxTTL: config.DurationJSON(time.Hour),
+			},
+			DNSSEC: config.DNSSEC{
+				SignatureValidity: config.DurationJSON(7 * 24 * time.Hour),
+			},
+			RateLimit: config.RateLimit{
+				Slip:             2,
+				IPv4PrefixLength: 24,
+				IPv6PrefixLength: 56,
+			},
+			Handoff: config.Handoff{
+				Wait: config.DurationJSON(30 * time.Second),
+			},
+			Search: config.Search{
+				NDots: 1,
+			},
+			Metrics: config.Metrics{
+				Address: "127.0.0.1",
+				Port:    53088,
+			},
+			UpcheckRecoveryAttempts: 3,
 		}))
 	})
 
+	Describe("health checkers", func() {
+		loadWithChecker := func(checker map[string]interface{}) (config.Config, error) {
+			configContents, err := json.Marshal(map[string]interface{}{
+				"port": 53,
+				"health": map[string]interface{}{
+					"checkers": []map[string]interface{}{checker},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			return config.LoadFromFile(writeConfigFile(string(configContents)))
+		}
+
+		It("loads checkers", func() {
+			dnsConfig, err := loadWithChecker(map[string]interface{}{
+				"instance_group":  "db",
+				"type":            "http",
+				"port":            8080,
+				"path":            "/healthz",
+				"expected_status": 204,
+				"timeout":         "3s",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dnsConfig.Health.Checkers).To(Equal([]config.HealthCheckerConfig{{
+				InstanceGroup:  "db",
+				Type:           "http",
+				Port:           8080,
+				Path:           "/healthz",
+				ExpectedStatus: 204,
+				Timeout:        config.DurationJSON(3 * time.Second),
+			}}))
+		})
+
+		DescribeTable("rejects invalid checkers", func(checker map[string]interface{}, message string) {
+			_, err := loadWithChecker(checker)
+			Expect(err).To(MatchError("invalid health checker #0: " + message))
+		},
+			Entry("no selector", map[string]interface{}{"type": "tcp", "port": 1}, "exactly one of alias or instance_group is required"),
+			Entry("both selectors", map[string]interface{}{"alias": "a.", "instance_group": "b", "type": "tcp", "port": 1}, "exactly one of alias or instance_group is required"),
+			Entry("unknown type", map[string]interface{}{"alias": "a.", "type": "icmp", "port": 1}, "unknown type 'icmp'"),
+			Entry("missing port", map[string]interface{}{"alias": "a.", "type": "tcp"}, "port is required"),
+			Entry("dns without query", map[string]interface{}{"alias": "a.", "type": "dns", "port": 53}, "query is required for dns health checkers"),
+			Entry("ca_file without https", map[string]interface{}{"alias": "a.", "type": "http", "port": 80, "ca_file": "/ca.pem"}, "ca_file and server_name are only supported by https health checkers"),
+			Entry("mtls without health.enabled", map[string]interface{}{"alias": "a.", "type": "mtls", "port": 8853}, "mtls health checkers require health.enabled"),
+		)
+
+		It("loads the CA and server name of https checkers", func() {
+			dnsConfig, err := loadWithChecker(map[string]interface{}{
+				"alias":       "legacy.internal.",
+				"type":        "https",
+				"port":        8443,
+				"ca_file":     "/var/vcap/jobs/legacy/config/ca.pem",
+				"server_name": "legacy.example.com",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dnsConfig.Health.Checkers[0].CAFile).To(Equal("/var/vcap/jobs/legacy/config/ca.pem"))
+			Expect(dnsConfig.Health.Checkers[0].ServerName).To(Equal("legacy.example.com"))
+		})
+	})
+
 	It("returns error if reading config file fails", func() {
 		bogusPath := "some-bogus-path"
 		_, err := config.LoadFromFile(bogusPath)
@@ -140,6 +238,66 @@ var _ = Describe("Config", func() {
 		Expect(err).To(MatchError("port is required"))
 	})
 
+	Context("address", func() {
+		It("accepts a list of addresses", func() {
+			configFilePath := writeConfigFile(`{"address": ["169.254.0.2", "fd00::2"], "port": 53}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Address).To(Equal(config.AddressList{"169.254.0.2", "fd00::2"}))
+			Expect(dnsConfig.ListenAddresses()).To(Equal([]string{"169.254.0.2:53", "[fd00::2]:53"}))
+		})
+
+		It("listens on all interfaces when no address is configured", func() {
+			configFilePath := writeConfigFile(`{"port": 53}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.ListenAddresses()).To(Equal([]string{":53"}))
+		})
+
+		It("accepts IPv6 addresses with a zone", func() {
+			configFilePath := writeConfigFile(`{"address": "fe80::1%eth0", "port": 53}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.ListenAddresses()).To(Equal([]string{"[fe80::1%eth0]:53"}))
+		})
+
+		It("returns error if an address is not an IP", func() {
+			configFilePath := writeConfigFile(`{"address": ["127.0.0.1", "localhost"], "port": 53}`)
+
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).To(MatchError("invalid address 'localhost': not an IP"))
+		})
+	})
+
+	Context("upchecks", func() {
+		It("defaults to three recovery attempts without a recursion check", func() {
+			dnsConfig, err := config.LoadFromFile(writeConfigFile(`{"port": 53}`))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.UpcheckRecoveryAttempts).To(Equal(3))
+			Expect(dnsConfig.UpcheckRecursionDomain).To(BeEmpty())
+		})
+
+		It("allows exiting on the first failure and checking recursion", func() {
+			dnsConfig, err := config.LoadFromFile(writeConfigFile(`{"port": 53, "upcheck_recovery_attempts": 0, "upcheck_recursion_domain": "example.com"}`))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.UpcheckRecoveryAttempts).To(Equal(0))
+			Expect(dnsConfig.UpcheckRecursionDomain).To(Equal("example.com."))
+		})
+
+		It("returns error if the recovery attempts are negative", func() {
+			_, err := config.LoadFromFile(writeConfigFile(`{"port": 53, "upcheck_recovery_attempts": -1}`))
+			Expect(err).To(MatchError("upcheck_recovery_attempts must not be negative"))
+		})
+	})
+
 	Context("recursor_timeout", func() {
 		It("defaults the recursor_timeout when not specified", func() {
 			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53}`)
@@ -151,6 +309,24 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	Context("max_udp_size", func() {
+		It("defaults to a size which avoids fragmentation", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.MaxUDPSize).To(Equal(1232))
+		})
+
+		It("returns error if the size is out of range", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "max_udp_size": 511}`)
+
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).To(MatchError("max_udp_size must be between 512 and 65535"))
+		})
+	})
+
 	Context("records_file", func() {
 		It("allows configuring the path", func() {
 			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "records_file": "/some/path"}`)
@@ -161,6 +337,411 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	Context("records_source", func() {
+		It("allows configuring an endpoint", func() {
+			configFilePath := writeConfigFile(`{"port": 53, "records_source": {"url": "http://127.0.0.1:8054/records", "poll_wait": "10s"}}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dnsConfig.RecordsSource).To(Equal(config.RecordsSource{
+				URL:           "http://127.0.0.1:8054/records",
+				PollWait:      config.DurationJSON(10 * time.Second),
+				RetryInterval: config.DurationJSON(time.Second),
+			}))
+		})
+	})
+
+	Context("zone_transfer", func() {
+		It("allows configuring bosh-dns as a primary", func() {
+			configFilePath := writeConfigFile(`{"port": 53, "zone_transfer": {
+				"enabled": true,
+				"nameservers": ["ns1.example.com."],
+				"secondaries": ["10.0.0.5", "10.1.0.0/16", "fd00::5"],
+				"notify": ["10.0.0.5:53"],
+				"tsig_keys": [{"name": "transfer", "secret": "c2VjcmV0"}],
+				"refresh": "5m"
+			}}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.ZoneTransfer).To(Equal(config.ZoneTransfer{
+				Enabled:     true,
+				Nameservers: []string{"ns1.example.com."},
+				Secondaries: []string{"10.0.0.5", "10.1.0.0/16", "fd00::5"},
+				Notify:      []string{"10.0.0.5:53"},
+				TSIGKeys:    []config.TSIGKey{{Name: "transfer", Algorithm: "hmac-sha256.", Secret: "c2VjcmV0"}},
+				Refresh:     config.DurationJSON(5 * time.Minute),
+				Retry:       config.DurationJSON(10 * time.Second),
+				Expire:      config.DurationJSON(time.Hour),
+			}))
+
+			Expect(dnsConfig.TSIGSecrets()).To(Equal(map[string]string{"transfer.": "c2VjcmV0"}))
+
+			networks, err := dnsConfig.ZoneTransfer.SecondaryNetworks()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(networks).To(HaveLen(3))
+			Expect(networks[0].String()).To(Equal("10.0.0.5/32"))
+			Expect(networks[1].String()).To(Equal("10.1.0.0/16"))
+			Expect(networks[2].String()).To(Equal("fd00::5/128"))
+		})
+
+		DescribeTable("rejects incomplete configuration",
+			func(zoneTransfer, expectedErr string) {
+				configFilePath := writeConfigFile(`{"port": 53, "zone_transfer": ` + zoneTransfer + `}`)
+				_, err := config.LoadFromFile(configFilePath)
+				Expect(err).To(MatchError("invalid zone_transfer: " + expectedErr))
+			},
+			Entry("without nameservers", `{"enabled": true, "secondaries": ["10.0.0.5"], "tsig_keys": [{"name": "k", "secret": "c2VjcmV0"}]}`, "at least one nameserver is required"),
+			Entry("without secondaries", `{"enabled": true, "nameservers": ["ns1."], "tsig_keys": [{"name": "k", "secret": "c2VjcmV0"}]}`, "at least one secondary is required"),
+			Entry("with an invalid secondary", `{"enabled": true, "nameservers": ["ns1."], "secondaries": ["nope"], "tsig_keys": [{"name": "k", "secret": "c2VjcmV0"}]}`, "invalid secondary 'nope': not an IP or CIDR"),
+			Entry("without tsig keys", `{"enabled": true, "nameservers": ["ns1."], "secondaries": ["10.0.0.5"]}`, "at least one tsig key is required"),
+			Entry("with a tsig key without secret", `{"enabled": true, "nameservers": ["ns1."], "secondaries": ["10.0.0.5"], "tsig_keys": [{"name": "k"}]}`, "tsig key #0 requires a name and a secret"),
+			Entry("with an unknown tsig algorithm", `{"enabled": true, "nameservers": ["ns1."], "secondaries": ["10.0.0.5"], "tsig_keys": [{"name": "k", "algorithm": "rot13", "secret": "c2VjcmV0"}]}`, "tsig key 'k' has unsupported algorithm 'rot13'"),
+			Entry("with a notify target without port", `{"enabled": true, "nameservers": ["ns1."], "secondaries": ["10.0.0.5"], "tsig_keys": [{"name": "k", "secret": "c2VjcmV0"}], "notify": ["10.0.0.5"]}`, "invalid notify target '10.0.0.5': address 10.0.0.5: missing port in address"),
+		)
+
+		It("ignores the configuration when disabled", func() {
+			configFilePath := writeConfigFile(`{"port": 53, "zone_transfer": {"enabled": false}}`)
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("dynamic_updates", func() {
+		It("allows accepting updates for zones", func() {
+			configFilePath := writeConfigFile(`{"port": 53, "dynamic_updates": {
+				"enabled": true,
+				"zones": ["dyn.internal."],
+				"tsig_keys": [{"name": "updates", "secret": "c2VjcmV0"}],
+				"persist_file": "/var/vcap/store/bosh-dns/dynamic.json",
+				"max_ttl": "15m"
+			}}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.DynamicUpdates).To(Equal(config.DynamicUpdates{
+				Enabled:     true,
+				Zones:       []string{"dyn.internal."},
+				TSIGKeys:    []config.TSIGKey{{Name: "updates", Algorithm: "hmac-sha256.", Secret: "c2VjcmV0"}},
+				PersistFile: "/var/vcap/store/bosh-dns/dynamic.json",
+				MaxTTL:      config.DurationJSON(15 * time.Minute),
+			}))
+
+			Expect(dnsConfig.TSIGSecrets()).To(Equal(map[string]string{"updates.": "c2VjcmV0"}))
+			Expect(config.TSIGKeyNames(dnsConfig.DynamicUpdates.TSIGKeys)).To(Equal([]string{"updates."}))
+		})
+
+		DescribeTable("rejects incomplete configuration",
+			func(dynamicUpdates, expectedErr string) {
+				configFilePath := writeConfigFile(`{"port": 53, "dynamic_updates": ` + dynamicUpdates + `}`)
+				_, err := config.LoadFromFile(configFilePath)
+				Expect(err).To(MatchError("invalid dynamic_updates: " + expectedErr))
+			},
+			Entry("without zones", `{"enabled": true, "tsig_keys": [{"name": "k", "secret": "c2VjcmV0"}]}`, "at least one zone is required"),
+			Entry("without tsig keys", `{"enabled": true, "zones": ["dyn."]}`, "at least one tsig key is required"),
+			Entry("with a tsig key without secret", `{"enabled": true, "zones": ["dyn."], "tsig_keys": [{"name": "k"}]}`, "tsig key #0 requires a name and a secret"),
+			Entry("with a max ttl below a second", `{"enabled": true, "zones": ["dyn."], "tsig_keys": [{"name": "k", "secret": "c2VjcmV0"}], "max_ttl": "500ms"}`, "max ttl must be at least 1s"),
+		)
+
+		It("merges the keys with those used for zone transfers", func() {
+			configFilePath := writeConfigFile(`{"port": 53,
+				"zone_transfer": {"enabled": true, "nameservers": ["ns1."], "secondaries": ["10.0.0.5"], "tsig_keys": [{"name": "shared", "secret": "c2VjcmV0"}]},
+				"dynamic_updates": {"enabled": true, "zones": ["dyn."], "tsig_keys": [{"name": "shared.", "secret": "c2VjcmV0"}, {"name": "updates", "secret": "dXBkYXRlcw=="}]}
+			}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.TSIGSecrets()).To(Equal(map[string]string{"shared.": "c2VjcmV0", "updates.": "dXBkYXRlcw=="}))
+		})
+
+		It("rejects a key configured with different secrets", func() {
+			configFilePath := writeConfigFile(`{"port": 53,
+				"zone_transfer": {"enabled": true, "nameservers": ["ns1."], "secondaries": ["10.0.0.5"], "tsig_keys": [{"name": "shared", "secret": "c2VjcmV0"}]},
+				"dynamic_updates": {"enabled": true, "zones": ["dyn."], "tsig_keys": [{"name": "shared", "secret": "b3RoZXI="}]}
+			}`)
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).To(MatchError("tsig key 'shared' is configured with different secrets"))
+		})
+
+		It("does not use any keys when disabled", func() {
+			configFilePath := writeConfigFile(`{"port": 53, "dynamic_updates": {"enabled": false}}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.TSIGSecrets()).To(BeNil())
+		})
+	})
+
+	Context("dnssec", func() {
+		It("allows signing with a key while publishing the next one", func() {
+			configFilePath := writeConfigFile(`{"port": 53, "dnssec": {
+				"enabled": true,
+				"keys": [
+					{"public_key_file": "active.key", "private_key_file": "active.private"},
+					{"public_key_file": "next.key", "publish_only": true}
+				],
+				"signature_validity": "72h"
+			}}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.DNSSEC).To(Equal(config.DNSSEC{
+				Enabled: true,
+				Keys: []config.DNSSECKey{
+					{PublicKeyFile: "active.key", PrivateKeyFile: "active.private"},
+					{PublicKeyFile: "next.key", PublishOnly: true},
+				},
+				SignatureValidity: config.DurationJSON(72 * time.Hour),
+			}))
+		})
+
+		DescribeTable("rejects incomplete configuration",
+			func(dnssec, expectedErr string) {
+				configFilePath := writeConfigFile(`{"port": 53, "dnssec": ` + dnssec + `}`)
+				_, err := config.LoadFromFile(configFilePath)
+				Expect(err).To(MatchError("invalid dnssec: " + expectedErr))
+			},
+			Entry("without keys", `{"enabled": true}`, "at least one signing key is required"),
+			Entry("with publish only keys alone", `{"enabled": true, "keys": [{"public_key_file": "next.key", "publish_only": true}]}`, "at least one signing key is required"),
+			Entry("with a key without public key", `{"enabled": true, "keys": [{"private_key_file": "a.private"}]}`, "key #0 requires a public key file"),
+			Entry("with a signing key without private key", `{"enabled": true, "keys": [{"public_key_file": "a.key"}]}`, "key #0 requires a private key file unless it is publish only"),
+			Entry("with a short signature validity", `{"enabled": true, "keys": [{"public_key_file": "a.key", "private_key_file": "a.private"}], "signature_validity": "5m"}`, "signature validity must be at least 1h"),
+		)
+	})
+
+	Context("dnssec_validation", func() {
+		It("loads trust anchors and negative trust anchors", func() {
+			configFilePath := writeConfigFile(`{"port": 53, "dnssec_validation": {
+				"enabled": true,
+				"trust_anchors": [". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D"],
+				"negative_trust_anchors": ["corp.internal."]
+			}}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.DNSSECValidation).To(Equal(config.DNSSECValidation{
+				Enabled:              true,
+				TrustAnchors:         []string{". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D"},
+				NegativeTrustAnchors: []string{"corp.internal."},
+			}))
+		})
+
+		DescribeTable("rejects incomplete configuration",
+			func(validation, expectedErr string) {
+				configFilePath := writeConfigFile(`{"port": 53, "dnssec_validation": ` + validation + `}`)
+				_, err := config.LoadFromFile(configFilePath)
+				Expect(err).To(MatchError("invalid dnssec_validation: " + expectedErr))
+			},
+			Entry("without trust anchors", `{"enabled": true}`, "at least one trust anchor is required"),
+			Entry("with an empty negative trust anchor", `{"enabled": true, "trust_anchors": ["a"], "negative_trust_anchors": [""]}`, "negative trust anchor #0 is not a domain name"),
+		)
+	})
+
+	Context("rate_limit", func() {
+		It("loads limits and exemptions", func() {
+			configFilePath := writeConfigFile(`{"port": 53, "rate_limit": {
+				"enabled": true,
+				"queries_per_second": 100,
+				"recursive_queries_per_second": 20,
+				"responses_per_second": 5,
+				"slip": 1,
+				"exempt": ["10.0.0.0/8", "::1"]
+			}}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.RateLimit).To(Equal(config.RateLimit{
+				Enabled:                   true,
+				QueriesPerSecond:          100,
+				RecursiveQueriesPerSecond: 20,
+				ResponsesPerSecond:        5,
+				Slip:                      1,
+				IPv4PrefixLength:          24,
+				IPv6PrefixLength:          56,
+				Exempt:                    []string{"10.0.0.0/8", "::1"},
+			}))
+
+			networks, err := dnsConfig.RateLimit.ExemptNetworks()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(networks[0].String()).To(Equal("10.0.0.0/8"))
+			Expect(networks[1].String()).To(Equal("::1/128"))
+		})
+
+		DescribeTable("rejects invalid configuration",
+			func(rateLimit, expectedErr string) {
+				configFilePath := writeConfigFile(`{"port": 53, "rate_limit": ` + rateLimit + `}`)
+				_, err := config.LoadFromFile(configFilePath)
+				Expect(err).To(MatchError("invalid rate_limit: " + expectedErr))
+			},
+			Entry("with a negative rate", `{"enabled": true, "responses_per_second": -1}`, "rates must not be negative"),
+			Entry("with a large slip", `{"enabled": true, "slip": 11}`, "slip must be between 0 and 10"),
+			Entry("with an invalid ipv4 prefix length", `{"enabled": true, "ipv4_prefix_length": 33}`, "ipv4 prefix length must be between 1 and 32"),
+			Entry("with an invalid ipv6 prefix length", `{"enabled": true, "ipv6_prefix_length": 0}`, "ipv6 prefix length must be between 1 and 128"),
+			Entry("with an invalid exemption", `{"enabled": true, "exempt": ["nope"]}`, "invalid exemption 'nope': not an IP or CIDR"),
+		)
+	})
+
+	Context("acl", func() {
+		It("loads the ACLs of the listener and of recursion", func() {
+			configFilePath := writeConfigFile(`{"port": 53,
+				"acl": {"deny": ["192.0.2.66"]},
+				"recursion_acl": {"allow": ["10.0.0.0/8"]}
+			}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.ACL).To(Equal(config.ACL{Deny: []string{"192.0.2.66"}}))
+			Expect(dnsConfig.RecursionACL).To(Equal(config.ACL{Allow: []string{"10.0.0.0/8"}}))
+
+			allow, deny, err := dnsConfig.ACL.Networks()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(allow).To(BeEmpty())
+			Expect(deny[0].String()).To(Equal("192.0.2.66/32"))
+		})
+
+		It("returns error if a network is invalid", func() {
+			_, err := config.LoadFromFile(writeConfigFile(`{"port": 53, "acl": {"allow": ["10.0.0.0/33"]}}`))
+			Expect(err).To(MatchError("invalid acl: invalid allowed network '10.0.0.0/33': not an IP or CIDR"))
+
+			_, err = config.LoadFromFile(writeConfigFile(`{"port": 53, "recursion_acl": {"deny": ["nope"]}}`))
+			Expect(err).To(MatchError("invalid recursion_acl: invalid denied network 'nope': not an IP or CIDR"))
+		})
+
+		It("loads ACLs of single listen addresses", func() {
+			configFilePath := writeConfigFile(`{"port": 53, "address": ["169.254.0.2", "10.0.0.5"],
+				"acl": {"deny": ["192.0.2.66"]},
+				"address_acls": {"10.0.0.5": {"allow": ["10.0.0.0/8"]}}
+			}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.ACLFor("10.0.0.5")).To(Equal(config.ACL{Allow: []string{"10.0.0.0/8"}}))
+			Expect(dnsConfig.ACLFor("169.254.0.2")).To(Equal(config.ACL{Deny: []string{"192.0.2.66"}}))
+		})
+
+		It("returns error if an ACL is given for an address which is not listened on", func() {
+			_, err := config.LoadFromFile(writeConfigFile(`{"port": 53, "address": "169.254.0.2", "address_acls": {"10.0.0.5": {"allow": ["10.0.0.0/8"]}}}`))
+			Expect(err).To(MatchError("invalid address_acls: '10.0.0.5' is not a listen address"))
+		})
+
+		It("returns error if a network of an address ACL is invalid", func() {
+			_, err := config.LoadFromFile(writeConfigFile(`{"port": 53, "address": "169.254.0.2", "address_acls": {"169.254.0.2": {"deny": ["nope"]}}}`))
+			Expect(err).To(MatchError("invalid address_acls of '169.254.0.2': invalid denied network 'nope': not an IP or CIDR"))
+		})
+	})
+
+	Context("handoff", func() {
+		BeforeEach(func() {
+			if runtime.GOOS == "windows" {
+				Skip("socket handoff is not supported on windows")
+			}
+		})
+
+		It("loads the socket and wait", func() {
+			configFilePath := writeConfigFile(`{"port": 53, "handoff": {"enabled": true, "socket": "/var/run/handoff.sock", "wait": "5s"}}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Handoff).To(Equal(config.Handoff{
+				Enabled: true,
+				Socket:  "/var/run/handoff.sock",
+				Wait:    config.DurationJSON(5 * time.Second),
+			}))
+		})
+
+		It("returns error if the socket is missing", func() {
+			_, err := config.LoadFromFile(writeConfigFile(`{"port": 53, "handoff": {"enabled": true}}`))
+			Expect(err).To(MatchError("invalid handoff: socket is required"))
+		})
+	})
+
+	Context("handoff on windows", func() {
+		BeforeEach(func() {
+			if runtime.GOOS != "windows" {
+				Skip("socket handoff is only rejected on windows")
+			}
+		})
+
+		It("returns error when handoff is enabled", func() {
+			_, err := config.LoadFromFile(writeConfigFile(`{"port": 53, "handoff": {"enabled": true, "socket": "C:\\handoff.sock"}}`))
+			Expect(err).To(MatchError("invalid handoff: socket handoff is not supported on windows"))
+		})
+	})
+
+	Context("metrics", func() {
+		It("loads the address and port", func() {
+			dnsConfig, err := config.LoadFromFile(writeConfigFile(`{"port": 53, "metrics": {"enabled": true, "address": "0.0.0.0", "port": 9100}}`))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Metrics).To(Equal(config.Metrics{Enabled: true, Address: "0.0.0.0", Port: 9100}))
+		})
+
+		DescribeTable("rejects invalid endpoints",
+			func(metrics, expectedErr string) {
+				_, err := config.LoadFromFile(writeConfigFile(`{"port": 53, "metrics": ` + metrics + `}`))
+				Expect(err).To(MatchError("invalid metrics: " + expectedErr))
+			},
+			Entry("with a host name", `{"enabled": true, "address": "localhost"}`, "address 'localhost' is not an IP"),
+			Entry("with a port out of range", `{"enabled": true, "port": 70000}`, "port 70000 is out of range"),
+		)
+	})
+
+	Context("resolver", func() {
+		It("loads the search domains and options", func() {
+			configFilePath := writeConfigFile(`{"port": 53, "resolver": {"search": ["service.internal", "bosh"], "options": ["ndots:2", "rotate"]}}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Resolver).To(Equal(config.Resolver{
+				Search:  []string{"service.internal", "bosh"},
+				Options: []string{"ndots:2", "rotate"},
+			}))
+		})
+
+		It("returns error if a search domain or option is not a single word", func() {
+			_, err := config.LoadFromFile(writeConfigFile(`{"port": 53, "resolver": {"search": ["service.internal bosh"]}}`))
+			Expect(err).To(MatchError("invalid resolver: invalid search domain 'service.internal bosh'"))
+
+			_, err = config.LoadFromFile(writeConfigFile(`{"port": 53, "resolver": {"options": [""]}}`))
+			Expect(err).To(MatchError("invalid resolver: invalid option ''"))
+		})
+	})
+
+	Context("search", func() {
+		It("loads the suffixes as fully qualified names", func() {
+			configFilePath := writeConfigFile(`{"port": 53, "search": {"suffixes": ["service.internal", "bosh."], "own_network": true, "ndots": 2}}`)
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Search).To(Equal(config.Search{
+				Suffixes:   []string{"service.internal.", "bosh."},
+				OwnNetwork: true,
+				NDots:      2,
+			}))
+			Expect(dnsConfig.Search.Enabled()).To(BeTrue())
+		})
+
+		It("is disabled by default", func() {
+			dnsConfig, err := config.LoadFromFile(writeConfigFile(`{"port": 53}`))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Search.Enabled()).To(BeFalse())
+		})
+
+		It("returns error if a suffix is not a domain name", func() {
+			_, err := config.LoadFromFile(writeConfigFile(`{"port": 53, "search": {"suffixes": ["service..internal"]}}`))
+			Expect(err).To(MatchError("invalid search: invalid suffix 'service..internal'"))
+
+			_, err = config.LoadFromFile(writeConfigFile(`{"port": 53, "search": {"suffixes": ["."]}}`))
+			Expect(err).To(MatchError("invalid search: invalid suffix '.'"))
+		})
+
+		It("returns error if ndots is not positive", func() {
+			_, err := config.LoadFromFile(writeConfigFile(`{"port": 53, "search": {"ndots": 0}}`))
+			Expect(err).To(MatchError("invalid search: ndots must be positive"))
+		})
+	})
+
 	Context("health.max_tracked_queries", func() {
 		It("defaults to 2000", func() {
 			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53}`)
@@ -26,7 +26,12 @@ func (l FSLoader) Load(filename string) (HandlerConfigs, error) {
 	}
 
 	for i := range handlers {
-		handlers[i].Source.Recursors, err = config.AppendDefaultDNSPortIfMissing(handlers[i].Source.Recursors)
+		port := "53"
+		if handlers[i].Source.TLSServerName != "" {
+			port = "853"
+		}
+
+		handlers[i].Source.Recursors, err = config.AppendDefaultPortIfMissing(handlers[i].Source.Recursors, port)
 		if err != nil {
 			return nil, err
 		}
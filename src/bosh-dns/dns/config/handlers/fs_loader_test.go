@@ -69,6 +69,39 @@ var _ = Describe("FSLoader", func() {
 				Expect(config[0].Source.Recursors).To(ContainElement("8.8.8.8:53"))
 				Expect(config[0].Source.Recursors).To(ContainElement("10.244.4.4:9700"))
 			})
+
+			It("defaults to the DNS over TLS port for TLS sources", func() {
+				fs.WriteFileString("/test/handlers.json", `[
+					{
+						"domain": "local.internal2.",
+						"source": { "type": "dns", "recursors": [ "9.9.9.9" ], "tls_server_name": "dns.quad9.net" },
+						"edns": { "client_subnet": { "mode": "strip" }, "padding": true }
+					}
+				]`)
+
+				config, err := parser.Load("/test/handlers.json")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config[0].Source.Recursors).To(Equal([]string{"9.9.9.9:853"}))
+				Expect(config[0].EDNS.ClientSubnet.Mode).To(Equal("strip"))
+				Expect(config[0].EDNS.Padding).To(BeTrue())
+			})
+
+			It("loads ACLs", func() {
+				fs.WriteFileString("/test/handlers.json", `[
+					{
+						"domain": "corp.internal.",
+						"source": { "type": "dns", "recursors": [ "10.0.0.2" ] },
+						"acl": { "allow": [ "10.0.0.0/8" ], "deny": [ "10.1.2.3" ] }
+					}
+				]`)
+
+				config, err := parser.Load("/test/handlers.json")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config[0].ACL.Allow).To(Equal([]string{"10.0.0.0/8"}))
+				Expect(config[0].ACL.Deny).To(Equal([]string{"10.1.2.3"}))
+			})
 		})
 
 		Context("missing file", func() {
@@ -3,6 +3,7 @@ package handlers
 import (
 	"bosh-dns/dns/config"
 	"fmt"
+	"net"
 
 	"github.com/miekg/dns"
 )
@@ -10,7 +11,8 @@ import (
 //go:generate counterfeiter . HandlerFactory
 type HandlerFactory interface {
 	CreateHTTPJSONHandler(string, bool) dns.Handler
-	CreateForwardHandler([]string, bool) dns.Handler
+	CreateForwardHandler([]string, string, config.EDNS, bool) dns.Handler
+	CreateACLHandler(dns.Handler, []*net.IPNet, []*net.IPNet) dns.Handler
 }
 
 type HandlerConfigs []HandlerConfig
@@ -19,12 +21,18 @@ type HandlerConfig struct {
 	Domain string       `json:"domain"`
 	Source Source       `json:"source"`
 	Cache  config.Cache `json:"cache,omitempty"`
+	EDNS   config.EDNS  `json:"edns,omitempty"`
+	ACL    config.ACL   `json:"acl,omitempty"`
 }
 
 type Source struct {
 	Type      string   `json:"type"`
 	URL       string   `json:"url,omitempty"`
 	Recursors []string `json:"recursors,omitempty"`
+
+	// TLSServerName queries the recursors over DNS over TLS (RFC 7858),
+	// verifying their certificates against this name.
+	TLSServerName string `json:"tls_server_name,omitempty"`
 }
 
 func (c HandlerConfigs) GenerateHandlers(factory HandlerFactory) (map[string]dns.Handler, error) {
@@ -44,11 +52,32 @@ func (c HandlerConfigs) GenerateHandlers(factory HandlerFactory) (map[string]dns
 				return nil, fmt.Errorf(`Configuring handler for "%s": No recursors present`, handlerConfig.Domain)
 			}
 
-			handler = factory.CreateForwardHandler(handlerConfig.Source.Recursors, handlerConfig.Cache.Enabled)
+			if err := handlerConfig.EDNS.ClientSubnet.Validate(); err != nil {
+				return nil, fmt.Errorf(`Configuring handler for "%s": %s`, handlerConfig.Domain, err)
+			}
+
+			if handlerConfig.EDNS.ClientSubnet.Mode == config.ClientSubnetAdd && handlerConfig.Cache.Enabled {
+				return nil, fmt.Errorf(`Configuring handler for "%s": Adding the client subnet requires the cache to be disabled, cached answers would be served to other subnets`, handlerConfig.Domain)
+			}
+
+			if handlerConfig.EDNS.Padding && handlerConfig.Source.TLSServerName == "" {
+				return nil, fmt.Errorf(`Configuring handler for "%s": Padding requires recursors queried over TLS`, handlerConfig.Domain)
+			}
+
+			handler = factory.CreateForwardHandler(handlerConfig.Source.Recursors, handlerConfig.Source.TLSServerName, handlerConfig.EDNS, handlerConfig.Cache.Enabled)
 		} else {
 			return nil, fmt.Errorf(`Configuring handler for "%s": Unexpected handler source type: %s`, handlerConfig.Domain, handlerConfig.Source.Type)
 		}
 
+		if !handlerConfig.ACL.Empty() {
+			allow, deny, err := handlerConfig.ACL.Networks()
+			if err != nil {
+				return nil, fmt.Errorf(`Configuring handler for "%s": %s`, handlerConfig.Domain, err)
+			}
+
+			handler = factory.CreateACLHandler(handler, allow, deny)
+		}
+
 		realHandlers[handlerConfig.Domain] = handler
 	}
 	return realHandlers, nil
@@ -1,6 +1,7 @@
 package handlers_test
 
 import (
+	"bosh-dns/dns/config"
 	. "bosh-dns/dns/config/handlers"
 	. "bosh-dns/dns/config/handlers/handlersfakes"
 
@@ -110,11 +111,97 @@ var _ = Describe("Handlers Configuration", func() {
 					Expect(len(handlers)).To(Equal(1))
 					Expect(handlers["my-tld."]).To(Equal(fakeDnsHandler))
 
-					recursors, enableCache := fakeHandlerFactory.CreateForwardHandlerArgsForCall(0)
+					recursors, tlsServerName, edns, enableCache := fakeHandlerFactory.CreateForwardHandlerArgsForCall(0)
 					Expect(recursors).To(Equal([]string{"some-recursor", "another-recursor"}))
+					Expect(tlsServerName).To(BeEmpty())
+					Expect(edns).To(Equal(config.EDNS{}))
 					Expect(enableCache).To(Equal(false))
 				})
 
+				Context("with EDNS options and TLS", func() {
+					BeforeEach(func() {
+						handlersConfig[0].Source.TLSServerName = "dns.example.com"
+						handlersConfig[0].EDNS = config.EDNS{
+							ClientSubnet: config.ClientSubnet{Mode: "add", IPv4PrefixLength: 20},
+							Padding:      true,
+						}
+					})
+
+					It("passes them to the factory", func() {
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).NotTo(HaveOccurred())
+
+						_, tlsServerName, edns, _ := fakeHandlerFactory.CreateForwardHandlerArgsForCall(0)
+						Expect(tlsServerName).To(Equal("dns.example.com"))
+						Expect(edns).To(Equal(handlersConfig[0].EDNS))
+					})
+
+					It("rejects padding without TLS", func() {
+						handlersConfig[0].Source.TLSServerName = ""
+
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).To(MatchError(`Configuring handler for "my-tld.": Padding requires recursors queried over TLS`))
+					})
+
+					It("rejects unknown client subnet modes", func() {
+						handlersConfig[0].EDNS.ClientSubnet.Mode = "replace"
+
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).To(MatchError(`Configuring handler for "my-tld.": unknown client subnet mode 'replace'`))
+					})
+
+					It("rejects prefix lengths longer than an address", func() {
+						handlersConfig[0].EDNS.ClientSubnet.IPv6PrefixLength = 129
+
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).To(MatchError(`Configuring handler for "my-tld.": client subnet ipv6 prefix length must be between 0 and 128`))
+					})
+
+					It("rejects adding the client subnet to cached handlers", func() {
+						handlersConfig[0].Cache.Enabled = true
+
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).To(MatchError(`Configuring handler for "my-tld.": Adding the client subnet requires the cache to be disabled, cached answers would be served to other subnets`))
+					})
+
+					It("allows stripping the client subnet from cached handlers", func() {
+						handlersConfig[0].Cache.Enabled = true
+						handlersConfig[0].EDNS.ClientSubnet.Mode = "strip"
+
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).NotTo(HaveOccurred())
+					})
+				})
+
+				Context("with an ACL", func() {
+					var fakeACLHandler *FakeDnsHandler
+
+					BeforeEach(func() {
+						fakeACLHandler = &FakeDnsHandler{}
+						fakeHandlerFactory.CreateACLHandlerReturns(fakeACLHandler)
+
+						handlersConfig[0].ACL = config.ACL{Allow: []string{"10.0.0.0/8"}, Deny: []string{"10.0.0.1"}}
+					})
+
+					It("wraps the handler with the ACL", func() {
+						handlers, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(handlers["my-tld."]).To(Equal(fakeACLHandler))
+
+						handler, allow, deny := fakeHandlerFactory.CreateACLHandlerArgsForCall(0)
+						Expect(handler).To(Equal(fakeDnsHandler))
+						Expect(allow[0].String()).To(Equal("10.0.0.0/8"))
+						Expect(deny[0].String()).To(Equal("10.0.0.1/32"))
+					})
+
+					It("rejects invalid networks", func() {
+						handlersConfig[0].ACL.Allow = []string{"corp"}
+
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).To(MatchError(`Configuring handler for "my-tld.": invalid allowed network 'corp': not an IP or CIDR`))
+					})
+				})
+
 				Context("but with no recursors declared", func() {
 					BeforeEach(func() {
 						handlersConfig[0].Source.Recursors = []string{}
@@ -2,30 +2,35 @@
 package handlersfakes
 
 import (
+	"bosh-dns/dns/config"
 	"bosh-dns/dns/config/handlers"
+	"net"
 	"sync"
 
 	"github.com/miekg/dns"
 )
 
 type FakeHandlerFactory struct {
-	CreateHTTPJSONHandlerStub        func(string, bool) dns.Handler
-	createHTTPJSONHandlerMutex       sync.RWMutex
-	createHTTPJSONHandlerArgsForCall []struct {
-		arg1 string
-		arg2 bool
+	CreateACLHandlerStub        func(dns.Handler, []*net.IPNet, []*net.IPNet) dns.Handler
+	createACLHandlerMutex       sync.RWMutex
+	createACLHandlerArgsForCall []struct {
+		arg1 dns.Handler
+		arg2 []*net.IPNet
+		arg3 []*net.IPNet
 	}
-	createHTTPJSONHandlerReturns struct {
+	createACLHandlerReturns struct {
 		result1 dns.Handler
 	}
-	createHTTPJSONHandlerReturnsOnCall map[int]struct {
+	createACLHandlerReturnsOnCall map[int]struct {
 		result1 dns.Handler
 	}
-	CreateForwardHandlerStub        func([]string, bool) dns.Handler
+	CreateForwardHandlerStub        func([]string, string, config.EDNS, bool) dns.Handler
 	createForwardHandlerMutex       sync.RWMutex
 	createForwardHandlerArgsForCall []struct {
 		arg1 []string
-		arg2 bool
+		arg2 string
+		arg3 config.EDNS
+		arg4 bool
 	}
 	createForwardHandlerReturns struct {
 		result1 dns.Handler
@@ -33,60 +38,96 @@ type FakeHandlerFactory struct {
 	createForwardHandlerReturnsOnCall map[int]struct {
 		result1 dns.Handler
 	}
+	CreateHTTPJSONHandlerStub        func(string, bool) dns.Handler
+	createHTTPJSONHandlerMutex       sync.RWMutex
+	createHTTPJSONHandlerArgsForCall []struct {
+		arg1 string
+		arg2 bool
+	}
+	createHTTPJSONHandlerReturns struct {
+		result1 dns.Handler
+	}
+	createHTTPJSONHandlerReturnsOnCall map[int]struct {
+		result1 dns.Handler
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeHandlerFactory) CreateHTTPJSONHandler(arg1 string, arg2 bool) dns.Handler {
-	fake.createHTTPJSONHandlerMutex.Lock()
-	ret, specificReturn := fake.createHTTPJSONHandlerReturnsOnCall[len(fake.createHTTPJSONHandlerArgsForCall)]
-	fake.createHTTPJSONHandlerArgsForCall = append(fake.createHTTPJSONHandlerArgsForCall, struct {
-		arg1 string
-		arg2 bool
-	}{arg1, arg2})
-	fake.recordInvocation("CreateHTTPJSONHandler", []interface{}{arg1, arg2})
-	fake.createHTTPJSONHandlerMutex.Unlock()
-	if fake.CreateHTTPJSONHandlerStub != nil {
-		return fake.CreateHTTPJSONHandlerStub(arg1, arg2)
+func (fake *FakeHandlerFactory) CreateACLHandler(arg1 dns.Handler, arg2 []*net.IPNet, arg3 []*net.IPNet) dns.Handler {
+	var arg2Copy []*net.IPNet
+	if arg2 != nil {
+		arg2Copy = make([]*net.IPNet, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	var arg3Copy []*net.IPNet
+	if arg3 != nil {
+		arg3Copy = make([]*net.IPNet, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.createACLHandlerMutex.Lock()
+	ret, specificReturn := fake.createACLHandlerReturnsOnCall[len(fake.createACLHandlerArgsForCall)]
+	fake.createACLHandlerArgsForCall = append(fake.createACLHandlerArgsForCall, struct {
+		arg1 dns.Handler
+		arg2 []*net.IPNet
+		arg3 []*net.IPNet
+	}{arg1, arg2Copy, arg3Copy})
+	stub := fake.CreateACLHandlerStub
+	fakeReturns := fake.createACLHandlerReturns
+	fake.recordInvocation("CreateACLHandler", []interface{}{arg1, arg2Copy, arg3Copy})
+	fake.createACLHandlerMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	return fake.createHTTPJSONHandlerReturns.result1
+	return fakeReturns.result1
 }
 
-func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerCallCount() int {
-	fake.createHTTPJSONHandlerMutex.RLock()
-	defer fake.createHTTPJSONHandlerMutex.RUnlock()
-	return len(fake.createHTTPJSONHandlerArgsForCall)
+func (fake *FakeHandlerFactory) CreateACLHandlerCallCount() int {
+	fake.createACLHandlerMutex.RLock()
+	defer fake.createACLHandlerMutex.RUnlock()
+	return len(fake.createACLHandlerArgsForCall)
 }
 
-func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerArgsForCall(i int) (string, bool) {
-	fake.createHTTPJSONHandlerMutex.RLock()
-	defer fake.createHTTPJSONHandlerMutex.RUnlock()
-	return fake.createHTTPJSONHandlerArgsForCall[i].arg1, fake.createHTTPJSONHandlerArgsForCall[i].arg2
+func (fake *FakeHandlerFactory) CreateACLHandlerCalls(stub func(dns.Handler, []*net.IPNet, []*net.IPNet) dns.Handler) {
+	fake.createACLHandlerMutex.Lock()
+	defer fake.createACLHandlerMutex.Unlock()
+	fake.CreateACLHandlerStub = stub
 }
 
-func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerReturns(result1 dns.Handler) {
-	fake.CreateHTTPJSONHandlerStub = nil
-	fake.createHTTPJSONHandlerReturns = struct {
+func (fake *FakeHandlerFactory) CreateACLHandlerArgsForCall(i int) (dns.Handler, []*net.IPNet, []*net.IPNet) {
+	fake.createACLHandlerMutex.RLock()
+	defer fake.createACLHandlerMutex.RUnlock()
+	argsForCall := fake.createACLHandlerArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeHandlerFactory) CreateACLHandlerReturns(result1 dns.Handler) {
+	fake.createACLHandlerMutex.Lock()
+	defer fake.createACLHandlerMutex.Unlock()
+	fake.CreateACLHandlerStub = nil
+	fake.createACLHandlerReturns = struct {
 		result1 dns.Handler
 	}{result1}
 }
 
-func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerReturnsOnCall(i int, result1 dns.Handler) {
-	fake.CreateHTTPJSONHandlerStub = nil
-	if fake.createHTTPJSONHandlerReturnsOnCall == nil {
-		fake.createHTTPJSONHandlerReturnsOnCall = make(map[int]struct {
+func (fake *FakeHandlerFactory) CreateACLHandlerReturnsOnCall(i int, result1 dns.Handler) {
+	fake.createACLHandlerMutex.Lock()
+	defer fake.createACLHandlerMutex.Unlock()
+	fake.CreateACLHandlerStub = nil
+	if fake.createACLHandlerReturnsOnCall == nil {
+		fake.createACLHandlerReturnsOnCall = make(map[int]struct {
 			result1 dns.Handler
 		})
 	}
-	fake.createHTTPJSONHandlerReturnsOnCall[i] = struct {
+	fake.createACLHandlerReturnsOnCall[i] = struct {
 		result1 dns.Handler
 	}{result1}
 }
 
-func (fake *FakeHandlerFactory) CreateForwardHandler(arg1 []string, arg2 bool) dns.Handler {
+func (fake *FakeHandlerFactory) CreateForwardHandler(arg1 []string, arg2 string, arg3 config.EDNS, arg4 bool) dns.Handler {
 	var arg1Copy []string
 	if arg1 != nil {
 		arg1Copy = make([]string, len(arg1))
@@ -96,17 +137,21 @@ func (fake *FakeHandlerFactory) CreateForwardHandler(arg1 []string, arg2 bool) d
 	ret, specificReturn := fake.createForwardHandlerReturnsOnCall[len(fake.createForwardHandlerArgsForCall)]
 	fake.createForwardHandlerArgsForCall = append(fake.createForwardHandlerArgsForCall, struct {
 		arg1 []string
-		arg2 bool
-	}{arg1Copy, arg2})
-	fake.recordInvocation("CreateForwardHandler", []interface{}{arg1Copy, arg2})
+		arg2 string
+		arg3 config.EDNS
+		arg4 bool
+	}{arg1Copy, arg2, arg3, arg4})
+	stub := fake.CreateForwardHandlerStub
+	fakeReturns := fake.createForwardHandlerReturns
+	fake.recordInvocation("CreateForwardHandler", []interface{}{arg1Copy, arg2, arg3, arg4})
 	fake.createForwardHandlerMutex.Unlock()
-	if fake.CreateForwardHandlerStub != nil {
-		return fake.CreateForwardHandlerStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	return fake.createForwardHandlerReturns.result1
+	return fakeReturns.result1
 }
 
 func (fake *FakeHandlerFactory) CreateForwardHandlerCallCount() int {
@@ -115,13 +160,22 @@ func (fake *FakeHandlerFactory) CreateForwardHandlerCallCount() int {
 	return len(fake.createForwardHandlerArgsForCall)
 }
 
-func (fake *FakeHandlerFactory) CreateForwardHandlerArgsForCall(i int) ([]string, bool) {
+func (fake *FakeHandlerFactory) CreateForwardHandlerCalls(stub func([]string, string, config.EDNS, bool) dns.Handler) {
+	fake.createForwardHandlerMutex.Lock()
+	defer fake.createForwardHandlerMutex.Unlock()
+	fake.CreateForwardHandlerStub = stub
+}
+
+func (fake *FakeHandlerFactory) CreateForwardHandlerArgsForCall(i int) ([]string, string, config.EDNS, bool) {
 	fake.createForwardHandlerMutex.RLock()
 	defer fake.createForwardHandlerMutex.RUnlock()
-	return fake.createForwardHandlerArgsForCall[i].arg1, fake.createForwardHandlerArgsForCall[i].arg2
+	argsForCall := fake.createForwardHandlerArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeHandlerFactory) CreateForwardHandlerReturns(result1 dns.Handler) {
+	fake.createForwardHandlerMutex.Lock()
+	defer fake.createForwardHandlerMutex.Unlock()
 	fake.CreateForwardHandlerStub = nil
 	fake.createForwardHandlerReturns = struct {
 		result1 dns.Handler
@@ -129,6 +183,8 @@ func (fake *FakeHandlerFactory) CreateForwardHandlerReturns(result1 dns.Handler)
 }
 
 func (fake *FakeHandlerFactory) CreateForwardHandlerReturnsOnCall(i int, result1 dns.Handler) {
+	fake.createForwardHandlerMutex.Lock()
+	defer fake.createForwardHandlerMutex.Unlock()
 	fake.CreateForwardHandlerStub = nil
 	if fake.createForwardHandlerReturnsOnCall == nil {
 		fake.createForwardHandlerReturnsOnCall = make(map[int]struct {
@@ -140,13 +196,77 @@ func (fake *FakeHandlerFactory) CreateForwardHandlerReturnsOnCall(i int, result1
 	}{result1}
 }
 
+func (fake *FakeHandlerFactory) CreateHTTPJSONHandler(arg1 string, arg2 bool) dns.Handler {
+	fake.createHTTPJSONHandlerMutex.Lock()
+	ret, specificReturn := fake.createHTTPJSONHandlerReturnsOnCall[len(fake.createHTTPJSONHandlerArgsForCall)]
+	fake.createHTTPJSONHandlerArgsForCall = append(fake.createHTTPJSONHandlerArgsForCall, struct {
+		arg1 string
+		arg2 bool
+	}{arg1, arg2})
+	stub := fake.CreateHTTPJSONHandlerStub
+	fakeReturns := fake.createHTTPJSONHandlerReturns
+	fake.recordInvocation("CreateHTTPJSONHandler", []interface{}{arg1, arg2})
+	fake.createHTTPJSONHandlerMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerCallCount() int {
+	fake.createHTTPJSONHandlerMutex.RLock()
+	defer fake.createHTTPJSONHandlerMutex.RUnlock()
+	return len(fake.createHTTPJSONHandlerArgsForCall)
+}
+
+func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerCalls(stub func(string, bool) dns.Handler) {
+	fake.createHTTPJSONHandlerMutex.Lock()
+	defer fake.createHTTPJSONHandlerMutex.Unlock()
+	fake.CreateHTTPJSONHandlerStub = stub
+}
+
+func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerArgsForCall(i int) (string, bool) {
+	fake.createHTTPJSONHandlerMutex.RLock()
+	defer fake.createHTTPJSONHandlerMutex.RUnlock()
+	argsForCall := fake.createHTTPJSONHandlerArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerReturns(result1 dns.Handler) {
+	fake.createHTTPJSONHandlerMutex.Lock()
+	defer fake.createHTTPJSONHandlerMutex.Unlock()
+	fake.CreateHTTPJSONHandlerStub = nil
+	fake.createHTTPJSONHandlerReturns = struct {
+		result1 dns.Handler
+	}{result1}
+}
+
+func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerReturnsOnCall(i int, result1 dns.Handler) {
+	fake.createHTTPJSONHandlerMutex.Lock()
+	defer fake.createHTTPJSONHandlerMutex.Unlock()
+	fake.CreateHTTPJSONHandlerStub = nil
+	if fake.createHTTPJSONHandlerReturnsOnCall == nil {
+		fake.createHTTPJSONHandlerReturnsOnCall = make(map[int]struct {
+			result1 dns.Handler
+		})
+	}
+	fake.createHTTPJSONHandlerReturnsOnCall[i] = struct {
+		result1 dns.Handler
+	}{result1}
+}
+
 func (fake *FakeHandlerFactory) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.createHTTPJSONHandlerMutex.RLock()
-	defer fake.createHTTPJSONHandlerMutex.RUnlock()
+	fake.createACLHandlerMutex.RLock()
+	defer fake.createACLHandlerMutex.RUnlock()
 	fake.createForwardHandlerMutex.RLock()
 	defer fake.createForwardHandlerMutex.RUnlock()
+	fake.createHTTPJSONHandlerMutex.RLock()
+	defer fake.createHTTPJSONHandlerMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -1,23 +1,23 @@
 package config
 
 import (
-	"fmt"
+	"net"
 
 	"bosh-dns/dns/manager"
 )
 
-const loopbackAddress = "127.0.0.1"
+var loopbackAddresses = []string{"127.0.0.1", "::1"}
 
-func NewRecursorReader(dnsManager manager.DNSManager, dnsNameServer string) recursorReader {
+func NewRecursorReader(dnsManager manager.DNSManager, dnsNameServers []string) recursorReader {
 	return recursorReader{
-		manager:       dnsManager,
-		dnsNameServer: dnsNameServer,
+		manager:        dnsManager,
+		dnsNameServers: dnsNameServers,
 	}
 }
 
 type recursorReader struct {
-	manager       manager.DNSManager
-	dnsNameServer string
+	manager        manager.DNSManager
+	dnsNameServers []string
 }
 
 func (r recursorReader) Get() ([]string, error) {
@@ -29,10 +29,20 @@ func (r recursorReader) Get() ([]string, error) {
 	}
 
 	for _, server := range nameservers {
-		if server != r.dnsNameServer && server != loopbackAddress {
-			recursors = append(recursors, fmt.Sprintf("%s:53", server))
+		if !contains(r.dnsNameServers, server) && !contains(loopbackAddresses, server) {
+			recursors = append(recursors, net.JoinHostPort(server, "53"))
 		}
 	}
 
 	return recursors, nil
 }
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
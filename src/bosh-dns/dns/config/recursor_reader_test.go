@@ -22,7 +22,7 @@ var _ = Describe("RecursorReader", func() {
 		dnsServerDomainName = "dns-server-hostname"
 		loopackAddress = "127.0.0.1"
 		dnsManager = new(managerfakes.FakeDNSManager)
-		recursorReader = NewRecursorReader(dnsManager, dnsServerDomainName)
+		recursorReader = NewRecursorReader(dnsManager, []string{dnsServerDomainName, "fd00::2"})
 	})
 
 	Context("when there are no dns servers", func() {
@@ -78,6 +78,19 @@ var _ = Describe("RecursorReader", func() {
 		})
 	})
 
+	Context("when the DNS server listens on several addresses", func() {
+		BeforeEach(func() {
+			dnsManager.ReadReturns([]string{"fd00::2", "::1", "recursor-1", "fd00::53"}, nil)
+		})
+
+		It("excludes every address and IPv6 loopback, and brackets IPv6 recursors", func() {
+			recursors, err := recursorReader.Get()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recursors).To(ConsistOf("recursor-1:53", "[fd00::53]:53"))
+		})
+	})
+
 	Context("when reading configuration errors", func() {
 		var readErr error
 
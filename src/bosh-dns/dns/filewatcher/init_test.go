@@ -0,0 +1,13 @@
+package filewatcher_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFileWatcher(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "dns/filewatcher")
+}
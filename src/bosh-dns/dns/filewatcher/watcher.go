@@ -0,0 +1,9 @@
+package filewatcher
+
+// Watcher reports changes to a set of files. Events are coalesced: a receive
+// names a file which changed at least once since the previous receive. The
+// channel is closed if the watcher stops working.
+type Watcher interface {
+	Events() <-chan string
+	Close() error
+}
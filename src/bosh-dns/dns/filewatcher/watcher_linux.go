@@ -0,0 +1,146 @@
+//go:build linux
+// +build linux
+
+package filewatcher
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+type inotifyWatcher struct {
+	fd     int
+	mutex  sync.Mutex
+	dirs   map[int32]string
+	paths  map[string]string
+	events chan string
+}
+
+// New watches the directories containing paths rather than the files
+// themselves so that files replaced by a rename are still noticed. Symlinked
+// paths, such as /etc/resolv.conf pointing into /run, are watched at their
+// target as well. Directories which do not exist are skipped.
+//
+// The inotify descriptor is blocking and read by its own goroutine, as it
+// cannot be handed to the runtime poller. Closing a descriptor does not wake
+// up a read blocked on it, so the watcher stops once all of its watches are
+// removed instead, either by Close or by the directories being deleted.
+func New(paths []string) (Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	w := &inotifyWatcher{
+		fd:     fd,
+		dirs:   map[int32]string{},
+		paths:  map[string]string{},
+		events: make(chan string, 1),
+	}
+
+	for _, path := range paths {
+		w.paths[path] = path
+		if target, err := filepath.EvalSymlinks(path); err == nil && target != path {
+			w.paths[target] = path
+		}
+	}
+
+	for watched := range w.paths {
+		dir := filepath.Dir(watched)
+
+		wd, err := syscall.InotifyAddWatch(fd, dir, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO|syscall.IN_CREATE|syscall.IN_DELETE)
+		if err == syscall.ENOENT {
+			continue
+		} else if err != nil {
+			syscall.Close(fd)
+			return nil, os.NewSyscallError("inotify_add_watch", err)
+		}
+
+		w.dirs[int32(wd)] = dir
+	}
+
+	if len(w.dirs) == 0 {
+		syscall.Close(fd)
+		return nil, errors.New("none of the directories to watch exist")
+	}
+
+	go w.read()
+
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *inotifyWatcher) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for wd := range w.dirs {
+		syscall.InotifyRmWatch(w.fd, uint32(wd))
+	}
+
+	return nil
+}
+
+func (w *inotifyWatcher) read() {
+	defer close(w.events)
+	defer syscall.Close(w.fd)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	for {
+		n, err := syscall.Read(w.fd, buf)
+		if err == syscall.EINTR {
+			continue
+		} else if err != nil || n <= 0 {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := string(bytes.TrimRight(buf[nameStart:nameStart+int(event.Len)], "\x00"))
+			offset = nameStart + int(event.Len)
+
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				if w.removeDir(event.Wd) == 0 {
+					return
+				}
+				continue
+			}
+
+			path, ok := w.paths[filepath.Join(w.dir(event.Wd), name)]
+			if !ok {
+				continue
+			}
+
+			select {
+			case w.events <- path:
+			default:
+			}
+		}
+	}
+}
+
+func (w *inotifyWatcher) dir(wd int32) string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.dirs[wd]
+}
+
+func (w *inotifyWatcher) removeDir(wd int32) int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	delete(w.dirs, wd)
+
+	return len(w.dirs)
+}
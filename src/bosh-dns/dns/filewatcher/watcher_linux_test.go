@@ -0,0 +1,77 @@
+//go:build linux
+// +build linux
+
+package filewatcher_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"bosh-dns/dns/filewatcher"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Watcher", func() {
+	var (
+		dir     string
+		path    string
+		watcher filewatcher.Watcher
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "filewatcher")
+		Expect(err).NotTo(HaveOccurred())
+
+		path = filepath.Join(dir, "watched.json")
+		Expect(ioutil.WriteFile(path, []byte("{}"), 0644)).To(Succeed())
+
+		watcher, err = filewatcher.New([]string{path, filepath.Join(dir, "missing", "other.json")})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		watcher.Close()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("reports files written in place", func() {
+		Expect(ioutil.WriteFile(path, []byte(`{"a":1}`), 0644)).To(Succeed())
+
+		Eventually(watcher.Events()).Should(Receive(Equal(path)))
+	})
+
+	It("reports files renamed into place", func() {
+		tmpPath := filepath.Join(dir, "watched.json.tmp")
+		Expect(ioutil.WriteFile(tmpPath, []byte(`{"a":2}`), 0644)).To(Succeed())
+		Expect(os.Rename(tmpPath, path)).To(Succeed())
+
+		Eventually(watcher.Events()).Should(Receive(Equal(path)))
+	})
+
+	It("ignores other files in the directory", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "other.json"), []byte("{}"), 0644)).To(Succeed())
+
+		Consistently(watcher.Events()).ShouldNot(Receive())
+	})
+
+	It("closes the events channel once closed", func() {
+		Expect(watcher.Close()).To(Succeed())
+
+		Eventually(watcher.Events()).Should(BeClosed())
+	})
+
+	It("closes the events channel once the watched directories are removed", func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+
+		Eventually(watcher.Events()).Should(BeClosed())
+	})
+
+	It("errors when none of the directories exist", func() {
+		_, err := filewatcher.New([]string{filepath.Join(dir, "missing", "watched.json")})
+		Expect(err).To(HaveOccurred())
+	})
+})
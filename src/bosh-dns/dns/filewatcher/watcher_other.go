@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package filewatcher
+
+import "errors"
+
+func New(paths []string) (Watcher, error) {
+	return nil, errors.New("file watching is not supported on this platform")
+}
@@ -10,5 +10,5 @@ import (
 )
 
 func newDNSManager(logger logger.Logger, clock clock.Clock, fs boshsys.FileSystem) manager.DNSManager {
-	return manager.NewResolvConfManager(clock, fs, boshsys.NewExecCmdRunner(logger))
+	return manager.NewUnixManager(logger, clock, fs, boshsys.NewExecCmdRunner(logger))
 }
@@ -4,8 +4,11 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -13,14 +16,20 @@ import (
 
 	dnsconfig "bosh-dns/dns/config"
 	handlersconfig "bosh-dns/dns/config/handlers"
+	"bosh-dns/dns/metrics"
 	"bosh-dns/dns/server"
 	"bosh-dns/dns/server/aliases"
+	"bosh-dns/dns/server/dnssec"
+	"bosh-dns/dns/server/dynamic"
 	"bosh-dns/dns/server/handlers"
 	"bosh-dns/dns/server/healthiness"
+	"bosh-dns/dns/server/ratelimit"
 	"bosh-dns/dns/server/records"
 	"bosh-dns/dns/server/records/dnsresolver"
+	"bosh-dns/dns/server/zone"
 	"bosh-dns/dns/shuffle"
 	"bosh-dns/healthcheck/healthclient"
+	"bosh-dns/healthcheck/healthtls"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -54,6 +63,10 @@ func mainExitCode() int {
 	logTag := "main"
 	defer logger.FlushTimeout(5 * time.Second)
 
+	if len(os.Args) > 1 && os.Args[1] == "validate-records" {
+		return validateRecords(os.Args[2:], os.Stdout, os.Stderr)
+	}
+
 	configPath, err := parseFlags()
 	if err != nil {
 		logger.Error(logTag, err.Error())
@@ -102,32 +115,170 @@ func mainExitCode() int {
 		return 1
 	}
 
+	shutdown := make(chan struct{})
+
+	if config.Metrics.Enabled {
+		metricsListener, err := net.Listen("tcp", net.JoinHostPort(config.Metrics.Address, strconv.Itoa(config.Metrics.Port)))
+		if err != nil {
+			logger.Error(logTag, fmt.Sprintf("Unable to listen for metrics: %s", err.Error()))
+			return 1
+		}
+
+		go func() {
+			if err := metrics.Serve(metricsListener, shutdown); err != nil {
+				logger.Error(logTag, fmt.Sprintf("Serving metrics failed: %s", err.Error()))
+			}
+		}()
+	}
+
 	var healthWatcher healthiness.HealthWatcher = healthiness.NewNopHealthWatcher()
+	var healthChecker *healthiness.RoutingHealthChecker
 	if config.Health.Enabled {
-		httpClient, err := healthclient.NewHealthClientFromFiles(config.Health.CAFile, config.Health.CertificateFile, config.Health.PrivateKeyFile, logger)
+		certs, err := healthtls.NewCertReloader(
+			config.Health.CertificateFile,
+			config.Health.PrivateKeyFile,
+			config.Health.CAFile,
+			clock,
+			logger,
+		)
 		if err != nil {
 			logger.Error(logTag, fmt.Sprintf("Unable to configure health checker %s", err.Error()))
 			return 1
 		}
-		healthChecker := healthiness.NewHealthChecker(httpClient, config.Health.Port)
-		checkInterval := time.Duration(config.Health.CheckInterval)
-		healthWatcher = healthiness.NewHealthWatcher(healthChecker, clock, checkInterval)
+		go certs.Run(time.Duration(config.Health.CertReloadInterval), shutdown)
+
+		httpClient := healthclient.NewReloadingHealthClient(certs, logger)
+		checkerRules, err := healthiness.NewCheckerRules(config.Health.Checkers, httpClient)
+		if err != nil {
+			logger.Error(logTag, fmt.Sprintf("Unable to configure health checkers %s", err.Error()))
+			return 1
+		}
+		healthChecker = healthiness.NewRoutingHealthChecker(
+			healthiness.NewHealthChecker(httpClient, config.Health.Port),
+			checkerRules,
+		)
+	} else if len(config.Health.Checkers) > 0 {
+		// without the healthcheck job only the configured checkers apply,
+		// every other instance is considered healthy
+		checkerRules, err := healthiness.NewCheckerRules(config.Health.Checkers, nil)
+		if err != nil {
+			logger.Error(logTag, fmt.Sprintf("Unable to configure health checkers %s", err.Error()))
+			return 1
+		}
+		healthChecker = healthiness.NewRoutingHealthChecker(healthiness.NewNopHealthChecker(), checkerRules)
 	}
 
-	shutdown := make(chan struct{})
+	if healthChecker != nil {
+		checkInterval := time.Duration(config.Health.CheckInterval)
+		healthWatcher = healthiness.NewHealthWatcher(healthChecker, clock, checkInterval, config.Health.MaxConcurrentChecks, logger)
+		healthiness.PublishStats("health_watcher", healthWatcher)
+	}
 
 	fileReader := records.NewFileReader(config.RecordsFile, system.NewOsFileSystem(logger), clock, logger, repoUpdate)
+	records.PublishRejections("records_file_rejections", fileReader)
+	if config.RecordsSource.URL != "" {
+		pollWait := time.Duration(config.RecordsSource.PollWait)
+		fileReader = records.NewHTTPReader(
+			config.RecordsSource.URL,
+			&http.Client{Timeout: pollWait + 5*time.Second},
+			fileReader,
+			pollWait,
+			time.Duration(config.RecordsSource.RetryInterval),
+			clock,
+			logger,
+			shutdown,
+		)
+	}
 	recordSet, err := records.NewRecordSet(fileReader, aliasConfiguration, healthWatcher, uint(config.Health.MaxTrackedQueries), shutdown, logger)
+	if healthChecker != nil {
+		healthChecker.SetTargets(recordSet)
+	}
 
-	localDomain := dnsresolver.NewLocalDomain(logger, recordSet, shuffle.New())
-	discoveryHandler := handlers.NewDiscoveryHandler(logger, localDomain)
+	var localRecords dnsresolver.RecordSet = recordSet
+	var domainProvider handlers.DomainProvider = recordSet
+	var updateStore *dynamic.Store
+	if config.DynamicUpdates.Enabled {
+		updateStore, err = dynamic.NewStore(config.DynamicUpdates.Zones, time.Duration(config.DynamicUpdates.MaxTTL), config.DynamicUpdates.PersistFile, fs, clock, logger)
+		if err != nil {
+			logger.Error(logTag, fmt.Sprintf("Unable to load dynamic records: %s", err))
+			return 1
+		}
+		go updateStore.Run(shutdown)
 
-	handlerRegistrar := handlers.NewHandlerRegistrar(logger, clock, recordSet, mux, discoveryHandler)
+		localRecords = dynamic.NewOverlay(recordSet, updateStore)
+		domainProvider = handlers.DomainProviders{recordSet, updateStore}
+	}
+
+	localDomain := dnsresolver.NewLocalDomain(logger, localRecords, shuffle.New())
+	var localHandler dns.Handler = handlers.NewDiscoveryHandler(logger, localDomain)
+
+	if config.DynamicUpdates.Enabled {
+		localHandler = handlers.NewUpdateHandler(localHandler, updateStore, dnsconfig.TSIGKeyNames(config.DynamicUpdates.TSIGKeys), clock, logger)
+	}
+
+	var primary *zone.Primary
+	if config.ZoneTransfer.Enabled || config.DNSSEC.Enabled {
+		var zoneSource zone.RecordSource = recordSet
+		if config.DynamicUpdates.Enabled {
+			zoneSource = dynamic.NewZoneSource(recordSet, updateStore)
+		}
+
+		notifier := zone.NewNotifier(config.ZoneTransfer.Notify, config.ZoneTransfer.TSIGKeys, time.Duration(config.RecursorTimeout), clock, logger)
+		primary = zone.NewPrimary(zoneSource, config.ZoneTransfer, notifier, clock, logger)
+		go primary.Run(shutdown)
+	}
+
+	if config.ZoneTransfer.Enabled {
+		secondaries, err := config.ZoneTransfer.SecondaryNetworks()
+		if err != nil {
+			logger.Error(logTag, err.Error())
+			return 1
+		}
+
+		localHandler = handlers.NewZoneTransferHandler(localHandler, primary, secondaries, dnsconfig.TSIGKeyNames(config.ZoneTransfer.TSIGKeys), clock, logger)
+	}
+
+	if config.DNSSEC.Enabled {
+		keys, err := dnssec.LoadKeys(fs, config.DNSSEC.Keys)
+		if err != nil {
+			logger.Error(logTag, fmt.Sprintf("Unable to load DNSSEC keys: %s", err))
+			return 1
+		}
+
+		signer := dnssec.NewSigner(keys, time.Duration(config.DNSSEC.SignatureValidity), clock)
+		localHandler = handlers.NewDNSSECHandler(localHandler, primary, signer, logger)
+	}
+
+	handlerRegistrar := handlers.NewHandlerRegistrar(logger, clock, domainProvider, mux, localHandler)
 
 	mux.Handle("arpa.", handlers.NewRequestLoggerHandler(handlers.NewArpaHandler(logger), clock, logger))
 
+	var rateLimitClients ratelimit.Clients
+	var recursiveLimiter *ratelimit.Limiter
+	if config.RateLimit.Enabled {
+		exempt, err := config.RateLimit.ExemptNetworks()
+		if err != nil {
+			logger.Error(logTag, err.Error())
+			return 1
+		}
+
+		rateLimitClients = ratelimit.NewClients(config.RateLimit.IPv4PrefixLength, config.RateLimit.IPv6PrefixLength, exempt)
+
+		if config.RateLimit.RecursiveQueriesPerSecond > 0 {
+			recursiveLimiter = ratelimit.NewLimiter("recursive queries", config.RateLimit.RecursiveQueriesPerSecond, clock, logger)
+			go recursiveLimiter.Run(shutdown)
+		}
+	}
+	limitRecursive := func(handler dns.Handler) dns.Handler {
+		if recursiveLimiter == nil {
+			return handler
+		}
+
+		return handlers.NewRateLimitHandler(handler, rateLimitClients, recursiveLimiter)
+	}
+
 	exchangerFactory := handlers.NewExchangerFactory(time.Duration(config.RecursorTimeout))
-	handlerFactory := handlers.NewFactory(exchangerFactory, clock, stringShuffler, logger)
+	handlerFactory := handlers.NewFactory(exchangerFactory, handlers.NewTLSExchangerFactory(time.Duration(config.RecursorTimeout)), clock, stringShuffler, logger)
 
 	delegatingHandlers, err := handlersConfiguration.GenerateHandlers(handlerFactory)
 	if err != nil {
@@ -135,36 +286,164 @@ func mainExitCode() int {
 		return 1
 	}
 	for domain, handler := range delegatingHandlers {
-		mux.Handle(domain, handlers.NewRequestLoggerHandler(handler, clock, logger))
+		mux.Handle(domain, limitRecursive(handlers.NewRequestLoggerHandler(handler, clock, logger)))
 	}
 
-	upchecks := []server.Upcheck{}
 	for _, upcheckDomain := range config.UpcheckDomains {
 		mux.Handle(upcheckDomain, handlers.NewRequestLoggerHandler(handlers.NewUpcheckHandler(logger), clock, logger))
-		upchecks = append(upchecks, server.NewDNSAnswerValidatingUpcheck(fmt.Sprintf("%s:%d", config.Address, config.Port), upcheckDomain, "udp"))
-		upchecks = append(upchecks, server.NewDNSAnswerValidatingUpcheck(fmt.Sprintf("%s:%d", config.Address, config.Port), upcheckDomain, "tcp"))
 	}
 
 	recursorPool := handlers.NewFailoverRecursorPool(config.Recursors, logger)
 	forwardHandler := handlers.NewForwardHandler(recursorPool, exchangerFactory, clock, logger)
+	if config.DNSSECValidation.Enabled {
+		anchors, err := dnssec.ParseTrustAnchors(config.DNSSECValidation.TrustAnchors)
+		if err != nil {
+			logger.Error(logTag, fmt.Sprintf("Unable to load DNSSEC trust anchors: %s", err))
+			return 1
+		}
+
+		validator := dnssec.NewValidator(anchors, config.DNSSECValidation.NegativeTrustAnchors, clock)
+		forwardHandler = handlers.NewValidatingForwardHandler(recursorPool, exchangerFactory, validator, clock, logger)
+	}
+	refusedHandler := handlers.NewRequestLoggerHandler(handlers.NewRefusedHandler(logger), clock, logger)
+
+	var recursiveHandler dns.Handler = forwardHandler
 	if config.Cache.Enabled {
-		mux.Handle(".", handlers.NewCachingDNSHandler(forwardHandler))
-	} else {
-		mux.Handle(".", forwardHandler)
+		recursiveHandler = handlers.NewCachingDNSHandler(forwardHandler)
+	}
+	if !config.RecursionACL.Empty() {
+		allow, deny, err := config.RecursionACL.Networks()
+		if err != nil {
+			logger.Error(logTag, err.Error())
+			return 1
+		}
+
+		recursiveHandler = handlers.NewACLHandler(recursiveHandler, refusedHandler, allow, deny)
+	}
+	mux.Handle(".", limitRecursive(recursiveHandler))
+
+	ownIPs, err := localIPs()
+	if err != nil {
+		logger.Error(logTag, err.Error())
+		return 1
+	}
+
+	var serverHandler dns.Handler = handlers.NewInstanceNamesHandler(mux, recordSet, ownIPs, logger)
+	if config.Search.Enabled() {
+		var searchSuffixes handlers.SearchSuffixProviders
+		if config.Search.OwnNetwork {
+			searchSuffixes = append(searchSuffixes, handlers.SearchSuffixFunc(func() []string {
+				return recordSet.SearchSuffixesForIPs(ownIPs)
+			}))
+		}
+		searchSuffixes = append(searchSuffixes, handlers.SearchSuffixes(config.Search.Suffixes))
+
+		serverHandler = handlers.NewSearchHandler(serverHandler, searchSuffixes, config.Search.NDots, logger)
+	}
+
+	var queryLimiter *ratelimit.Limiter
+	if config.RateLimit.Enabled && config.RateLimit.QueriesPerSecond > 0 {
+		queryLimiter = ratelimit.NewLimiter("queries", config.RateLimit.QueriesPerSecond, clock, logger)
+		go queryLimiter.Run(shutdown)
+	}
+	var responseLimiter *ratelimit.ResponseLimiter
+	if config.RateLimit.Enabled && config.RateLimit.ResponsesPerSecond > 0 {
+		responseLimiter = ratelimit.NewResponseLimiter(config.RateLimit.ResponsesPerSecond, config.RateLimit.Slip, clock, logger)
+		go responseLimiter.Run(shutdown)
+	}
+
+	// every listen address may have its own ACL, the limiters are shared
+	handlerFor := func(address string) (dns.Handler, error) {
+		handler := serverHandler
+
+		if acl := config.ACLFor(address); !acl.Empty() {
+			allow, deny, err := acl.Networks()
+			if err != nil {
+				return nil, err
+			}
+
+			handler = handlers.NewACLHandler(handler, refusedHandler, allow, deny)
+		}
+
+		handler = handlers.NewEDNSHandler(handler, uint16(config.MaxUDPSize), logger)
+		if queryLimiter != nil {
+			handler = handlers.NewRateLimitHandler(handler, rateLimitClients, queryLimiter)
+		}
+		if responseLimiter != nil {
+			handler = handlers.NewResponseRateLimitHandler(handler, rateLimitClients, responseLimiter)
+		}
+
+		return handler, nil
+	}
+
+	tsigSecrets := config.TSIGSecrets()
+	newDNSServer := func(listenAddress, network string, handler dns.Handler) *dns.Server {
+		srv := &dns.Server{Addr: listenAddress, Net: network, Handler: handler, TsigSecret: tsigSecrets}
+		if network == "udp" {
+			srv.UDPSize = 65535
+		}
+
+		return srv
+	}
+
+	listeners := []server.Listener{}
+	dnsServers := []*dns.Server{}
+	for _, listenAddress := range config.ListenAddresses() {
+		address, _, _ := net.SplitHostPort(listenAddress)
+		handler, err := handlerFor(address)
+		if err != nil {
+			logger.Error(logTag, err.Error())
+			return 1
+		}
+
+		for _, network := range []string{"tcp", "udp"} {
+			listenAddress, network := listenAddress, network
+
+			upchecks := []server.Upcheck{}
+			for _, upcheckDomain := range config.UpcheckDomains {
+				upchecks = append(upchecks, server.NewDNSAnswerValidatingUpcheck(listenAddress, upcheckDomain, network))
+			}
+			if config.UpcheckRecursionDomain != "" {
+				upchecks = append(upchecks, server.NewRecursionUpcheck(listenAddress, config.UpcheckRecursionDomain, network))
+			}
+
+			srv := newDNSServer(listenAddress, network, handler)
+			dnsServers = append(dnsServers, srv)
+			listeners = append(listeners, server.Listener{
+				Name:     fmt.Sprintf("%s %s", network, listenAddress),
+				Server:   srv,
+				Upchecks: upchecks,
+				Rebind: func() server.DNSServer {
+					return newDNSServer(listenAddress, network, handler)
+				},
+			})
+		}
+	}
+
+	var handoff *server.SocketHandoff
+	if config.Handoff.Enabled {
+		handoff = server.NewSocketHandoff(config.Handoff.Socket, dnsServers, time.Duration(config.Timeout), logger)
+
+		inherited, err := handoff.Inherit()
+		if err != nil {
+			logger.Error(logTag, fmt.Sprintf("could not inherit sockets: %s", err.Error()))
+			return 1
+		}
+		for i := range listeners {
+			listeners[i].Server = inherited[i]
+		}
 	}
 
-	bindAddress := fmt.Sprintf("%s:%d", config.Address, config.Port)
 	dnsServer := server.New(
-		[]server.DNSServer{
-			&dns.Server{Addr: bindAddress, Net: "tcp", Handler: mux},
-			&dns.Server{Addr: bindAddress, Net: "udp", Handler: mux, UDPSize: 65535},
-		},
-		upchecks,
+		listeners,
 		time.Duration(config.Timeout),
 		time.Duration(5*time.Second),
 		shutdown,
 		logger,
-	)
+	).WithRecoveryAttempts(config.UpcheckRecoveryAttempts)
+	if handoff != nil {
+		dnsServer = dnsServer.WithHandoff(handoff, time.Duration(config.Handoff.Wait))
+	}
 
 	go func() {
 		err := handlerRegistrar.Run(shutdown)
@@ -191,3 +470,20 @@ func mainExitCode() int {
 
 	return 0
 }
+
+// localIPs returns the addresses of the network interfaces of this VM.
+func localIPs() ([]string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listing interface addresses")
+	}
+
+	ips := []string{}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			ips = append(ips, ipNet.IP.String())
+		}
+	}
+
+	return ips, nil
+}
@@ -96,6 +96,73 @@ var _ = Describe("main", func() {
 		})
 	})
 
+	Describe("validate-records", func() {
+		var recordsFile *os.File
+
+		BeforeEach(func() {
+			var err error
+			recordsFile, err = ioutil.TempFile("", "records")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.Remove(recordsFile.Name())).To(Succeed())
+		})
+
+		It("exits 0 and prints the report for a valid file", func() {
+			_, err := recordsFile.Write([]byte(`{
+				"record_keys": ["id", "instance_group", "network", "deployment", "ip", "domain", "weight"],
+				"record_infos": [["instance0", "my-group", "my-network", "my-deployment", "127.0.0.1", "bosh.", 5]]
+			}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			session, err := gexec.Start(exec.Command(pathToServer, "validate-records", recordsFile.Name()), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(session.Out).To(gbytes.Say("rows: 1 \\(1 valid\\)"))
+			Expect(session.Out).To(gbytes.Say("extra columns kept as attributes: weight"))
+		})
+
+		It("exits 1 and lists the problems for an invalid file", func() {
+			_, err := recordsFile.Write([]byte(`{
+				"record_keys": ["id", "instance_group", "network", "deployment", "ip"],
+				"record_infos": [["instance0", "my-group", "my-network", "my-deployment"]]
+			}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			session, err := gexec.Start(exec.Command(pathToServer, "validate-records", "--json", recordsFile.Name()), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+
+			var report map[string]interface{}
+			Expect(json.Unmarshal(session.Out.Contents(), &report)).To(Succeed())
+			Expect(report["missing_columns"]).To(Equal([]interface{}{"domain"}))
+			Expect(report["row_problems"]).To(Equal([]interface{}{
+				map[string]interface{}{"row": float64(0), "problem": "found 4 fields, expected 5"},
+			}))
+		})
+
+		It("exits 2 without a file", func() {
+			session, err := gexec.Start(exec.Command(pathToServer, "validate-records"), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(2))
+			Expect(session.Err).To(gbytes.Say("usage: bosh-dns validate-records"))
+			Expect(session.Out.Contents()).To(BeEmpty())
+		})
+
+		It("exits 1 and reports unreadable files on stderr", func() {
+			session, err := gexec.Start(exec.Command(pathToServer, "validate-records", "--json", recordsFile.Name()+".missing"), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say("no such file or directory"))
+			Expect(session.Out.Contents()).To(BeEmpty())
+		})
+	})
+
 	Context("when the server starts successfully", func() {
 		var (
 			cmd                   *exec.Cmd
@@ -106,11 +173,13 @@ var _ = Describe("main", func() {
 			checkInterval         time.Duration
 			httpJSONServer        *ghttp.Server
 			handlerCachingEnabled bool
+			metricsConfig         config.Metrics
 		)
 
 		BeforeEach(func() {
 			checkInterval = 100 * time.Millisecond
 			handlerCachingEnabled = false
+			metricsConfig = config.Metrics{}
 		})
 
 		JustBeforeEach(func() {
@@ -214,7 +283,7 @@ var _ = Describe("main", func() {
 			})
 
 			cmd = newCommandWithConfig(config.Config{
-				Address:           listenAddress,
+				Address:           config.AddressList{listenAddress},
 				Port:              listenPort,
 				RecordsFile:       recordsFilePath,
 				AliasFilesGlob:    path.Join(aliasesDir, "*"),
@@ -228,6 +297,7 @@ var _ = Describe("main", func() {
 					PrivateKeyFile:  "../healthcheck/assets/test_certs/test_client.key",
 					CheckInterval:   config.DurationJSON(checkInterval),
 				},
+				Metrics: metricsConfig,
 			})
 
 			session, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
@@ -787,6 +857,46 @@ var _ = Describe("main", func() {
 			Eventually(session).Should(gexec.Exit(0))
 		})
 
+		Context("when metrics are enabled", func() {
+			BeforeEach(func() {
+				metricsConfig = config.Metrics{
+					Enabled: true,
+					Address: "127.0.0.1",
+					Port:    53088 + ginkgoconfig.GinkgoConfig.ParallelNode,
+				}
+			})
+
+			It("publishes the expiry of the health client certificate", func() {
+				resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/debug/vars", metricsConfig.Port))
+				Expect(err).NotTo(HaveOccurred())
+				defer resp.Body.Close()
+
+				var vars map[string]interface{}
+				Expect(json.NewDecoder(resp.Body).Decode(&vars)).To(Succeed())
+				Expect(vars).To(HaveKeyWithValue("health_tls_certificate_not_after", HaveKey("../healthcheck/assets/test_certs/test_client.pem")))
+			})
+
+			It("publishes the health watcher stats", func() {
+				resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/debug/vars", metricsConfig.Port))
+				Expect(err).NotTo(HaveOccurred())
+				defer resp.Body.Close()
+
+				var vars map[string]interface{}
+				Expect(json.NewDecoder(resp.Body).Decode(&vars)).To(Succeed())
+				Expect(vars).To(HaveKeyWithValue("health_watcher", HaveKey("queue_depth")))
+			})
+
+			It("publishes the records file rejections", func() {
+				resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/debug/vars", metricsConfig.Port))
+				Expect(err).NotTo(HaveOccurred())
+				defer resp.Body.Close()
+
+				var vars map[string]interface{}
+				Expect(json.NewDecoder(resp.Body).Decode(&vars)).To(Succeed())
+				Expect(vars).To(HaveKeyWithValue("records_file_rejections", BeNumerically("==", 0)))
+			})
+		})
+
 		Context("health checking", func() {
 			var healthServers []*ghttp.Server
 
@@ -915,7 +1025,7 @@ var _ = Describe("main", func() {
 			}()
 
 			cmd = newCommandWithConfig(config.Config{
-				Address:         listenAddress,
+				Address:         config.AddressList{listenAddress},
 				Port:            listenPort,
 				Recursors:       []string{l.Addr().String()},
 				RecursorTimeout: config.DurationJSON(time.Second),
@@ -949,7 +1059,7 @@ var _ = Describe("main", func() {
 			var err error
 
 			cmd = newCommandWithConfig(config.Config{
-				Address:         listenAddress,
+				Address:         config.AddressList{listenAddress},
 				Port:            listenPort,
 				Recursors:       []string{"8.8.8.8"},
 				RecursorTimeout: config.DurationJSON(time.Second),
@@ -992,7 +1102,7 @@ var _ = Describe("main", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			cmd = newCommandWithConfig(config.Config{
-				Address:        listenAddress,
+				Address:        config.AddressList{listenAddress},
 				Port:           listenPort,
 				Recursors:      []string{"8.8.8.8"},
 				UpcheckDomains: []string{"upcheck.bosh-dns."},
@@ -1026,7 +1136,7 @@ var _ = Describe("main", func() {
 
 		It("exits 1 and logs a helpful error message when the server times out binding to ports", func() {
 			cmd := newCommandWithConfig(config.Config{
-				Address:        listenAddress,
+				Address:        config.AddressList{listenAddress},
 				Port:           listenPort,
 				UpcheckDomains: []string{"upcheck.bosh-dns."},
 				Timeout:        config.DurationJSON(-1),
@@ -1062,7 +1172,7 @@ var _ = Describe("main", func() {
 				})
 
 				cmd := newCommandWithConfig(config.Config{
-					Address:           listenAddress,
+					Address:           config.AddressList{listenAddress},
 					Port:              listenPort,
 					UpcheckDomains:    []string{"upcheck.bosh-dns."},
 					HandlersFilesGlob: filepath.Join(handlersDir, "*"),
@@ -1086,7 +1196,7 @@ var _ = Describe("main", func() {
 				})
 
 				cmd := newCommandWithConfig(config.Config{
-					Address:           listenAddress,
+					Address:           config.AddressList{listenAddress},
 					Port:              listenPort,
 					UpcheckDomains:    []string{"upcheck.bosh-dns."},
 					HandlersFilesGlob: filepath.Join(handlersDir, "*"),
@@ -3,6 +3,14 @@ package manager
 //go:generate counterfeiter . DNSManager
 
 type DNSManager interface {
-	SetPrimary(string) error
+	SetPrimary(addresses ...string) error
+	SetResolverOptions(options ResolverOptions) error
 	Read() ([]string, error)
 }
+
+// ResolverOptions are the search list and options of the system resolver.
+// Empty ResolverOptions leave the system resolver as configured otherwise.
+type ResolverOptions struct {
+	Search  []string
+	Options []string
+}
@@ -7,10 +7,22 @@ import (
 )
 
 type FakeDNSManager struct {
-	SetPrimaryStub        func(string) error
+	ReadStub        func() ([]string, error)
+	readMutex       sync.RWMutex
+	readArgsForCall []struct {
+	}
+	readReturns struct {
+		result1 []string
+		result2 error
+	}
+	readReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	SetPrimaryStub        func(...string) error
 	setPrimaryMutex       sync.RWMutex
 	setPrimaryArgsForCall []struct {
-		arg1 string
+		arg1 []string
 	}
 	setPrimaryReturns struct {
 		result1 error
@@ -18,36 +30,94 @@ type FakeDNSManager struct {
 	setPrimaryReturnsOnCall map[int]struct {
 		result1 error
 	}
-	ReadStub        func() ([]string, error)
-	readMutex       sync.RWMutex
-	readArgsForCall []struct{}
-	readReturns     struct {
+	SetResolverOptionsStub        func(manager.ResolverOptions) error
+	setResolverOptionsMutex       sync.RWMutex
+	setResolverOptionsArgsForCall []struct {
+		arg1 manager.ResolverOptions
+	}
+	setResolverOptionsReturns struct {
+		result1 error
+	}
+	setResolverOptionsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeDNSManager) Read() ([]string, error) {
+	fake.readMutex.Lock()
+	ret, specificReturn := fake.readReturnsOnCall[len(fake.readArgsForCall)]
+	fake.readArgsForCall = append(fake.readArgsForCall, struct {
+	}{})
+	stub := fake.ReadStub
+	fakeReturns := fake.readReturns
+	fake.recordInvocation("Read", []interface{}{})
+	fake.readMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDNSManager) ReadCallCount() int {
+	fake.readMutex.RLock()
+	defer fake.readMutex.RUnlock()
+	return len(fake.readArgsForCall)
+}
+
+func (fake *FakeDNSManager) ReadCalls(stub func() ([]string, error)) {
+	fake.readMutex.Lock()
+	defer fake.readMutex.Unlock()
+	fake.ReadStub = stub
+}
+
+func (fake *FakeDNSManager) ReadReturns(result1 []string, result2 error) {
+	fake.readMutex.Lock()
+	defer fake.readMutex.Unlock()
+	fake.ReadStub = nil
+	fake.readReturns = struct {
 		result1 []string
 		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDNSManager) ReadReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.readMutex.Lock()
+	defer fake.readMutex.Unlock()
+	fake.ReadStub = nil
+	if fake.readReturnsOnCall == nil {
+		fake.readReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
 	}
-	readReturnsOnCall map[int]struct {
+	fake.readReturnsOnCall[i] = struct {
 		result1 []string
 		result2 error
-	}
-	invocations      map[string][][]interface{}
-	invocationsMutex sync.RWMutex
+	}{result1, result2}
 }
 
-func (fake *FakeDNSManager) SetPrimary(arg1 string) error {
+func (fake *FakeDNSManager) SetPrimary(arg1 ...string) error {
 	fake.setPrimaryMutex.Lock()
 	ret, specificReturn := fake.setPrimaryReturnsOnCall[len(fake.setPrimaryArgsForCall)]
 	fake.setPrimaryArgsForCall = append(fake.setPrimaryArgsForCall, struct {
-		arg1 string
+		arg1 []string
 	}{arg1})
+	stub := fake.SetPrimaryStub
+	fakeReturns := fake.setPrimaryReturns
 	fake.recordInvocation("SetPrimary", []interface{}{arg1})
 	fake.setPrimaryMutex.Unlock()
-	if fake.SetPrimaryStub != nil {
-		return fake.SetPrimaryStub(arg1)
+	if stub != nil {
+		return stub(arg1...)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	return fake.setPrimaryReturns.result1
+	return fakeReturns.result1
 }
 
 func (fake *FakeDNSManager) SetPrimaryCallCount() int {
@@ -56,13 +126,22 @@ func (fake *FakeDNSManager) SetPrimaryCallCount() int {
 	return len(fake.setPrimaryArgsForCall)
 }
 
-func (fake *FakeDNSManager) SetPrimaryArgsForCall(i int) string {
+func (fake *FakeDNSManager) SetPrimaryCalls(stub func(...string) error) {
+	fake.setPrimaryMutex.Lock()
+	defer fake.setPrimaryMutex.Unlock()
+	fake.SetPrimaryStub = stub
+}
+
+func (fake *FakeDNSManager) SetPrimaryArgsForCall(i int) []string {
 	fake.setPrimaryMutex.RLock()
 	defer fake.setPrimaryMutex.RUnlock()
-	return fake.setPrimaryArgsForCall[i].arg1
+	argsForCall := fake.setPrimaryArgsForCall[i]
+	return argsForCall.arg1
 }
 
 func (fake *FakeDNSManager) SetPrimaryReturns(result1 error) {
+	fake.setPrimaryMutex.Lock()
+	defer fake.setPrimaryMutex.Unlock()
 	fake.SetPrimaryStub = nil
 	fake.setPrimaryReturns = struct {
 		result1 error
@@ -70,6 +149,8 @@ func (fake *FakeDNSManager) SetPrimaryReturns(result1 error) {
 }
 
 func (fake *FakeDNSManager) SetPrimaryReturnsOnCall(i int, result1 error) {
+	fake.setPrimaryMutex.Lock()
+	defer fake.setPrimaryMutex.Unlock()
 	fake.SetPrimaryStub = nil
 	if fake.setPrimaryReturnsOnCall == nil {
 		fake.setPrimaryReturnsOnCall = make(map[int]struct {
@@ -81,56 +162,76 @@ func (fake *FakeDNSManager) SetPrimaryReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeDNSManager) Read() ([]string, error) {
-	fake.readMutex.Lock()
-	ret, specificReturn := fake.readReturnsOnCall[len(fake.readArgsForCall)]
-	fake.readArgsForCall = append(fake.readArgsForCall, struct{}{})
-	fake.recordInvocation("Read", []interface{}{})
-	fake.readMutex.Unlock()
-	if fake.ReadStub != nil {
-		return fake.ReadStub()
+func (fake *FakeDNSManager) SetResolverOptions(arg1 manager.ResolverOptions) error {
+	fake.setResolverOptionsMutex.Lock()
+	ret, specificReturn := fake.setResolverOptionsReturnsOnCall[len(fake.setResolverOptionsArgsForCall)]
+	fake.setResolverOptionsArgsForCall = append(fake.setResolverOptionsArgsForCall, struct {
+		arg1 manager.ResolverOptions
+	}{arg1})
+	stub := fake.SetResolverOptionsStub
+	fakeReturns := fake.setResolverOptionsReturns
+	fake.recordInvocation("SetResolverOptions", []interface{}{arg1})
+	fake.setResolverOptionsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
-		return ret.result1, ret.result2
+		return ret.result1
 	}
-	return fake.readReturns.result1, fake.readReturns.result2
+	return fakeReturns.result1
 }
 
-func (fake *FakeDNSManager) ReadCallCount() int {
-	fake.readMutex.RLock()
-	defer fake.readMutex.RUnlock()
-	return len(fake.readArgsForCall)
+func (fake *FakeDNSManager) SetResolverOptionsCallCount() int {
+	fake.setResolverOptionsMutex.RLock()
+	defer fake.setResolverOptionsMutex.RUnlock()
+	return len(fake.setResolverOptionsArgsForCall)
 }
 
-func (fake *FakeDNSManager) ReadReturns(result1 []string, result2 error) {
-	fake.ReadStub = nil
-	fake.readReturns = struct {
-		result1 []string
-		result2 error
-	}{result1, result2}
+func (fake *FakeDNSManager) SetResolverOptionsCalls(stub func(manager.ResolverOptions) error) {
+	fake.setResolverOptionsMutex.Lock()
+	defer fake.setResolverOptionsMutex.Unlock()
+	fake.SetResolverOptionsStub = stub
 }
 
-func (fake *FakeDNSManager) ReadReturnsOnCall(i int, result1 []string, result2 error) {
-	fake.ReadStub = nil
-	if fake.readReturnsOnCall == nil {
-		fake.readReturnsOnCall = make(map[int]struct {
-			result1 []string
-			result2 error
+func (fake *FakeDNSManager) SetResolverOptionsArgsForCall(i int) manager.ResolverOptions {
+	fake.setResolverOptionsMutex.RLock()
+	defer fake.setResolverOptionsMutex.RUnlock()
+	argsForCall := fake.setResolverOptionsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeDNSManager) SetResolverOptionsReturns(result1 error) {
+	fake.setResolverOptionsMutex.Lock()
+	defer fake.setResolverOptionsMutex.Unlock()
+	fake.SetResolverOptionsStub = nil
+	fake.setResolverOptionsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDNSManager) SetResolverOptionsReturnsOnCall(i int, result1 error) {
+	fake.setResolverOptionsMutex.Lock()
+	defer fake.setResolverOptionsMutex.Unlock()
+	fake.SetResolverOptionsStub = nil
+	if fake.setResolverOptionsReturnsOnCall == nil {
+		fake.setResolverOptionsReturnsOnCall = make(map[int]struct {
+			result1 error
 		})
 	}
-	fake.readReturnsOnCall[i] = struct {
-		result1 []string
-		result2 error
-	}{result1, result2}
+	fake.setResolverOptionsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
 }
 
 func (fake *FakeDNSManager) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.setPrimaryMutex.RLock()
-	defer fake.setPrimaryMutex.RUnlock()
 	fake.readMutex.RLock()
 	defer fake.readMutex.RUnlock()
+	fake.setPrimaryMutex.RLock()
+	defer fake.setPrimaryMutex.RUnlock()
+	fake.setResolverOptionsMutex.RLock()
+	defer fake.setResolverOptionsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
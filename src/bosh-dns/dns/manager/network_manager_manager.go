@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	// NetworkManagerConfPath configures the global DNS servers of
+	// NetworkManager.
+	NetworkManagerConfPath = "/etc/NetworkManager/conf.d/bosh-dns.conf"
+
+	// NetworkManagerSearchConfPath configures the global search domains and
+	// options of NetworkManager.
+	NetworkManagerSearchConfPath = "/etc/NetworkManager/conf.d/bosh-dns-search.conf"
+)
+
+// networkManagerManager configures NetworkManager to write us as the only
+// nameservers of /etc/resolv.conf, overriding those of every connection.
+type networkManagerManager struct {
+	fs        boshsys.FileSystem
+	cmdRunner boshsys.CmdRunner
+	clock     clock.Clock
+}
+
+func NewNetworkManagerManager(clock clock.Clock, fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner) *networkManagerManager {
+	return &networkManagerManager{
+		fs:        fs,
+		cmdRunner: cmdRunner,
+		clock:     clock,
+	}
+}
+
+// Read returns the nameservers of /etc/resolv.conf followed by those of the
+// network devices, which the global servers hide from /etc/resolv.conf.
+func (r *networkManagerManager) Read() ([]string, error) {
+	nameservers, err := readNameservers(r.fs, "/etc/resolv.conf")
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, _, _, err := r.cmdRunner.RunCommand("nmcli", "--terse", "--fields", "IP4.DNS,IP6.DNS", "device", "show")
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listing nameservers of network devices")
+	}
+
+	for _, line := range strings.Split(stdout, "\n") {
+		// e.g. IP6.DNS[1]:fd00\:\:2, as terse output escapes colons in values
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			continue
+		}
+
+		nameserver := strings.Replace(parts[1], `\:`, ":", -1)
+		if !contains(nameservers, nameserver) {
+			nameservers = append(nameservers, nameserver)
+		}
+	}
+
+	return nameservers, nil
+}
+
+func (r *networkManagerManager) SetPrimary(addresses ...string) error {
+	conf := fmt.Sprintf("%s\n[global-dns-domain-*]\nservers=%s\n", warningLine, strings.Join(addresses, ","))
+
+	if correct, _ := r.isCorrect(addresses, conf); correct {
+		return nil
+	}
+
+	err := r.fs.WriteFileString(NetworkManagerConfPath, conf)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing NetworkManager configuration")
+	}
+
+	err = r.reload()
+	if err != nil {
+		return err
+	}
+
+	return confirm(r.clock, "nameserver", "/etc/resolv.conf", func() (bool, error) { return r.isCorrect(addresses, conf) })
+}
+
+func (r *networkManagerManager) SetResolverOptions(options ResolverOptions) error {
+	if len(options.Search) == 0 && len(options.Options) == 0 {
+		if !r.fs.FileExists(NetworkManagerSearchConfPath) {
+			return nil
+		}
+
+		err := r.fs.RemoveAll(NetworkManagerSearchConfPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Removing NetworkManager search configuration")
+		}
+
+		return r.reload()
+	}
+
+	conf := warningLine + "\n[global-dns]\n"
+	if len(options.Search) > 0 {
+		conf += fmt.Sprintf("searches=%s\n", strings.Join(options.Search, ","))
+	}
+	if len(options.Options) > 0 {
+		conf += fmt.Sprintf("options=%s\n", strings.Join(options.Options, ","))
+	}
+
+	if applied, _ := r.resolverOptionsApplied(options, conf); applied {
+		return nil
+	}
+
+	err := r.fs.WriteFileString(NetworkManagerSearchConfPath, conf)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing NetworkManager search configuration")
+	}
+
+	err = r.reload()
+	if err != nil {
+		return err
+	}
+
+	return confirm(r.clock, "resolver options", "/etc/resolv.conf", func() (bool, error) { return r.resolverOptionsApplied(options, conf) })
+}
+
+func (r *networkManagerManager) resolverOptionsApplied(options ResolverOptions, conf string) (bool, error) {
+	contents, err := r.fs.ReadFileString(NetworkManagerSearchConfPath)
+	if err != nil || contents != conf {
+		return false, err
+	}
+
+	resolvConf, err := r.fs.ReadFileString("/etc/resolv.conf")
+	if err != nil {
+		return false, err
+	}
+
+	return options.appliedIn(resolvConf), nil
+}
+
+func (r *networkManagerManager) reload() error {
+	_, _, _, err := r.cmdRunner.RunCommand("nmcli", "general", "reload")
+	if err != nil {
+		return bosherr.WrapError(err, "Reloading NetworkManager")
+	}
+
+	return nil
+}
+
+func (r *networkManagerManager) isCorrect(addresses []string, conf string) (bool, error) {
+	contents, err := r.fs.ReadFileString(NetworkManagerConfPath)
+	if err != nil || contents != conf {
+		return false, err
+	}
+
+	servers, err := readNameservers(r.fs, "/etc/resolv.conf")
+	if err != nil {
+		return false, err
+	}
+
+	return startsWith(servers, addresses), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
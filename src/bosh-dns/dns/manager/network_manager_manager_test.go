@@ -0,0 +1,180 @@
+package manager_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/manager"
+
+	boshsysfakes "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NetworkManagerManager", func() {
+	var (
+		dnsManager    manager.DNSManager
+		fs            *boshsysfakes.FakeFileSystem
+		clock         *fakeclock.FakeClock
+		fakeCmdRunner *boshsysfakes.FakeCmdRunner
+	)
+
+	const (
+		listDevices = "nmcli --terse --fields IP4.DNS,IP6.DNS device show"
+		conf        = `# This file was automatically updated by bosh-dns
+[global-dns-domain-*]
+servers=169.254.0.2,fd00::2
+`
+	)
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		fakeCmdRunner = boshsysfakes.NewFakeCmdRunner()
+		fs = boshsysfakes.NewFakeFileSystem()
+		dnsManager = manager.NewNetworkManagerManager(clock, fs, fakeCmdRunner)
+	})
+
+	Describe("Read", func() {
+		BeforeEach(func() {
+			_ = fs.WriteFileString("/etc/resolv.conf", "# Generated by NetworkManager\nnameserver 169.254.0.2\n")
+		})
+
+		It("returns the nameservers of resolv.conf followed by those of the devices", func() {
+			fakeCmdRunner.AddCmdResult(listDevices, boshsysfakes.FakeCmdResult{Stdout: `IP4.DNS[1]:10.0.0.2
+IP4.DNS[2]:169.254.0.2
+IP6.DNS[1]:fd00\:\:53
+
+IP4.DNS[1]:10.1.0.2
+`})
+
+			nameservers, err := dnsManager.Read()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nameservers).To(Equal([]string{"169.254.0.2", "10.0.0.2", "fd00::53", "10.1.0.2"}))
+		})
+
+		It("errors when nmcli fails", func() {
+			fakeCmdRunner.AddCmdResult(listDevices, boshsysfakes.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-err1")})
+
+			_, err := dnsManager.Read()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Listing nameservers of network devices"))
+			Expect(err.Error()).To(ContainSubstring("fake-err1"))
+		})
+	})
+
+	Describe("SetPrimary", func() {
+		It("configures our servers globally and reloads NetworkManager", func() {
+			_ = fs.WriteFileString("/etc/resolv.conf", "# Generated by NetworkManager\nnameserver 10.0.0.2\n")
+			fakeCmdRunner.AddCmdResult("nmcli general reload", boshsysfakes.FakeCmdResult{})
+			fakeCmdRunner.SetCmdCallback("nmcli general reload", func() {
+				_ = fs.WriteFileString("/etc/resolv.conf", "# Generated by NetworkManager\nnameserver 169.254.0.2\nnameserver fd00::2\n")
+			})
+
+			err := dnsManager.SetPrimary("169.254.0.2", "fd00::2")
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/NetworkManager/conf.d/bosh-dns.conf")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(conf))
+			Expect(fakeCmdRunner.RunCommands).To(Equal([][]string{{"nmcli", "general", "reload"}}))
+		})
+
+		It("skips if resolv.conf already lists our servers first", func() {
+			_ = fs.WriteFileString("/etc/NetworkManager/conf.d/bosh-dns.conf", conf)
+			_ = fs.WriteFileString("/etc/resolv.conf", "# Generated by NetworkManager\nnameserver 169.254.0.2\nnameserver fd00::2\n")
+
+			err := dnsManager.SetPrimary("169.254.0.2", "fd00::2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(0))
+		})
+
+		It("errors when writing the configuration fails", func() {
+			fs.WriteFileError = errors.New("fake-err1")
+
+			err := dnsManager.SetPrimary("169.254.0.2")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Writing NetworkManager configuration"))
+			Expect(err.Error()).To(ContainSubstring("fake-err1"))
+		})
+
+		It("errors when reloading NetworkManager fails", func() {
+			fakeCmdRunner.AddCmdResult("nmcli general reload", boshsysfakes.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-err1")})
+
+			err := dnsManager.SetPrimary("169.254.0.2")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reloading NetworkManager"))
+			Expect(err.Error()).To(ContainSubstring("fake-err1"))
+		})
+
+		It("errors when NetworkManager does not write our servers", func() {
+			_ = fs.WriteFileString("/etc/resolv.conf", "# Generated by NetworkManager\nnameserver 10.0.0.2\n")
+			fakeCmdRunner.AddCmdResult("nmcli general reload", boshsysfakes.FakeCmdResult{})
+
+			go func() {
+				for i := 0; i < manager.MaxResolvConfRetries; i++ {
+					clock.WaitForWatcherAndIncrement(time.Second * 2)
+				}
+			}()
+			err := dnsManager.SetPrimary("169.254.0.2")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Failed to confirm nameserver in /etc/resolv.conf"))
+		})
+	})
+
+	Describe("SetResolverOptions", func() {
+		const searchConf = `# This file was automatically updated by bosh-dns
+[global-dns]
+searches=service.internal,bosh
+options=ndots:2,rotate
+`
+		var options manager.ResolverOptions
+
+		BeforeEach(func() {
+			options = manager.ResolverOptions{Search: []string{"service.internal", "bosh"}, Options: []string{"ndots:2", "rotate"}}
+		})
+
+		It("configures them globally and reloads NetworkManager", func() {
+			fakeCmdRunner.AddCmdResult("nmcli general reload", boshsysfakes.FakeCmdResult{})
+			fakeCmdRunner.SetCmdCallback("nmcli general reload", func() {
+				_ = fs.WriteFileString("/etc/resolv.conf", "# Generated by NetworkManager\nsearch service.internal bosh\nnameserver 169.254.0.2\noptions ndots:2 rotate\n")
+			})
+
+			err := dnsManager.SetResolverOptions(options)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/NetworkManager/conf.d/bosh-dns-search.conf")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(searchConf))
+			Expect(fakeCmdRunner.RunCommands).To(Equal([][]string{{"nmcli", "general", "reload"}}))
+		})
+
+		It("skips if resolv.conf already resolves with them", func() {
+			_ = fs.WriteFileString("/etc/NetworkManager/conf.d/bosh-dns-search.conf", searchConf)
+			_ = fs.WriteFileString("/etc/resolv.conf", "# Generated by NetworkManager\nsearch service.internal bosh\nnameserver 169.254.0.2\noptions ndots:2 rotate\n")
+
+			err := dnsManager.SetResolverOptions(options)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(0))
+		})
+
+		It("removes the configuration when there are none", func() {
+			_ = fs.WriteFileString("/etc/NetworkManager/conf.d/bosh-dns-search.conf", searchConf)
+			fakeCmdRunner.AddCmdResult("nmcli general reload", boshsysfakes.FakeCmdResult{})
+
+			err := dnsManager.SetResolverOptions(manager.ResolverOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fs.FileExists("/etc/NetworkManager/conf.d/bosh-dns-search.conf")).To(BeFalse())
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(1))
+		})
+
+		It("errors when reloading NetworkManager fails", func() {
+			fakeCmdRunner.AddCmdResult("nmcli general reload", boshsysfakes.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-err1")})
+
+			err := dnsManager.SetResolverOptions(options)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reloading NetworkManager"))
+		})
+	})
+})
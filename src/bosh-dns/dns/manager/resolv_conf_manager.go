@@ -1,7 +1,6 @@
 package manager
 
 import (
-	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -15,10 +14,14 @@ import (
 
 const MaxResolvConfRetries = 8
 
+const resolvConfTailPath = "/etc/resolvconf/resolv.conf.d/tail"
+
 var warningLine = "# This file was automatically updated by bosh-dns"
 
 var nameserverLineRegex = regexp.MustCompile("^nameserver (.+)")
 
+var resolverOptionLineRegex = regexp.MustCompile("^\\s*(search|options)\\s+(.+?)\\s*$")
+
 type resolvConfManager struct {
 	fs        boshsys.FileSystem
 	cmdRunner boshsys.CmdRunner
@@ -34,36 +37,16 @@ func NewResolvConfManager(clock clock.Clock, fs boshsys.FileSystem, cmdRunner bo
 }
 
 func (r *resolvConfManager) Read() ([]string, error) {
-	nameserverRegexp, err := regexp.Compile("^\\s*nameserver\\s+(\\S+)$")
-	if err != nil {
-		return nil, err
-	}
-
-	nameservers := []string{}
-	contents, err := r.fs.ReadFileString("/etc/resolv.conf")
-
-	if err != nil {
-		return nil, bosherr.WrapError(err, "attempting to read dns nameservers")
-	}
-
-	resolvConfLines := strings.Split(contents, "\n")
-	for _, line := range resolvConfLines {
-		submatch := nameserverRegexp.FindAllStringSubmatch(line, 1)
-
-		if len(submatch) > 0 {
-			nameservers = append(nameservers, submatch[0][1])
-		}
-	}
-
-	return nameservers, nil
+	return readNameservers(r.fs, "/etc/resolv.conf")
 }
 
-func (r *resolvConfManager) SetPrimary(address string) error {
-	writeString := fmt.Sprintf(`%s
-nameserver %s
-`, warningLine, address)
+func (r *resolvConfManager) SetPrimary(addresses ...string) error {
+	writeString := warningLine + "\n"
+	for _, address := range addresses {
+		writeString += fmt.Sprintf("nameserver %s\n", address)
+	}
 
-	if correct, _ := r.isCorrect(address); correct {
+	if correct, _ := r.isCorrect(addresses); correct {
 		return nil
 	}
 
@@ -73,8 +56,12 @@ nameserver %s
 			return bosherr.WrapError(err, "Reading existing head")
 		}
 
-		if !r.isStringCorrect(address, append) {
+		if !r.isStringCorrect(addresses, append) {
 			writeString = fmt.Sprintf("%s\n%s", writeString, append)
+		} else {
+			for _, line := range resolverOptionLines(append) {
+				writeString += line + "\n"
+			}
 		}
 	}
 
@@ -88,50 +75,194 @@ nameserver %s
 		return bosherr.WrapError(err, "Executing resolvconf")
 	}
 
-	for i := 0; i < MaxResolvConfRetries; i++ {
-		if correct, _ := r.isCorrect(address); correct {
+	// seems like `resolvconf -u` may not immediately update /etc/resolv.conf, so
+	// block here briefly to try and ensure it was successful before we error
+	return confirm(r.clock, "nameserver", "/etc/resolv.conf", func() (bool, error) { return r.isCorrect(addresses) })
+}
+
+// SetResolverOptions writes the search list and options to the tail, so that
+// they come last in /etc/resolv.conf and take precedence. Everything in the
+// tail after our warning line is ours.
+func (r *resolvConfManager) SetResolverOptions(options ResolverOptions) error {
+	tail := ""
+	if r.fs.FileExists(resolvConfTailPath) {
+		var err error
+		tail, err = r.fs.ReadFileString(resolvConfTailPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Reading existing tail")
+		}
+	}
+
+	lines := options.lines()
+	ours := strings.Index(tail, warningLine)
+
+	if len(lines) == 0 && ours == -1 {
+		return nil
+	}
+
+	if len(lines) > 0 {
+		if applied, _ := r.resolverOptionsApplied(options); applied {
 			return nil
 		}
+	}
+
+	if ours != -1 {
+		tail = tail[:ours]
+	}
+	if len(lines) > 0 {
+		tail += warningLine + "\n" + strings.Join(lines, "\n") + "\n"
+	}
+
+	err := r.fs.WriteFileString(resolvConfTailPath, tail)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing tail")
+	}
 
-		// seems like `resolvconf -u` may not immediately update /etc/resolv.conf, so
-		// block here briefly to try and ensure it was successful before we error
-		r.clock.Sleep(2 * time.Second)
+	_, _, _, err = r.cmdRunner.RunCommand("resolvconf", "-u")
+	if err != nil {
+		return bosherr.WrapError(err, "Executing resolvconf")
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return confirm(r.clock, "resolver options", "/etc/resolv.conf", func() (bool, error) { return r.resolverOptionsApplied(options) })
+}
+
+func (r *resolvConfManager) resolverOptionsApplied(options ResolverOptions) (bool, error) {
+	contents, err := r.fs.ReadFileString("/etc/resolv.conf")
+	if err != nil {
+		return false, err
 	}
 
-	return errors.New("Failed to confirm nameserver in /etc/resolv.conf")
+	return options.appliedIn(contents), nil
 }
 
-func (r *resolvConfManager) isCorrect(address string) (bool, error) {
+func (r *resolvConfManager) isCorrect(addresses []string) (bool, error) {
 	servers, err := r.Read()
 	if err != nil {
 		return false, err
 	}
 
-	for _, server := range servers {
-		if server == address {
-			return true, nil
+	if !startsWith(servers, addresses) {
+		return false, nil
+	}
+
+	return r.hasHeadResolverOptions()
+}
+
+// hasHeadResolverOptions reports whether every search and options line of
+// the head is still in /etc/resolv.conf, which is not the case once another
+// tool rewrote it without resolvconf.
+func (r *resolvConfManager) hasHeadResolverOptions() (bool, error) {
+	if !r.fs.FileExists("/etc/resolvconf/resolv.conf.d/head") {
+		return true, nil
+	}
+
+	head, err := r.fs.ReadFileString("/etc/resolvconf/resolv.conf.d/head")
+	if err != nil {
+		return false, err
+	}
+
+	contents, err := r.fs.ReadFileString("/etc/resolv.conf")
+	if err != nil {
+		return false, err
+	}
+
+	present := map[string]bool{}
+	for _, line := range resolverOptionLines(contents) {
+		present[line] = true
+	}
+
+	for _, line := range resolverOptionLines(head) {
+		if !present[line] {
+			return false, nil
 		}
+	}
 
-		return false, nil
+	return true, nil
+}
+
+// resolverOptionLines returns the search and options lines of contents with
+// their whitespace normalized.
+func resolverOptionLines(contents string) []string {
+	lines := []string{}
+	for _, l := range strings.Split(contents, "\n") {
+		submatch := resolverOptionLineRegex.FindStringSubmatch(l)
+		if submatch != nil {
+			lines = append(lines, submatch[1]+" "+strings.Join(strings.Fields(submatch[2]), " "))
+		}
 	}
 
-	return false, nil
+	return lines
 }
 
-func (r resolvConfManager) isStringCorrect(address, contents string) bool {
-	lines := strings.Split(contents, "\n")
+func (r resolvConfManager) isStringCorrect(addresses []string, contents string) bool {
+	servers := []string{}
 
-	for _, l := range lines {
-		if !nameserverLineRegex.MatchString(l) {
-			continue
+	for _, l := range strings.Split(contents, "\n") {
+		submatch := nameserverLineRegex.FindStringSubmatch(l)
+		if submatch != nil {
+			servers = append(servers, submatch[1])
 		}
+	}
+
+	return startsWith(servers, addresses)
+}
 
-		if l == fmt.Sprintf("nameserver %s", address) {
-			return true
+// readNameservers returns the nameservers listed in the resolv.conf(5)
+// formatted file at path.
+func readNameservers(fs boshsys.FileSystem, path string) ([]string, error) {
+	nameserverRegexp, err := regexp.Compile("^\\s*nameserver\\s+(\\S+)$")
+	if err != nil {
+		return nil, err
+	}
+
+	nameservers := []string{}
+	contents, err := fs.ReadFileString(path)
+
+	if err != nil {
+		return nil, bosherr.WrapError(err, "attempting to read dns nameservers")
+	}
+
+	resolvConfLines := strings.Split(contents, "\n")
+	for _, line := range resolvConfLines {
+		submatch := nameserverRegexp.FindAllStringSubmatch(line, 1)
+
+		if len(submatch) > 0 {
+			nameservers = append(nameservers, submatch[0][1])
+		}
+	}
+
+	return nameservers, nil
+}
+
+// confirm waits for isCorrect to hold, as the system resolver applies
+// configuration changes asynchronously.
+func confirm(clock clock.Clock, what, path string, isCorrect func() (bool, error)) error {
+	for i := 0; i < MaxResolvConfRetries; i++ {
+		if correct, _ := isCorrect(); correct {
+			return nil
 		}
 
+		clock.Sleep(2 * time.Second)
+	}
+
+	return fmt.Errorf("Failed to confirm %s in %s", what, path)
+}
+
+// startsWith reports whether servers lists the addresses first, in order.
+func startsWith(servers, addresses []string) bool {
+	if len(addresses) == 0 || len(servers) < len(addresses) {
 		return false
 	}
 
-	return false
+	for i, address := range addresses {
+		if servers[i] != address {
+			return false
+		}
+	}
+
+	return true
 }
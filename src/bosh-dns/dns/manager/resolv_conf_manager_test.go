@@ -240,5 +240,209 @@ nameserver 192.0.3.1
 nameserver 192.0.3.2
 `))
 		})
+
+		Context("when the head declares search domains and options", func() {
+			BeforeEach(func() {
+				_ = fs.WriteFileString("/etc/resolvconf/resolv.conf.d/head", `# This file was automatically updated by bosh-dns
+nameserver 192.0.2.100
+
+search corp.internal
+options  timeout:1   attempts:2
+`)
+			})
+
+			It("skips if resolvconf still has them", func() {
+				_ = fs.WriteFileString("/etc/resolv.conf", `nameserver 192.0.2.100
+search corp.internal
+options timeout:1 attempts:2
+`)
+
+				err := dnsManager.SetPrimary("192.0.2.100")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeCmdRunner.RunCommands).To(HaveLen(0))
+			})
+
+			It("updates resolvconf if another tool dropped them, keeping them in the head", func() {
+				_ = fs.WriteFileString("/etc/resolv.conf", `nameserver 192.0.2.100
+search other.internal
+`)
+				fakeCmdRunner.AddCmdResult("resolvconf -u", boshsysfakes.FakeCmdResult{})
+				fakeCmdRunner.SetCmdCallback("resolvconf -u", func() {
+					_ = fs.WriteFileString("/etc/resolv.conf", `nameserver 192.0.2.100
+search corp.internal
+options timeout:1 attempts:2
+search other.internal
+`)
+				})
+
+				go clock.WaitForWatcherAndIncrement(time.Second * 2)
+				err := dnsManager.SetPrimary("192.0.2.100")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeCmdRunner.RunCommands).To(HaveLen(1))
+
+				contents, err := fs.ReadFileString("/etc/resolvconf/resolv.conf.d/head")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(contents).To(Equal(`# This file was automatically updated by bosh-dns
+nameserver 192.0.2.100
+search corp.internal
+options timeout:1 attempts:2
+`))
+			})
+
+			It("errors if resolvconf does not restore them", func() {
+				_ = fs.WriteFileString("/etc/resolv.conf", `nameserver 192.0.2.100`)
+				fakeCmdRunner.AddCmdResult("resolvconf -u", boshsysfakes.FakeCmdResult{})
+
+				go func() {
+					for i := 0; i < manager.MaxResolvConfRetries; i++ {
+						clock.WaitForWatcherAndIncrement(time.Second * 2)
+					}
+				}()
+				err := dnsManager.SetPrimary("192.0.2.100")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Failed to confirm nameserver "))
+			})
+		})
+
+		Context("when the DNS server listens on several addresses", func() {
+			It("writes a nameserver line for each address in order", func() {
+				fakeCmdRunner.AddCmdResult("resolvconf -u", boshsysfakes.FakeCmdResult{})
+				fakeCmdRunner.SetCmdCallback("resolvconf -u", func() {
+					_ = fs.WriteFileString("/etc/resolv.conf", "nameserver 169.254.0.2\nnameserver fd00::2\n")
+				})
+
+				go clock.WaitForWatcherAndIncrement(time.Second * 2)
+				err := dnsManager.SetPrimary("169.254.0.2", "fd00::2")
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := fs.ReadFileString("/etc/resolvconf/resolv.conf.d/head")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(contents).To(Equal(`# This file was automatically updated by bosh-dns
+nameserver 169.254.0.2
+nameserver fd00::2
+`))
+			})
+
+			It("skips if resolvconf already lists all of our servers first", func() {
+				_ = fs.WriteFileString("/etc/resolv.conf", "nameserver 169.254.0.2\nnameserver fd00::2\nnameserver 8.8.8.8\n")
+
+				err := dnsManager.SetPrimary("169.254.0.2", "fd00::2")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeCmdRunner.RunCommands).To(HaveLen(0))
+			})
+
+			It("updates resolvconf if only some of our servers are listed first", func() {
+				_ = fs.WriteFileString("/etc/resolv.conf", "nameserver 169.254.0.2\nnameserver 8.8.8.8\n")
+				fakeCmdRunner.AddCmdResult("resolvconf -u", boshsysfakes.FakeCmdResult{})
+				fakeCmdRunner.SetCmdCallback("resolvconf -u", func() {
+					_ = fs.WriteFileString("/etc/resolv.conf", "nameserver 169.254.0.2\nnameserver fd00::2\nnameserver 8.8.8.8\n")
+				})
+
+				go clock.WaitForWatcherAndIncrement(time.Second * 2)
+				err := dnsManager.SetPrimary("169.254.0.2", "fd00::2")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeCmdRunner.RunCommands).To(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("SetResolverOptions", func() {
+		var options manager.ResolverOptions
+
+		BeforeEach(func() {
+			options = manager.ResolverOptions{
+				Search:  []string{"service.internal", "bosh"},
+				Options: []string{"ndots:2", "timeout:1", "rotate"},
+			}
+			fakeCmdRunner.AddCmdResult("resolvconf -u", boshsysfakes.FakeCmdResult{})
+		})
+
+		It("writes them to the tail, after what others put there", func() {
+			_ = fs.WriteFileString("/etc/resolvconf/resolv.conf.d/tail", "# local additions\noptions edns0\n")
+			fakeCmdRunner.SetCmdCallback("resolvconf -u", func() {
+				_ = fs.WriteFileString("/etc/resolv.conf", "nameserver 192.0.2.100\nsearch dhcp.internal\noptions edns0\nsearch service.internal bosh\noptions ndots:2 timeout:1 rotate\n")
+			})
+
+			err := dnsManager.SetResolverOptions(options)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/resolvconf/resolv.conf.d/tail")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(`# local additions
+options edns0
+# This file was automatically updated by bosh-dns
+search service.internal bosh
+options ndots:2 timeout:1 rotate
+`))
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(1))
+		})
+
+		It("replaces the ones it wrote before", func() {
+			_ = fs.WriteFileString("/etc/resolvconf/resolv.conf.d/tail", "# This file was automatically updated by bosh-dns\nsearch old.internal\n")
+			fakeCmdRunner.SetCmdCallback("resolvconf -u", func() {
+				_ = fs.WriteFileString("/etc/resolv.conf", "nameserver 192.0.2.100\nsearch service.internal bosh\noptions ndots:2 timeout:1 rotate\n")
+			})
+
+			err := dnsManager.SetResolverOptions(options)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/resolvconf/resolv.conf.d/tail")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(`# This file was automatically updated by bosh-dns
+search service.internal bosh
+options ndots:2 timeout:1 rotate
+`))
+		})
+
+		It("skips if resolv.conf already resolves with them", func() {
+			_ = fs.WriteFileString("/etc/resolv.conf", "nameserver 192.0.2.100\noptions ndots:1\nsearch service.internal bosh\noptions rotate ndots:2 timeout:1\n")
+
+			err := dnsManager.SetResolverOptions(options)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(0))
+		})
+
+		It("updates resolvconf if a later line overrides them", func() {
+			_ = fs.WriteFileString("/etc/resolv.conf", "nameserver 192.0.2.100\nsearch service.internal bosh\noptions ndots:2 timeout:1 rotate\nsearch dhcp.internal\n")
+			fakeCmdRunner.SetCmdCallback("resolvconf -u", func() {
+				_ = fs.WriteFileString("/etc/resolv.conf", "nameserver 192.0.2.100\nsearch service.internal bosh\noptions ndots:2 timeout:1 rotate\n")
+			})
+
+			err := dnsManager.SetResolverOptions(options)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(1))
+		})
+
+		It("removes the ones it wrote when there are none", func() {
+			_ = fs.WriteFileString("/etc/resolvconf/resolv.conf.d/tail", "options edns0\n# This file was automatically updated by bosh-dns\nsearch old.internal\n")
+
+			err := dnsManager.SetResolverOptions(manager.ResolverOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/resolvconf/resolv.conf.d/tail")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal("options edns0\n"))
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(1))
+		})
+
+		It("leaves the tail alone when there are none", func() {
+			_ = fs.WriteFileString("/etc/resolvconf/resolv.conf.d/tail", "search local.internal\n")
+
+			err := dnsManager.SetResolverOptions(manager.ResolverOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(0))
+		})
+
+		It("errors if resolvconf does not apply them", func() {
+			_ = fs.WriteFileString("/etc/resolv.conf", "nameserver 192.0.2.100\n")
+
+			go func() {
+				for i := 0; i < manager.MaxResolvConfRetries; i++ {
+					clock.WaitForWatcherAndIncrement(time.Second * 2)
+				}
+			}()
+			err := dnsManager.SetResolverOptions(options)
+			Expect(err).To(MatchError("Failed to confirm resolver options in /etc/resolv.conf"))
+		})
 	})
 })
@@ -0,0 +1,60 @@
+package manager
+
+import (
+	"strings"
+)
+
+// lines returns the resolv.conf(5) lines configuring o.
+func (o ResolverOptions) lines() []string {
+	lines := []string{}
+	if len(o.Search) > 0 {
+		lines = append(lines, "search "+strings.Join(o.Search, " "))
+	}
+	if len(o.Options) > 0 {
+		lines = append(lines, "options "+strings.Join(o.Options, " "))
+	}
+
+	return lines
+}
+
+// appliedIn reports whether the resolv.conf(5) formatted contents resolve
+// with o: the last search line, which is the one in effect, lists the search
+// domains of o first and every option of o is set, to its value if it takes one.
+func (o ResolverOptions) appliedIn(contents string) bool {
+	search := []string{}
+	options := map[string]string{}
+
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "search", "domain":
+			search = fields[1:]
+		case "options":
+			// later options override earlier ones of the same name
+			for _, option := range fields[1:] {
+				options[optionName(option)] = option
+			}
+		}
+	}
+
+	if len(o.Search) > 0 && !startsWith(search, o.Search) {
+		return false
+	}
+
+	for _, option := range o.Options {
+		if options[optionName(option)] != option {
+			return false
+		}
+	}
+
+	return true
+}
+
+// optionName returns the name of option, e.g. ndots for ndots:2.
+func optionName(option string) string {
+	return strings.SplitN(option, ":", 2)[0]
+}
@@ -0,0 +1,157 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	// ResolvedDropInPath configures the global DNS servers of systemd-resolved.
+	ResolvedDropInPath = "/etc/systemd/resolved.conf.d/bosh-dns.conf"
+
+	// ResolvedSearchDropInPath configures the global search domains of
+	// systemd-resolved. It is read before ResolvedDropInPath, so that its
+	// domains come first.
+	ResolvedSearchDropInPath = "/etc/systemd/resolved.conf.d/bosh-dns-search.conf"
+
+	// ResolvedUpstreamPath lists the DNS servers systemd-resolved forwards
+	// to, global ones first.
+	ResolvedUpstreamPath = "/run/systemd/resolve/resolv.conf"
+)
+
+// systemdResolvedManager configures systemd-resolved to forward every query
+// to us. /etc/resolv.conf keeps pointing at the resolved stub listener.
+type systemdResolvedManager struct {
+	fs        boshsys.FileSystem
+	cmdRunner boshsys.CmdRunner
+	clock     clock.Clock
+}
+
+func NewSystemdResolvedManager(clock clock.Clock, fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner) *systemdResolvedManager {
+	return &systemdResolvedManager{
+		fs:        fs,
+		cmdRunner: cmdRunner,
+		clock:     clock,
+	}
+}
+
+// Read returns the servers systemd-resolved forwards to: ours followed by
+// those of the network links, such as the ones learned through DHCP.
+func (r *systemdResolvedManager) Read() ([]string, error) {
+	return readNameservers(r.fs, ResolvedUpstreamPath)
+}
+
+func (r *systemdResolvedManager) SetPrimary(addresses ...string) error {
+	// the ~. routing domain prefers the global servers over those of the links
+	dropIn := fmt.Sprintf("%s\n[Resolve]\nDNS=%s\nDomains=~.\n", warningLine, strings.Join(addresses, " "))
+
+	if correct, _ := r.isCorrect(addresses, dropIn); correct {
+		return nil
+	}
+
+	err := r.fs.WriteFileString(ResolvedDropInPath, dropIn)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing resolved drop-in")
+	}
+
+	err = r.restart()
+	if err != nil {
+		return err
+	}
+
+	return confirm(r.clock, "nameserver", ResolvedUpstreamPath, func() (bool, error) { return r.isCorrect(addresses, dropIn) })
+}
+
+// SetResolverOptions configures the search domains globally. Options cannot be
+// configured, as /etc/resolv.conf points at the stub file of resolved.
+func (r *systemdResolvedManager) SetResolverOptions(options ResolverOptions) error {
+	err := r.setSearch(options.Search)
+	if err != nil {
+		return err
+	}
+
+	if len(options.Options) > 0 {
+		return errors.New("Resolver options are not supported by systemd-resolved")
+	}
+
+	return nil
+}
+
+func (r *systemdResolvedManager) setSearch(search []string) error {
+	exists := r.fs.FileExists(ResolvedSearchDropInPath)
+
+	if len(search) == 0 {
+		if !exists {
+			return nil
+		}
+
+		err := r.fs.RemoveAll(ResolvedSearchDropInPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Removing resolved search drop-in")
+		}
+
+		return r.restart()
+	}
+
+	dropIn := fmt.Sprintf("%s\n[Resolve]\nDomains=%s\n", warningLine, strings.Join(search, " "))
+	options := ResolverOptions{Search: search}
+
+	if applied, _ := r.searchApplied(options, dropIn); applied {
+		return nil
+	}
+
+	err := r.fs.WriteFileString(ResolvedSearchDropInPath, dropIn)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing resolved search drop-in")
+	}
+
+	err = r.restart()
+	if err != nil {
+		return err
+	}
+
+	return confirm(r.clock, "search domains", ResolvedUpstreamPath, func() (bool, error) { return r.searchApplied(options, dropIn) })
+}
+
+func (r *systemdResolvedManager) searchApplied(options ResolverOptions, dropIn string) (bool, error) {
+	contents, err := r.fs.ReadFileString(ResolvedSearchDropInPath)
+	if err != nil || contents != dropIn {
+		return false, err
+	}
+
+	upstream, err := r.fs.ReadFileString(ResolvedUpstreamPath)
+	if err != nil {
+		return false, err
+	}
+
+	return options.appliedIn(upstream), nil
+}
+
+func (r *systemdResolvedManager) restart() error {
+	_, _, _, err := r.cmdRunner.RunCommand("systemctl", "restart", "systemd-resolved")
+	if err != nil {
+		return bosherr.WrapError(err, "Restarting systemd-resolved")
+	}
+
+	return nil
+}
+
+func (r *systemdResolvedManager) isCorrect(addresses []string, dropIn string) (bool, error) {
+	contents, err := r.fs.ReadFileString(ResolvedDropInPath)
+	if err != nil || contents != dropIn {
+		return false, err
+	}
+
+	servers, err := r.Read()
+	if err != nil {
+		return false, err
+	}
+
+	return startsWith(servers, addresses), nil
+}
@@ -0,0 +1,174 @@
+package manager_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/manager"
+
+	boshsysfakes "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SystemdResolvedManager", func() {
+	var (
+		dnsManager    manager.DNSManager
+		fs            *boshsysfakes.FakeFileSystem
+		clock         *fakeclock.FakeClock
+		fakeCmdRunner *boshsysfakes.FakeCmdRunner
+	)
+
+	const dropIn = `# This file was automatically updated by bosh-dns
+[Resolve]
+DNS=169.254.0.2 fd00::2
+Domains=~.
+`
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		fakeCmdRunner = boshsysfakes.NewFakeCmdRunner()
+		fs = boshsysfakes.NewFakeFileSystem()
+		dnsManager = manager.NewSystemdResolvedManager(clock, fs, fakeCmdRunner)
+	})
+
+	Describe("Read", func() {
+		It("returns the servers resolved forwards to rather than its stub listener", func() {
+			_ = fs.WriteFileString("/etc/resolv.conf", "nameserver 127.0.0.53\n")
+			_ = fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 169.254.0.2\nnameserver 10.0.0.2\nsearch corp.internal\n")
+
+			nameservers, err := dnsManager.Read()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nameservers).To(Equal([]string{"169.254.0.2", "10.0.0.2"}))
+		})
+
+		It("errors when resolved does not list its servers", func() {
+			_, err := dnsManager.Read()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("attempting to read dns nameservers"))
+		})
+	})
+
+	Describe("SetPrimary", func() {
+		It("configures our servers globally and restarts resolved", func() {
+			fakeCmdRunner.AddCmdResult("systemctl restart systemd-resolved", boshsysfakes.FakeCmdResult{})
+			fakeCmdRunner.SetCmdCallback("systemctl restart systemd-resolved", func() {
+				_ = fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 169.254.0.2\nnameserver fd00::2\nnameserver 10.0.0.2\n")
+			})
+
+			err := dnsManager.SetPrimary("169.254.0.2", "fd00::2")
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/systemd/resolved.conf.d/bosh-dns.conf")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(dropIn))
+			Expect(fakeCmdRunner.RunCommands).To(Equal([][]string{{"systemctl", "restart", "systemd-resolved"}}))
+		})
+
+		It("skips if resolved already forwards to our servers first", func() {
+			_ = fs.WriteFileString("/etc/systemd/resolved.conf.d/bosh-dns.conf", dropIn)
+			_ = fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 169.254.0.2\nnameserver fd00::2\nnameserver 10.0.0.2\n")
+
+			err := dnsManager.SetPrimary("169.254.0.2", "fd00::2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(0))
+		})
+
+		It("rewrites the drop-in when it was changed", func() {
+			_ = fs.WriteFileString("/etc/systemd/resolved.conf.d/bosh-dns.conf", "[Resolve]\nDNS=8.8.8.8\n")
+			_ = fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 169.254.0.2\nnameserver fd00::2\n")
+			fakeCmdRunner.AddCmdResult("systemctl restart systemd-resolved", boshsysfakes.FakeCmdResult{})
+
+			err := dnsManager.SetPrimary("169.254.0.2", "fd00::2")
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/systemd/resolved.conf.d/bosh-dns.conf")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(dropIn))
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(1))
+		})
+
+		It("errors when writing the drop-in fails", func() {
+			fs.WriteFileError = errors.New("fake-err1")
+
+			err := dnsManager.SetPrimary("169.254.0.2")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Writing resolved drop-in"))
+			Expect(err.Error()).To(ContainSubstring("fake-err1"))
+		})
+
+		It("errors when restarting resolved fails", func() {
+			fakeCmdRunner.AddCmdResult("systemctl restart systemd-resolved", boshsysfakes.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-err1")})
+
+			err := dnsManager.SetPrimary("169.254.0.2")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Restarting systemd-resolved"))
+			Expect(err.Error()).To(ContainSubstring("fake-err1"))
+		})
+
+		It("errors when resolved does not pick up our servers", func() {
+			fakeCmdRunner.AddCmdResult("systemctl restart systemd-resolved", boshsysfakes.FakeCmdResult{})
+			_ = fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 10.0.0.2\n")
+
+			go func() {
+				for i := 0; i < manager.MaxResolvConfRetries; i++ {
+					clock.WaitForWatcherAndIncrement(time.Second * 2)
+				}
+			}()
+			err := dnsManager.SetPrimary("169.254.0.2")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Failed to confirm nameserver in /run/systemd/resolve/resolv.conf"))
+		})
+	})
+
+	Describe("SetResolverOptions", func() {
+		const searchDropIn = `# This file was automatically updated by bosh-dns
+[Resolve]
+Domains=service.internal bosh
+`
+
+		It("configures the search domains globally", func() {
+			fakeCmdRunner.AddCmdResult("systemctl restart systemd-resolved", boshsysfakes.FakeCmdResult{})
+			fakeCmdRunner.SetCmdCallback("systemctl restart systemd-resolved", func() {
+				_ = fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 169.254.0.2\nsearch service.internal bosh dhcp.internal\n")
+			})
+
+			err := dnsManager.SetResolverOptions(manager.ResolverOptions{Search: []string{"service.internal", "bosh"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/systemd/resolved.conf.d/bosh-dns-search.conf")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(searchDropIn))
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(1))
+		})
+
+		It("skips if resolved already searches them first", func() {
+			_ = fs.WriteFileString("/etc/systemd/resolved.conf.d/bosh-dns-search.conf", searchDropIn)
+			_ = fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 169.254.0.2\nsearch service.internal bosh dhcp.internal\n")
+
+			err := dnsManager.SetResolverOptions(manager.ResolverOptions{Search: []string{"service.internal", "bosh"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(0))
+		})
+
+		It("removes the search domains it configured when there are none", func() {
+			_ = fs.WriteFileString("/etc/systemd/resolved.conf.d/bosh-dns-search.conf", searchDropIn)
+			fakeCmdRunner.AddCmdResult("systemctl restart systemd-resolved", boshsysfakes.FakeCmdResult{})
+
+			err := dnsManager.SetResolverOptions(manager.ResolverOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fs.FileExists("/etc/systemd/resolved.conf.d/bosh-dns-search.conf")).To(BeFalse())
+			Expect(fakeCmdRunner.RunCommands).To(HaveLen(1))
+		})
+
+		It("errors on options after configuring the search domains", func() {
+			_ = fs.WriteFileString("/etc/systemd/resolved.conf.d/bosh-dns-search.conf", searchDropIn)
+			_ = fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 169.254.0.2\nsearch service.internal bosh\n")
+
+			err := dnsManager.SetResolverOptions(manager.ResolverOptions{Search: []string{"service.internal", "bosh"}, Options: []string{"ndots:2"}})
+			Expect(err).To(MatchError("Resolver options are not supported by systemd-resolved"))
+		})
+	})
+})
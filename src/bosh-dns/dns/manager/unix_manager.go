@@ -0,0 +1,39 @@
+package manager
+
+import (
+	"strings"
+
+	"code.cloudfoundry.org/clock"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// resolvedStubAddress is where the stub listener of systemd-resolved answers.
+const resolvedStubAddress = "127.0.0.53"
+
+// NewUnixManager returns the manager for whichever tool owns /etc/resolv.conf:
+// systemd-resolved when it points at the resolved stub listener,
+// NetworkManager when it was generated by NetworkManager, and resolvconf
+// otherwise.
+func NewUnixManager(logger boshlog.Logger, clock clock.Clock, fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner) DNSManager {
+	contents, err := fs.ReadFileString("/etc/resolv.conf")
+	if err != nil {
+		logger.Warn("DNSManager", "managing nameservers through resolvconf, unable to read /etc/resolv.conf: %s", err)
+		return NewResolvConfManager(clock, fs, cmdRunner)
+	}
+
+	nameservers, _ := readNameservers(fs, "/etc/resolv.conf")
+
+	switch {
+	case contains(nameservers, resolvedStubAddress):
+		logger.Info("DNSManager", "managing nameservers through systemd-resolved")
+		return NewSystemdResolvedManager(clock, fs, cmdRunner)
+	case strings.Contains(contents, "Generated by NetworkManager"):
+		logger.Info("DNSManager", "managing nameservers through NetworkManager")
+		return NewNetworkManagerManager(clock, fs, cmdRunner)
+	default:
+		logger.Info("DNSManager", "managing nameservers through resolvconf")
+		return NewResolvConfManager(clock, fs, cmdRunner)
+	}
+}
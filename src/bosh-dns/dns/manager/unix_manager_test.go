@@ -0,0 +1,55 @@
+package manager_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/manager"
+
+	"github.com/cloudfoundry/bosh-utils/logger/fakes"
+	boshsysfakes "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewUnixManager", func() {
+	var (
+		logger        *fakes.FakeLogger
+		fs            *boshsysfakes.FakeFileSystem
+		fakeCmdRunner *boshsysfakes.FakeCmdRunner
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.FakeLogger{}
+		fs = boshsysfakes.NewFakeFileSystem()
+		fakeCmdRunner = boshsysfakes.NewFakeCmdRunner()
+	})
+
+	newManager := func() manager.DNSManager {
+		return manager.NewUnixManager(logger, fakeclock.NewFakeClock(time.Now()), fs, fakeCmdRunner)
+	}
+
+	It("picks systemd-resolved when resolv.conf points at its stub listener", func() {
+		_ = fs.WriteFileString("/etc/resolv.conf", "# This is /run/systemd/resolve/stub-resolv.conf managed by man:systemd-resolved(8).\nnameserver 127.0.0.53\noptions edns0 trust-ad\n")
+
+		Expect(newManager()).To(BeAssignableToTypeOf(manager.NewSystemdResolvedManager(nil, nil, nil)))
+	})
+
+	It("picks NetworkManager when it generated resolv.conf", func() {
+		_ = fs.WriteFileString("/etc/resolv.conf", "# Generated by NetworkManager\nnameserver 10.0.0.2\n")
+
+		Expect(newManager()).To(BeAssignableToTypeOf(manager.NewNetworkManagerManager(nil, nil, nil)))
+	})
+
+	It("picks resolvconf otherwise", func() {
+		_ = fs.WriteFileString("/etc/resolv.conf", "nameserver 10.0.0.2\n")
+
+		Expect(newManager()).To(BeAssignableToTypeOf(manager.NewResolvConfManager(nil, nil, nil)))
+	})
+
+	It("picks resolvconf when resolv.conf cannot be read", func() {
+		Expect(newManager()).To(BeAssignableToTypeOf(manager.NewResolvConfManager(nil, nil, nil)))
+		Expect(logger.WarnCallCount()).To(Equal(1))
+	})
+})
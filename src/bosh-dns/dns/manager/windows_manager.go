@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"errors"
 	"path/filepath"
 	"strings"
 
@@ -25,7 +26,7 @@ Exit 0
 `
 
 const prependDNSServer = `
-param ($DNSAddress = $(throw "DNSAddress parameter is required."))
+param ($DNSAddresses = $(throw "DNSAddresses parameter is required."))
 
 $ErrorActionPreference = "Stop"
 
@@ -33,24 +34,51 @@ function DnsServers($interface) {
   return (Get-DnsClientServerAddress -InterfaceAlias $interface -AddressFamily ipv4 -ErrorAction Stop).ServerAddresses
 }
 
+function IsFirst($servers, $addresses) {
+  return (($servers | Select-Object -First $addresses.Count) -join ",") -eq ($addresses -join ",")
+}
+
 try {
+  [array]$addresses = $DNSAddresses -split ","
+
   # identify our interface
   [array]$routeable_interfaces = Get-WmiObject Win32_NetworkAdapterConfiguration | Where { $_.IpAddress -AND ($_.IpAddress | Where { $addr = [Net.IPAddress] $_; $addr.AddressFamily -eq "InterNetwork" -AND ($addr.address -BAND ([Net.IPAddress] "255.255.0.0").address) -ne ([Net.IPAddress] "169.254.0.0").address }) }
   $interface = (Get-WmiObject Win32_NetworkAdapter | Where { $_.DeviceID -eq $routeable_interfaces[0].Index }).netconnectionid
 
   # avoid prepending if we happen to already be at the top to try and avoid races
   [array]$servers = DnsServers($interface)
-  if($servers[0] -eq $DNSAddress) {
+  if(IsFirst $servers $addresses) {
     Exit 0
   }
 
-  Set-DnsClientServerAddress -InterfaceAlias $interface -ServerAddresses (,$DNSAddress + $servers)
+  Set-DnsClientServerAddress -InterfaceAlias $interface -ServerAddresses ($addresses + $servers)
 
   # read back the servers in case set silently failed
   [array]$servers = DnsServers($interface)
-  if($servers[0] -ne $DNSAddress) {
-      Write-Error "Failed to set '${DNSAddress}' as the first dns client server address"
+  if(-not (IsFirst $servers $addresses)) {
+      Write-Error "Failed to set '${DNSAddresses}' as the first dns client server addresses"
+  }
+} catch {
+  $Host.UI.WriteErrorLine($_.Exception.Message)
+  Exit 1
+}
+
+Exit 0
+`
+
+const setSuffixSearchList = `
+param ($SuffixSearchList = $(throw "SuffixSearchList parameter is required."))
+
+$ErrorActionPreference = "Stop"
+
+try {
+  [array]$suffixes = $SuffixSearchList -split ","
+
+  if (((Get-DnsClientGlobalSetting).SuffixSearchList -join ",") -eq ($suffixes -join ",")) {
+    Exit 0
   }
+
+  Set-DnsClientGlobalSetting -SuffixSearchList $suffixes
 } catch {
   $Host.UI.WriteErrorLine($_.Exception.Message)
   Exit 1
@@ -68,13 +96,13 @@ func NewWindowsManager(runner boshsys.CmdRunner, fs boshsys.FileSystem) *windows
 	return &windowsManager{runner: runner, fs: fs}
 }
 
-func (manager *windowsManager) SetPrimary(address string) error {
+func (manager *windowsManager) SetPrimary(addresses ...string) error {
 	servers, err := manager.Read()
 	if err != nil {
 		return err
 	}
 
-	if len(servers) > 0 && servers[0] == address {
+	if startsWith(servers, addresses) {
 		return nil
 	}
 
@@ -84,7 +112,7 @@ func (manager *windowsManager) SetPrimary(address string) error {
 	}
 	defer manager.fs.RemoveAll(filepath.Dir(scriptName))
 
-	_, _, _, err = manager.runner.RunCommand("powershell.exe", scriptName, address)
+	_, _, _, err = manager.runner.RunCommand("powershell.exe", scriptName, strings.Join(addresses, ","))
 	if err != nil {
 		return bosherr.WrapError(err, "Executing prepend-dns-server.ps1")
 	}
@@ -92,6 +120,30 @@ func (manager *windowsManager) SetPrimary(address string) error {
 	return nil
 }
 
+// SetResolverOptions sets the suffix search list of the DNS client. Without
+// search domains, the suffix search list is left alone. Windows has no
+// equivalent of resolver options.
+func (manager *windowsManager) SetResolverOptions(options ResolverOptions) error {
+	if len(options.Search) > 0 {
+		scriptName, err := manager.writeScript("set-suffix-search-list", setSuffixSearchList)
+		if err != nil {
+			return bosherr.WrapError(err, "Creating set-suffix-search-list.ps1")
+		}
+		defer manager.fs.RemoveAll(filepath.Dir(scriptName))
+
+		_, _, _, err = manager.runner.RunCommand("powershell.exe", scriptName, strings.Join(options.Search, ","))
+		if err != nil {
+			return bosherr.WrapError(err, "Executing set-suffix-search-list.ps1")
+		}
+	}
+
+	if len(options.Options) > 0 {
+		return errors.New("Resolver options are not supported on windows")
+	}
+
+	return nil
+}
+
 func (manager *windowsManager) Read() ([]string, error) {
 	scriptName, err := manager.writeScript("list-dns-servers", listResolvers)
 	if err != nil {
@@ -141,5 +141,59 @@ var _ = Describe("WindowsManager", func() {
 
 			Expect(fakeCmdRunner.RunCommandCallCount()).To(Equal(1))
 		})
+
+		Context("when the DNS server listens on several addresses", func() {
+			It("prepends all addresses in a single call", func() {
+				fakeCmdRunner.RunCommandReturns(fmt.Sprintf("%s\r\n%s", "8.8.8.8", address), "", 0, nil)
+
+				err := dnsManager.SetPrimary(address, "192.0.2.101")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeCmdRunner.RunCommandCallCount()).To(Equal(2))
+				_, args := fakeCmdRunner.RunCommandArgsForCall(1)
+				Expect(args[1]).To(Equal("192.0.2.100,192.0.2.101"))
+			})
+
+			It("skips if dns already lists all addresses first", func() {
+				fakeCmdRunner.RunCommandReturns(fmt.Sprintf("%s\r\n%s\r\n%s", address, "192.0.2.101", "8.8.8.8"), "", 0, nil)
+
+				err := dnsManager.SetPrimary(address, "192.0.2.101")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeCmdRunner.RunCommandCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("SetResolverOptions", func() {
+		It("sets the suffix search list", func() {
+			err := dnsManager.SetResolverOptions(manager.ResolverOptions{Search: []string{"service.internal", "bosh"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeCmdRunner.RunCommandCallCount()).To(Equal(1))
+			cmd, args := fakeCmdRunner.RunCommandArgsForCall(0)
+			Expect(cmd).To(Equal("powershell.exe"))
+			Expect(filepath.Base(args[0])).To(Equal("set-suffix-search-list.ps1"))
+			Expect(args[1]).To(Equal("service.internal,bosh"))
+			Expect(fakeFileSystem.FileExists(filepath.Dir(args[0]))).To(BeFalse())
+		})
+
+		It("leaves the suffix search list alone without search domains", func() {
+			err := dnsManager.SetResolverOptions(manager.ResolverOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeCmdRunner.RunCommandCallCount()).To(Equal(0))
+		})
+
+		It("errors when powershell fails", func() {
+			fakeCmdRunner.RunCommandReturns("", "", 1, errors.New("fake-err1"))
+
+			err := dnsManager.SetResolverOptions(manager.ResolverOptions{Search: []string{"bosh"}})
+			Expect(err).To(MatchError("Executing set-suffix-search-list.ps1: fake-err1"))
+		})
+
+		It("errors on options, which windows does not have", func() {
+			err := dnsManager.SetResolverOptions(manager.ResolverOptions{Options: []string{"ndots:2"}})
+			Expect(err).To(MatchError("Resolver options are not supported on windows"))
+		})
 	})
 })
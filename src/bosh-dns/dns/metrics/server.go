@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Serve answers with the expvars of the process at /debug/vars on listener
+// until signal is closed. Only the expvars are served, unlike the handlers
+// registered on http.DefaultServeMux.
+func Serve(listener net.Listener, signal <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/vars", serveVars)
+
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		<-signal
+		server.Close()
+	}()
+
+	err := server.Serve(listener)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
+
+// serveVars writes every expvar as one JSON object, like the handler expvar
+// registers on http.DefaultServeMux.
+func serveVars(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	fmt.Fprintf(w, "{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if !first {
+			fmt.Fprintf(w, ",\n")
+		}
+		first = false
+		fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+	})
+	fmt.Fprintf(w, "\n}\n")
+}
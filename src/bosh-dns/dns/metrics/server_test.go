@@ -0,0 +1,69 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"net"
+	"net/http"
+
+	"bosh-dns/dns/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var published = expvar.NewInt("metrics_test_value")
+
+var _ = Describe("Serve", func() {
+	var (
+		listener net.Listener
+		signal   chan struct{}
+		served   chan error
+	)
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		signal = make(chan struct{})
+		served = make(chan error, 1)
+		go func(listener net.Listener, signal <-chan struct{}, served chan<- error) {
+			served <- metrics.Serve(listener, signal)
+		}(listener, signal, served)
+	})
+
+	AfterEach(func() {
+		select {
+		case <-signal:
+		default:
+			close(signal)
+		}
+	})
+
+	It("serves the expvars", func() {
+		published.Set(42)
+
+		resp, err := http.Get("http://" + listener.Addr().String() + "/debug/vars")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		var vars map[string]interface{}
+		Expect(json.NewDecoder(resp.Body).Decode(&vars)).To(Succeed())
+		Expect(vars).To(HaveKeyWithValue("metrics_test_value", BeNumerically("==", 42)))
+	})
+
+	It("serves nothing else", func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/debug/pprof/")
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("stops once signalled", func() {
+		close(signal)
+
+		Eventually(served).Should(Receive(BeNil()))
+	})
+})
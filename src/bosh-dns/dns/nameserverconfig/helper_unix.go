@@ -9,6 +9,17 @@ import (
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+// watchedFiles are the files whose changes may put other nameservers ahead
+// of ours, whichever of resolvconf, systemd-resolved or NetworkManager
+// manages them.
+var watchedFiles = []string{
+	"/etc/resolv.conf",
+	"/etc/resolvconf/resolv.conf.d/head",
+	manager.ResolvedDropInPath,
+	manager.ResolvedUpstreamPath,
+	manager.NetworkManagerConfPath,
+}
+
 func newDNSManager(logger logger.Logger, clock clock.Clock, fs boshsys.FileSystem) manager.DNSManager {
-	return manager.NewResolvConfManager(clock, fs, boshsys.NewExecCmdRunner(logger))
+	return manager.NewUnixManager(logger, clock, fs, boshsys.NewExecCmdRunner(logger))
 }
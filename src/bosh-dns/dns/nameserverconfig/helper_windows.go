@@ -8,6 +8,10 @@ import (
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+// watchedFiles is empty as the nameservers of network adapters are not kept
+// in files.
+var watchedFiles = []string{}
+
 func newDNSManager(logger logger.Logger, _ clock.Clock, fs boshsys.FileSystem) manager.DNSManager {
 	return manager.NewWindowsManager(boshsys.NewExecCmdRunner(logger), fs)
 }
@@ -6,9 +6,12 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/manager"
 	"bosh-dns/dns/nameserverconfig/monitor"
 
 	"code.cloudfoundry.org/clock"
@@ -17,12 +20,27 @@ import (
 )
 
 func main() {
-	var bindAddress string
-	flag.StringVar(&bindAddress, "bindAddress", "", "address that our dns server is binding to")
+	var bindAddress, configPath string
+	flag.StringVar(&bindAddress, "bindAddress", "", "comma separated addresses that our dns server is binding to")
+	flag.StringVar(&configPath, "config", "", "path to the config.json of our dns server, whose resolver search list and options are enforced")
 	flag.Parse()
 
-	if net.ParseIP(bindAddress) == nil {
-		log.Fatalf("invalid ip: %s", bindAddress)
+	bindAddresses := strings.Split(bindAddress, ",")
+	for _, address := range bindAddresses {
+		if net.ParseIP(address) == nil {
+			log.Fatalf("invalid ip: %s", address)
+		}
+	}
+
+	resolverOptions := manager.ResolverOptions{}
+	if configPath != "" {
+		c, err := config.LoadFromFile(configPath)
+		if err != nil {
+			log.Fatalf("loading config: %s", err)
+		}
+
+		resolverOptions.Search = c.Resolver.Search
+		resolverOptions.Options = c.Resolver.Options
 	}
 
 	logger := boshlog.NewAsyncWriterLogger(boshlog.LevelDebug, os.Stdout)
@@ -40,10 +58,10 @@ func main() {
 
 	monitor := monitor.NewMonitor(
 		logger,
-		bindAddress,
+		bindAddresses,
 		dnsManager,
 		ticker,
-	)
+	).WithWatchedFiles(watchedFiles...).WithResolverOptions(resolverOptions)
 	go monitor.Run(shutdown)
 
 	<-sigterm
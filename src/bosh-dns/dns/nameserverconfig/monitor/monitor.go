@@ -1,6 +1,9 @@
 package monitor
 
 import (
+	"strings"
+
+	"bosh-dns/dns/filewatcher"
 	"bosh-dns/dns/manager"
 
 	"code.cloudfoundry.org/clock"
@@ -8,42 +11,136 @@ import (
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
+const logTag = "NameserverConfigMonitor"
+
 type Monitor struct {
 	logger     boshlog.Logger
-	address    string
+	addresses  []string
 	dnsManager manager.DNSManager
 	signal     clock.Ticker
+
+	watchedFiles    []string
+	resolverOptions manager.ResolverOptions
 }
 
-func NewMonitor(logger boshlog.Logger, address string, dnsManager manager.DNSManager, signal clock.Ticker) Monitor {
+func NewMonitor(logger boshlog.Logger, addresses []string, dnsManager manager.DNSManager, signal clock.Ticker) Monitor {
 	return Monitor{
 		logger:     logger,
-		address:    address,
+		addresses:  addresses,
 		dnsManager: dnsManager,
 		signal:     signal,
 	}
 }
 
+// WithWatchedFiles returns a copy of c which also applies the configuration
+// as soon as one of paths changes, rather than only on the next tick.
+func (c Monitor) WithWatchedFiles(paths ...string) Monitor {
+	c.watchedFiles = paths
+
+	return c
+}
+
+// WithResolverOptions returns a copy of c which also enforces the search list
+// and options of the system resolver.
+func (c Monitor) WithResolverOptions(options manager.ResolverOptions) Monitor {
+	c.resolverOptions = options
+
+	return c
+}
+
 func (c Monitor) RunOnce() error {
-	err := c.dnsManager.SetPrimary(c.address)
+	c.recordDrift()
+
+	err := c.dnsManager.SetPrimary(c.addresses...)
 	if err != nil {
 		return bosherr.WrapError(err, "Updating nameserver configs")
 	}
 
+	err = c.dnsManager.SetResolverOptions(c.resolverOptions)
+	if err != nil {
+		return bosherr.WrapError(err, "Updating resolver options")
+	}
+
 	return nil
 }
 
+// recordDrift logs the nameservers which were put ahead of ours.
+func (c Monitor) recordDrift() {
+	nameservers, err := c.dnsManager.Read()
+	if err != nil {
+		return
+	}
+
+	if startsWith(nameservers, c.addresses) {
+		return
+	}
+
+	foreign := []string{}
+	for _, nameserver := range nameservers {
+		if !contains(c.addresses, nameserver) {
+			foreign = append(foreign, nameserver)
+		}
+	}
+
+	c.logger.Warn(logTag, "nameservers drifted to [%s], foreign nameservers: [%s]", strings.Join(nameservers, ", "), strings.Join(foreign, ", "))
+}
+
 func (c Monitor) Run(shutdown chan struct{}) {
+	var changes <-chan string
+	if len(c.watchedFiles) > 0 {
+		watcher, err := filewatcher.New(c.watchedFiles)
+		if err != nil {
+			c.logger.Info(logTag, "only checking periodically, unable to watch %s: %s", strings.Join(c.watchedFiles, ", "), err)
+		} else {
+			defer watcher.Close()
+			changes = watcher.Events()
+		}
+	}
+
 	run := c.signal.C()
 	for {
 		select {
 		case <-shutdown:
 			return
-		case <-run:
-			err := c.RunOnce()
-			if err != nil {
-				c.logger.Error("NameserverConfigMonitor", "running: %s", err)
+		case path, ok := <-changes:
+			if !ok {
+				c.logger.Error(logTag, "stopped watching for changes, only checking periodically")
+				changes = nil
+				continue
 			}
+
+			c.logger.Debug(logTag, "%s changed", path)
+		case <-run:
+		}
+
+		err := c.RunOnce()
+		if err != nil {
+			c.logger.Error(logTag, "running: %s", err)
 		}
 	}
 }
+
+// startsWith reports whether nameservers lists the addresses first, in order.
+func startsWith(nameservers, addresses []string) bool {
+	if len(nameservers) < len(addresses) {
+		return false
+	}
+
+	for i, address := range addresses {
+		if nameservers[i] != address {
+			return false
+		}
+	}
+
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,85 @@
+//go:build linux
+// +build linux
+
+package monitor_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"bosh-dns/dns/manager/managerfakes"
+	"bosh-dns/dns/nameserverconfig/monitor"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/cloudfoundry/bosh-utils/logger/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Monitor watching files", func() {
+	var (
+		dir        string
+		targetDir  string
+		resolvConf string
+		head       string
+		dnsManager *managerfakes.FakeDNSManager
+		shutdown   chan struct{}
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "nameserverconfig")
+		Expect(err).NotTo(HaveOccurred())
+		targetDir, err = ioutil.TempDir("", "nameserverconfig-run")
+		Expect(err).NotTo(HaveOccurred())
+
+		target := filepath.Join(targetDir, "resolv.conf")
+		Expect(ioutil.WriteFile(target, []byte("nameserver 169.254.0.2\n"), 0644)).To(Succeed())
+
+		resolvConf = filepath.Join(dir, "resolv.conf")
+		Expect(os.Symlink(target, resolvConf)).To(Succeed())
+		head = filepath.Join(dir, "head")
+		Expect(ioutil.WriteFile(head, []byte("nameserver 169.254.0.2\n"), 0644)).To(Succeed())
+
+		dnsManager = &managerfakes.FakeDNSManager{}
+		fakeClock := fakeclock.NewFakeClock(time.Now())
+
+		applier := monitor.NewMonitor(&fakes.FakeLogger{}, []string{"169.254.0.2"}, dnsManager, fakeClock.NewTicker(time.Hour)).
+			WithWatchedFiles(resolvConf, head)
+
+		shutdown = make(chan struct{})
+		go applier.Run(shutdown)
+
+		// give the watcher a moment to be set up
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		close(shutdown)
+		Expect(os.RemoveAll(dir)).To(Succeed())
+		Expect(os.RemoveAll(targetDir)).To(Succeed())
+	})
+
+	It("applies the configuration as soon as the symlink target is replaced", func() {
+		tmpPath := filepath.Join(targetDir, "resolv.conf.tmp")
+		Expect(ioutil.WriteFile(tmpPath, []byte("nameserver 10.0.0.2\n"), 0644)).To(Succeed())
+		Expect(os.Rename(tmpPath, filepath.Join(targetDir, "resolv.conf"))).To(Succeed())
+
+		Eventually(dnsManager.SetPrimaryCallCount).Should(BeNumerically(">=", 1))
+	})
+
+	It("applies the configuration as soon as the head is written", func() {
+		Expect(ioutil.WriteFile(head, []byte("nameserver 10.0.0.2\n"), 0644)).To(Succeed())
+
+		Eventually(dnsManager.SetPrimaryCallCount).Should(BeNumerically(">=", 1))
+	})
+
+	It("ignores other files in the directories", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "hosts"), []byte("127.0.0.1 localhost\n"), 0644)).To(Succeed())
+
+		Consistently(dnsManager.SetPrimaryCallCount).Should(Equal(0))
+	})
+})
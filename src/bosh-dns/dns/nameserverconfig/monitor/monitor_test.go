@@ -2,8 +2,10 @@ package monitor_test
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
+	"bosh-dns/dns/manager"
 	"bosh-dns/dns/manager/managerfakes"
 	"bosh-dns/dns/nameserverconfig/monitor"
 
@@ -18,7 +20,7 @@ import (
 var _ = Describe("Monitor", func() {
 	var (
 		logger     *fakes.FakeLogger
-		address    string
+		addresses  []string
 		applier    monitor.Monitor
 		dnsManager *managerfakes.FakeDNSManager
 		fakeClock  *fakeclock.FakeClock
@@ -27,11 +29,11 @@ var _ = Describe("Monitor", func() {
 
 	BeforeEach(func() {
 		logger = &fakes.FakeLogger{}
-		address = "some-address"
+		addresses = []string{"some-address", "some-other-address"}
 		dnsManager = &managerfakes.FakeDNSManager{}
 		fakeClock = fakeclock.NewFakeClock(time.Now())
 		ticker = fakeClock.NewTicker(time.Second)
-		applier = monitor.NewMonitor(logger, address, dnsManager, ticker)
+		applier = monitor.NewMonitor(logger, addresses, dnsManager, ticker)
 	})
 
 	Describe("RunOnce", func() {
@@ -41,7 +43,55 @@ var _ = Describe("Monitor", func() {
 			err := applier.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
 			Expect(dnsManager.SetPrimaryCallCount()).To(Equal(1))
-			Expect(dnsManager.SetPrimaryArgsForCall(0)).To(Equal(address))
+			Expect(dnsManager.SetPrimaryArgsForCall(0)).To(Equal(addresses))
+		})
+
+		It("enforces the resolver options", func() {
+			options := manager.ResolverOptions{Search: []string{"service.internal"}, Options: []string{"ndots:2"}}
+			applier = applier.WithResolverOptions(options)
+
+			err := applier.RunOnce()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dnsManager.SetResolverOptionsCallCount()).To(Equal(1))
+			Expect(dnsManager.SetResolverOptionsArgsForCall(0)).To(Equal(options))
+		})
+
+		Context("when the resolver options cannot be enforced", func() {
+			It("returns a wrapped error", func() {
+				dnsManager.SetResolverOptionsReturns(errors.New("fake-err1"))
+
+				err := applier.RunOnce()
+				Expect(err).To(MatchError("Updating resolver options: fake-err1"))
+				Expect(dnsManager.SetPrimaryCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when other nameservers were put ahead of ours", func() {
+			BeforeEach(func() {
+				dnsManager.ReadReturns([]string{"10.0.0.2", "some-address", "8.8.8.8"}, nil)
+			})
+
+			It("records the foreign nameservers", func() {
+				err := applier.RunOnce()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(logger.WarnCallCount()).To(Equal(1))
+				_, msg, args := logger.WarnArgsForCall(0)
+				Expect(fmt.Sprintf(msg, args...)).To(Equal("nameservers drifted to [10.0.0.2, some-address, 8.8.8.8], foreign nameservers: [10.0.0.2, 8.8.8.8]"))
+				Expect(dnsManager.SetPrimaryCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when our nameservers are still first", func() {
+			BeforeEach(func() {
+				dnsManager.ReadReturns([]string{"some-address", "some-other-address", "8.8.8.8"}, nil)
+			})
+
+			It("records no drift", func() {
+				err := applier.RunOnce()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(logger.WarnCallCount()).To(Equal(0))
+			})
 		})
 
 		Context("dns manager fails", func() {
@@ -68,7 +118,7 @@ var _ = Describe("Monitor", func() {
 			BeforeEach(func() {
 				isWaiting = make(chan struct{})
 				stopWaiting = make(chan struct{})
-				dnsManager.SetPrimaryStub = func(s string) error {
+				dnsManager.SetPrimaryStub = func(s ...string) error {
 					close(isWaiting)
 					<-stopWaiting
 					return nil
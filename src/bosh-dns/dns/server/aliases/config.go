@@ -202,6 +202,19 @@ func (c Config) reduce2(alias string, depth int) ([]string, error) {
 	return resolved, nil
 }
 
+// Aliases returns every alias name, sorted. Underscore aliases are left out
+// as they match any first label.
+func (c Config) Aliases() []string {
+	aliases := make([]string, 0, len(c.aliases))
+	for alias := range c.aliases {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Strings(aliases)
+
+	return aliases
+}
+
 func (c Config) AliasHosts() []string {
 	return c.aliasHosts
 }
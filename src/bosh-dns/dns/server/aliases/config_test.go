@@ -308,6 +308,18 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	Describe("Aliases", func() {
+		It("returns the sorted alias names without underscore aliases", func() {
+			c := MustNewConfigFromMap(map[string][]string{
+				"alias2":   {"1.1.1.2"},
+				"alias1":   {"1.1.1.1"},
+				"_.alias3": {"1.1.1.3"},
+			})
+
+			Expect(c.Aliases()).To(Equal([]string{"alias1.", "alias2."}))
+		})
+	})
+
 	Describe("AliasHosts", func() {
 		It("returns the set of hosts used by aliases", func() {
 			c := MustNewConfigFromMap(map[string][]string{
@@ -0,0 +1,167 @@
+package dnssec
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+const maxLabelLength = 63
+
+// NoData returns the NSEC proving that name has none of the records of qtype.
+// The NSEC claims every type of types other than qtype, as answers are
+// synthesized and the types present at a name are not known up front.
+func NoData(name string, qtype uint16, types []uint16, ttl uint32) *dns.NSEC {
+	bitmap := []uint16{dns.TypeRRSIG, dns.TypeNSEC}
+	for _, t := range types {
+		if t != qtype {
+			bitmap = append(bitmap, t)
+		}
+	}
+
+	sort.Slice(bitmap, func(i, j int) bool { return bitmap[i] < bitmap[j] })
+
+	return &dns.NSEC{
+		Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: ttl},
+		NextDomain: `\000.` + strings.ToLower(name),
+		TypeBitMap: bitmap,
+	}
+}
+
+// NameError returns the NSECs proving that name, which must be below the
+// zone apex, does not exist: one covering name and one covering the wildcard
+// at its parent. Both only cover the names immediately around the denied
+// name, as described by RFC 4470, so that resolvers caching them
+// aggressively never deny names which do exist.
+func NameError(name string, ttl uint32) []dns.RR {
+	wildcard := "*." + parent(name)
+
+	return []dns.RR{
+		covering(name, ttl),
+		covering(wildcard, ttl),
+	}
+}
+
+func covering(name string, ttl uint32) *dns.NSEC {
+	return &dns.NSEC{
+		Hdr:        dns.RR_Header{Name: predecessor(name), Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: ttl},
+		NextDomain: successor(name),
+		TypeBitMap: []uint16{dns.TypeRRSIG, dns.TypeNSEC},
+	}
+}
+
+// predecessor returns a name sorting shortly before name in canonical order
+// by decrementing the last octet of its first label and padding the label
+// with the highest octet.
+func predecessor(name string) string {
+	label, rest := firstLabel(name)
+
+	last := label[len(label)-1]
+	label = label[:len(label)-1]
+
+	if last == 0 {
+		if len(label) == 0 {
+			return rest
+		}
+
+		return joinLabel(label, rest)
+	}
+
+	label = append(label, decrement(last))
+	for len(label) < maxLabelLength {
+		label = append(label, 0xff)
+	}
+
+	return joinLabel(label, rest)
+}
+
+// successor returns a name sorting shortly after name, and its descendants,
+// in canonical order.
+func successor(name string) string {
+	label, rest := firstLabel(name)
+
+	if len(label) < maxLabelLength {
+		return joinLabel(append(label, 0), rest)
+	}
+
+	for i := len(label) - 1; i >= 0; i-- {
+		if label[i] < 0xff {
+			label[i] = increment(label[i])
+			return joinLabel(label[:i+1], rest)
+		}
+	}
+
+	return `\000.` + name
+}
+
+// decrement and increment skip the upper case letters, which sort as their
+// lower case equivalents.
+func decrement(octet byte) byte {
+	octet--
+	if octet >= 'A' && octet <= 'Z' {
+		return 'A' - 1
+	}
+
+	return octet
+}
+
+func increment(octet byte) byte {
+	octet++
+	if octet >= 'A' && octet <= 'Z' {
+		return 'Z' + 1
+	}
+
+	return octet
+}
+
+// firstLabel returns the lower cased octets of the first label of name and
+// the remaining name.
+func firstLabel(name string) ([]byte, string) {
+	name = strings.ToLower(dns.Fqdn(name))
+
+	wire := make([]byte, 256)
+	if _, err := dns.PackDomainName(name, wire, 0, nil, false); err != nil || wire[0] == 0 {
+		return []byte{0}, name
+	}
+
+	label := append([]byte{}, wire[1:1+wire[0]]...)
+
+	return label, parentOf(name)
+}
+
+func joinLabel(label []byte, rest string) string {
+	var b bytes.Buffer
+	for _, octet := range label {
+		switch {
+		case octet >= 'a' && octet <= 'z', octet >= '0' && octet <= '9', octet == '-', octet == '_':
+			b.WriteByte(octet)
+		default:
+			fmt.Fprintf(&b, `\%03d`, octet)
+		}
+	}
+
+	if rest != "." {
+		b.WriteByte('.')
+		b.WriteString(rest)
+	} else {
+		b.WriteByte('.')
+	}
+
+	return b.String()
+}
+
+func parent(name string) string {
+	return parentOf(strings.ToLower(dns.Fqdn(name)))
+}
+
+func parentOf(name string) string {
+	labels := dns.Split(name)
+	if len(labels) < 2 {
+		return "."
+	}
+
+	return name[labels[1]:]
+}
@@ -0,0 +1,52 @@
+package dnssec_test
+
+import (
+	"bosh-dns/dns/server/dnssec"
+
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Denial of existence", func() {
+	Describe("NoData", func() {
+		It("lists every type but the queried one", func() {
+			nsec := dnssec.NoData("Web.bosh.", dns.TypeAAAA, []uint16{dns.TypeA, dns.TypeAAAA}, 30)
+
+			Expect(nsec.String()).To(Equal("Web.bosh.\t30\tIN\tNSEC\t\\000.web.bosh. A RRSIG NSEC"))
+		})
+	})
+
+	Describe("NameError", func() {
+		It("covers the name and the wildcard at its parent as narrowly as possible", func() {
+			nsecs := dnssec.NameError("missing.bosh.", 30)
+
+			Expect(nsecs).To(HaveLen(2))
+			Expect(nsecs[0].Header().Name).To(Equal("missinf" + repeat(`\255`, 56) + ".bosh."))
+			Expect(nsecs[0].(*dns.NSEC).NextDomain).To(Equal(`missing\000.bosh.`))
+			Expect(nsecs[1].Header().Name).To(Equal(`\041` + repeat(`\255`, 62) + ".bosh."))
+			Expect(nsecs[1].(*dns.NSEC).NextDomain).To(Equal(`\042\000.bosh.`))
+			Expect(nsecs[1].(*dns.NSEC).TypeBitMap).To(Equal([]uint16{dns.TypeRRSIG, dns.TypeNSEC}))
+		})
+
+		It("skips upper case letters, which sort as lower case", func() {
+			nsecs := dnssec.NameError("a[.bosh.", 30)
+
+			Expect(nsecs[0].Header().Name).To(Equal(`a\064` + repeat(`\255`, 61) + ".bosh."))
+		})
+
+		It("stays below the parent of a name ending in a zero octet", func() {
+			nsecs := dnssec.NameError(`a\000.bosh.`, 30)
+
+			Expect(nsecs[0].Header().Name).To(Equal("a.bosh."))
+		})
+	})
+})
+
+func repeat(s string, n int) string {
+	repeated := ""
+	for i := 0; i < n; i++ {
+		repeated += s
+	}
+	return repeated
+}
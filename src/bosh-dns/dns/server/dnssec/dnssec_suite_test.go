@@ -0,0 +1,13 @@
+package dnssec_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDNSSEC(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "dns/server/dnssec")
+}
@@ -0,0 +1,101 @@
+package dnssec
+
+import (
+	"bytes"
+	"crypto"
+	"fmt"
+
+	"bosh-dns/dns/config"
+
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/miekg/dns"
+)
+
+// Key is a zone key. Signer is nil for keys which are only published.
+type Key struct {
+	DNSKEY *dns.DNSKEY
+	Signer crypto.Signer
+}
+
+// LoadKeys reads key pairs written by dnssec-keygen. Only ECDSA P-256 keys
+// are supported.
+func LoadKeys(fs boshsys.FileSystem, keys []config.DNSSECKey) ([]Key, error) {
+	loaded := []Key{}
+
+	for _, keyConfig := range keys {
+		dnskey, err := readPublicKey(fs, keyConfig.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		key := Key{DNSKEY: dnskey}
+
+		if !keyConfig.PublishOnly {
+			key.Signer, err = readPrivateKey(fs, dnskey, keyConfig.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		loaded = append(loaded, key)
+	}
+
+	return loaded, nil
+}
+
+func readPublicKey(fs boshsys.FileSystem, path string) (*dns.DNSKEY, error) {
+	contents, err := fs.ReadFileString(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading public key '%s': %s", path, err)
+	}
+
+	rr, err := dns.ReadRR(bytes.NewBufferString(contents), path)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key '%s': %s", path, err)
+	}
+
+	dnskey, ok := rr.(*dns.DNSKEY)
+	if !ok {
+		return nil, fmt.Errorf("parsing public key '%s': not a DNSKEY record", path)
+	}
+
+	if dnskey.Algorithm != dns.ECDSAP256SHA256 {
+		return nil, fmt.Errorf("public key '%s' uses algorithm %s, only ECDSAP256SHA256 is supported", path, dns.AlgorithmToString[dnskey.Algorithm])
+	}
+
+	return dnskey, nil
+}
+
+func readPrivateKey(fs boshsys.FileSystem, dnskey *dns.DNSKEY, path string) (crypto.Signer, error) {
+	contents, err := fs.ReadFileString(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading private key '%s': %s", path, err)
+	}
+
+	privateKey, err := dnskey.ReadPrivateKey(bytes.NewBufferString(contents), path)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key '%s': %s", path, err)
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key '%s' cannot sign", path)
+	}
+
+	probe := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: dnskey.Hdr.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET},
+		Algorithm:  dnskey.Algorithm,
+		KeyTag:     dnskey.KeyTag(),
+		SignerName: dnskey.Hdr.Name,
+		Expiration: 2,
+	}
+	if err := probe.Sign(signer, []dns.RR{dnskey}); err != nil {
+		return nil, fmt.Errorf("private key '%s' cannot sign: %s", path, err)
+	}
+
+	if err := probe.Verify(dnskey, []dns.RR{dnskey}); err != nil {
+		return nil, fmt.Errorf("private key '%s' does not match its public key", path)
+	}
+
+	return signer, nil
+}
@@ -0,0 +1,111 @@
+package dnssec_test
+
+import (
+	"crypto"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/dnssec"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func generateKey(algorithm uint8, bits int) (*dns.DNSKEY, crypto.PrivateKey) {
+	dnskey := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "bosh.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: algorithm,
+	}
+
+	privateKey, err := dnskey.Generate(bits)
+	Expect(err).NotTo(HaveOccurred())
+
+	return dnskey, privateKey
+}
+
+func writeKeyFiles(dir, name string, dnskey *dns.DNSKEY, privateKey crypto.PrivateKey) config.DNSSECKey {
+	key := config.DNSSECKey{
+		PublicKeyFile:  filepath.Join(dir, name+".key"),
+		PrivateKeyFile: filepath.Join(dir, name+".private"),
+	}
+
+	Expect(ioutil.WriteFile(key.PublicKeyFile, []byte(dnskey.String()+"\n"), 0644)).To(Succeed())
+	Expect(ioutil.WriteFile(key.PrivateKeyFile, []byte(dnskey.PrivateKeyString(privateKey)), 0600)).To(Succeed())
+
+	return key
+}
+
+var _ = Describe("LoadKeys", func() {
+	var (
+		dir string
+		fs  boshsys.FileSystem
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "dnssec-keys")
+		Expect(err).NotTo(HaveOccurred())
+
+		fs = boshsys.NewOsFileSystem(&loggerfakes.FakeLogger{})
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("loads signing and publish only keys", func() {
+		activeKey, activePrivate := generateKey(dns.ECDSAP256SHA256, 256)
+		nextKey, nextPrivate := generateKey(dns.ECDSAP256SHA256, 256)
+
+		active := writeKeyFiles(dir, "active", activeKey, activePrivate)
+		next := writeKeyFiles(dir, "next", nextKey, nextPrivate)
+		next.PrivateKeyFile = ""
+		next.PublishOnly = true
+
+		keys, err := dnssec.LoadKeys(fs, []config.DNSSECKey{active, next})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(keys).To(HaveLen(2))
+		Expect(keys[0].DNSKEY.KeyTag()).To(Equal(activeKey.KeyTag()))
+		Expect(keys[0].Signer).NotTo(BeNil())
+		Expect(keys[1].DNSKEY.KeyTag()).To(Equal(nextKey.KeyTag()))
+		Expect(keys[1].Signer).To(BeNil())
+	})
+
+	It("rejects algorithms other than ECDSA P-256", func() {
+		dnskey, privateKey := generateKey(dns.ECDSAP384SHA384, 384)
+		key := writeKeyFiles(dir, "p384", dnskey, privateKey)
+
+		_, err := dnssec.LoadKeys(fs, []config.DNSSECKey{key})
+		Expect(err).To(MatchError(ContainSubstring("uses algorithm ECDSAP384SHA384, only ECDSAP256SHA256 is supported")))
+	})
+
+	It("rejects a private key which does not belong to the public key", func() {
+		dnskey, _ := generateKey(dns.ECDSAP256SHA256, 256)
+		_, otherPrivate := generateKey(dns.ECDSAP256SHA256, 256)
+		key := writeKeyFiles(dir, "mismatched", dnskey, otherPrivate)
+
+		_, err := dnssec.LoadKeys(fs, []config.DNSSECKey{key})
+		Expect(err).To(MatchError(ContainSubstring("does not match its public key")))
+	})
+
+	It("fails on missing files", func() {
+		_, err := dnssec.LoadKeys(fs, []config.DNSSECKey{{PublicKeyFile: filepath.Join(dir, "missing.key")}})
+		Expect(err).To(MatchError(ContainSubstring("reading public key")))
+	})
+
+	It("fails on files which hold no DNSKEY", func() {
+		path := filepath.Join(dir, "a.key")
+		Expect(ioutil.WriteFile(path, []byte("bosh. 3600 IN A 10.0.0.1\n"), 0644)).To(Succeed())
+
+		_, err := dnssec.LoadKeys(fs, []config.DNSSECKey{{PublicKeyFile: path, PublishOnly: true}})
+		Expect(err).To(MatchError(ContainSubstring("not a DNSKEY record")))
+	})
+})
@@ -0,0 +1,296 @@
+package dnssec
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// maxNSEC3Iterations follows RFC 9276: denials hashed more often than this
+// are not trusted.
+const maxNSEC3Iterations = 150
+
+// denials holds validated NSEC and NSEC3 records of a response, and checks
+// the proofs they make.
+type denials struct {
+	nsecs  []*dns.NSEC
+	nsec3s []*dns.NSEC3
+}
+
+func (d *denials) add(rrs []dns.RR) {
+	for _, rr := range rrs {
+		switch rr := rr.(type) {
+		case *dns.NSEC:
+			d.nsecs = append(d.nsecs, rr)
+		case *dns.NSEC3:
+			if rr.Hash == dns.SHA1 && rr.Iterations <= maxNSEC3Iterations {
+				d.nsec3s = append(d.nsec3s, rr)
+			}
+		}
+	}
+}
+
+// nameError checks that neither name nor a wildcard which would match it
+// exist.
+func (d denials) nameError(name string) bool {
+	for _, nsec := range d.nsecs {
+		if !covers(nsec, name) {
+			continue
+		}
+
+		if d.nsecCovers(wildcard(nsecClosestEncloser(name, nsec))) {
+			return true
+		}
+	}
+
+	if closestEncloser, _, _, ok := d.nsec3ClosestEncloser(name); ok {
+		return d.nsec3Covers(wildcard(closestEncloser))
+	}
+
+	return false
+}
+
+// noData checks that name exists without records of qtype, either itself or
+// through the wildcard matching it.
+func (d denials) noData(name string, qtype uint16) bool {
+	for _, nsec := range d.nsecs {
+		if strings.EqualFold(nsec.Hdr.Name, name) && lacks(nsec.TypeBitMap, qtype) {
+			return true
+		}
+
+		if !covers(nsec, name) {
+			continue
+		}
+
+		// An empty non-terminal sorts right before its descendants
+		if dns.IsSubDomain(name, strings.ToLower(nsec.NextDomain)) {
+			return true
+		}
+
+		closestWildcard := wildcard(nsecClosestEncloser(name, nsec))
+		for _, wildcardNSEC := range d.nsecs {
+			if strings.EqualFold(wildcardNSEC.Hdr.Name, closestWildcard) && lacks(wildcardNSEC.TypeBitMap, qtype) {
+				return true
+			}
+		}
+	}
+
+	for _, nsec3 := range d.nsec3s {
+		if nsec3.Match(name) && lacks(nsec3.TypeBitMap, qtype) {
+			return true
+		}
+	}
+
+	closestEncloser, _, optOut, ok := d.nsec3ClosestEncloser(name)
+	if !ok {
+		return false
+	}
+
+	if qtype == dns.TypeDS && optOut {
+		return true
+	}
+
+	for _, nsec3 := range d.nsec3s {
+		if nsec3.Match(wildcard(closestEncloser)) && lacks(nsec3.TypeBitMap, qtype) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expansion checks that owner, answered from a wildcard whose signature
+// covers labels of its labels, does not exist itself.
+func (d denials) expansion(owner string, labels uint8) bool {
+	if d.nsecCovers(owner) {
+		return true
+	}
+
+	indices := dns.Split(owner)
+	nextCloser := len(indices) - int(labels) - 1
+	if nextCloser < 0 {
+		return false
+	}
+
+	return d.nsec3Covers(owner[indices[nextCloser]:])
+}
+
+// delegation tells from the denial of a DS set at child whether child is an
+// insecure delegation or no zone cut at all.
+func (d denials) delegation(child string) (zoneState, bool) {
+	for _, nsec := range d.nsecs {
+		if strings.EqualFold(nsec.Hdr.Name, child) {
+			return cutState(nsec.TypeBitMap)
+		}
+
+		if covers(nsec, child) {
+			return notAZone, true
+		}
+	}
+
+	for _, nsec3 := range d.nsec3s {
+		if nsec3.Match(child) {
+			return cutState(nsec3.TypeBitMap)
+		}
+	}
+
+	for _, nsec3 := range d.nsec3s {
+		if nsec3.Cover(child) {
+			if nsec3.Flags&1 == 1 {
+				return zoneInsecure, true
+			}
+
+			return notAZone, true
+		}
+	}
+
+	return 0, false
+}
+
+func cutState(types []uint16) (zoneState, bool) {
+	if has(types, dns.TypeDS) {
+		return 0, false
+	}
+
+	if has(types, dns.TypeNS) && !has(types, dns.TypeSOA) {
+		return zoneInsecure, true
+	}
+
+	return notAZone, true
+}
+
+func (d denials) nsecCovers(name string) bool {
+	for _, nsec := range d.nsecs {
+		if covers(nsec, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d denials) nsec3Covers(name string) bool {
+	for _, nsec3 := range d.nsec3s {
+		if nsec3.Cover(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nsec3ClosestEncloser finds the closest provable encloser of name as
+// described by RFC 5155 section 8.3, along with the next closer name and
+// whether the NSEC3 covering the latter has the opt-out flag set.
+func (d denials) nsec3ClosestEncloser(name string) (string, string, bool, bool) {
+	indices := dns.Split(name)
+
+	for i := 1; i <= len(indices); i++ {
+		closestEncloser := "."
+		if i < len(indices) {
+			closestEncloser = name[indices[i]:]
+		}
+
+		matched := false
+		for _, nsec3 := range d.nsec3s {
+			if nsec3.Match(closestEncloser) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		nextCloser := name[indices[i-1]:]
+		for _, nsec3 := range d.nsec3s {
+			if nsec3.Cover(nextCloser) {
+				return closestEncloser, nextCloser, nsec3.Flags&1 == 1, true
+			}
+		}
+
+		return "", "", false, false
+	}
+
+	return "", "", false, false
+}
+
+// nsecClosestEncloser returns the longest ancestor of name which nsec, which
+// covers name, proves to exist.
+func nsecClosestEncloser(name string, nsec *dns.NSEC) string {
+	labels := dns.CompareDomainName(name, nsec.Hdr.Name)
+	if next := dns.CompareDomainName(name, nsec.NextDomain); next > labels {
+		labels = next
+	}
+
+	indices := dns.Split(name)
+	if labels == 0 || labels > len(indices) {
+		return "."
+	}
+
+	return strings.ToLower(name[indices[len(indices)-labels]:])
+}
+
+func wildcard(name string) string {
+	if name == "." {
+		return "*."
+	}
+
+	return "*." + name
+}
+
+// covers reports whether name sorts strictly between the owner and the
+// next name of nsec in canonical order. The last NSEC of a zone points back
+// at the apex.
+func covers(nsec *dns.NSEC, name string) bool {
+	owner, next := nsec.Hdr.Name, nsec.NextDomain
+
+	if canonicalCompare(owner, next) < 0 {
+		return canonicalCompare(owner, name) < 0 && canonicalCompare(name, next) < 0
+	}
+
+	return canonicalCompare(owner, name) < 0 || canonicalCompare(name, next) < 0
+}
+
+// canonicalCompare orders names as described by RFC 4034 section 6.1.
+func canonicalCompare(a, b string) int {
+	aLabels, bLabels := wireLabels(a), wireLabels(b)
+
+	for i := 1; i <= len(aLabels) && i <= len(bLabels); i++ {
+		if c := bytes.Compare(aLabels[len(aLabels)-i], bLabels[len(bLabels)-i]); c != 0 {
+			return c
+		}
+	}
+
+	return len(aLabels) - len(bLabels)
+}
+
+func wireLabels(name string) [][]byte {
+	wire := make([]byte, 256)
+	n, err := dns.PackDomainName(strings.ToLower(dns.Fqdn(name)), wire, 0, nil, false)
+	if err != nil {
+		return nil
+	}
+
+	labels := [][]byte{}
+	for i := 0; i < n && wire[i] != 0; i += int(wire[i]) + 1 {
+		labels = append(labels, wire[i+1:i+1+int(wire[i])])
+	}
+
+	return labels
+}
+
+func lacks(types []uint16, qtype uint16) bool {
+	return !has(types, qtype) && !has(types, dns.TypeCNAME)
+}
+
+func has(types []uint16, t uint16) bool {
+	for _, present := range types {
+		if present == t {
+			return true
+		}
+	}
+
+	return false
+}
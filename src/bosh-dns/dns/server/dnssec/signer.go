@@ -0,0 +1,120 @@
+package dnssec
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// inceptionSkew keeps fresh signatures valid for resolvers whose clocks
+	// lag behind.
+	inceptionSkew = time.Hour
+
+	maxCachedSignatures = 10000
+)
+
+type cachedSignatures struct {
+	rrsigs  []dns.RR
+	refresh time.Time
+}
+
+// Signer signs RRsets online with every signing key. Signatures are reused
+// until half of their validity has passed, as ECDSA signing dominates the
+// cost of a signed answer.
+type Signer struct {
+	keys     []Key
+	validity time.Duration
+	clock    clock.Clock
+
+	mutex *sync.Mutex
+	cache map[string]cachedSignatures
+}
+
+func NewSigner(keys []Key, validity time.Duration, clock clock.Clock) *Signer {
+	return &Signer{
+		keys:     keys,
+		validity: validity,
+		clock:    clock,
+
+		mutex: &sync.Mutex{},
+		cache: map[string]cachedSignatures{},
+	}
+}
+
+// DNSKEYs returns the DNSKEY RRset of origin, which lists published only keys
+// too. Every zone shares the same keys.
+func (s *Signer) DNSKEYs(origin string, ttl uint32) []dns.RR {
+	dnskeys := []dns.RR{}
+
+	for _, key := range s.keys {
+		dnskey := *key.DNSKEY
+		dnskey.Hdr = dns.RR_Header{Name: origin, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: ttl}
+		dnskeys = append(dnskeys, &dnskey)
+	}
+
+	return dnskeys
+}
+
+// Sign returns an RRSIG for rrset from every signing key of zone origin.
+func (s *Signer) Sign(origin string, rrset []dns.RR) ([]dns.RR, error) {
+	now := s.clock.Now()
+	cacheKey := signatureCacheKey(origin, rrset)
+
+	s.mutex.Lock()
+	cached, found := s.cache[cacheKey]
+	s.mutex.Unlock()
+
+	if found && now.Before(cached.refresh) {
+		return cached.rrsigs, nil
+	}
+
+	header := rrset[0].Header()
+	rrsigs := []dns.RR{}
+
+	for _, key := range s.keys {
+		if key.Signer == nil {
+			continue
+		}
+
+		rrsig := &dns.RRSIG{
+			Hdr:        dns.RR_Header{Name: header.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: header.Ttl},
+			Algorithm:  key.DNSKEY.Algorithm,
+			KeyTag:     key.DNSKEY.KeyTag(),
+			SignerName: origin,
+			Inception:  uint32(now.Add(-inceptionSkew).Unix()),
+			Expiration: uint32(now.Add(s.validity).Unix()),
+		}
+
+		if err := rrsig.Sign(key.Signer, rrset); err != nil {
+			return nil, err
+		}
+
+		rrsigs = append(rrsigs, rrsig)
+	}
+
+	s.mutex.Lock()
+	if len(s.cache) >= maxCachedSignatures {
+		s.cache = map[string]cachedSignatures{}
+	}
+	s.cache[cacheKey] = cachedSignatures{rrsigs: rrsigs, refresh: now.Add(s.validity / 2)}
+	s.mutex.Unlock()
+
+	return rrsigs, nil
+}
+
+// signatureCacheKey ignores the order of the records, which answers shuffle.
+func signatureCacheKey(origin string, rrset []dns.RR) string {
+	records := make([]string, len(rrset))
+	for i, rr := range rrset {
+		records[i] = strings.ToLower(rr.String())
+	}
+	sort.Strings(records)
+
+	return strings.ToLower(origin) + "\n" + strings.Join(records, "\n")
+}
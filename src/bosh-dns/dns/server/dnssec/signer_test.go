@@ -0,0 +1,88 @@
+package dnssec_test
+
+import (
+	"crypto"
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/dnssec"
+
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Signer", func() {
+	var (
+		fakeClock  *fakeclock.FakeClock
+		activeKey  *dns.DNSKEY
+		nextKey    *dns.DNSKEY
+		signer     *dnssec.Signer
+		rrset      []dns.RR
+		validity   time.Duration
+		privateKey crypto.PrivateKey
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(1500000000, 0))
+		validity = 24 * time.Hour
+
+		activeKey, privateKey = generateKey(dns.ECDSAP256SHA256, 256)
+		nextKey, _ = generateKey(dns.ECDSAP256SHA256, 256)
+
+		signer = dnssec.NewSigner([]dnssec.Key{
+			{DNSKEY: activeKey, Signer: privateKey.(crypto.Signer)},
+			{DNSKEY: nextKey},
+		}, validity, fakeClock)
+
+		rrset = []dns.RR{
+			&dns.A{Hdr: dns.RR_Header{Name: "web.bosh.", Rrtype: dns.TypeA, Class: dns.ClassINET}, A: net.ParseIP("10.0.0.1")},
+			&dns.A{Hdr: dns.RR_Header{Name: "web.bosh.", Rrtype: dns.TypeA, Class: dns.ClassINET}, A: net.ParseIP("10.0.0.2")},
+		}
+	})
+
+	It("publishes every key at the zone apex", func() {
+		dnskeys := signer.DNSKEYs("db.internal.", 300)
+
+		Expect(dnskeys).To(HaveLen(2))
+		Expect(dnskeys[0].Header().Name).To(Equal("db.internal."))
+		Expect(dnskeys[0].Header().Ttl).To(Equal(uint32(300)))
+		Expect(dnskeys[0].(*dns.DNSKEY).KeyTag()).To(Equal(activeKey.KeyTag()))
+		Expect(dnskeys[1].(*dns.DNSKEY).KeyTag()).To(Equal(nextKey.KeyTag()))
+	})
+
+	It("signs with signing keys only", func() {
+		rrsigs, err := signer.Sign("bosh.", rrset)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rrsigs).To(HaveLen(1))
+
+		rrsig := rrsigs[0].(*dns.RRSIG)
+		Expect(rrsig.SignerName).To(Equal("bosh."))
+		Expect(rrsig.TypeCovered).To(Equal(dns.TypeA))
+		Expect(rrsig.Inception).To(Equal(uint32(1500000000 - 3600)))
+		Expect(rrsig.Expiration).To(Equal(uint32(1500000000 + 86400)))
+
+		apexKey := signer.DNSKEYs("bosh.", 0)[0].(*dns.DNSKEY)
+		Expect(rrsig.Verify(apexKey, rrset)).To(Succeed())
+	})
+
+	It("reuses signatures regardless of the record order", func() {
+		first, _ := signer.Sign("bosh.", rrset)
+
+		fakeClock.Increment(time.Hour)
+		second, _ := signer.Sign("bosh.", []dns.RR{rrset[1], rrset[0]})
+
+		Expect(second[0]).To(BeIdenticalTo(first[0]))
+	})
+
+	It("signs again once half of the validity has passed", func() {
+		first, _ := signer.Sign("bosh.", rrset)
+
+		fakeClock.Increment(validity / 2)
+		second, _ := signer.Sign("bosh.", rrset)
+
+		Expect(second[0].(*dns.RRSIG).Expiration).To(BeNumerically(">", first[0].(*dns.RRSIG).Expiration))
+	})
+})
@@ -0,0 +1,665 @@
+package dnssec
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// maxZoneCacheTTL bounds how long a validated DNSKEY set or delegation
+	// is trusted, whatever the TTL of its records.
+	maxZoneCacheTTL = 3600
+
+	maxCachedZones = 10000
+
+	// maxQueriesPerAnswer bounds the DS and DNSKEY lookups needed to build
+	// the chain of trust for a single answer.
+	maxQueriesPerAnswer = 32
+
+	maxCNAMEChain = 16
+)
+
+// Querier resolves name and qtype upstream with the DO and CD bits set.
+type Querier func(name string, qtype uint16) (*dns.Msg, error)
+
+type zoneState int
+
+const (
+	zoneSigned zoneState = iota
+	zoneInsecure
+	notAZone
+)
+
+type cachedZone struct {
+	state   zoneState
+	keys    []*dns.DNSKEY
+	expires time.Time
+}
+
+// Validator builds chains of trust from the trust anchors down to the
+// answers of a non validating recursor, fetching the DS and DNSKEY sets it
+// needs through that same recursor. Validated DNSKEY sets and delegations
+// are cached for their TTL, but never longer than an hour.
+type Validator struct {
+	anchors         map[string][]dns.RR
+	negativeAnchors []string
+	clock           clock.Clock
+
+	mutex *sync.Mutex
+	zones map[string]cachedZone
+}
+
+// ParseTrustAnchors parses DS or DNSKEY records in presentation format.
+func ParseTrustAnchors(anchors []string) ([]dns.RR, error) {
+	parsed := []dns.RR{}
+
+	for i, anchor := range anchors {
+		rr, err := dns.NewRR(anchor)
+		if err != nil {
+			return nil, fmt.Errorf("parsing trust anchor #%d: %s", i, err)
+		}
+
+		switch rr.(type) {
+		case *dns.DS, *dns.DNSKEY:
+			parsed = append(parsed, rr)
+		default:
+			return nil, fmt.Errorf("parsing trust anchor #%d: not a DS or DNSKEY record", i)
+		}
+	}
+
+	return parsed, nil
+}
+
+func NewValidator(anchors []dns.RR, negativeAnchors []string, clock clock.Clock) *Validator {
+	anchorsByZone := map[string][]dns.RR{}
+	for _, anchor := range anchors {
+		zone := strings.ToLower(dns.Fqdn(anchor.Header().Name))
+		anchorsByZone[zone] = append(anchorsByZone[zone], anchor)
+	}
+
+	lowered := make([]string, len(negativeAnchors))
+	for i, name := range negativeAnchors {
+		lowered[i] = strings.ToLower(dns.Fqdn(name))
+	}
+
+	return &Validator{
+		anchors:         anchorsByZone,
+		negativeAnchors: lowered,
+		clock:           clock,
+
+		mutex: &sync.Mutex{},
+		zones: map[string]cachedZone{},
+	}
+}
+
+// Validate reports whether response is secure. It returns an error when
+// response is bogus. Answers which lie under a negative trust anchor, under
+// no trust anchor, or below a provably insecure delegation are insecure.
+func (v *Validator) Validate(query Querier, response *dns.Msg) (bool, error) {
+	if len(response.Question) == 0 {
+		return false, nil
+	}
+
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return false, nil
+	}
+
+	question := response.Question[0]
+	name := strings.ToLower(dns.Fqdn(question.Name))
+
+	anchor, ok := v.anchorFor(name)
+	if !ok {
+		return false, nil
+	}
+
+	c := &chain{
+		validator: v,
+		querier:   query,
+		anchor:    anchor,
+		now:       v.clock.Now(),
+	}
+
+	return c.validate(name, question.Qtype, response)
+}
+
+// anchorFor returns the closest trust anchor enclosing name, unless a
+// negative trust anchor encloses it.
+func (v *Validator) anchorFor(name string) (string, bool) {
+	for _, negativeAnchor := range v.negativeAnchors {
+		if dns.IsSubDomain(negativeAnchor, name) {
+			return "", false
+		}
+	}
+
+	for _, suffix := range suffixes(name) {
+		if _, ok := v.anchors[suffix]; ok {
+			return suffix, true
+		}
+	}
+
+	return "", false
+}
+
+func (v *Validator) lookup(name string, now time.Time) (cachedZone, bool) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	cached, ok := v.zones[name]
+	if !ok || !now.Before(cached.expires) {
+		return cachedZone{}, false
+	}
+
+	return cached, true
+}
+
+func (v *Validator) store(name string, zone cachedZone) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if len(v.zones) >= maxCachedZones {
+		v.zones = map[string]cachedZone{}
+	}
+	v.zones[name] = zone
+}
+
+// expansion is an answer synthesized from the wildcard whose signature
+// covers labels labels of owner.
+type expansion struct {
+	owner  string
+	labels uint8
+}
+
+// chain validates a single response.
+type chain struct {
+	validator *Validator
+	querier   Querier
+	anchor    string
+	now       time.Time
+	queries   int
+}
+
+func (c *chain) validate(name string, qtype uint16, response *dns.Msg) (bool, error) {
+	secure := true
+	expansions := []expansion{}
+
+	answer := groupRRsets(response.Answer)
+	for _, set := range answer {
+		if set.rrtype == dns.TypeCNAME && len(set.sigs) == 0 && synthesizedFromDNAME(set.name, answer) {
+			continue
+		}
+
+		setSecure, sig, err := c.verify(set)
+		if err != nil {
+			return false, err
+		}
+
+		if setSecure && wildcardExpanded(sig, set.name) {
+			expansions = append(expansions, expansion{owner: set.name, labels: sig.Labels})
+		}
+
+		secure = secure && setSecure
+	}
+
+	proofs := denials{}
+	authoritySecure, hasAuthority := true, false
+	for _, set := range groupRRsets(response.Ns) {
+		if set.rrtype != dns.TypeSOA && set.rrtype != dns.TypeNSEC && set.rrtype != dns.TypeNSEC3 {
+			continue
+		}
+		hasAuthority = true
+
+		setSecure, _, err := c.verify(set)
+		if err != nil {
+			return false, err
+		}
+
+		if !setSecure {
+			authoritySecure = false
+			continue
+		}
+
+		proofs.add(set.records)
+	}
+
+	for _, e := range expansions {
+		if !proofs.expansion(e.owner, e.labels) {
+			return false, fmt.Errorf("no proof that %s does not exist for wildcard answer", e.owner)
+		}
+	}
+
+	target := finalTarget(name, answer)
+	if qtype == dns.TypeANY || qtype == dns.TypeCNAME || hasRRset(answer, target, qtype) {
+		return secure, nil
+	}
+
+	if !hasAuthority {
+		_, _, zoneSecure, err := c.walk(target)
+		if err != nil {
+			return false, err
+		}
+		if zoneSecure {
+			return false, fmt.Errorf("no proof of denial for %s", target)
+		}
+
+		return false, nil
+	}
+
+	if !authoritySecure {
+		return false, nil
+	}
+
+	if response.Rcode == dns.RcodeNameError {
+		if !proofs.nameError(target) {
+			return false, fmt.Errorf("no proof that %s does not exist", target)
+		}
+	} else if !proofs.noData(target, qtype) {
+		return false, fmt.Errorf("no proof that %s has no %s records", target, dns.TypeToString[qtype])
+	}
+
+	return secure, nil
+}
+
+// verify checks the signatures of set against the keys of the zone which
+// signed it. Unsigned sets are insecure if they lie below an insecure
+// delegation.
+func (c *chain) verify(set rrset) (bool, *dns.RRSIG, error) {
+	if len(set.sigs) == 0 {
+		owner := set.name
+		if set.rrtype == dns.TypeDS {
+			owner = parent(owner)
+		}
+
+		_, _, secure, err := c.walk(owner)
+		if err != nil {
+			return false, nil, err
+		}
+		if secure {
+			return false, nil, fmt.Errorf("%s %s is not signed", set.name, dns.TypeToString[set.rrtype])
+		}
+
+		return false, nil, nil
+	}
+
+	var lastErr error = fmt.Errorf("no signature of %s %s verifies", set.name, dns.TypeToString[set.rrtype])
+	for _, sig := range set.sigs {
+		signer := strings.ToLower(dns.Fqdn(sig.SignerName))
+		if !dns.IsSubDomain(signer, set.name) || !dns.IsSubDomain(c.anchor, signer) {
+			continue
+		}
+		if set.rrtype == dns.TypeDS && signer == set.name {
+			continue
+		}
+
+		zone, keys, secure, err := c.walk(signer)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !secure {
+			return false, nil, nil
+		}
+		if zone != signer {
+			lastErr = fmt.Errorf("%s signed by %s, which is not a zone", set.name, signer)
+			continue
+		}
+
+		if verified, err := c.verifyWith(set, signer, keys); err == nil {
+			return true, verified, nil
+		} else {
+			lastErr = err
+		}
+	}
+
+	return false, nil, lastErr
+}
+
+// verifyWith returns the first signature of set by zone which verifies with
+// one of keys.
+func (c *chain) verifyWith(set rrset, zone string, keys []*dns.DNSKEY) (*dns.RRSIG, error) {
+	for _, sig := range set.sigs {
+		if strings.ToLower(dns.Fqdn(sig.SignerName)) != zone || !sig.ValidityPeriod(c.now) {
+			continue
+		}
+
+		for _, key := range keys {
+			if key.KeyTag() == sig.KeyTag && key.Algorithm == sig.Algorithm && sig.Verify(key, set.records) == nil {
+				return sig, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no signature of %s %s by %s verifies", set.name, dns.TypeToString[set.rrtype], zone)
+}
+
+// walk follows the delegations from the trust anchor down to name. It
+// returns the closest zone enclosing name with its keys, or reports that an
+// insecure delegation lies in between.
+func (c *chain) walk(name string) (string, []*dns.DNSKEY, bool, error) {
+	zone := c.anchor
+	entry, err := c.anchorZone()
+	if err != nil {
+		return "", nil, false, err
+	}
+	if entry.state == zoneInsecure {
+		return zone, nil, false, nil
+	}
+
+	labels := dns.Split(name)
+	for i := len(labels) - dns.CountLabel(c.anchor) - 1; i >= 0; i-- {
+		child := name[labels[i]:]
+
+		childEntry, err := c.delegation(zone, entry.keys, child)
+		if err != nil {
+			return "", nil, false, err
+		}
+
+		switch childEntry.state {
+		case zoneInsecure:
+			return child, nil, false, nil
+		case zoneSigned:
+			zone, entry = child, childEntry
+		}
+	}
+
+	return zone, entry.keys, true, nil
+}
+
+func (c *chain) anchorZone() (cachedZone, error) {
+	if cached, ok := c.validator.lookup(c.anchor, c.now); ok {
+		return cached, nil
+	}
+
+	entry, err := c.zoneKeys(c.anchor, c.validator.anchors[c.anchor], maxZoneCacheTTL)
+	if err != nil {
+		return cachedZone{}, err
+	}
+
+	c.validator.store(c.anchor, entry)
+
+	return entry, nil
+}
+
+// delegation determines from the DS set of child whether child is a signed
+// zone, an insecure delegation, or no zone cut at all. The DS set, or its
+// denial, must be signed by zone.
+func (c *chain) delegation(zone string, keys []*dns.DNSKEY, child string) (cachedZone, error) {
+	if cached, ok := c.validator.lookup(child, c.now); ok {
+		return cached, nil
+	}
+
+	response, err := c.query(child, dns.TypeDS)
+	if err != nil {
+		return cachedZone{}, err
+	}
+
+	answer := groupRRsets(response.Answer)
+	entry := cachedZone{state: notAZone}
+	ttl := uint32(maxZoneCacheTTL)
+
+	if ds, ok := findRRset(answer, child, dns.TypeDS); ok {
+		if _, err := c.verifyWith(ds, zone, keys); err != nil {
+			return cachedZone{}, err
+		}
+
+		entry, err = c.zoneKeys(child, ds.records, minTTL(ttl, ds.records))
+		if err != nil {
+			return cachedZone{}, err
+		}
+	} else if _, ok := findRRset(answer, child, dns.TypeCNAME); !ok {
+		proofs := denials{}
+		for _, set := range groupRRsets(response.Ns) {
+			if set.rrtype != dns.TypeNSEC && set.rrtype != dns.TypeNSEC3 {
+				continue
+			}
+
+			if _, err := c.verifyWith(set, zone, keys); err == nil {
+				proofs.add(set.records)
+				ttl = minTTL(ttl, set.records)
+			}
+		}
+
+		state, ok := proofs.delegation(child)
+		if !ok {
+			return cachedZone{}, fmt.Errorf("no proof for the absence of DS records at %s", child)
+		}
+
+		entry.state = state
+	}
+
+	entry.expires = c.now.Add(time.Duration(ttl) * time.Second)
+	c.validator.store(child, entry)
+
+	return entry, nil
+}
+
+// zoneKeys fetches and validates the DNSKEY set of zone against anchors,
+// which are either DS or DNSKEY records. Zones whose anchors all use
+// algorithms which cannot be validated are insecure.
+func (c *chain) zoneKeys(zone string, anchors []dns.RR, ttl uint32) (cachedZone, error) {
+	if !supported(anchors) {
+		return cachedZone{state: zoneInsecure, expires: c.now.Add(time.Duration(ttl) * time.Second)}, nil
+	}
+
+	response, err := c.query(zone, dns.TypeDNSKEY)
+	if err != nil {
+		return cachedZone{}, err
+	}
+
+	set, ok := findRRset(groupRRsets(response.Answer), zone, dns.TypeDNSKEY)
+	if !ok {
+		return cachedZone{}, fmt.Errorf("no DNSKEY records for %s", zone)
+	}
+
+	trusted := []*dns.DNSKEY{}
+	keys := []*dns.DNSKEY{}
+	for _, rr := range set.records {
+		key := rr.(*dns.DNSKEY)
+		if key.Flags&dns.ZONE == 0 || key.Flags&dns.REVOKE != 0 {
+			continue
+		}
+
+		keys = append(keys, key)
+		if matchesAnchor(key, anchors) {
+			trusted = append(trusted, key)
+		}
+	}
+
+	if _, err := c.verifyWith(set, zone, trusted); err != nil {
+		return cachedZone{}, fmt.Errorf("DNSKEY set of %s does not validate against its trust anchor: %s", zone, err)
+	}
+
+	ttl = minTTL(ttl, set.records)
+
+	return cachedZone{
+		state:   zoneSigned,
+		keys:    keys,
+		expires: c.now.Add(time.Duration(ttl) * time.Second),
+	}, nil
+}
+
+func (c *chain) query(name string, qtype uint16) (*dns.Msg, error) {
+	c.queries++
+	if c.queries > maxQueriesPerAnswer {
+		return nil, errors.New("too many queries to build the chain of trust")
+	}
+
+	response, err := c.querier(name, qtype)
+	if err != nil {
+		return nil, fmt.Errorf("querying %s %s: %s", name, dns.TypeToString[qtype], err)
+	}
+
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("querying %s %s: %s", name, dns.TypeToString[qtype], dns.RcodeToString[response.Rcode])
+	}
+
+	return response, nil
+}
+
+func matchesAnchor(key *dns.DNSKEY, anchors []dns.RR) bool {
+	for _, anchor := range anchors {
+		switch anchor := anchor.(type) {
+		case *dns.DS:
+			ds := key.ToDS(anchor.DigestType)
+			if ds != nil && ds.KeyTag == anchor.KeyTag && ds.Algorithm == anchor.Algorithm && strings.EqualFold(ds.Digest, anchor.Digest) {
+				return true
+			}
+		case *dns.DNSKEY:
+			if key.Algorithm == anchor.Algorithm && key.Protocol == anchor.Protocol && key.PublicKey == anchor.PublicKey {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func supported(anchors []dns.RR) bool {
+	for _, anchor := range anchors {
+		switch anchor := anchor.(type) {
+		case *dns.DS:
+			if supportedAlgorithm(anchor.Algorithm) && (anchor.DigestType == dns.SHA1 || anchor.DigestType == dns.SHA256 || anchor.DigestType == dns.SHA384) {
+				return true
+			}
+		case *dns.DNSKEY:
+			if supportedAlgorithm(anchor.Algorithm) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func supportedAlgorithm(algorithm uint8) bool {
+	switch algorithm {
+	case dns.RSASHA1, dns.RSASHA1NSEC3SHA1, dns.RSASHA256, dns.RSASHA512, dns.ECDSAP256SHA256, dns.ECDSAP384SHA384:
+		return true
+	}
+
+	return false
+}
+
+func minTTL(ttl uint32, rrs []dns.RR) uint32 {
+	for _, rr := range rrs {
+		if rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+
+	return ttl
+}
+
+// wildcardExpanded reports whether sig was made over a wildcard which
+// synthesized owner.
+func wildcardExpanded(sig *dns.RRSIG, owner string) bool {
+	labels := dns.CountLabel(owner)
+	if strings.HasPrefix(owner, "*.") {
+		labels--
+	}
+
+	return int(sig.Labels) < labels
+}
+
+func synthesizedFromDNAME(name string, answer []rrset) bool {
+	for _, set := range answer {
+		if set.rrtype == dns.TypeDNAME && set.name != name && dns.IsSubDomain(set.name, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// finalTarget follows the CNAME chain of answer from name.
+func finalTarget(name string, answer []rrset) string {
+	for i := 0; i < maxCNAMEChain; i++ {
+		set, ok := findRRset(answer, name, dns.TypeCNAME)
+		if !ok {
+			break
+		}
+
+		name = strings.ToLower(dns.Fqdn(set.records[0].(*dns.CNAME).Target))
+	}
+
+	return name
+}
+
+func hasRRset(sets []rrset, name string, rrtype uint16) bool {
+	_, ok := findRRset(sets, name, rrtype)
+	return ok
+}
+
+func findRRset(sets []rrset, name string, rrtype uint16) (rrset, bool) {
+	for _, set := range sets {
+		if set.name == name && set.rrtype == rrtype {
+			return set, true
+		}
+	}
+
+	return rrset{}, false
+}
+
+// suffixes returns name and all of its ancestors, longest first.
+func suffixes(name string) []string {
+	names := []string{}
+	for _, i := range dns.Split(name) {
+		names = append(names, name[i:])
+	}
+
+	return append(names, ".")
+}
+
+type rrset struct {
+	name    string
+	rrtype  uint16
+	records []dns.RR
+	sigs    []*dns.RRSIG
+}
+
+// groupRRsets groups the records of a section into RRsets along with the
+// signatures covering them.
+func groupRRsets(rrs []dns.RR) []rrset {
+	sets := []rrset{}
+	index := map[string]int{}
+
+	key := func(name string, rrtype uint16) string {
+		return fmt.Sprintf("%s/%d", name, rrtype)
+	}
+
+	for _, rr := range rrs {
+		header := rr.Header()
+		if header.Rrtype == dns.TypeRRSIG || header.Rrtype == dns.TypeOPT {
+			continue
+		}
+
+		name := strings.ToLower(header.Name)
+		k := key(name, header.Rrtype)
+		if i, ok := index[k]; ok {
+			sets[i].records = append(sets[i].records, rr)
+			continue
+		}
+
+		index[k] = len(sets)
+		sets = append(sets, rrset{name: name, rrtype: header.Rrtype, records: []dns.RR{rr}})
+	}
+
+	for _, rr := range rrs {
+		sig, ok := rr.(*dns.RRSIG)
+		if !ok {
+			continue
+		}
+
+		if i, ok := index[key(strings.ToLower(sig.Hdr.Name), sig.TypeCovered)]; ok {
+			sets[i].sigs = append(sets[i].sigs, sig)
+		}
+	}
+
+	return sets
+}
@@ -0,0 +1,384 @@
+package dnssec_test
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/dnssec"
+
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type testZone struct {
+	origin string
+	dnskey *dns.DNSKEY
+	signer crypto.Signer
+}
+
+func newTestZone(origin string) testZone {
+	dnskey, privateKey := generateKey(dns.ECDSAP256SHA256, 256)
+	dnskey.Hdr.Name = origin
+
+	return testZone{origin: origin, dnskey: dnskey, signer: privateKey.(crypto.Signer)}
+}
+
+var _ = Describe("Validator", func() {
+	var (
+		fakeClock *fakeclock.FakeClock
+		validator *dnssec.Validator
+		root      testZone
+		child     testZone
+		upstream  map[string]*dns.Msg
+		queries   []string
+		anchors   []dns.RR
+		negative  []string
+	)
+
+	sign := func(z testZone, expiration time.Time, rrs ...dns.RR) []dns.RR {
+		header := rrs[0].Header()
+		rrsig := &dns.RRSIG{
+			Hdr:        dns.RR_Header{Name: header.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: header.Ttl},
+			Algorithm:  z.dnskey.Algorithm,
+			KeyTag:     z.dnskey.KeyTag(),
+			SignerName: z.origin,
+			Inception:  uint32(fakeClock.Now().Add(-time.Hour).Unix()),
+			Expiration: uint32(expiration.Unix()),
+		}
+		Expect(rrsig.Sign(z.signer, rrs)).To(Succeed())
+
+		return append(rrs, rrsig)
+	}
+
+	signed := func(z testZone, rrs ...dns.RR) []dns.RR {
+		return sign(z, fakeClock.Now().Add(time.Hour), rrs...)
+	}
+
+	a := func(name, ip string) *dns.A {
+		return &dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300}, A: net.ParseIP(ip)}
+	}
+
+	nsec := func(name, next string, types ...uint16) *dns.NSEC {
+		return &dns.NSEC{
+			Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+			NextDomain: next,
+			TypeBitMap: types,
+		}
+	}
+
+	soa := func(z testZone) *dns.SOA {
+		return &dns.SOA{
+			Hdr:     dns.RR_Header{Name: z.origin, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 300},
+			Ns:      "ns." + z.origin,
+			Mbox:    "hostmaster." + z.origin,
+			Serial:  1,
+			Minttl:  300,
+			Refresh: 60,
+			Retry:   10,
+			Expire:  3600,
+		}
+	}
+
+	serve := func(name string, qtype uint16, rcode int, answer, ns []dns.RR) {
+		m := &dns.Msg{}
+		m.SetQuestion(name, qtype)
+		m.Rcode = rcode
+		m.Answer = answer
+		m.Ns = ns
+		upstream[fmt.Sprintf("%s/%s", name, dns.TypeToString[qtype])] = m
+	}
+
+	query := func(name string, qtype uint16) (*dns.Msg, error) {
+		key := fmt.Sprintf("%s/%s", name, dns.TypeToString[qtype])
+		queries = append(queries, key)
+
+		m, ok := upstream[key]
+		if !ok {
+			return nil, errors.New("unexpected query " + key)
+		}
+
+		return m, nil
+	}
+
+	response := func(name string, qtype uint16, rcode int, answer, ns []dns.RR) *dns.Msg {
+		m := &dns.Msg{}
+		m.SetQuestion(name, qtype)
+		m.Rcode = rcode
+		m.Answer = answer
+		m.Ns = ns
+		return m
+	}
+
+	concat := func(sets ...[]dns.RR) []dns.RR {
+		all := []dns.RR{}
+		for _, set := range sets {
+			all = append(all, set...)
+		}
+		return all
+	}
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(1500000000, 0))
+		upstream = map[string]*dns.Msg{}
+		queries = nil
+		negative = nil
+
+		root = newTestZone("example.")
+		child = newTestZone("signed.example.")
+
+		anchors = []dns.RR{root.dnskey.ToDS(dns.SHA256)}
+
+		serve("example.", dns.TypeDNSKEY, dns.RcodeSuccess, signed(root, root.dnskey), nil)
+		serve("signed.example.", dns.TypeDS, dns.RcodeSuccess, signed(root, child.dnskey.ToDS(dns.SHA256)), nil)
+		serve("signed.example.", dns.TypeDNSKEY, dns.RcodeSuccess, signed(child, child.dnskey), nil)
+		serve("insecure.example.", dns.TypeDS, dns.RcodeSuccess, nil, signed(root, nsec("insecure.example.", "signed.example.", dns.TypeNS, dns.TypeRRSIG, dns.TypeNSEC)))
+		serve("www.example.", dns.TypeDS, dns.RcodeSuccess, nil, signed(root, nsec("www.example.", "example.", dns.TypeA, dns.TypeRRSIG, dns.TypeNSEC)))
+		serve("www.signed.example.", dns.TypeDS, dns.RcodeSuccess, nil, signed(child, nsec("www.signed.example.", "signed.example.", dns.TypeA, dns.TypeRRSIG, dns.TypeNSEC)))
+	})
+
+	JustBeforeEach(func() {
+		validator = dnssec.NewValidator(anchors, negative, fakeClock)
+	})
+
+	It("parses DS and DNSKEY trust anchors", func() {
+		parsed, err := dnssec.ParseTrustAnchors([]string{
+			root.dnskey.ToDS(dns.SHA256).String(),
+			root.dnskey.String(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed).To(HaveLen(2))
+
+		_, err = dnssec.ParseTrustAnchors([]string{"example. 300 IN A 10.0.0.1"})
+		Expect(err).To(MatchError("parsing trust anchor #0: not a DS or DNSKEY record"))
+
+		_, err = dnssec.ParseTrustAnchors([]string{"garbage"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("validates answers signed by the trust anchor", func() {
+		secure, err := validator.Validate(query, response("www.example.", dns.TypeA, dns.RcodeSuccess, signed(root, a("www.example.", "10.0.0.1")), nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secure).To(BeTrue())
+	})
+
+	It("accepts DNSKEY records as trust anchors", func() {
+		validator = dnssec.NewValidator([]dns.RR{root.dnskey}, nil, fakeClock)
+
+		secure, err := validator.Validate(query, response("www.example.", dns.TypeA, dns.RcodeSuccess, signed(root, a("www.example.", "10.0.0.1")), nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secure).To(BeTrue())
+	})
+
+	It("follows signed delegations", func() {
+		secure, err := validator.Validate(query, response("www.signed.example.", dns.TypeA, dns.RcodeSuccess, signed(child, a("www.signed.example.", "10.0.0.1")), nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secure).To(BeTrue())
+		Expect(queries).To(ConsistOf("example./DNSKEY", "signed.example./DS", "signed.example./DNSKEY"))
+	})
+
+	It("caches validated keys and delegations", func() {
+		answer := response("www.signed.example.", dns.TypeA, dns.RcodeSuccess, signed(child, a("www.signed.example.", "10.0.0.1")), nil)
+
+		_, err := validator.Validate(query, answer)
+		Expect(err).NotTo(HaveOccurred())
+		queries = nil
+
+		secure, err := validator.Validate(query, answer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secure).To(BeTrue())
+		Expect(queries).To(BeEmpty())
+
+		fakeClock.Increment(time.Hour)
+
+		validator.Validate(query, answer)
+		Expect(queries).NotTo(BeEmpty())
+	})
+
+	It("rejects tampered answers", func() {
+		answer := signed(root, a("www.example.", "10.0.0.1"))
+		answer[0].(*dns.A).A = net.ParseIP("10.0.0.2")
+
+		_, err := validator.Validate(query, response("www.example.", dns.TypeA, dns.RcodeSuccess, answer, nil))
+		Expect(err).To(MatchError(ContainSubstring("no signature of www.example. A by example. verifies")))
+	})
+
+	It("rejects expired signatures", func() {
+		answer := sign(root, fakeClock.Now().Add(-time.Minute), a("www.example.", "10.0.0.1"))
+
+		_, err := validator.Validate(query, response("www.example.", dns.TypeA, dns.RcodeSuccess, answer, nil))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects unsigned answers from signed zones", func() {
+		_, err := validator.Validate(query, response("www.example.", dns.TypeA, dns.RcodeSuccess, []dns.RR{a("www.example.", "10.0.0.1")}, nil))
+		Expect(err).To(MatchError("www.example. A is not signed"))
+	})
+
+	It("rejects keys which do not match the DS records", func() {
+		impostor := newTestZone("signed.example.")
+		serve("signed.example.", dns.TypeDNSKEY, dns.RcodeSuccess, signed(impostor, impostor.dnskey), nil)
+
+		_, err := validator.Validate(query, response("www.signed.example.", dns.TypeA, dns.RcodeSuccess, signed(impostor, a("www.signed.example.", "10.0.0.1")), nil))
+		Expect(err).To(MatchError(ContainSubstring("DNSKEY set of signed.example. does not validate against its trust anchor")))
+	})
+
+	It("treats answers below insecure delegations as insecure", func() {
+		secure, err := validator.Validate(query, response("www.insecure.example.", dns.TypeA, dns.RcodeSuccess, []dns.RR{a("www.insecure.example.", "10.0.0.1")}, nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secure).To(BeFalse())
+	})
+
+	It("treats opted out NSEC3 delegations as insecure", func() {
+		nsec3 := &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: strings.Repeat("0", 32) + ".example.", Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 300},
+			Hash:       dns.SHA1,
+			Flags:      1,
+			HashLength: 20,
+			NextDomain: strings.Repeat("V", 32),
+			TypeBitMap: []uint16{dns.TypeNS},
+		}
+		serve("optout.example.", dns.TypeDS, dns.RcodeSuccess, nil, signed(root, nsec3))
+
+		secure, err := validator.Validate(query, response("www.optout.example.", dns.TypeA, dns.RcodeSuccess, []dns.RR{a("www.optout.example.", "10.0.0.1")}, nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secure).To(BeFalse())
+	})
+
+	It("rejects unproven insecure delegations", func() {
+		serve("insecure.example.", dns.TypeDS, dns.RcodeSuccess, nil, []dns.RR{nsec("insecure.example.", "signed.example.", dns.TypeNS)})
+
+		_, err := validator.Validate(query, response("www.insecure.example.", dns.TypeA, dns.RcodeSuccess, []dns.RR{a("www.insecure.example.", "10.0.0.1")}, nil))
+		Expect(err).To(MatchError("no proof for the absence of DS records at insecure.example."))
+	})
+
+	It("skips names under negative trust anchors", func() {
+		validator = dnssec.NewValidator(anchors, []string{"Signed.Example"}, fakeClock)
+
+		secure, err := validator.Validate(query, response("www.signed.example.", dns.TypeA, dns.RcodeSuccess, []dns.RR{a("www.signed.example.", "10.0.0.1")}, nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secure).To(BeFalse())
+		Expect(queries).To(BeEmpty())
+	})
+
+	It("skips names under no trust anchor", func() {
+		secure, err := validator.Validate(query, response("www.example.org.", dns.TypeA, dns.RcodeSuccess, []dns.RR{a("www.example.org.", "10.0.0.1")}, nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secure).To(BeFalse())
+		Expect(queries).To(BeEmpty())
+	})
+
+	It("passes failures through", func() {
+		secure, err := validator.Validate(query, response("www.example.", dns.TypeA, dns.RcodeServerFailure, nil, nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secure).To(BeFalse())
+	})
+
+	Describe("denials", func() {
+		It("validates proven name errors", func() {
+			ns := concat(
+				signed(root, soa(root)),
+				signed(root, nsec("example.", "www.example.", dns.TypeSOA, dns.TypeRRSIG, dns.TypeNSEC, dns.TypeDNSKEY)),
+			)
+
+			secure, err := validator.Validate(query, response("missing.example.", dns.TypeA, dns.RcodeNameError, nil, ns))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secure).To(BeTrue())
+		})
+
+		It("rejects name errors which do not deny the wildcard", func() {
+			ns := concat(
+				signed(root, soa(root)),
+				signed(root, nsec("m.example.", "n.example.", dns.TypeA, dns.TypeRRSIG, dns.TypeNSEC)),
+			)
+
+			_, err := validator.Validate(query, response("missing.example.", dns.TypeA, dns.RcodeNameError, nil, ns))
+			Expect(err).To(MatchError("no proof that missing.example. does not exist"))
+		})
+
+		It("validates proven missing types", func() {
+			ns := concat(
+				signed(root, soa(root)),
+				signed(root, nsec("www.example.", "example.", dns.TypeA, dns.TypeRRSIG, dns.TypeNSEC)),
+			)
+
+			secure, err := validator.Validate(query, response("www.example.", dns.TypeAAAA, dns.RcodeSuccess, nil, ns))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secure).To(BeTrue())
+
+			_, err = validator.Validate(query, response("www.example.", dns.TypeA, dns.RcodeSuccess, nil, ns))
+			Expect(err).To(MatchError("no proof that www.example. has no A records"))
+		})
+
+		It("validates missing types proven by NSEC3", func() {
+			nsec3 := &dns.NSEC3{
+				Hdr:        dns.RR_Header{Name: dns.HashName("www.example.", dns.SHA1, 0, "") + ".example.", Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 300},
+				Hash:       dns.SHA1,
+				HashLength: 20,
+				NextDomain: strings.Repeat("V", 32),
+				TypeBitMap: []uint16{dns.TypeA, dns.TypeRRSIG},
+			}
+			ns := concat(signed(root, soa(root)), signed(root, nsec3))
+
+			secure, err := validator.Validate(query, response("www.example.", dns.TypeAAAA, dns.RcodeSuccess, nil, ns))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secure).To(BeTrue())
+		})
+
+		It("rejects unsigned denials from signed zones", func() {
+			_, err := validator.Validate(query, response("missing.example.", dns.TypeA, dns.RcodeNameError, nil, []dns.RR{soa(root)}))
+			Expect(err).To(MatchError("example. SOA is not signed"))
+
+			serve("missing.example.", dns.TypeDS, dns.RcodeNameError, nil, signed(root, nsec("insecure.example.", "signed.example.", dns.TypeNS, dns.TypeRRSIG, dns.TypeNSEC)))
+
+			_, err = validator.Validate(query, response("missing.example.", dns.TypeA, dns.RcodeNameError, nil, nil))
+			Expect(err).To(MatchError("no proof of denial for missing.example."))
+		})
+	})
+
+	Describe("wildcards", func() {
+		var expanded []dns.RR
+
+		BeforeEach(func() {
+			expanded = signed(root, a("*.example.", "10.0.0.1"))
+			expanded[0].Header().Name = "host.example."
+			expanded[1].Header().Name = "host.example."
+		})
+
+		It("validates expanded answers along with the proof that the name does not exist", func() {
+			ns := signed(root, nsec("example.", "www.example.", dns.TypeSOA, dns.TypeRRSIG, dns.TypeNSEC))
+
+			secure, err := validator.Validate(query, response("host.example.", dns.TypeA, dns.RcodeSuccess, expanded, ns))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secure).To(BeTrue())
+		})
+
+		It("rejects expanded answers without the proof", func() {
+			_, err := validator.Validate(query, response("host.example.", dns.TypeA, dns.RcodeSuccess, expanded, nil))
+			Expect(err).To(MatchError("no proof that host.example. does not exist for wildcard answer"))
+		})
+	})
+
+	It("validates every RRset of a CNAME chain", func() {
+		cname := &dns.CNAME{Hdr: dns.RR_Header{Name: "alias.example.", Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 300}, Target: "www.insecure.example."}
+
+		secure, err := validator.Validate(query, response("alias.example.", dns.TypeA, dns.RcodeSuccess, concat(
+			signed(root, cname),
+			[]dns.RR{a("www.insecure.example.", "10.0.0.1")},
+		), nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secure).To(BeFalse())
+
+		secure, err = validator.Validate(query, response("alias.example.", dns.TypeA, dns.RcodeSuccess, concat(
+			signed(root, cname),
+			signed(child, a("www.insecure.example.", "10.0.0.1")),
+		), nil))
+		Expect(err).To(HaveOccurred())
+	})
+})
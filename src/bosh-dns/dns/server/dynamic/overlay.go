@@ -0,0 +1,53 @@
+package dynamic
+
+import "net"
+
+// Overlay answers from the dynamic records in addition to the records file.
+type Overlay struct {
+	static RecordSet
+	store  *Store
+}
+
+//go:generate counterfeiter . RecordSet
+
+type RecordSet interface {
+	Resolve(fqdn string) ([]string, error)
+}
+
+func NewOverlay(static RecordSet, store *Store) Overlay {
+	return Overlay{static: static, store: store}
+}
+
+// Resolve ignores errors from the records file when dynamic records exist
+// for fqdn, as names below a BOSH domain rarely parse as queries.
+func (o Overlay) Resolve(fqdn string) ([]string, error) {
+	dynamicIPs := o.store.Addresses(fqdn)
+
+	ips, err := o.static.Resolve(fqdn)
+	if err != nil {
+		if len(dynamicIPs) > 0 {
+			return dynamicIPs, nil
+		}
+
+		return nil, err
+	}
+
+	for _, ip := range dynamicIPs {
+		if !containsIP(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips, nil
+}
+
+func containsIP(ips []string, ip string) bool {
+	parsed := net.ParseIP(ip)
+	for _, existing := range ips {
+		if net.ParseIP(existing).Equal(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
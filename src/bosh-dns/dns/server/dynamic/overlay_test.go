@@ -0,0 +1,69 @@
+package dynamic_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/dynamic"
+	"bosh-dns/dns/server/dynamic/dynamicfakes"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Overlay", func() {
+	var (
+		static  *dynamicfakes.FakeRecordSet
+		store   *dynamic.Store
+		overlay dynamic.Overlay
+	)
+
+	BeforeEach(func() {
+		fakeLogger := &loggerfakes.FakeLogger{}
+		static = &dynamicfakes.FakeRecordSet{}
+
+		var err error
+		store, err = dynamic.NewStore([]string{"bosh."}, time.Hour, "", boshsys.NewOsFileSystem(fakeLogger), fakeclock.NewFakeClock(time.Now()), fakeLogger)
+		Expect(err).NotTo(HaveOccurred())
+
+		overlay = dynamic.NewOverlay(static, store)
+	})
+
+	It("answers from the records file alone without dynamic records", func() {
+		static.ResolveReturns([]string{"10.0.0.1"}, nil)
+
+		ips, err := overlay.Resolve("app.bosh.")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ips).To(Equal([]string{"10.0.0.1"}))
+		Expect(static.ResolveArgsForCall(0)).To(Equal("app.bosh."))
+	})
+
+	It("adds dynamic records which are not in the records file", func() {
+		static.ResolveReturns([]string{"10.0.0.1"}, nil)
+		store.Update("bosh.", nil, rrs("app.bosh. 60 IN A 10.0.0.1", "app.bosh. 60 IN A 10.0.0.2"))
+
+		ips, err := overlay.Resolve("app.bosh.")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ips).To(Equal([]string{"10.0.0.1", "10.0.0.2"}))
+	})
+
+	It("answers with dynamic records the records file cannot resolve", func() {
+		static.ResolveReturns(nil, errors.New("not a bosh query"))
+		store.Update("bosh.", nil, rrs("app.bosh. 60 IN A 10.0.0.2"))
+
+		ips, err := overlay.Resolve("app.bosh.")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ips).To(Equal([]string{"10.0.0.2"}))
+	})
+
+	It("returns the records file error without dynamic records", func() {
+		static.ResolveReturns(nil, errors.New("not a bosh query"))
+
+		_, err := overlay.Resolve("app.bosh.")
+		Expect(err).To(MatchError("not a bosh query"))
+	})
+})
@@ -0,0 +1,464 @@
+package dynamic
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/miekg/dns"
+)
+
+const (
+	storeLogTag = "DynamicRecords"
+
+	expiryInterval = time.Second
+)
+
+type entry struct {
+	rr      dns.RR
+	expires time.Time
+}
+
+type persistedEntry struct {
+	RR      string    `json:"rr"`
+	Expires time.Time `json:"expires"`
+}
+
+// Store holds the A and AAAA records added through dynamic updates. Every
+// record expires once its TTL, capped at maxTTL, passes without the record
+// being added again.
+type Store struct {
+	zones       []string
+	maxTTL      time.Duration
+	persistFile string
+	fs          boshsys.FileSystem
+	clock       clock.Clock
+	logger      boshlog.Logger
+
+	mutex   *sync.RWMutex
+	entries map[string][]entry
+
+	subscribersMutex *sync.Mutex
+	subscribers      []chan struct{}
+}
+
+// NewStore loads any unexpired records from persistFile, which may be empty
+// to keep records in memory only.
+func NewStore(zones []string, maxTTL time.Duration, persistFile string, fs boshsys.FileSystem, clock clock.Clock, logger boshlog.Logger) (*Store, error) {
+	s := &Store{
+		maxTTL:      maxTTL,
+		persistFile: persistFile,
+		fs:          fs,
+		clock:       clock,
+		logger:      logger,
+
+		mutex:   &sync.RWMutex{},
+		entries: map[string][]entry{},
+
+		subscribersMutex: &sync.Mutex{},
+	}
+
+	for _, zone := range zones {
+		s.zones = append(s.zones, strings.ToLower(dns.Fqdn(zone)))
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Domains returns the zones which accept updates.
+func (s *Store) Domains() []string {
+	return s.zones
+}
+
+// Subscribe is notified whenever records are added, deleted or expire.
+// Notifications are coalesced for subscribers which have not received the
+// previous one yet.
+func (s *Store) Subscribe() <-chan struct{} {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	c := make(chan struct{}, 1)
+	s.subscribers = append(s.subscribers, c)
+	return c
+}
+
+func (s *Store) notify() {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	for _, c := range s.subscribers {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Store) Run(signal <-chan struct{}) {
+	ticker := s.clock.NewTicker(expiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-signal:
+			return
+		case <-ticker.C():
+			if s.expire() > 0 {
+				s.persist()
+				s.notify()
+			}
+		}
+	}
+}
+
+// Addresses returns the IPs of the unexpired records at name.
+func (s *Store) Addresses(name string) []string {
+	now := s.clock.Now()
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ips := []string{}
+	for _, e := range s.entries[strings.ToLower(name)] {
+		if !now.Before(e.expires) {
+			continue
+		}
+
+		switch rr := e.rr.(type) {
+		case *dns.A:
+			ips = append(ips, rr.A.String())
+		case *dns.AAAA:
+			ips = append(ips, rr.AAAA.String())
+		}
+	}
+
+	return ips
+}
+
+// Names returns the names which have unexpired records.
+func (s *Store) Names() []string {
+	now := s.clock.Now()
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	names := []string{}
+	for name := range s.entries {
+		if s.exists(name, dns.TypeANY, now) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Update applies an RFC 2136 update to zone once all prerequisites hold,
+// and returns the rcode to answer with.
+func (s *Store) Update(zone string, prerequisites, updates []dns.RR) int {
+	zone = strings.ToLower(zone)
+	if !s.managed(zone) {
+		return dns.RcodeNotAuth
+	}
+
+	if rcode := s.prescan(zone, updates); rcode != dns.RcodeSuccess {
+		return rcode
+	}
+
+	s.mutex.Lock()
+
+	if rcode := s.checkPrerequisites(zone, prerequisites); rcode != dns.RcodeSuccess {
+		s.mutex.Unlock()
+		return rcode
+	}
+
+	now := s.clock.Now()
+	for _, rr := range updates {
+		s.apply(rr, now)
+	}
+
+	s.mutex.Unlock()
+
+	s.persist()
+	s.notify()
+
+	return dns.RcodeSuccess
+}
+
+func (s *Store) managed(zone string) bool {
+	for _, z := range s.zones {
+		if z == zone {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Store) prescan(zone string, updates []dns.RR) int {
+	for _, rr := range updates {
+		header := rr.Header()
+
+		if !dns.IsSubDomain(zone, strings.ToLower(header.Name)) {
+			return dns.RcodeNotZone
+		}
+
+		switch header.Class {
+		case dns.ClassINET:
+			if header.Rrtype != dns.TypeA && header.Rrtype != dns.TypeAAAA {
+				return dns.RcodeRefused
+			}
+
+			if header.Ttl == 0 {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassANY:
+			if header.Ttl != 0 || header.Rdlength != 0 {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassNONE:
+			if header.Ttl != 0 {
+				return dns.RcodeFormatError
+			}
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+// checkPrerequisites supports the RRset existence prerequisites; value
+// dependent ones are not implemented.
+func (s *Store) checkPrerequisites(zone string, prerequisites []dns.RR) int {
+	now := s.clock.Now()
+
+	for _, rr := range prerequisites {
+		header := rr.Header()
+		name := strings.ToLower(header.Name)
+
+		if !dns.IsSubDomain(zone, name) {
+			return dns.RcodeNotZone
+		}
+
+		if header.Ttl != 0 {
+			return dns.RcodeFormatError
+		}
+
+		exists := s.exists(name, header.Rrtype, now)
+
+		switch header.Class {
+		case dns.ClassANY:
+			if !exists {
+				if header.Rrtype == dns.TypeANY {
+					return dns.RcodeNameError
+				}
+				return dns.RcodeNXRrset
+			}
+		case dns.ClassNONE:
+			if exists {
+				if header.Rrtype == dns.TypeANY {
+					return dns.RcodeYXDomain
+				}
+				return dns.RcodeYXRrset
+			}
+		case dns.ClassINET:
+			return dns.RcodeNotImplemented
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+func (s *Store) exists(name string, rrtype uint16, now time.Time) bool {
+	for _, e := range s.entries[name] {
+		if now.Before(e.expires) && (rrtype == dns.TypeANY || e.rr.Header().Rrtype == rrtype) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Store) apply(rr dns.RR, now time.Time) {
+	header := rr.Header()
+	name := strings.ToLower(header.Name)
+
+	switch header.Class {
+	case dns.ClassINET:
+		if ttl := uint32(s.maxTTL / time.Second); header.Ttl > ttl {
+			header.Ttl = ttl
+		}
+
+		expires := now.Add(time.Duration(header.Ttl) * time.Second)
+		entries := s.entries[name]
+
+		for i, e := range entries {
+			if sameData(e.rr, rr) {
+				entries[i] = entry{rr: rr, expires: expires}
+				return
+			}
+		}
+
+		s.entries[name] = append(entries, entry{rr: rr, expires: expires})
+		s.logger.Info(storeLogTag, "Added %s", rr)
+	case dns.ClassANY:
+		s.remove(name, "Deleted", func(e entry) bool {
+			return header.Rrtype == dns.TypeANY || e.rr.Header().Rrtype == header.Rrtype
+		})
+	case dns.ClassNONE:
+		s.remove(name, "Deleted", func(e entry) bool {
+			return sameData(e.rr, rr)
+		})
+	}
+}
+
+func (s *Store) remove(name, reason string, matches func(entry) bool) {
+	kept := []entry{}
+	for _, e := range s.entries[name] {
+		if matches(e) {
+			s.logger.Info(storeLogTag, "%s %s", reason, e.rr)
+			continue
+		}
+
+		kept = append(kept, e)
+	}
+
+	if len(kept) == 0 {
+		delete(s.entries, name)
+	} else {
+		s.entries[name] = kept
+	}
+}
+
+func (s *Store) expire() int {
+	now := s.clock.Now()
+	expired := 0
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for name := range s.entries {
+		s.remove(name, "Expired", func(e entry) bool {
+			if now.Before(e.expires) {
+				return false
+			}
+
+			expired++
+			return true
+		})
+	}
+
+	return expired
+}
+
+func (s *Store) load() error {
+	if s.persistFile == "" || !s.fs.FileExists(s.persistFile) {
+		return nil
+	}
+
+	contents, err := s.fs.ReadFile(s.persistFile)
+	if err != nil {
+		return err
+	}
+
+	var persisted []persistedEntry
+	if err := json.Unmarshal(contents, &persisted); err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+	latest := now.Add(s.maxTTL)
+	loaded := 0
+
+	for _, p := range persisted {
+		if !now.Before(p.Expires) {
+			continue
+		}
+
+		// records persisted before max_ttl was lowered
+		if p.Expires.After(latest) {
+			p.Expires = latest
+		}
+
+		rr, err := dns.NewRR(p.RR)
+		if err != nil || rr == nil {
+			s.logger.Error(storeLogTag, "Skipping persisted record '%s': %v", p.RR, err)
+			continue
+		}
+
+		name := strings.ToLower(rr.Header().Name)
+		s.entries[name] = append(s.entries[name], entry{rr: rr, expires: p.Expires})
+		loaded++
+	}
+
+	s.logger.Info(storeLogTag, "Loaded %d records from %s", loaded, s.persistFile)
+
+	return nil
+}
+
+// persist writes all records to a temporary file first so that a crash never
+// leaves a truncated file behind.
+func (s *Store) persist() {
+	if s.persistFile == "" {
+		return
+	}
+
+	s.mutex.RLock()
+	persisted := []persistedEntry{}
+	for _, entries := range s.entries {
+		for _, e := range entries {
+			persisted = append(persisted, persistedEntry{RR: e.rr.String(), Expires: e.expires})
+		}
+	}
+	s.mutex.RUnlock()
+
+	sort.Slice(persisted, func(i, j int) bool {
+		return persisted[i].RR < persisted[j].RR
+	})
+
+	contents, err := json.Marshal(persisted)
+	if err != nil {
+		s.logger.Error(storeLogTag, "Unable to persist records: %s", err)
+		return
+	}
+
+	tmpFile := s.persistFile + ".tmp"
+	if err := s.fs.WriteFile(tmpFile, contents); err != nil {
+		s.logger.Error(storeLogTag, "Unable to persist records: %s", err)
+		return
+	}
+
+	if err := s.fs.Rename(tmpFile, s.persistFile); err != nil {
+		s.logger.Error(storeLogTag, "Unable to persist records: %s", err)
+	}
+}
+
+// sameData compares everything but the TTL.
+func sameData(a, b dns.RR) bool {
+	if a.Header().Rrtype != b.Header().Rrtype || !strings.EqualFold(a.Header().Name, b.Header().Name) {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *dns.A:
+		return a.A.Equal(b.(*dns.A).A)
+	case *dns.AAAA:
+		return a.AAAA.Equal(b.(*dns.AAAA).AAAA)
+	}
+
+	return false
+}
@@ -0,0 +1,291 @@
+package dynamic_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/dynamic"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func mustRR(s string) dns.RR {
+	rr, err := dns.NewRR(s)
+	Expect(err).NotTo(HaveOccurred())
+	return rr
+}
+
+// emptyRR builds the RDATA-less records used to delete RRsets and to express
+// existence prerequisites.
+func emptyRR(name string, class, rrtype uint16) dns.RR {
+	return &dns.ANY{Hdr: dns.RR_Header{Name: name, Rrtype: rrtype, Class: class}}
+}
+
+func rrs(records ...string) []dns.RR {
+	parsed := []dns.RR{}
+	for _, s := range records {
+		parsed = append(parsed, mustRR(s))
+	}
+	return parsed
+}
+
+var _ = Describe("Store", func() {
+	var (
+		fakeClock   *fakeclock.FakeClock
+		fakeLogger  *loggerfakes.FakeLogger
+		fs          boshsys.FileSystem
+		persistFile string
+		store       *dynamic.Store
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(1500000000, 0))
+		fakeLogger = &loggerfakes.FakeLogger{}
+		fs = boshsys.NewOsFileSystem(fakeLogger)
+		persistFile = ""
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		store, err = dynamic.NewStore([]string{"Dyn.Internal"}, time.Hour, persistFile, fs, fakeClock, fakeLogger)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("normalizes the zones it accepts updates for", func() {
+		Expect(store.Domains()).To(Equal([]string{"dyn.internal."}))
+	})
+
+	It("adds A and AAAA records", func() {
+		rcode := store.Update("dyn.internal.", nil, rrs(
+			"app.dyn.internal. 60 IN A 10.0.0.1",
+			"app.dyn.internal. 60 IN AAAA ::1",
+		))
+
+		Expect(rcode).To(Equal(dns.RcodeSuccess))
+		Expect(store.Addresses("APP.dyn.internal.")).To(ConsistOf("10.0.0.1", "::1"))
+	})
+
+	It("refreshes records which are added again", func() {
+		store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 60 IN A 10.0.0.1"))
+		fakeClock.Increment(50 * time.Second)
+		store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 60 IN A 10.0.0.1"))
+		fakeClock.Increment(50 * time.Second)
+
+		Expect(store.Addresses("app.dyn.internal.")).To(Equal([]string{"10.0.0.1"}))
+	})
+
+	It("stops answering with records once their TTL passes", func() {
+		store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 60 IN A 10.0.0.1"))
+		fakeClock.Increment(time.Minute)
+
+		Expect(store.Addresses("app.dyn.internal.")).To(BeEmpty())
+	})
+
+	It("deletes RRsets, names and single records", func() {
+		store.Update("dyn.internal.", nil, rrs(
+			"app.dyn.internal. 60 IN A 10.0.0.1",
+			"app.dyn.internal. 60 IN A 10.0.0.2",
+			"app.dyn.internal. 60 IN AAAA ::1",
+			"db.dyn.internal. 60 IN A 10.0.1.1",
+		))
+
+		Expect(store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 0 NONE A 10.0.0.1"))).To(Equal(dns.RcodeSuccess))
+		Expect(store.Addresses("app.dyn.internal.")).To(ConsistOf("10.0.0.2", "::1"))
+
+		Expect(store.Update("dyn.internal.", nil, []dns.RR{emptyRR("app.dyn.internal.", dns.ClassANY, dns.TypeAAAA)})).To(Equal(dns.RcodeSuccess))
+		Expect(store.Addresses("app.dyn.internal.")).To(ConsistOf("10.0.0.2"))
+
+		Expect(store.Update("dyn.internal.", nil, []dns.RR{emptyRR("db.dyn.internal.", dns.ClassANY, dns.TypeANY)})).To(Equal(dns.RcodeSuccess))
+		Expect(store.Addresses("db.dyn.internal.")).To(BeEmpty())
+	})
+
+	It("rejects updates outside of the managed zones", func() {
+		Expect(store.Update("other.internal.", nil, rrs("app.other.internal. 60 IN A 10.0.0.1"))).To(Equal(dns.RcodeNotAuth))
+		Expect(store.Update("dyn.internal.", nil, rrs("app.other.internal. 60 IN A 10.0.0.1"))).To(Equal(dns.RcodeNotZone))
+	})
+
+	It("refuses record types other than A and AAAA", func() {
+		rcode := store.Update("dyn.internal.", nil, rrs(
+			"app.dyn.internal. 60 IN A 10.0.0.1",
+			`app.dyn.internal. 60 IN TXT "hello"`,
+		))
+
+		Expect(rcode).To(Equal(dns.RcodeRefused))
+		Expect(store.Addresses("app.dyn.internal.")).To(BeEmpty())
+	})
+
+	It("caps the TTL of added records", func() {
+		Expect(store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 2147483647 IN A 10.0.0.1"))).To(Equal(dns.RcodeSuccess))
+
+		fakeClock.Increment(time.Hour - time.Second)
+		Expect(store.Addresses("app.dyn.internal.")).To(Equal([]string{"10.0.0.1"}))
+
+		fakeClock.Increment(time.Second)
+		Expect(store.Addresses("app.dyn.internal.")).To(BeEmpty())
+	})
+
+	It("notifies subscribers of updates", func() {
+		subscription := store.Subscribe()
+
+		store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 60 IN A 10.0.0.1"))
+		store.Update("dyn.internal.", nil, rrs("db.dyn.internal. 60 IN A 10.0.1.1"))
+
+		Expect(subscription).To(Receive())
+		Expect(subscription).NotTo(Receive())
+	})
+
+	It("lists the names with unexpired records", func() {
+		store.Update("dyn.internal.", nil, rrs(
+			"db.dyn.internal. 600 IN A 10.0.1.1",
+			"App.dyn.internal. 60 IN A 10.0.0.1",
+		))
+		Expect(store.Names()).To(Equal([]string{"app.dyn.internal.", "db.dyn.internal."}))
+
+		fakeClock.Increment(time.Minute)
+		Expect(store.Names()).To(Equal([]string{"db.dyn.internal."}))
+	})
+
+	It("rejects additions without a TTL", func() {
+		Expect(store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 0 IN A 10.0.0.1"))).To(Equal(dns.RcodeFormatError))
+	})
+
+	Describe("prerequisites", func() {
+		JustBeforeEach(func() {
+			store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 60 IN A 10.0.0.1"))
+		})
+
+		It("applies the update when all prerequisites hold", func() {
+			rcode := store.Update("dyn.internal.",
+				[]dns.RR{
+					emptyRR("app.dyn.internal.", dns.ClassANY, dns.TypeA),
+					emptyRR("db.dyn.internal.", dns.ClassNONE, dns.TypeANY),
+				},
+				rrs("db.dyn.internal. 60 IN A 10.0.1.1"),
+			)
+
+			Expect(rcode).To(Equal(dns.RcodeSuccess))
+			Expect(store.Addresses("db.dyn.internal.")).To(Equal([]string{"10.0.1.1"}))
+		})
+
+		expectFailure := func(prerequisite dns.RR, expected int) {
+			rcode := store.Update("dyn.internal.", []dns.RR{prerequisite}, rrs("db.dyn.internal. 60 IN A 10.0.1.1"))
+
+			Expect(rcode).To(Equal(expected))
+			Expect(store.Addresses("db.dyn.internal.")).To(BeEmpty())
+		}
+
+		It("fails when a required name is missing", func() {
+			expectFailure(emptyRR("db.dyn.internal.", dns.ClassANY, dns.TypeANY), dns.RcodeNameError)
+		})
+
+		It("fails when a required RRset is missing", func() {
+			expectFailure(emptyRR("app.dyn.internal.", dns.ClassANY, dns.TypeAAAA), dns.RcodeNXRrset)
+		})
+
+		It("fails when a name which must not exist does", func() {
+			expectFailure(emptyRR("app.dyn.internal.", dns.ClassNONE, dns.TypeANY), dns.RcodeYXDomain)
+		})
+
+		It("fails when an RRset which must not exist does", func() {
+			expectFailure(emptyRR("app.dyn.internal.", dns.ClassNONE, dns.TypeA), dns.RcodeYXRrset)
+		})
+
+		It("does not implement value dependent prerequisites", func() {
+			expectFailure(mustRR("app.dyn.internal. 0 IN A 10.0.0.1"), dns.RcodeNotImplemented)
+		})
+	})
+
+	Describe("Run", func() {
+		It("expires records on every tick", func() {
+			store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 1 IN A 10.0.0.1"))
+
+			shutdown := make(chan struct{})
+			defer close(shutdown)
+			go store.Run(shutdown)
+
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+			fakeClock.Increment(time.Second)
+
+			Eventually(func() int {
+				count := 0
+				for i := 0; i < fakeLogger.InfoCallCount(); i++ {
+					_, message, args := fakeLogger.InfoArgsForCall(i)
+					if message == "%s %s" && args[0] == "Expired" {
+						count++
+					}
+				}
+				return count
+			}).Should(Equal(1))
+		})
+
+		It("notifies subscribers of expired records", func() {
+			store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 1 IN A 10.0.0.1"))
+			subscription := store.Subscribe()
+
+			shutdown := make(chan struct{})
+			defer close(shutdown)
+			go store.Run(shutdown)
+
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+			fakeClock.Increment(time.Second)
+
+			Eventually(subscription).Should(Receive())
+		})
+	})
+
+	Context("with a persist file", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "dynamic-records")
+			Expect(err).NotTo(HaveOccurred())
+			persistFile = filepath.Join(dir, "records.json")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("restores unexpired records on start", func() {
+			store.Update("dyn.internal.", nil, rrs(
+				"app.dyn.internal. 60 IN A 10.0.0.1",
+				"db.dyn.internal. 600 IN A 10.0.1.1",
+			))
+			Expect(persistFile).To(BeAnExistingFile())
+
+			fakeClock.Increment(2 * time.Minute)
+
+			restored, err := dynamic.NewStore([]string{"dyn.internal."}, time.Hour, persistFile, fs, fakeClock, fakeLogger)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(restored.Addresses("app.dyn.internal.")).To(BeEmpty())
+			Expect(restored.Addresses("db.dyn.internal.")).To(Equal([]string{"10.0.1.1"}))
+		})
+
+		It("caps the expiry of restored records at the max TTL", func() {
+			store.Update("dyn.internal.", nil, rrs("db.dyn.internal. 600 IN A 10.0.1.1"))
+
+			restored, err := dynamic.NewStore([]string{"dyn.internal."}, time.Minute, persistFile, fs, fakeClock, fakeLogger)
+			Expect(err).NotTo(HaveOccurred())
+
+			fakeClock.Increment(time.Minute)
+			Expect(restored.Addresses("db.dyn.internal.")).To(BeEmpty())
+		})
+
+		It("fails to start with a corrupt persist file", func() {
+			Expect(ioutil.WriteFile(persistFile, []byte("{"), 0644)).To(Succeed())
+
+			_, err := dynamic.NewStore([]string{"dyn.internal."}, time.Hour, persistFile, fs, fakeClock, fakeLogger)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package dynamic
+
+import (
+	"bosh-dns/dns/server/records"
+	"bosh-dns/dns/server/zone"
+)
+
+// ZoneSource adds the dynamic records to the zones synthesized for zone
+// transfers and DNSSEC. Dynamic names are presented as aliases, and so is
+// the apex of every dynamic zone, which keeps a zone outside of the BOSH
+// domains a single origin however many names it holds.
+type ZoneSource struct {
+	static zone.RecordSource
+	store  *Store
+}
+
+func NewZoneSource(static zone.RecordSource, store *Store) ZoneSource {
+	return ZoneSource{static: static, store: store}
+}
+
+func (z ZoneSource) AllRecords() []records.Record {
+	return z.static.AllRecords()
+}
+
+func (z ZoneSource) Aliases() []string {
+	seen := map[string]struct{}{}
+	aliases := []string{}
+
+	for _, names := range [][]string{z.static.Aliases(), z.store.Domains(), z.store.Names()} {
+		for _, name := range names {
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				aliases = append(aliases, name)
+			}
+		}
+	}
+
+	return aliases
+}
+
+func (z ZoneSource) AliasIPs(alias string) []string {
+	return append(z.static.AliasIPs(alias), z.store.Addresses(alias)...)
+}
+
+// SubscribeChanges forwards the changes of the records file and sends an
+// empty diff whenever the dynamic records change. Changes are coalesced for
+// subscribers which have not received the previous one yet, as every change
+// only prompts rebuilding the zones from the current records. The
+// subscription is closed along with the one of the records file.
+func (z ZoneSource) SubscribeChanges() <-chan records.RecordsDiff {
+	staticChanges := z.static.SubscribeChanges()
+	dynamicChanges := z.store.Subscribe()
+	changes := make(chan records.RecordsDiff, 1)
+
+	go func() {
+		defer close(changes)
+
+		for {
+			var diff records.RecordsDiff
+
+			select {
+			case d, ok := <-staticChanges:
+				if !ok {
+					return
+				}
+				diff = d
+			case <-dynamicChanges:
+			}
+
+			select {
+			case changes <- diff:
+			default:
+			}
+		}
+	}()
+
+	return changes
+}
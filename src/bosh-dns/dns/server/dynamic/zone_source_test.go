@@ -0,0 +1,112 @@
+package dynamic_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/dynamic"
+	"bosh-dns/dns/server/records"
+	"bosh-dns/dns/server/zone"
+	"bosh-dns/dns/server/zone/zonefakes"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func recordStrings(rrs []dns.RR) []string {
+	strs := []string{}
+	for _, rr := range rrs {
+		strs = append(strs, rr.String())
+	}
+	return strs
+}
+
+var _ = Describe("ZoneSource", func() {
+	var (
+		static        *zonefakes.FakeRecordSource
+		staticChanges chan records.RecordsDiff
+		store         *dynamic.Store
+		source        dynamic.ZoneSource
+	)
+
+	BeforeEach(func() {
+		fakeLogger := &loggerfakes.FakeLogger{}
+
+		staticChanges = make(chan records.RecordsDiff, 1)
+		static = &zonefakes.FakeRecordSource{}
+		static.AllRecordsReturns([]records.Record{
+			{ID: "abc", Group: "app", Network: "default", Deployment: "cf", IP: "10.0.0.1", Domain: "bosh."},
+		})
+		static.AliasesReturns([]string{"alias.internal."})
+		static.AliasIPsStub = func(alias string) []string {
+			if alias == "alias.internal." {
+				return []string{"10.0.0.1"}
+			}
+			return []string{}
+		}
+		static.SubscribeChangesReturns(staticChanges)
+
+		var err error
+		store, err = dynamic.NewStore([]string{"bosh.", "dyn.internal."}, time.Hour, "", boshsys.NewOsFileSystem(fakeLogger), fakeclock.NewFakeClock(time.Now()), fakeLogger)
+		Expect(err).NotTo(HaveOccurred())
+
+		source = dynamic.NewZoneSource(static, store)
+	})
+
+	It("adds every dynamic zone as an origin", func() {
+		store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 60 IN A 10.0.1.1", "db.dyn.internal. 60 IN A 10.0.1.2"))
+
+		Expect(zone.Origins(source)).To(Equal([]string{"alias.internal.", "bosh.", "dyn.internal."}))
+	})
+
+	It("includes dynamic records in the synthesized zones", func() {
+		store.Update("bosh.", nil, rrs("app.bosh. 60 IN A 10.0.2.1"))
+		store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 60 IN AAAA ::1"))
+
+		zones := zone.Build(source, zone.Origins(source), config.ZoneTransfer{TTL: config.DurationJSON(time.Minute)}, 1)
+
+		Expect(recordStrings(zones["bosh."].Records)).To(ContainElement("app.bosh.\t60\tIN\tA\t10.0.2.1"))
+		Expect(recordStrings(zones["dyn.internal."].Records)).To(Equal([]string{"app.dyn.internal.\t60\tIN\tAAAA\t::1"}))
+	})
+
+	It("merges the addresses of aliases which are also dynamic names", func() {
+		store.Update("dyn.internal.", nil, rrs("alias.internal.dyn.internal. 60 IN A 10.0.1.1"))
+		static.AliasesReturns([]string{"alias.internal.dyn.internal."})
+		static.AliasIPsReturns([]string{"10.0.0.1"})
+
+		Expect(source.Aliases()).To(Equal([]string{"alias.internal.dyn.internal.", "bosh.", "dyn.internal."}))
+		Expect(source.AliasIPs("alias.internal.dyn.internal.")).To(Equal([]string{"10.0.0.1", "10.0.1.1"}))
+	})
+
+	Describe("SubscribeChanges", func() {
+		It("forwards records file changes", func() {
+			changes := source.SubscribeChanges()
+
+			diff := records.RecordsDiff{Added: []records.Record{{IP: "10.0.0.2"}}}
+			staticChanges <- diff
+
+			Eventually(changes).Should(Receive(Equal(diff)))
+		})
+
+		It("reports dynamic record changes", func() {
+			changes := source.SubscribeChanges()
+
+			store.Update("dyn.internal.", nil, rrs("app.dyn.internal. 60 IN A 10.0.1.1"))
+
+			Eventually(changes).Should(Receive(Equal(records.RecordsDiff{})))
+		})
+
+		It("closes along with the records file subscription", func() {
+			changes := source.SubscribeChanges()
+
+			close(staticChanges)
+
+			Eventually(changes).Should(BeClosed())
+		})
+	})
+})
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// ACLHandler passes the queries of clients within none of the deny networks
+// and, unless allow is empty, within one of the allow networks to next. The
+// queries of other clients go to refused, which may be wrapped with a
+// RequestLoggerHandler to log them.
+type ACLHandler struct {
+	next    dns.Handler
+	refused dns.Handler
+	allow   []*net.IPNet
+	deny    []*net.IPNet
+}
+
+func NewACLHandler(next, refused dns.Handler, allow, deny []*net.IPNet) ACLHandler {
+	return ACLHandler{
+		next:    next,
+		refused: refused,
+		allow:   allow,
+		deny:    deny,
+	}
+}
+
+func (h ACLHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	if h.allowed(w.RemoteAddr()) {
+		h.next.ServeDNS(w, r)
+	} else {
+		h.refused.ServeDNS(w, r)
+	}
+}
+
+func (h ACLHandler) allowed(addr net.Addr) bool {
+	var ip net.IP
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		ip = addr.IP
+	case *net.TCPAddr:
+		ip = addr.IP
+	}
+
+	for _, network := range h.deny {
+		if ip == nil || network.Contains(ip) {
+			return false
+		}
+	}
+
+	if len(h.allow) == 0 {
+		return true
+	}
+
+	for _, network := range h.allow {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
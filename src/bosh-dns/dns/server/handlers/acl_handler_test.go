@@ -0,0 +1,125 @@
+package handlers_test
+
+import (
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/internal/internalfakes"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ACLHandler", func() {
+	var (
+		fakeWriter *internalfakes.FakeResponseWriter
+		next       dns.Handler
+		served     int
+		allow      []*net.IPNet
+		deny       []*net.IPNet
+		request    *dns.Msg
+	)
+
+	network := func(cidr string) *net.IPNet {
+		_, network, err := net.ParseCIDR(cidr)
+		Expect(err).NotTo(HaveOccurred())
+		return network
+	}
+
+	BeforeEach(func() {
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		served = 0
+		next = dns.HandlerFunc(func(dns.ResponseWriter, *dns.Msg) {
+			served++
+		})
+
+		allow = nil
+		deny = nil
+
+		request = &dns.Msg{}
+		request.SetQuestion("db.corp.internal.", dns.TypeA)
+	})
+
+	serveFrom := func(addr net.Addr) {
+		fakeWriter.RemoteAddrReturns(addr)
+		handler := handlers.NewACLHandler(next, handlers.NewRefusedHandler(&loggerfakes.FakeLogger{}), allow, deny)
+		handler.ServeDNS(fakeWriter, request)
+	}
+
+	Context("when only denying networks", func() {
+		BeforeEach(func() {
+			deny = []*net.IPNet{network("192.0.2.0/24")}
+		})
+
+		It("refuses clients in the denied networks", func() {
+			serveFrom(&net.UDPAddr{IP: net.ParseIP("192.0.2.10"), Port: 5353})
+
+			Expect(served).To(Equal(0))
+			Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+
+			response := fakeWriter.WriteMsgArgsForCall(0)
+			Expect(response.Rcode).To(Equal(dns.RcodeRefused))
+			Expect(response.Id).To(Equal(request.Id))
+			Expect(response.Question).To(Equal(request.Question))
+		})
+
+		It("allows every other client", func() {
+			serveFrom(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5353})
+
+			Expect(served).To(Equal(1))
+			Expect(fakeWriter.WriteMsgCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when allowing networks", func() {
+		BeforeEach(func() {
+			allow = []*net.IPNet{network("10.0.0.0/8"), network("2001:db8::/32")}
+			deny = []*net.IPNet{network("10.0.0.66/32")}
+		})
+
+		It("allows clients in the allowed networks", func() {
+			serveFrom(&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5353})
+			serveFrom(&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 5353})
+
+			Expect(served).To(Equal(2))
+		})
+
+		It("refuses clients outside the allowed networks", func() {
+			serveFrom(&net.UDPAddr{IP: net.ParseIP("172.16.0.1"), Port: 5353})
+
+			Expect(served).To(Equal(0))
+			Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeRefused))
+		})
+
+		It("refuses denied clients within the allowed networks", func() {
+			serveFrom(&net.UDPAddr{IP: net.ParseIP("10.0.0.66"), Port: 5353})
+
+			Expect(served).To(Equal(0))
+		})
+
+		It("refuses clients without an address", func() {
+			serveFrom(nil)
+
+			Expect(served).To(Equal(0))
+		})
+	})
+
+	It("is composable with the request logger", func() {
+		deny = []*net.IPNet{network("0.0.0.0/0")}
+		fakeLogger := &loggerfakes.FakeLogger{}
+		refused := handlers.NewRequestLoggerHandler(handlers.NewRefusedHandler(fakeLogger), fakeclock.NewFakeClock(time.Now()), fakeLogger)
+
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5353})
+		handlers.NewACLHandler(next, refused, allow, deny).ServeDNS(fakeWriter, request)
+
+		Expect(fakeLogger.InfoCallCount()).To(Equal(1))
+		_, message, _ := fakeLogger.InfoArgsForCall(0)
+		Expect(message).To(MatchRegexp(`handlers.RefusedHandler Request \[1\] \[db.corp.internal.\] 5 \d+ns`))
+	})
+})
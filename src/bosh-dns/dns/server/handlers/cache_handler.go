@@ -8,6 +8,9 @@ import (
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
+// CachingDNSHandler only ever wraps forward and HTTP JSON handlers. Records
+// served from records.json are looked up on every query, so records changes
+// never have to invalidate its entries.
 type CachingDNSHandler struct {
 	next   dns.Handler
 	ca     *cache.Cache
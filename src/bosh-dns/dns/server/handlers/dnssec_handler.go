@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"net"
+	"strings"
+
+	"bosh-dns/dns/server/dnssec"
+	"bosh-dns/dns/server/handlers/internal"
+	"bosh-dns/dns/server/zone"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+const dnssecUDPSize = 4096
+
+// DNSSECHandler signs the answers next gives for names in the served zones
+// whenever the request sets the DO bit. It answers DNSKEY queries at the
+// zone apex itself, and DS queries with a signed denial.
+type DNSSECHandler struct {
+	next   dns.Handler
+	zones  ZoneSource
+	signer *dnssec.Signer
+	logger logger.Logger
+	logTag string
+}
+
+func NewDNSSECHandler(next dns.Handler, zones ZoneSource, signer *dnssec.Signer, logger logger.Logger) DNSSECHandler {
+	return DNSSECHandler{
+		next:   next,
+		zones:  zones,
+		signer: signer,
+		logger: logger,
+		logTag: "DNSSECHandler",
+	}
+}
+
+func (h DNSSECHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	opt := r.IsEdns0()
+	if r.Opcode != dns.OpcodeQuery || len(r.Question) == 0 || opt == nil || !opt.Do() {
+		h.next.ServeDNS(w, r)
+		return
+	}
+
+	question := r.Question[0]
+	if question.Qtype == dns.TypeAXFR || question.Qtype == dns.TypeIXFR {
+		h.next.ServeDNS(w, r)
+		return
+	}
+
+	z, ok := h.zoneFor(question.Name)
+	if !ok {
+		h.next.ServeDNS(w, r)
+		return
+	}
+
+	switch question.Qtype {
+	case dns.TypeDNSKEY, dns.TypeDS:
+		m := &dns.Msg{}
+		m.SetReply(r)
+		m.Authoritative = true
+		if question.Qtype == dns.TypeDNSKEY && strings.EqualFold(question.Name, z.Origin) {
+			m.Answer = h.signer.DNSKEYs(z.Origin, z.SOA.Hdr.Ttl)
+		}
+
+		h.sign(w, r, z, m)
+		if err := w.WriteMsg(m); err != nil {
+			h.logger.Error(h.logTag, err.Error())
+		}
+	default:
+		h.next.ServeDNS(internal.WrapWriterWithIntercept(w, func(m *dns.Msg) {
+			h.sign(w, r, z, m)
+		}), r)
+	}
+}
+
+// zoneFor returns the closest zone enclosing name.
+func (h DNSSECHandler) zoneFor(name string) (zone.Zone, bool) {
+	name = strings.ToLower(dns.Fqdn(name))
+
+	for _, i := range dns.Split(name) {
+		if z, ok := h.zones.Zone(name[i:]); ok {
+			return z, true
+		}
+	}
+
+	return zone.Zone{}, false
+}
+
+func (h DNSSECHandler) sign(w dns.ResponseWriter, r *dns.Msg, z zone.Zone, m *dns.Msg) {
+	question := r.Question[0]
+	negativeTTL := z.SOA.Minttl
+
+	switch m.Rcode {
+	case dns.RcodeSuccess:
+		if len(m.Answer) == 0 {
+			m.Ns = append(m.Ns, z.SOA, dnssec.NoData(question.Name, question.Qtype, h.types(question.Name, z), negativeTTL))
+		}
+	case dns.RcodeNameError:
+		m.Ns = append(m.Ns, z.SOA)
+		m.Ns = append(m.Ns, dnssec.NameError(question.Name, negativeTTL)...)
+	default:
+		return
+	}
+
+	var err error
+	if m.Answer, err = h.signSection(z.Origin, m.Answer); err == nil {
+		m.Ns, err = h.signSection(z.Origin, m.Ns)
+	}
+	if err != nil {
+		h.logger.Error(h.logTag, "Failed signing answer for %s: %s", question.Name, err)
+		m.Answer = nil
+		m.Ns = nil
+		m.Rcode = dns.RcodeServerFailure
+	}
+
+	if opt := m.IsEdns0(); opt != nil {
+		opt.SetDo()
+	} else {
+		m.SetEdns0(dnssecUDPSize, true)
+	}
+
+	h.truncate(w, r, m)
+}
+
+// types lists the types which may exist at name.
+func (h DNSSECHandler) types(name string, z zone.Zone) []uint16 {
+	types := []uint16{dns.TypeA, dns.TypeAAAA}
+
+	if strings.EqualFold(name, z.Origin) {
+		types = append(types, dns.TypeSOA, dns.TypeDNSKEY)
+		if len(z.NS()) > 0 {
+			types = append(types, dns.TypeNS)
+		}
+	}
+
+	return types
+}
+
+// signSection appends the signatures of every RRset right after it.
+func (h DNSSECHandler) signSection(origin string, rrs []dns.RR) ([]dns.RR, error) {
+	signed := []dns.RR{}
+
+	for len(rrs) > 0 {
+		rrset := []dns.RR{}
+		rest := []dns.RR{}
+		header := rrs[0].Header()
+
+		for _, rr := range rrs {
+			if rr.Header().Rrtype == header.Rrtype && strings.EqualFold(rr.Header().Name, header.Name) {
+				rrset = append(rrset, rr)
+			} else {
+				rest = append(rest, rr)
+			}
+		}
+
+		signed = append(signed, rrset...)
+		rrs = rest
+
+		if header.Rrtype == dns.TypeRRSIG || header.Rrtype == dns.TypeOPT {
+			continue
+		}
+
+		rrsigs, err := h.signer.Sign(origin, rrset)
+		if err != nil {
+			return nil, err
+		}
+
+		signed = append(signed, rrsigs...)
+	}
+
+	return signed, nil
+}
+
+// truncate drops all records when a signed answer does not fit, as a partial
+// answer cannot be validated.
+func (h DNSSECHandler) truncate(w dns.ResponseWriter, r *dns.Msg, m *dns.Msg) {
+	if _, udp := w.RemoteAddr().(*net.UDPAddr); !udp {
+		return
+	}
+
+	maxLength := int(r.IsEdns0().UDPSize())
+	if maxLength < dns.MinMsgSize {
+		maxLength = dns.MinMsgSize
+	}
+
+	// Len overestimates names holding escaped octets, as denials do
+	packed, err := m.Pack()
+	if err == nil && len(packed) <= maxLength {
+		return
+	}
+
+	m.Answer = nil
+	m.Ns = nil
+	m.Truncated = true
+}
@@ -0,0 +1,268 @@
+package handlers_test
+
+import (
+	"crypto"
+	"net"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/dnssec"
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/handlers/handlersfakes"
+	"bosh-dns/dns/server/internal/internalfakes"
+	"bosh-dns/dns/server/zone"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// validate checks every RRset of section against its RRSIG the way a
+// validating resolver would, and returns the validated records by type.
+func validate(section []dns.RR, dnskey *dns.DNSKEY, now time.Time) map[uint16][]dns.RR {
+	type rrsetKey struct {
+		name   string
+		rrtype uint16
+	}
+
+	rrsets := map[rrsetKey][]dns.RR{}
+	rrsigs := map[rrsetKey]*dns.RRSIG{}
+
+	for _, rr := range section {
+		if rrsig, ok := rr.(*dns.RRSIG); ok {
+			rrsigs[rrsetKey{strings.ToLower(rrsig.Hdr.Name), rrsig.TypeCovered}] = rrsig
+			continue
+		}
+
+		key := rrsetKey{strings.ToLower(rr.Header().Name), rr.Header().Rrtype}
+		rrsets[key] = append(rrsets[key], rr)
+	}
+
+	byType := map[uint16][]dns.RR{}
+	for key, rrset := range rrsets {
+		rrsig, ok := rrsigs[key]
+		Expect(ok).To(BeTrue(), "missing RRSIG for %s %s", key.name, dns.TypeToString[key.rrtype])
+		Expect(rrsig.Verify(dnskey, rrset)).To(Succeed())
+		Expect(rrsig.ValidityPeriod(now)).To(BeTrue())
+
+		byType[key.rrtype] = append(byType[key.rrtype], rrset...)
+	}
+
+	return byType
+}
+
+func doQuestion(name string, qtype uint16) *dns.Msg {
+	m := &dns.Msg{}
+	m.SetQuestion(name, qtype)
+	m.SetEdns0(1232, true)
+	return m
+}
+
+var _ = Describe("DNSSECHandler", func() {
+	var (
+		next       dns.Handler
+		nextCalls  int
+		answer     func(r *dns.Msg) *dns.Msg
+		zones      *handlersfakes.FakeZoneSource
+		fakeWriter *internalfakes.FakeResponseWriter
+		fakeLogger *loggerfakes.FakeLogger
+		now        time.Time
+		dnskey     *dns.DNSKEY
+		handler    handlers.DNSSECHandler
+	)
+
+	BeforeEach(func() {
+		nextCalls = 0
+		answer = func(r *dns.Msg) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetReply(r)
+			m.Answer = []dns.RR{&dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET},
+				A:   net.ParseIP("10.0.0.1"),
+			}}
+			return m
+		}
+		next = dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			nextCalls++
+			w.WriteMsg(answer(r))
+		})
+
+		zones = &handlersfakes.FakeZoneSource{}
+		zones.ZoneStub = func(origin string) (zone.Zone, bool) {
+			if origin == "bosh." {
+				z := testZone(7, 0)
+				z.SOA.Minttl = 30
+				return z, true
+			}
+			return zone.Zone{}, false
+		}
+
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5353})
+		fakeLogger = &loggerfakes.FakeLogger{}
+		now = time.Unix(1500000000, 0)
+
+		dnskey = &dns.DNSKEY{
+			Hdr:       dns.RR_Header{Name: "bosh.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET},
+			Flags:     257,
+			Protocol:  3,
+			Algorithm: dns.ECDSAP256SHA256,
+		}
+		privateKey, err := dnskey.Generate(256)
+		Expect(err).NotTo(HaveOccurred())
+
+		signer := dnssec.NewSigner([]dnssec.Key{{DNSKEY: dnskey, Signer: privateKey.(crypto.Signer)}}, 24*time.Hour, fakeclock.NewFakeClock(now))
+		handler = handlers.NewDNSSECHandler(next, zones, signer, fakeLogger)
+	})
+
+	It("does not sign answers for requests without the DO bit", func() {
+		m := &dns.Msg{}
+		m.SetQuestion("web.bosh.", dns.TypeA)
+
+		handler.ServeDNS(fakeWriter, m)
+
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Answer).To(HaveLen(1))
+		Expect(response.IsEdns0()).To(BeNil())
+	})
+
+	It("does not sign answers outside of the served zones", func() {
+		handler.ServeDNS(fakeWriter, doQuestion("example.com.", dns.TypeA))
+
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Answer).To(HaveLen(1))
+	})
+
+	It("signs answers and sets the DO bit", func() {
+		handler.ServeDNS(fakeWriter, doQuestion("instance0.web.default.cf.bosh.", dns.TypeA))
+
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Answer).To(HaveLen(2))
+		Expect(response.IsEdns0()).NotTo(BeNil())
+		Expect(response.IsEdns0().Do()).To(BeTrue())
+
+		rrsets := validate(response.Answer, dnskey, now)
+		Expect(rrsets[dns.TypeA]).To(HaveLen(1))
+	})
+
+	It("proves that there is no data for the queried type", func() {
+		answer = func(r *dns.Msg) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetReply(r)
+			return m
+		}
+
+		handler.ServeDNS(fakeWriter, doQuestion("instance0.web.default.cf.bosh.", dns.TypeAAAA))
+
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Rcode).To(Equal(dns.RcodeSuccess))
+
+		rrsets := validate(response.Ns, dnskey, now)
+		Expect(rrsets[dns.TypeSOA]).To(HaveLen(1))
+
+		nsec := rrsets[dns.TypeNSEC][0].(*dns.NSEC)
+		Expect(nsec.Hdr.Name).To(Equal("instance0.web.default.cf.bosh."))
+		Expect(nsec.Hdr.Ttl).To(Equal(uint32(30)))
+		Expect(nsec.TypeBitMap).To(Equal([]uint16{dns.TypeA, dns.TypeRRSIG, dns.TypeNSEC}))
+	})
+
+	It("proves that names do not exist", func() {
+		answer = func(r *dns.Msg) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetRcode(r, dns.RcodeNameError)
+			return m
+		}
+
+		handler.ServeDNS(fakeWriter, doQuestion("missing.bosh.", dns.TypeA))
+
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Rcode).To(Equal(dns.RcodeNameError))
+
+		rrsets := validate(response.Ns, dnskey, now)
+		Expect(rrsets[dns.TypeNSEC]).To(HaveLen(2))
+
+		packed, err := response.Pack()
+		Expect(err).NotTo(HaveOccurred())
+		Expect((&dns.Msg{}).Unpack(packed)).To(Succeed())
+	})
+
+	It("answers DNSKEY queries at the zone apex", func() {
+		handler.ServeDNS(fakeWriter, doQuestion("bosh.", dns.TypeDNSKEY))
+
+		Expect(nextCalls).To(Equal(0))
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Authoritative).To(BeTrue())
+
+		rrsets := validate(response.Answer, dnskey, now)
+		Expect(rrsets[dns.TypeDNSKEY]).To(HaveLen(1))
+	})
+
+	It("denies DS records", func() {
+		handler.ServeDNS(fakeWriter, doQuestion("cf.bosh.", dns.TypeDS))
+
+		Expect(nextCalls).To(Equal(0))
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Answer).To(BeEmpty())
+
+		rrsets := validate(response.Ns, dnskey, now)
+		Expect(rrsets[dns.TypeNSEC][0].(*dns.NSEC).TypeBitMap).NotTo(ContainElement(dns.TypeDS))
+	})
+
+	It("lists the apex types in denials at the apex", func() {
+		handler.ServeDNS(fakeWriter, doQuestion("bosh.", dns.TypeDS))
+
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		rrsets := validate(response.Ns, dnskey, now)
+		Expect(rrsets[dns.TypeNSEC][0].(*dns.NSEC).TypeBitMap).To(Equal([]uint16{
+			dns.TypeA, dns.TypeNS, dns.TypeSOA, dns.TypeAAAA, dns.TypeRRSIG, dns.TypeNSEC, dns.TypeDNSKEY,
+		}))
+	})
+
+	It("leaves failures unsigned", func() {
+		answer = func(r *dns.Msg) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetRcode(r, dns.RcodeServerFailure)
+			return m
+		}
+
+		handler.ServeDNS(fakeWriter, doQuestion("web.bosh.", dns.TypeTXT))
+
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Rcode).To(Equal(dns.RcodeServerFailure))
+		Expect(response.Ns).To(BeEmpty())
+	})
+
+	It("truncates signed answers which do not fit the advertised buffer", func() {
+		answer = func(r *dns.Msg) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetReply(r)
+			for i := 0; i < 10; i++ {
+				m.Answer = append(m.Answer, &dns.A{
+					Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET},
+					A:   net.IPv4(10, 0, 0, byte(i)),
+				})
+			}
+			return m
+		}
+
+		request := doQuestion("q-s0.web.default.cf.bosh.", dns.TypeA)
+		request.IsEdns0().SetUDPSize(256)
+
+		handler.ServeDNS(fakeWriter, request)
+
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Truncated).To(BeTrue())
+		Expect(response.Answer).To(BeEmpty())
+	})
+
+	It("passes zone transfers on untouched", func() {
+		request := (&dns.Msg{}).SetAxfr("bosh.")
+		request.SetEdns0(1232, true)
+
+		handler.ServeDNS(fakeWriter, request)
+
+		Expect(nextCalls).To(Equal(1))
+	})
+})
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"net"
+
+	"bosh-dns/dns/config"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// ednsPadding is the option code of RFC 7830, which the vendored dns
+	// package does not know about.
+	ednsPadding = 12
+
+	// paddingBlockSize is the block length recommended for queries by
+	// RFC 8467.
+	paddingBlockSize = 128
+)
+
+// upstreamRequest returns the request to forward: request itself, or a copy
+// carrying the EDNS(0) options the handler is configured for.
+func (r ForwardHandler) upstreamRequest(responseWriter dns.ResponseWriter, request *dns.Msg, validating bool) *dns.Msg {
+	if !validating && r.edns.ClientSubnet.Mode == "" && !r.edns.Padding {
+		return request
+	}
+
+	upstreamRequest := request.Copy()
+	opt := upstreamRequest.IsEdns0()
+	if opt == nil {
+		upstreamRequest.SetEdns0(dnssecUDPSize, false)
+		opt = upstreamRequest.IsEdns0()
+	}
+
+	if validating {
+		// A validating recursor would hide bogus answers otherwise
+		opt.SetDo()
+		upstreamRequest.CheckingDisabled = true
+	}
+
+	switch r.edns.ClientSubnet.Mode {
+	case config.ClientSubnetStrip:
+		opt.Option = withoutOption(opt.Option, dns.EDNS0SUBNET)
+	case config.ClientSubnetAdd:
+		opt.Option = r.clientSubnet(responseWriter, opt.Option)
+	}
+
+	if r.edns.Padding {
+		pad(upstreamRequest)
+	}
+
+	return upstreamRequest
+}
+
+// clientSubnet truncates the subnet the client sent to the configured
+// prefix length, or derives it from the client address. Loopback and
+// link-local clients, such as the agent on 169.254.0.2, are not revealed.
+func (r ForwardHandler) clientSubnet(responseWriter dns.ResponseWriter, options []dns.EDNS0) []dns.EDNS0 {
+	ipv4PrefixLength, ipv6PrefixLength := r.edns.ClientSubnet.PrefixLengths()
+
+	var ip net.IP
+	prefixLength := -1
+	for _, option := range options {
+		if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+			ip = subnet.Address
+			prefixLength = int(subnet.SourceNetmask)
+		}
+	}
+
+	if ip == nil {
+		switch addr := responseWriter.RemoteAddr().(type) {
+		case *net.UDPAddr:
+			ip = addr.IP
+		case *net.TCPAddr:
+			ip = addr.IP
+		}
+
+		if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+			return options
+		}
+	}
+
+	subnet := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET}
+	if ip4 := ip.To4(); ip4 != nil {
+		subnet.Family = 1
+		subnet.Address = ip4
+		if prefixLength < 0 || prefixLength > ipv4PrefixLength {
+			prefixLength = ipv4PrefixLength
+		}
+	} else {
+		subnet.Family = 2
+		subnet.Address = ip.To16()
+		if prefixLength < 0 || prefixLength > ipv6PrefixLength {
+			prefixLength = ipv6PrefixLength
+		}
+	}
+	subnet.SourceNetmask = uint8(prefixLength)
+	subnet.Address = subnet.Address.Mask(net.CIDRMask(prefixLength, len(subnet.Address)*8))
+
+	return append(withoutOption(options, dns.EDNS0SUBNET), subnet)
+}
+
+// pad appends the padding option to the OPT record of m so that its length
+// is a multiple of the padding block size.
+func pad(m *dns.Msg) {
+	opt := m.IsEdns0()
+	opt.Option = withoutOption(opt.Option, ednsPadding)
+
+	packed, err := m.Pack()
+	if err != nil {
+		return
+	}
+
+	// The option code and length take four octets
+	length := len(packed) + 4
+	padding := (paddingBlockSize - length%paddingBlockSize) % paddingBlockSize
+
+	opt.Option = append(opt.Option, &dns.EDNS0_LOCAL{Code: ednsPadding, Data: make([]byte, padding)})
+}
+
+// restoreEDNS removes the EDNS(0) options from response which the client
+// did not ask for.
+func restoreEDNS(request, response *dns.Msg) {
+	opt := response.IsEdns0()
+	if opt == nil {
+		return
+	}
+
+	requestOpt := request.IsEdns0()
+	if requestOpt == nil {
+		response.Extra = withoutOPT(response.Extra)
+		return
+	}
+
+	opt.Option = withoutOption(opt.Option, ednsPadding)
+	if !hasOption(requestOpt.Option, dns.EDNS0SUBNET) {
+		opt.Option = withoutOption(opt.Option, dns.EDNS0SUBNET)
+	}
+}
+
+func withoutOPT(rrs []dns.RR) []dns.RR {
+	kept := []dns.RR{}
+	for _, rr := range rrs {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			kept = append(kept, rr)
+		}
+	}
+
+	return kept
+}
+
+func withoutOption(options []dns.EDNS0, code uint16) []dns.EDNS0 {
+	kept := []dns.EDNS0{}
+	for _, option := range options {
+		if option.Option() != code {
+			kept = append(kept, option)
+		}
+	}
+
+	return kept
+}
+
+func hasOption(options []dns.EDNS0, code uint16) bool {
+	for _, option := range options {
+		if option.Option() == code {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"bosh-dns/dns/server/handlers/internal"
+	"bosh-dns/dns/server/records/dnsresolver"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+// EDNSHandler answers EDNS(0) queries as described by RFC 6891. It rejects
+// EDNS versions other than 0 with BADVERS, caps the payload size clients
+// advertise to maxUDPSize and advertises maxUDPSize in every answer. Answers
+// which still do not fit are truncated.
+type EDNSHandler struct {
+	next       dns.Handler
+	maxUDPSize uint16
+	logger     logger.Logger
+	logTag     string
+}
+
+func NewEDNSHandler(next dns.Handler, maxUDPSize uint16, logger logger.Logger) EDNSHandler {
+	return EDNSHandler{
+		next:       next,
+		maxUDPSize: maxUDPSize,
+		logger:     logger,
+		logTag:     "EDNSHandler",
+	}
+}
+
+func (h EDNSHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	opt := r.IsEdns0()
+	if opt == nil {
+		h.next.ServeDNS(internal.WrapWriterWithIntercept(w, func(m *dns.Msg) {
+			m.Extra = withoutOPT(m.Extra)
+			dnsresolver.TruncateIfNeeded(w, r, m)
+		}), r)
+		return
+	}
+
+	if opt.Version() != 0 {
+		m := &dns.Msg{}
+		m.SetReply(r)
+		m.SetEdns0(h.maxUDPSize, false)
+		// BADVERS does not fit the four bits of the header; its upper bits
+		// go into the OPT record
+		m.IsEdns0().Hdr.Ttl |= uint32(dns.RcodeBadVers>>4) << 24
+
+		if err := w.WriteMsg(m); err != nil {
+			h.logger.Error(h.logTag, err.Error())
+		}
+		return
+	}
+
+	request := r
+	if opt.UDPSize() > h.maxUDPSize {
+		request = r.Copy()
+		request.IsEdns0().SetUDPSize(h.maxUDPSize)
+	}
+
+	h.next.ServeDNS(internal.WrapWriterWithIntercept(w, func(m *dns.Msg) {
+		dnsresolver.TruncateIfNeeded(w, request, m)
+
+		responseOPT := m.IsEdns0()
+		responseOPT.SetVersion(0)
+		responseOPT.SetUDPSize(h.maxUDPSize)
+	}), request)
+}
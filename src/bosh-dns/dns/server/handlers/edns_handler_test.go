@@ -0,0 +1,141 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net"
+
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/internal/internalfakes"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EDNSHandler", func() {
+	var (
+		fakeWriter  *internalfakes.FakeResponseWriter
+		ednsHandler handlers.EDNSHandler
+		received    *dns.Msg
+		answers     int
+		request     *dns.Msg
+	)
+
+	BeforeEach(func() {
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5353})
+
+		received = nil
+		answers = 100
+
+		next := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			received = r
+
+			m := &dns.Msg{}
+			m.SetReply(r)
+			for i := 0; i < answers; i++ {
+				m.Answer = append(m.Answer, &dns.A{
+					Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET},
+					A:   net.ParseIP(fmt.Sprintf("10.0.%d.%d", i/256, i%256)),
+				})
+			}
+
+			Expect(w.WriteMsg(m)).To(Succeed())
+		})
+
+		ednsHandler = handlers.NewEDNSHandler(next, 1232, &loggerfakes.FakeLogger{})
+
+		request = &dns.Msg{}
+		request.SetQuestion("my-instance.my-group.my-network.my-deployment.bosh.", dns.TypeA)
+	})
+
+	Context("when the request does not use EDNS(0)", func() {
+		It("truncates UDP answers to 512 bytes", func() {
+			ednsHandler.ServeDNS(fakeWriter, request)
+
+			Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+			response := fakeWriter.WriteMsgArgsForCall(0)
+			Expect(response.IsEdns0()).To(BeNil())
+			Expect(response.Truncated).To(BeTrue())
+			Expect(response.Len()).To(BeNumerically("<=", 512))
+		})
+
+		It("does not truncate TCP answers", func() {
+			fakeWriter.RemoteAddrReturns(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5353})
+
+			ednsHandler.ServeDNS(fakeWriter, request)
+
+			response := fakeWriter.WriteMsgArgsForCall(0)
+			Expect(response.Truncated).To(BeFalse())
+			Expect(response.Answer).To(HaveLen(100))
+		})
+	})
+
+	Context("when the request uses EDNS(0)", func() {
+		It("echoes an OPT record advertising the server maximum", func() {
+			answers = 1
+			request.SetEdns0(4096, false)
+
+			ednsHandler.ServeDNS(fakeWriter, request)
+
+			response := fakeWriter.WriteMsgArgsForCall(0)
+			Expect(response.IsEdns0()).NotTo(BeNil())
+			Expect(response.IsEdns0().UDPSize()).To(Equal(uint16(1232)))
+			Expect(response.IsEdns0().Version()).To(Equal(uint8(0)))
+			Expect(response.Answer).To(HaveLen(1))
+		})
+
+		It("honors payload sizes below the server maximum", func() {
+			request.SetEdns0(1000, false)
+
+			ednsHandler.ServeDNS(fakeWriter, request)
+
+			Expect(received).To(BeIdenticalTo(request))
+
+			response := fakeWriter.WriteMsgArgsForCall(0)
+			Expect(response.Truncated).To(BeTrue())
+			Expect(response.Len()).To(BeNumerically("<=", 1000))
+			Expect(response.Len()).To(BeNumerically(">", 512))
+		})
+
+		It("caps payload sizes above the server maximum", func() {
+			request.SetEdns0(4096, false)
+
+			ednsHandler.ServeDNS(fakeWriter, request)
+
+			By("passing the capped size to the next handler")
+			Expect(received.IsEdns0().UDPSize()).To(Equal(uint16(1232)))
+			Expect(request.IsEdns0().UDPSize()).To(Equal(uint16(4096)))
+
+			response := fakeWriter.WriteMsgArgsForCall(0)
+			Expect(response.Truncated).To(BeTrue())
+			Expect(response.Len()).To(BeNumerically("<=", 1232))
+			Expect(response.Len()).To(BeNumerically(">", 1000))
+		})
+
+		It("answers unsupported versions with BADVERS", func() {
+			request.SetEdns0(4096, false)
+			request.IsEdns0().SetVersion(1)
+
+			ednsHandler.ServeDNS(fakeWriter, request)
+
+			Expect(received).To(BeNil())
+
+			response := fakeWriter.WriteMsgArgsForCall(0)
+			Expect(response.Id).To(Equal(request.Id))
+			Expect(response.Answer).To(BeEmpty())
+			Expect(response.IsEdns0()).NotTo(BeNil())
+			Expect(response.IsEdns0().Version()).To(Equal(uint8(0)))
+			Expect(response.IsEdns0().UDPSize()).To(Equal(uint16(1232)))
+
+			packed, err := response.Pack()
+			Expect(err).NotTo(HaveOccurred())
+			unpacked := &dns.Msg{}
+			Expect(unpacked.Unpack(packed)).To(Succeed())
+			Expect(unpacked.Rcode).To(Equal(dns.RcodeSuccess))
+			Expect(unpacked.IsEdns0().ExtendedRcode()).To(Equal(dns.RcodeBadVers))
+		})
+	})
+})
@@ -0,0 +1,235 @@
+package handlers_test
+
+import (
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/internal/internalfakes"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type receivedQuery struct {
+	msg    *dns.Msg
+	length int
+}
+
+var _ = Describe("ForwardHandler EDNS options", func() {
+	var (
+		conn       net.PacketConn
+		received   chan receivedQuery
+		reply      func(*dns.Msg) *dns.Msg
+		fakeWriter *internalfakes.FakeResponseWriter
+		edns       config.EDNS
+		request    *dns.Msg
+	)
+
+	option := func(m *dns.Msg, code uint16) dns.EDNS0 {
+		opt := m.IsEdns0()
+		if opt == nil {
+			return nil
+		}
+
+		for _, o := range opt.Option {
+			if o.Option() == code {
+				return o
+			}
+		}
+
+		return nil
+	}
+
+	BeforeEach(func() {
+		var err error
+		conn, err = net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		received = make(chan receivedQuery, 1)
+		reply = func(query *dns.Msg) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetReply(query)
+			m.Answer = []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET}, A: net.ParseIP("10.0.0.1")}}
+			if opt := query.IsEdns0(); opt != nil {
+				m.SetEdns0(4096, false)
+				m.IsEdns0().Option = append(opt.Option, &dns.EDNS0_LOCAL{Code: 12, Data: make([]byte, 8)})
+			}
+			return m
+		}
+
+		go func() {
+			defer GinkgoRecover()
+
+			buf := make([]byte, 65535)
+			for {
+				n, addr, err := conn.ReadFrom(buf)
+				if err != nil {
+					return
+				}
+
+				query := &dns.Msg{}
+				Expect(query.Unpack(buf[:n])).To(Succeed())
+				received <- receivedQuery{msg: query, length: n}
+
+				packed, err := reply(query).Pack()
+				Expect(err).NotTo(HaveOccurred())
+				conn.WriteTo(packed, addr)
+			}
+		}()
+
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5353})
+
+		edns = config.EDNS{}
+
+		request = &dns.Msg{}
+		request.SetQuestion("example.com.", dns.TypeA)
+	})
+
+	AfterEach(func() {
+		conn.Close()
+	})
+
+	serve := func() (receivedQuery, *dns.Msg) {
+		logger := &loggerfakes.FakeLogger{}
+		pool := handlers.NewFailoverRecursorPool([]string{conn.LocalAddr().String()}, logger)
+		handler := handlers.NewEDNSForwardHandler(pool, handlers.NewExchangerFactory(time.Second), edns, fakeclock.NewFakeClock(time.Now()), logger)
+
+		handler.ServeDNS(fakeWriter, request)
+
+		var query receivedQuery
+		Eventually(received).Should(Receive(&query))
+		Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+
+		return query, fakeWriter.WriteMsgArgsForCall(0)
+	}
+
+	It("forwards queries untouched by default", func() {
+		request.SetEdns0(1232, false)
+		request.IsEdns0().Option = []dns.EDNS0{&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 32, Address: net.ParseIP("192.0.2.1").To4()}}
+
+		received, _ := serve()
+		query := received.msg
+
+		subnet := option(query, dns.EDNS0SUBNET).(*dns.EDNS0_SUBNET)
+		Expect(subnet.SourceNetmask).To(Equal(uint8(32)))
+		Expect(option(query, 12)).To(BeNil())
+	})
+
+	Context("when stripping the client subnet", func() {
+		BeforeEach(func() {
+			edns.ClientSubnet.Mode = "strip"
+		})
+
+		It("removes the option from forwarded queries", func() {
+			request.SetEdns0(1232, false)
+			request.IsEdns0().Option = []dns.EDNS0{&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()}}
+
+			received, response := serve()
+			query := received.msg
+
+			Expect(option(query, dns.EDNS0SUBNET)).To(BeNil())
+			Expect(query.IsEdns0().UDPSize()).To(Equal(uint16(1232)))
+			Expect(response.Answer).To(HaveLen(1))
+		})
+	})
+
+	Context("when adding the client subnet", func() {
+		BeforeEach(func() {
+			edns.ClientSubnet.Mode = "add"
+		})
+
+		It("sends the truncated subnet of the client", func() {
+			received, response := serve()
+			query := received.msg
+
+			subnet := option(query, dns.EDNS0SUBNET).(*dns.EDNS0_SUBNET)
+			Expect(subnet.Family).To(Equal(uint16(1)))
+			Expect(subnet.SourceNetmask).To(Equal(uint8(24)))
+			Expect(subnet.Address.String()).To(Equal("10.1.2.0"))
+
+			By("answering without EDNS(0), which the client did not use")
+			Expect(response.IsEdns0()).To(BeNil())
+			Expect(response.Answer).To(HaveLen(1))
+		})
+
+		It("truncates the subnet sent by the client", func() {
+			edns.ClientSubnet.IPv4PrefixLength = 16
+			request.SetEdns0(1232, false)
+			request.IsEdns0().Option = []dns.EDNS0{&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 32, Address: net.ParseIP("192.0.2.1").To4()}}
+
+			received, response := serve()
+			query := received.msg
+
+			subnet := option(query, dns.EDNS0SUBNET).(*dns.EDNS0_SUBNET)
+			Expect(subnet.SourceNetmask).To(Equal(uint8(16)))
+			Expect(subnet.Address.String()).To(Equal("192.0.0.0"))
+
+			By("returning the option the client sent")
+			Expect(option(response, dns.EDNS0SUBNET)).NotTo(BeNil())
+			Expect(option(response, 12)).To(BeNil())
+		})
+
+		It("sends IPv6 subnets", func() {
+			fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("2001:db8:1:2:3::1"), Port: 5353})
+
+			received, _ := serve()
+			query := received.msg
+
+			subnet := option(query, dns.EDNS0SUBNET).(*dns.EDNS0_SUBNET)
+			Expect(subnet.Family).To(Equal(uint16(2)))
+			Expect(subnet.SourceNetmask).To(Equal(uint8(56)))
+			Expect(subnet.Address.String()).To(Equal("2001:db8:1::"))
+		})
+
+		It("does not reveal loopback clients", func() {
+			fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5353})
+
+			received, _ := serve()
+			query := received.msg
+
+			Expect(option(query, dns.EDNS0SUBNET)).To(BeNil())
+		})
+
+		It("does not reveal link-local clients", func() {
+			for _, ip := range []string{"169.254.0.2", "fe80::1"} {
+				fakeWriter = &internalfakes.FakeResponseWriter{}
+				fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP(ip), Port: 5353})
+
+				received, _ := serve()
+				query := received.msg
+
+				Expect(option(query, dns.EDNS0SUBNET)).To(BeNil())
+			}
+		})
+	})
+
+	Context("when padding", func() {
+		BeforeEach(func() {
+			edns.Padding = true
+		})
+
+		It("pads queries to a multiple of the block size", func() {
+			for _, name := range []string{"example.com.", "a-much-longer-name.example.com."} {
+				request.SetQuestion(name, dns.TypeA)
+				fakeWriter = &internalfakes.FakeResponseWriter{}
+				fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5353})
+
+				query, response := serve()
+
+				Expect(query.length % 128).To(Equal(0))
+				Expect(option(query.msg, 12)).NotTo(BeNil())
+
+				By("removing the padding of the answer")
+				Expect(response.IsEdns0()).To(BeNil())
+			}
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"crypto/tls"
 	"time"
 
 	"github.com/miekg/dns"
@@ -8,8 +9,20 @@ import (
 
 type ExchangerFactory func(string) Exchanger
 
+type TLSExchangerFactory func(serverName string) ExchangerFactory
+
 func NewExchangerFactory(timeout time.Duration) ExchangerFactory {
 	return func(net string) Exchanger {
 		return &dns.Client{Net: net, Timeout: timeout, UDPSize: 65535}
 	}
 }
+
+// NewTLSExchangerFactory returns factories of DNS over TLS clients, whatever
+// the network the request came in over.
+func NewTLSExchangerFactory(timeout time.Duration) TLSExchangerFactory {
+	return func(serverName string) ExchangerFactory {
+		return func(string) Exchanger {
+			return &dns.Client{Net: "tcp-tls", Timeout: timeout, TLSConfig: &tls.Config{ServerName: serverName}}
+		}
+	}
+}
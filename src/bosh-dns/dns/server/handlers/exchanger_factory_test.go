@@ -27,4 +27,15 @@ var _ = Describe("ExchangerFactory", func() {
 		Expect(client.Net).To(Equal(net))
 		Expect(client.Timeout).To(Equal(timeout))
 	})
+
+	It("returns DNS over TLS exchangers", func() {
+		timeout := time.Duration(rand.Int())
+
+		exchangerFactory := handlers.NewTLSExchangerFactory(timeout)("dns.example.com")
+		client := exchangerFactory("udp").(*dns.Client)
+
+		Expect(client.Net).To(Equal("tcp-tls"))
+		Expect(client.Timeout).To(Equal(timeout))
+		Expect(client.TLSConfig.ServerName).To(Equal("dns.example.com"))
+	})
 })
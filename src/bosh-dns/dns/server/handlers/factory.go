@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"net"
+
+	"bosh-dns/dns/config"
 	"bosh-dns/dns/shuffle"
 
 	"code.cloudfoundry.org/clock"
@@ -9,18 +12,20 @@ import (
 )
 
 type Factory struct {
-	exchangerFactory ExchangerFactory
-	clock            clock.Clock
-	shuffler         shuffle.StringShuffle
-	logger           boshlog.Logger
+	exchangerFactory    ExchangerFactory
+	tlsExchangerFactory TLSExchangerFactory
+	clock               clock.Clock
+	shuffler            shuffle.StringShuffle
+	logger              boshlog.Logger
 }
 
-func NewFactory(exchangerFactory ExchangerFactory, clock clock.Clock, shuffler shuffle.StringShuffle, logger boshlog.Logger) *Factory {
+func NewFactory(exchangerFactory ExchangerFactory, tlsExchangerFactory TLSExchangerFactory, clock clock.Clock, shuffler shuffle.StringShuffle, logger boshlog.Logger) *Factory {
 	return &Factory{
-		exchangerFactory: exchangerFactory,
-		clock:            clock,
-		shuffler:         shuffler,
-		logger:           logger,
+		exchangerFactory:    exchangerFactory,
+		tlsExchangerFactory: tlsExchangerFactory,
+		clock:               clock,
+		shuffler:            shuffler,
+		logger:              logger,
 	}
 }
 
@@ -34,13 +39,22 @@ func (f *Factory) CreateHTTPJSONHandler(url string, cache bool) dns.Handler {
 	return handler
 }
 
-func (f *Factory) CreateForwardHandler(recursors []string, cache bool) dns.Handler {
+func (f *Factory) CreateForwardHandler(recursors []string, tlsServerName string, edns config.EDNS, cache bool) dns.Handler {
 	var handler dns.Handler
 	pool := NewFailoverRecursorPool(f.shuffler.Shuffle(recursors), f.logger)
-	handler = NewForwardHandler(pool, f.exchangerFactory, f.clock, f.logger)
+
+	exchangerFactory := f.exchangerFactory
+	if tlsServerName != "" {
+		exchangerFactory = f.tlsExchangerFactory(tlsServerName)
+	}
+	handler = NewEDNSForwardHandler(pool, exchangerFactory, edns, f.clock, f.logger)
 
 	if cache {
 		handler = NewCachingDNSHandler(handler)
 	}
 	return handler
 }
+
+func (f *Factory) CreateACLHandler(handler dns.Handler, allow, deny []*net.IPNet) dns.Handler {
+	return NewACLHandler(handler, NewRefusedHandler(f.logger), allow, deny)
+}
@@ -8,6 +8,9 @@ import (
 
 	"code.cloudfoundry.org/clock"
 
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/dnssec"
+
 	"github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/miekg/dns"
 )
@@ -16,6 +19,8 @@ type ForwardHandler struct {
 	clock            clock.Clock
 	recursors        RecursorPool
 	exchangerFactory ExchangerFactory
+	validator        Validator
+	edns             config.EDNS
 	logger           logger.Logger
 	logTag           string
 }
@@ -26,6 +31,15 @@ type Exchanger interface {
 	Exchange(*dns.Msg, string) (*dns.Msg, time.Duration, error)
 }
 
+//go:generate counterfeiter . Validator
+
+// Validator checks the DNSSEC signatures of an answer, resolving the DS and
+// DNSKEY records it needs with the querier. It reports whether the answer is
+// secure, and returns an error when it is bogus.
+type Validator interface {
+	Validate(dnssec.Querier, *dns.Msg) (bool, error)
+}
+
 type Cache interface {
 	Get(req *dns.Msg) *dns.Msg
 	Write(req, answer *dns.Msg)
@@ -42,6 +56,25 @@ func NewForwardHandler(recursors RecursorPool, exchangerFactory ExchangerFactory
 	}
 }
 
+// NewValidatingForwardHandler returns a ForwardHandler which validates the
+// answers of the recursors unless the request sets the CD bit. Bogus answers
+// are replaced by SERVFAIL and secure answers have the AD bit set.
+func NewValidatingForwardHandler(recursors RecursorPool, exchangerFactory ExchangerFactory, validator Validator, clock clock.Clock, logger logger.Logger) ForwardHandler {
+	handler := NewForwardHandler(recursors, exchangerFactory, clock, logger)
+	handler.validator = validator
+
+	return handler
+}
+
+// NewEDNSForwardHandler returns a ForwardHandler which rewrites the EDNS
+// Client Subnet and padding options of the queries it forwards.
+func NewEDNSForwardHandler(recursors RecursorPool, exchangerFactory ExchangerFactory, edns config.EDNS, clock clock.Clock, logger logger.Logger) ForwardHandler {
+	handler := NewForwardHandler(recursors, exchangerFactory, clock, logger)
+	handler.edns = edns
+
+	return handler
+}
+
 func (r ForwardHandler) ServeDNS(responseWriter dns.ResponseWriter, request *dns.Msg) {
 	before := r.clock.Now()
 
@@ -54,9 +87,25 @@ func (r ForwardHandler) ServeDNS(responseWriter dns.ResponseWriter, request *dns
 
 	client := r.exchangerFactory(network)
 
+	validating := r.validator != nil && !request.CheckingDisabled
+	upstreamRequest := r.upstreamRequest(responseWriter, request, validating)
+
 	err := r.recursors.PerformStrategically(func(recursor string) error {
-		exchangeAnswer, _, err := client.Exchange(request, recursor)
+		exchangeAnswer, _, err := client.Exchange(upstreamRequest, recursor)
 		if err == nil || err == dns.ErrTruncated {
+			if validating && !exchangeAnswer.Truncated {
+				if validationErr := r.validate(client, recursor, request, exchangeAnswer); validationErr != nil {
+					r.logger.Info(r.logTag, "bogus answer from %q: %s", recursor, validationErr.Error())
+					r.writeNoResponseMessage(responseWriter, request)
+					r.logRecursor(before, request, dns.RcodeServerFailure, "recursor="+recursor)
+					return nil
+				}
+			}
+
+			if upstreamRequest != request {
+				restoreEDNS(request, exchangeAnswer)
+			}
+
 			response := r.compressIfNeeded(responseWriter, request, exchangeAnswer)
 
 			if writeErr := responseWriter.WriteMsg(response); writeErr != nil {
@@ -78,6 +127,72 @@ func (r ForwardHandler) ServeDNS(responseWriter dns.ResponseWriter, request *dns
 	}
 }
 
+// validate sets the AD bit on secure answers for clients which understand
+// it, and strips the DNSSEC records requested on behalf of other clients.
+func (r ForwardHandler) validate(client Exchanger, recursor string, request, response *dns.Msg) error {
+	secure, err := r.validator.Validate(r.querier(client, recursor), response)
+	if err != nil {
+		return err
+	}
+
+	dnssecOK := request.IsEdns0() != nil && request.IsEdns0().Do()
+	response.AuthenticatedData = secure && (dnssecOK || request.AuthenticatedData)
+	response.CheckingDisabled = false
+
+	if !dnssecOK {
+		stripDNSSEC(request, response)
+	}
+
+	return nil
+}
+
+// querier resolves the records needed for validation through the recursor
+// which gave the answer, retrying over TCP when the answer is truncated.
+func (r ForwardHandler) querier(client Exchanger, recursor string) dnssec.Querier {
+	return func(name string, qtype uint16) (*dns.Msg, error) {
+		query := &dns.Msg{}
+		query.SetQuestion(name, qtype)
+		query.SetEdns0(dnssecUDPSize, true)
+		query.CheckingDisabled = true
+		if r.edns.Padding {
+			pad(query)
+		}
+
+		response, _, err := client.Exchange(query, recursor)
+		if err == dns.ErrTruncated || (err == nil && response.Truncated) {
+			response, _, err = r.exchangerFactory("tcp").Exchange(query, recursor)
+		}
+
+		return response, err
+	}
+}
+
+func stripDNSSEC(request, response *dns.Msg) {
+	qtype := request.Question[0].Qtype
+	strip := func(rrs []dns.RR) []dns.RR {
+		kept := []dns.RR{}
+		for _, rr := range rrs {
+			switch rr.Header().Rrtype {
+			case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+				if rr.Header().Rrtype != qtype {
+					continue
+				}
+			case dns.TypeOPT:
+				if request.IsEdns0() == nil {
+					continue
+				}
+				rr.(*dns.OPT).SetDo(false)
+			}
+			kept = append(kept, rr)
+		}
+		return kept
+	}
+
+	response.Answer = strip(response.Answer)
+	response.Ns = strip(response.Ns)
+	response.Extra = strip(response.Extra)
+}
+
 func (r ForwardHandler) logRecursor(before time.Time, request *dns.Msg, code int, recursor string) {
 	duration := r.clock.Now().Sub(before).Nanoseconds()
 	types := make([]string, len(request.Question))
@@ -386,5 +386,156 @@ var _ = Describe("ForwardHandler", func() {
 				})
 			})
 		})
+
+		Context("when validating DNSSEC", func() {
+			var (
+				fakeValidator  *handlersfakes.FakeValidator
+				recursorAnswer *dns.Msg
+				request        *dns.Msg
+			)
+
+			BeforeEach(func() {
+				fakeValidator = &handlersfakes.FakeValidator{}
+				fakeValidator.ValidateReturns(true, nil)
+				recursionHandler = handlers.NewValidatingForwardHandler(fakeRecursorPool, fakeExchangerFactory, fakeValidator, fakeClock, fakeLogger)
+
+				request = &dns.Msg{}
+				request.SetQuestion("example.com.", dns.TypeA)
+
+				recursorAnswer = &dns.Msg{}
+				recursorAnswer.SetReply(request)
+				recursorAnswer.Answer = []dns.RR{
+					&dns.A{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET}, A: net.ParseIP("10.0.0.1")},
+					&dns.RRSIG{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeRRSIG, Class: dns.ClassINET}, TypeCovered: dns.TypeA, SignerName: "com."},
+				}
+				recursorAnswer.SetEdns0(4096, true)
+				fakeExchanger.ExchangeReturns(recursorAnswer, 0, nil)
+			})
+
+			It("asks the recursor for DNSSEC records without changing the request", func() {
+				recursionHandler.ServeDNS(fakeWriter, request)
+
+				upstreamRequest, _ := fakeExchanger.ExchangeArgsForCall(0)
+				Expect(upstreamRequest.IsEdns0().Do()).To(BeTrue())
+				Expect(upstreamRequest.CheckingDisabled).To(BeTrue())
+				Expect(upstreamRequest.Id).To(Equal(request.Id))
+
+				Expect(request.IsEdns0()).To(BeNil())
+				Expect(request.CheckingDisabled).To(BeFalse())
+			})
+
+			It("sets the AD bit on secure answers for clients asking for DNSSEC records", func() {
+				request.SetEdns0(4096, true)
+
+				recursionHandler.ServeDNS(fakeWriter, request)
+
+				Expect(fakeValidator.ValidateCallCount()).To(Equal(1))
+				_, validated := fakeValidator.ValidateArgsForCall(0)
+				Expect(validated).To(Equal(recursorAnswer))
+
+				response := fakeWriter.WriteMsgArgsForCall(0)
+				Expect(response.AuthenticatedData).To(BeTrue())
+				Expect(response.CheckingDisabled).To(BeFalse())
+				Expect(response.Answer).To(HaveLen(2))
+			})
+
+			It("strips DNSSEC records for other clients", func() {
+				recursionHandler.ServeDNS(fakeWriter, request)
+
+				response := fakeWriter.WriteMsgArgsForCall(0)
+				Expect(response.AuthenticatedData).To(BeFalse())
+				Expect(response.Answer).To(HaveLen(1))
+				Expect(response.Answer[0].Header().Rrtype).To(Equal(dns.TypeA))
+				Expect(response.IsEdns0()).To(BeNil())
+			})
+
+			It("sets the AD bit for clients setting it on the request", func() {
+				request.AuthenticatedData = true
+
+				recursionHandler.ServeDNS(fakeWriter, request)
+
+				response := fakeWriter.WriteMsgArgsForCall(0)
+				Expect(response.AuthenticatedData).To(BeTrue())
+			})
+
+			It("does not set the AD bit on insecure answers", func() {
+				fakeValidator.ValidateReturns(false, nil)
+				request.SetEdns0(4096, true)
+
+				recursionHandler.ServeDNS(fakeWriter, request)
+
+				response := fakeWriter.WriteMsgArgsForCall(0)
+				Expect(response.AuthenticatedData).To(BeFalse())
+				Expect(response.Rcode).To(Equal(dns.RcodeSuccess))
+			})
+
+			It("fails bogus answers", func() {
+				fakeValidator.ValidateReturns(false, errors.New("no signature verifies"))
+
+				recursionHandler.ServeDNS(fakeWriter, request)
+
+				Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+				response := fakeWriter.WriteMsgArgsForCall(0)
+				Expect(response.Rcode).To(Equal(dns.RcodeServerFailure))
+				Expect(response.Answer).To(BeEmpty())
+
+				tag, msg, args := fakeLogger.InfoArgsForCall(0)
+				Expect(tag).To(Equal("ForwardHandler"))
+				Expect(fmt.Sprintf(msg, args...)).To(Equal(`bogus answer from "127.0.0.1": no signature verifies`))
+			})
+
+			It("leaves validation to clients setting the CD bit", func() {
+				request.CheckingDisabled = true
+
+				recursionHandler.ServeDNS(fakeWriter, request)
+
+				Expect(fakeValidator.ValidateCallCount()).To(Equal(0))
+				upstreamRequest, _ := fakeExchanger.ExchangeArgsForCall(0)
+				Expect(upstreamRequest).To(Equal(request))
+			})
+
+			It("resolves the chain of trust through the same recursor", func() {
+				recursionHandler.ServeDNS(fakeWriter, request)
+
+				query, _ := fakeValidator.ValidateArgsForCall(0)
+
+				keys := &dns.Msg{}
+				fakeExchanger.ExchangeReturns(keys, 0, nil)
+
+				response, err := query("com.", dns.TypeDNSKEY)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response).To(Equal(keys))
+
+				keysRequest, recursor := fakeExchanger.ExchangeArgsForCall(1)
+				Expect(recursor).To(Equal("127.0.0.1"))
+				Expect(keysRequest.Question).To(Equal([]dns.Question{{Name: "com.", Qtype: dns.TypeDNSKEY, Qclass: dns.ClassINET}}))
+				Expect(keysRequest.IsEdns0().Do()).To(BeTrue())
+				Expect(keysRequest.CheckingDisabled).To(BeTrue())
+			})
+
+			It("retries truncated lookups over TCP", func() {
+				var networks []string
+				tcpExchanger := &handlersfakes.FakeExchanger{}
+				recursionHandler = handlers.NewValidatingForwardHandler(fakeRecursorPool, func(network string) handlers.Exchanger {
+					networks = append(networks, network)
+					if network == "tcp" {
+						return tcpExchanger
+					}
+					return fakeExchanger
+				}, fakeValidator, fakeClock, fakeLogger)
+
+				recursionHandler.ServeDNS(fakeWriter, request)
+				query, _ := fakeValidator.ValidateArgsForCall(0)
+
+				fakeExchanger.ExchangeReturns(&dns.Msg{MsgHdr: dns.MsgHdr{Truncated: true}}, 0, nil)
+				keys := &dns.Msg{}
+				tcpExchanger.ExchangeReturns(keys, 0, nil)
+
+				response, err := query("com.", dns.TypeDNSKEY)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response).To(Equal(keys))
+				Expect(networks).To(Equal([]string{"udp", "tcp"}))
+			})
+		})
 	})
 })
@@ -23,6 +23,18 @@ type DomainProvider interface {
 	Domains() []string
 }
 
+// DomainProviders registers the domains of every provider.
+type DomainProviders []DomainProvider
+
+func (p DomainProviders) Domains() []string {
+	domains := []string{}
+	for _, provider := range p {
+		domains = append(domains, provider.Domains()...)
+	}
+
+	return domains
+}
+
 type HandlerRegistrar struct {
 	logger         logger.Logger
 	clock          clock.Clock
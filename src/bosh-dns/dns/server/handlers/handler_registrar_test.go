@@ -110,4 +110,17 @@ var _ = Describe("HandlerRegistrar", func() {
 			})
 		})
 	})
+
+	Describe("DomainProviders", func() {
+		It("returns the domains of every provider", func() {
+			first := &handlersfakes.FakeDomainProvider{}
+			first.DomainsReturns([]string{"bosh."})
+			second := &handlersfakes.FakeDomainProvider{}
+			second.DomainsReturns([]string{"dyn.internal.", "other.internal."})
+
+			providers := handlers.DomainProviders{first, second}
+
+			Expect(providers.Domains()).To(Equal([]string{"bosh.", "dyn.internal.", "other.internal."}))
+		})
+	})
 })
@@ -50,7 +50,7 @@ func NewHTTPJSONHandler(address string, logger logger.Logger) HTTPJSONHandler {
 func (h HTTPJSONHandler) ServeDNS(responseWriter dns.ResponseWriter, request *dns.Msg) {
 	responseMsg := h.buildResponse(request)
 
-	dnsresolver.TruncateIfNeeded(responseWriter, responseMsg)
+	dnsresolver.TruncateIfNeeded(responseWriter, request, responseMsg)
 
 	if err := responseWriter.WriteMsg(responseMsg); err != nil {
 		h.logger.Error(h.logTag, err.Error())
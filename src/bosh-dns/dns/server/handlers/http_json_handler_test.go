@@ -387,7 +387,7 @@ var _ = Describe("HttpJsonHandler", func() {
 				}`))
 		})
 
-		It("truncates the answers to fit", func() {
+		It("compresses the answers to fit", func() {
 			req := &dns.Msg{}
 			req.SetQuestion("app-id.internal-domain.", dns.TypeA)
 			handler.ServeDNS(fakeWriter, req)
@@ -396,9 +396,22 @@ var _ = Describe("HttpJsonHandler", func() {
 			resp := fakeWriter.WriteMsgArgsForCall(0)
 			Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
 			Expect(resp.RecursionAvailable).To(BeTrue())
-			Expect(resp.Truncated).To(BeTrue())
+			Expect(resp.Compress).To(BeTrue())
+			Expect(resp.Truncated).To(BeFalse())
 			Expect(resp.Question).To(Equal(req.Question))
-			Expect(resp.Answer).To(HaveLen(12))
+			Expect(resp.Answer).To(HaveLen(13))
+			Expect(resp.Len()).To(BeNumerically("<=", 512))
+		})
+
+		It("echoes the OPT record of EDNS(0) requests", func() {
+			req := &dns.Msg{}
+			req.SetQuestion("app-id.internal-domain.", dns.TypeA)
+			req.SetEdns0(1232, false)
+			handler.ServeDNS(fakeWriter, req)
+
+			resp := fakeWriter.WriteMsgArgsForCall(0)
+			Expect(resp.IsEdns0()).NotTo(BeNil())
+			Expect(resp.Answer).To(HaveLen(13))
 		})
 	})
 })
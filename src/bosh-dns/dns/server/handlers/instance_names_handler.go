@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"bosh-dns/dns/server/records"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+const (
+	selfLabel  = "_self"
+	groupLabel = "_group"
+	peersLabel = "_peers"
+)
+
+var healthStrategyRegex = regexp.MustCompile("s([0-9]+)")
+
+//go:generate counterfeiter . InstanceRecords
+
+type InstanceRecords interface {
+	RecordsWithIP(ip string) []records.Record
+	IsHealthy(ip string) bool
+}
+
+// InstanceNamesHandler resolves names relative to the querying instance:
+// _self.<domain> to the instance itself, _group.<domain> to every instance of
+// its instance group and _peers.<domain> to the others. Like group names they
+// accept a leading q- query, e.g. q-s4._peers.bosh. for all peers regardless
+// of health. The health strategy of _peers is applied after removing the
+// querying instance, so that the smart strategy does not settle on it alone.
+// Queries from this VM itself are answered for the instance this VM runs.
+type InstanceNamesHandler struct {
+	next     dns.Handler
+	records  InstanceRecords
+	localIPs []string
+	logger   logger.Logger
+	logTag   string
+}
+
+func NewInstanceNamesHandler(next dns.Handler, records InstanceRecords, localIPs []string, logger logger.Logger) InstanceNamesHandler {
+	return InstanceNamesHandler{
+		next:     next,
+		records:  records,
+		localIPs: localIPs,
+		logger:   logger,
+		logTag:   "InstanceNamesHandler",
+	}
+}
+
+func (h InstanceNamesHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	if r.Opcode != dns.OpcodeQuery || len(r.Question) != 1 {
+		h.next.ServeDNS(w, r)
+		return
+	}
+
+	name := r.Question[0].Name
+	query, kind, domain, ok := parseInstanceName(name)
+	if !ok {
+		h.next.ServeDNS(w, r)
+		return
+	}
+
+	record, found := h.recordFor(w.RemoteAddr(), domain)
+	if !found {
+		h.logger.Debug(h.logTag, "no instance with address %s in %s", w.RemoteAddr(), domain)
+
+		m := &dns.Msg{}
+		m.SetRcode(r, dns.RcodeNameError)
+		m.Authoritative = true
+		m.RecursionAvailable = true
+		h.write(w, m)
+		return
+	}
+
+	if query == "" {
+		query = "q-s0"
+		if kind == selfLabel {
+			query = record.ID
+		}
+	}
+
+	healthStrategy := ""
+	if kind == peersLabel {
+		query, healthStrategy = withAllInstances(query)
+	}
+
+	req := r.Copy()
+	req.Question[0].Name = fmt.Sprintf("%s.%s.%s.%s.%s", query, record.Group, record.Network, record.Deployment, record.Domain)
+
+	captured := &capturingWriter{ResponseWriter: w}
+	h.next.ServeDNS(captured, req)
+	if captured.msg == nil {
+		return
+	}
+
+	m := captured.msg
+	m.Question = r.Question
+
+	recordIP := net.ParseIP(record.IP)
+	answers := []dns.RR{}
+	for _, rr := range m.Answer {
+		self := recordIP.Equal(answerIP(rr))
+		if (kind == selfLabel && !self) || (kind == peersLabel && self) {
+			continue
+		}
+
+		rr.Header().Name = name
+		answers = append(answers, rr)
+	}
+	if kind == peersLabel {
+		answers = h.filterByHealthStrategy(answers, healthStrategy)
+	}
+	m.Answer = answers
+
+	h.write(w, m)
+}
+
+// filterByHealthStrategy keeps the answers whose addresses healthStrategy
+// picks among the health of all of them.
+func (h InstanceNamesHandler) filterByHealthStrategy(answers []dns.RR, healthStrategy string) []dns.RR {
+	var healthyIPs, unhealthyIPs []string
+	for _, rr := range answers {
+		ip := answerIP(rr)
+		if ip == nil {
+			continue
+		}
+
+		if h.records.IsHealthy(ip.String()) {
+			healthyIPs = append(healthyIPs, ip.String())
+		} else {
+			unhealthyIPs = append(unhealthyIPs, ip.String())
+		}
+	}
+
+	picked := map[string]struct{}{}
+	for _, ip := range records.FilterByHealthStrategy(healthyIPs, unhealthyIPs, healthStrategy) {
+		picked[ip] = struct{}{}
+	}
+
+	filtered := []dns.RR{}
+	for _, rr := range answers {
+		if ip := answerIP(rr); ip != nil {
+			if _, ok := picked[ip.String()]; !ok {
+				continue
+			}
+		}
+
+		filtered = append(filtered, rr)
+	}
+
+	return filtered
+}
+
+// recordFor returns the record in domain of the instance at addr.
+func (h InstanceNamesHandler) recordFor(addr net.Addr, domain string) (records.Record, bool) {
+	ips := h.localIPs
+	if ip := addrIP(addr); ip != nil && !ip.IsLoopback() && !h.isLocal(ip) {
+		ips = []string{ip.String()}
+	}
+
+	for _, ip := range ips {
+		for _, record := range h.records.RecordsWithIP(ip) {
+			if strings.EqualFold(record.Domain, domain) {
+				return record, true
+			}
+		}
+	}
+
+	return records.Record{}, false
+}
+
+func (h InstanceNamesHandler) isLocal(ip net.IP) bool {
+	for _, localIP := range h.localIPs {
+		if ip.Equal(net.ParseIP(localIP)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h InstanceNamesHandler) write(w dns.ResponseWriter, m *dns.Msg) {
+	if err := w.WriteMsg(m); err != nil {
+		h.logger.Error(h.logTag, err.Error())
+	}
+}
+
+// parseInstanceName splits [<q-query>.]<_self|_group|_peers>.<domain>.
+func parseInstanceName(name string) (string, string, string, bool) {
+	labels := dns.SplitDomainName(strings.ToLower(name))
+
+	query := ""
+	if len(labels) > 0 && strings.HasPrefix(labels[0], "q-") {
+		query = labels[0]
+		labels = labels[1:]
+	}
+
+	if len(labels) < 2 {
+		return "", "", "", false
+	}
+
+	switch labels[0] {
+	case selfLabel, groupLabel, peersLabel:
+		return query, labels[0], dns.Fqdn(strings.Join(labels[1:], ".")), true
+	default:
+		return "", "", "", false
+	}
+}
+
+// withAllInstances replaces the health strategy of query by s4, returning the
+// one it asked for, or the smart strategy when it asked for none.
+func withAllInstances(query string) (string, string) {
+	healthStrategy := "0"
+
+	criteria := strings.TrimPrefix(query, "q-")
+	if match := healthStrategyRegex.FindStringSubmatch(criteria); match != nil {
+		healthStrategy = match[1]
+		criteria = healthStrategyRegex.ReplaceAllString(criteria, "")
+	}
+
+	return "q-" + criteria + "s4", healthStrategy
+}
+
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	default:
+		return nil
+	}
+}
+
+func answerIP(rr dns.RR) net.IP {
+	switch answer := rr.(type) {
+	case *dns.A:
+		return answer.A
+	case *dns.AAAA:
+		return answer.AAAA
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,185 @@
+package handlers_test
+
+import (
+	"net"
+
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/handlers/handlersfakes"
+	"bosh-dns/dns/server/internal/internalfakes"
+	"bosh-dns/dns/server/records"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InstanceNamesHandler", func() {
+	var (
+		fakeWriter  *internalfakes.FakeResponseWriter
+		fakeRecords *handlersfakes.FakeInstanceRecords
+		handler     handlers.InstanceNamesHandler
+		queried     []string
+	)
+
+	BeforeEach(func() {
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.0.0.2"), Port: 5353})
+
+		fakeRecords = &handlersfakes.FakeInstanceRecords{}
+		fakeRecords.RecordsWithIPStub = func(ip string) []records.Record {
+			switch ip {
+			case "10.0.0.2":
+				return []records.Record{
+					{ID: "instance1", Group: "api", Network: "default", Deployment: "cf", IP: "10.0.0.2", Domain: "other."},
+					{ID: "instance1", Group: "api", Network: "default", Deployment: "cf", IP: "10.0.0.2", Domain: "bosh."},
+				}
+			case "10.0.0.9":
+				return []records.Record{
+					{ID: "instance9", Group: "router", Network: "default", Deployment: "cf", IP: "10.0.0.9", Domain: "bosh."},
+				}
+			default:
+				return nil
+			}
+		}
+		fakeRecords.IsHealthyReturns(true)
+		queried = []string{}
+
+		next := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			name := r.Question[0].Name
+			queried = append(queried, name)
+
+			m := &dns.Msg{}
+			m.SetReply(r)
+			m.Authoritative = true
+			for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+				m.Answer = append(m.Answer, &dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET}, A: net.ParseIP(ip)})
+			}
+
+			Expect(w.WriteMsg(m)).To(Succeed())
+		})
+
+		handler = handlers.NewInstanceNamesHandler(next, fakeRecords, []string{"127.0.0.1", "169.254.0.2", "10.0.0.9"}, &loggerfakes.FakeLogger{})
+	})
+
+	question := func(name string) *dns.Msg {
+		m := &dns.Msg{}
+		m.SetQuestion(name, dns.TypeA)
+		return m
+	}
+
+	answerIPs := func(m *dns.Msg) []string {
+		ips := []string{}
+		for _, rr := range m.Answer {
+			Expect(rr.Header().Name).To(Equal(m.Question[0].Name))
+			ips = append(ips, rr.(*dns.A).A.String())
+		}
+		return ips
+	}
+
+	It("resolves _self to the querying instance", func() {
+		request := question("_self.bosh.")
+		handler.ServeDNS(fakeWriter, request)
+
+		Expect(queried).To(Equal([]string{"instance1.api.default.cf.bosh."}))
+		Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+
+		resp := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(resp.Id).To(Equal(request.Id))
+		Expect(resp.Question).To(Equal(request.Question))
+		Expect(answerIPs(resp)).To(Equal([]string{"10.0.0.2"}))
+	})
+
+	It("resolves _group to the healthy instances of the group of the querying instance", func() {
+		handler.ServeDNS(fakeWriter, question("_group.bosh."))
+
+		Expect(queried).To(Equal([]string{"q-s0.api.default.cf.bosh."}))
+		Expect(answerIPs(fakeWriter.WriteMsgArgsForCall(0))).To(Equal([]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}))
+	})
+
+	It("resolves _peers to the group without the querying instance", func() {
+		handler.ServeDNS(fakeWriter, question("_peers.bosh."))
+
+		Expect(queried).To(Equal([]string{"q-s4.api.default.cf.bosh."}))
+		Expect(answerIPs(fakeWriter.WriteMsgArgsForCall(0))).To(Equal([]string{"10.0.0.1", "10.0.0.3"}))
+	})
+
+	It("applies q- queries", func() {
+		handler.ServeDNS(fakeWriter, question("q-a1s3._peers.bosh."))
+
+		Expect(queried).To(Equal([]string{"q-a1s4.api.default.cf.bosh."}))
+		Expect(answerIPs(fakeWriter.WriteMsgArgsForCall(0))).To(Equal([]string{"10.0.0.1", "10.0.0.3"}))
+	})
+
+	Context("when only some instances are healthy", func() {
+		var healthy map[string]bool
+
+		BeforeEach(func() {
+			healthy = map[string]bool{"10.0.0.2": true}
+			fakeRecords.IsHealthyStub = func(ip string) bool {
+				return healthy[ip]
+			}
+		})
+
+		It("applies the health strategy to the peers without the querying instance", func() {
+			handler.ServeDNS(fakeWriter, question("_peers.bosh."))
+
+			Expect(answerIPs(fakeWriter.WriteMsgArgsForCall(0))).To(Equal([]string{"10.0.0.1", "10.0.0.3"}))
+		})
+
+		It("picks the healthy peers with the smart strategy", func() {
+			healthy["10.0.0.3"] = true
+
+			handler.ServeDNS(fakeWriter, question("_peers.bosh."))
+
+			Expect(answerIPs(fakeWriter.WriteMsgArgsForCall(0))).To(Equal([]string{"10.0.0.3"}))
+		})
+
+		It("applies the health strategy of q- queries", func() {
+			handler.ServeDNS(fakeWriter, question("q-s1._peers.bosh."))
+
+			Expect(queried).To(Equal([]string{"q-s4.api.default.cf.bosh."}))
+			Expect(answerIPs(fakeWriter.WriteMsgArgsForCall(0))).To(Equal([]string{"10.0.0.1", "10.0.0.3"}))
+
+			handler.ServeDNS(fakeWriter, question("q-s3._peers.bosh."))
+
+			Expect(answerIPs(fakeWriter.WriteMsgArgsForCall(1))).To(BeEmpty())
+		})
+	})
+
+	It("uses the record in the queried domain", func() {
+		handler.ServeDNS(fakeWriter, question("_group.other."))
+
+		Expect(queried).To(Equal([]string{"q-s0.api.default.cf.other."}))
+	})
+
+	It("resolves queries from this VM for the instance this VM runs", func() {
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("169.254.0.2"), Port: 5353})
+		handler.ServeDNS(fakeWriter, question("_group.bosh."))
+
+		fakeWriter.RemoteAddrReturns(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5353})
+		handler.ServeDNS(fakeWriter, question("_self.bosh."))
+
+		Expect(queried).To(Equal([]string{"q-s0.router.default.cf.bosh.", "instance9.router.default.cf.bosh."}))
+	})
+
+	It("returns NXDOMAIN when the querying address has no record", func() {
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.0.0.4"), Port: 5353})
+		handler.ServeDNS(fakeWriter, question("_self.bosh."))
+
+		Expect(queried).To(BeEmpty())
+		resp := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(resp.Rcode).To(Equal(dns.RcodeNameError))
+		Expect(resp.Authoritative).To(BeTrue())
+	})
+
+	It("passes other names through", func() {
+		handler.ServeDNS(fakeWriter, question("instance1.api.default.cf.bosh."))
+		handler.ServeDNS(fakeWriter, question("_selfish.bosh."))
+		handler.ServeDNS(fakeWriter, question("_self."))
+
+		Expect(queried).To(Equal([]string{"instance1.api.default.cf.bosh.", "_selfish.bosh.", "_self."}))
+		Expect(fakeRecords.RecordsWithIPCallCount()).To(Equal(0))
+	})
+})
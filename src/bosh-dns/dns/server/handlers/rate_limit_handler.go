@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"bosh-dns/dns/server/ratelimit"
+
+	"github.com/miekg/dns"
+)
+
+// RateLimitHandler drops the queries of client prefixes which exceed the
+// rate of limiter.
+type RateLimitHandler struct {
+	next    dns.Handler
+	clients ratelimit.Clients
+	limiter *ratelimit.Limiter
+}
+
+func NewRateLimitHandler(next dns.Handler, clients ratelimit.Clients, limiter *ratelimit.Limiter) RateLimitHandler {
+	return RateLimitHandler{
+		next:    next,
+		clients: clients,
+		limiter: limiter,
+	}
+}
+
+func (h RateLimitHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	if prefix, limited := h.clients.Prefix(w.RemoteAddr()); limited && !h.limiter.Allow(prefix) {
+		return
+	}
+
+	h.next.ServeDNS(w, r)
+}
@@ -0,0 +1,71 @@
+package handlers_test
+
+import (
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/internal/internalfakes"
+	"bosh-dns/dns/server/ratelimit"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimitHandler", func() {
+	var (
+		fakeWriter *internalfakes.FakeResponseWriter
+		served     int
+		handler    handlers.RateLimitHandler
+		request    *dns.Msg
+	)
+
+	BeforeEach(func() {
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5353})
+		served = 0
+
+		_, exempt, err := net.ParseCIDR("127.0.0.0/8")
+		Expect(err).NotTo(HaveOccurred())
+
+		clients := ratelimit.NewClients(24, 56, []*net.IPNet{exempt})
+		limiter := ratelimit.NewLimiter("queries", 2, fakeclock.NewFakeClock(time.Now()), &loggerfakes.FakeLogger{})
+		next := dns.HandlerFunc(func(dns.ResponseWriter, *dns.Msg) {
+			served++
+		})
+		handler = handlers.NewRateLimitHandler(next, clients, limiter)
+
+		request = &dns.Msg{}
+		request.SetQuestion("example.com.", dns.TypeA)
+	})
+
+	It("drops queries of client prefixes over the limit", func() {
+		for i := 0; i < 3; i++ {
+			handler.ServeDNS(fakeWriter, request)
+		}
+		Expect(served).To(Equal(2))
+
+		fakeWriter.RemoteAddrReturns(&net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 5353})
+		handler.ServeDNS(fakeWriter, request)
+		Expect(served).To(Equal(2))
+		Expect(fakeWriter.WriteMsgCallCount()).To(Equal(0))
+
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.0.1.1"), Port: 5353})
+		handler.ServeDNS(fakeWriter, request)
+		Expect(served).To(Equal(3))
+	})
+
+	It("does not limit exempt clients", func() {
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5353})
+
+		for i := 0; i < 5; i++ {
+			handler.ServeDNS(fakeWriter, request)
+		}
+		Expect(served).To(Equal(5))
+	})
+})
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+// RefusedHandler answers every query with REFUSED.
+type RefusedHandler struct {
+	logger logger.Logger
+	logTag string
+}
+
+func NewRefusedHandler(logger logger.Logger) RefusedHandler {
+	return RefusedHandler{
+		logger: logger,
+		logTag: "RefusedHandler",
+	}
+}
+
+func (h RefusedHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := &dns.Msg{}
+	m.SetRcode(r, dns.RcodeRefused)
+
+	if err := w.WriteMsg(m); err != nil {
+		h.logger.Error(h.logTag, err.Error())
+	}
+}
@@ -0,0 +1,54 @@
+package handlers_test
+
+import (
+	"errors"
+
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/internal/internalfakes"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RefusedHandler", func() {
+	var (
+		fakeLogger *loggerfakes.FakeLogger
+		fakeWriter *internalfakes.FakeResponseWriter
+		handler    handlers.RefusedHandler
+		request    *dns.Msg
+	)
+
+	BeforeEach(func() {
+		fakeLogger = &loggerfakes.FakeLogger{}
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		handler = handlers.NewRefusedHandler(fakeLogger)
+
+		request = &dns.Msg{}
+		request.SetQuestion("db.corp.internal.", dns.TypeA)
+	})
+
+	It("answers with REFUSED", func() {
+		handler.ServeDNS(fakeWriter, request)
+
+		Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+		message := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(message.Rcode).To(Equal(dns.RcodeRefused))
+		Expect(message.Id).To(Equal(request.Id))
+		Expect(message.Question).To(Equal(request.Question))
+		Expect(message.Answer).To(BeEmpty())
+	})
+
+	It("logs an error when the response cannot be written", func() {
+		fakeWriter.WriteMsgReturns(errors.New("failed to write"))
+
+		handler.ServeDNS(fakeWriter, request)
+
+		Expect(fakeLogger.ErrorCallCount()).To(Equal(1))
+		tag, message, _ := fakeLogger.ErrorArgsForCall(0)
+		Expect(tag).To(Equal("RefusedHandler"))
+		Expect(message).To(Equal("failed to write"))
+	})
+})
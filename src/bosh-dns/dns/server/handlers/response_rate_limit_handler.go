@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net"
+
+	"bosh-dns/dns/server/ratelimit"
+
+	"github.com/miekg/dns"
+)
+
+// ResponseRateLimitHandler applies response rate limiting to the UDP
+// answers next gives, so that spoofed queries cannot use the server for
+// amplification. TCP clients have proven their address and are not limited.
+type ResponseRateLimitHandler struct {
+	next    dns.Handler
+	clients ratelimit.Clients
+	limiter *ratelimit.ResponseLimiter
+}
+
+func NewResponseRateLimitHandler(next dns.Handler, clients ratelimit.Clients, limiter *ratelimit.ResponseLimiter) ResponseRateLimitHandler {
+	return ResponseRateLimitHandler{
+		next:    next,
+		clients: clients,
+		limiter: limiter,
+	}
+}
+
+func (h ResponseRateLimitHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	if _, udp := w.RemoteAddr().(*net.UDPAddr); !udp {
+		h.next.ServeDNS(w, r)
+		return
+	}
+
+	prefix, limited := h.clients.Prefix(w.RemoteAddr())
+	if !limited {
+		h.next.ServeDNS(w, r)
+		return
+	}
+
+	h.next.ServeDNS(&rateLimitedWriter{ResponseWriter: w, prefix: prefix, limiter: h.limiter}, r)
+}
+
+type rateLimitedWriter struct {
+	dns.ResponseWriter
+	prefix  string
+	limiter *ratelimit.ResponseLimiter
+}
+
+func (w *rateLimitedWriter) WriteMsg(m *dns.Msg) error {
+	switch w.limiter.Limit(w.prefix, m) {
+	case ratelimit.Slip:
+		slipped := &dns.Msg{MsgHdr: m.MsgHdr, Question: m.Question}
+		slipped.Truncated = true
+		if opt := m.IsEdns0(); opt != nil {
+			slipped.Extra = []dns.RR{opt}
+		}
+
+		return w.ResponseWriter.WriteMsg(slipped)
+	case ratelimit.Drop:
+		return nil
+	default:
+		return w.ResponseWriter.WriteMsg(m)
+	}
+}
@@ -0,0 +1,74 @@
+package handlers_test
+
+import (
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/internal/internalfakes"
+	"bosh-dns/dns/server/ratelimit"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResponseRateLimitHandler", func() {
+	var (
+		fakeWriter *internalfakes.FakeResponseWriter
+		handler    handlers.ResponseRateLimitHandler
+		request    *dns.Msg
+	)
+
+	BeforeEach(func() {
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5353})
+
+		next := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			m := &dns.Msg{}
+			m.SetReply(r)
+			m.Answer = []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET}, A: net.ParseIP("10.0.0.5")}}
+			m.SetEdns0(1232, false)
+
+			Expect(w.WriteMsg(m)).To(Succeed())
+		})
+
+		clients := ratelimit.NewClients(24, 56, nil)
+		limiter := ratelimit.NewResponseLimiter(1, 2, fakeclock.NewFakeClock(time.Now()), &loggerfakes.FakeLogger{})
+		handler = handlers.NewResponseRateLimitHandler(next, clients, limiter)
+
+		request = &dns.Msg{}
+		request.SetQuestion("example.com.", dns.TypeA)
+	})
+
+	It("drops or slips identical UDP responses over the limit", func() {
+		for i := 0; i < 3; i++ {
+			handler.ServeDNS(fakeWriter, request)
+		}
+
+		Expect(fakeWriter.WriteMsgCallCount()).To(Equal(2))
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Answer).To(HaveLen(1))
+
+		slipped := fakeWriter.WriteMsgArgsForCall(1)
+		Expect(slipped.Id).To(Equal(request.Id))
+		Expect(slipped.Truncated).To(BeTrue())
+		Expect(slipped.Question).To(Equal(request.Question))
+		Expect(slipped.Answer).To(BeEmpty())
+		Expect(slipped.IsEdns0()).NotTo(BeNil())
+	})
+
+	It("does not limit TCP responses", func() {
+		fakeWriter.RemoteAddrReturns(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5353})
+
+		for i := 0; i < 3; i++ {
+			handler.ServeDNS(fakeWriter, request)
+		}
+
+		Expect(fakeWriter.WriteMsgCallCount()).To(Equal(3))
+		Expect(fakeWriter.WriteMsgArgsForCall(2).Truncated).To(BeFalse())
+	})
+})
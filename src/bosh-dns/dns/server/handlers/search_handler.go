@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+//go:generate counterfeiter . SearchSuffixProvider
+
+type SearchSuffixProvider interface {
+	SearchSuffixes() []string
+}
+
+// SearchSuffixes is a fixed list of fully qualified search suffixes.
+type SearchSuffixes []string
+
+func (s SearchSuffixes) SearchSuffixes() []string {
+	return s
+}
+
+// SearchSuffixFunc adapts a function to a SearchSuffixProvider.
+type SearchSuffixFunc func() []string
+
+func (f SearchSuffixFunc) SearchSuffixes() []string {
+	return f()
+}
+
+// SearchSuffixProviders searches the suffixes of every provider in order.
+type SearchSuffixProviders []SearchSuffixProvider
+
+func (p SearchSuffixProviders) SearchSuffixes() []string {
+	suffixes := []string{}
+	for _, provider := range p {
+		suffixes = append(suffixes, provider.SearchSuffixes()...)
+	}
+
+	return suffixes
+}
+
+// SearchHandler retries A and AAAA queries for names with fewer than ndots
+// dots with each search suffix in turn when the name as queried does not
+// exist or has no answers. Other failures are returned as they are, without
+// searching. The first expanded name
+// with answers is returned behind a CNAME from the queried name, so that
+// clients sending bare names such as "uaa" reach the instance group of the
+// same network.
+type SearchHandler struct {
+	next     dns.Handler
+	provider SearchSuffixProvider
+	ndots    int
+	logger   logger.Logger
+	logTag   string
+}
+
+func NewSearchHandler(next dns.Handler, provider SearchSuffixProvider, ndots int, logger logger.Logger) SearchHandler {
+	return SearchHandler{
+		next:     next,
+		provider: provider,
+		ndots:    ndots,
+		logger:   logger,
+		logTag:   "SearchHandler",
+	}
+}
+
+func (h SearchHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	if !h.searchable(r) {
+		h.next.ServeDNS(w, r)
+		return
+	}
+
+	original := h.serve(w, r)
+	if !missed(original) {
+		h.write(w, original)
+		return
+	}
+
+	name := r.Question[0].Name
+	for _, suffix := range h.provider.SearchSuffixes() {
+		expanded := name + suffix
+
+		req := r.Copy()
+		req.Question[0].Name = expanded
+
+		resp := h.serve(w, req)
+		if !answered(resp) {
+			continue
+		}
+
+		h.logger.Debug(h.logTag, "expanded %s to %s", name, expanded)
+		h.write(w, h.expandedReply(r, expanded, resp))
+		return
+	}
+
+	h.write(w, original)
+}
+
+func (h SearchHandler) searchable(r *dns.Msg) bool {
+	if r.Opcode != dns.OpcodeQuery || len(r.Question) != 1 {
+		return false
+	}
+
+	question := r.Question[0]
+	if question.Qtype != dns.TypeA && question.Qtype != dns.TypeAAAA {
+		return false
+	}
+
+	name := dns.Fqdn(question.Name)
+	if name == "." {
+		return false
+	}
+
+	return strings.Count(name, ".")-1 < h.ndots
+}
+
+// serve returns the response next writes for r, if any.
+func (h SearchHandler) serve(w dns.ResponseWriter, r *dns.Msg) *dns.Msg {
+	captured := &capturingWriter{ResponseWriter: w}
+	h.next.ServeDNS(captured, r)
+
+	return captured.msg
+}
+
+func (h SearchHandler) expandedReply(r *dns.Msg, expanded string, resp *dns.Msg) *dns.Msg {
+	ttl := resp.Answer[0].Header().Ttl
+	for _, rr := range resp.Answer {
+		if rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+
+	m := &dns.Msg{}
+	m.SetReply(r)
+	m.Authoritative = resp.Authoritative
+	m.RecursionAvailable = resp.RecursionAvailable
+	m.Truncated = resp.Truncated
+	m.Answer = append([]dns.RR{&dns.CNAME{
+		Hdr: dns.RR_Header{
+			Name:   r.Question[0].Name,
+			Rrtype: dns.TypeCNAME,
+			Class:  dns.ClassINET,
+			Ttl:    ttl,
+		},
+		Target: expanded,
+	}}, resp.Answer...)
+	m.Extra = resp.Extra
+
+	return m
+}
+
+func (h SearchHandler) write(w dns.ResponseWriter, m *dns.Msg) {
+	if m == nil {
+		return
+	}
+
+	if err := w.WriteMsg(m); err != nil {
+		h.logger.Error(h.logTag, err.Error())
+	}
+}
+
+func missed(m *dns.Msg) bool {
+	return m == nil || m.Rcode == dns.RcodeNameError || (m.Rcode == dns.RcodeSuccess && len(m.Answer) == 0)
+}
+
+func answered(m *dns.Msg) bool {
+	return m != nil && m.Rcode == dns.RcodeSuccess && len(m.Answer) > 0
+}
+
+type capturingWriter struct {
+	dns.ResponseWriter
+	msg *dns.Msg
+}
+
+func (w *capturingWriter) WriteMsg(m *dns.Msg) error {
+	w.msg = m
+	return nil
+}
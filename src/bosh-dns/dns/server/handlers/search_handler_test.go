@@ -0,0 +1,182 @@
+package handlers_test
+
+import (
+	"net"
+
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/handlers/handlersfakes"
+	"bosh-dns/dns/server/internal/internalfakes"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SearchHandler", func() {
+	var (
+		fakeWriter   *internalfakes.FakeResponseWriter
+		fakeProvider *handlersfakes.FakeSearchSuffixProvider
+		handler      handlers.SearchHandler
+		next         dns.Handler
+		known        map[string]string
+		rcodes       map[string]int
+		queried      []string
+	)
+
+	BeforeEach(func() {
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		fakeProvider = &handlersfakes.FakeSearchSuffixProvider{}
+		fakeProvider.SearchSuffixesReturns([]string{"my-network.my-deployment.bosh.", "service.internal."})
+
+		known = map[string]string{
+			"uaa.service.internal.":              "10.0.0.6",
+			"uaa.my-network.my-deployment.bosh.": "10.0.0.5",
+		}
+		rcodes = map[string]int{}
+		queried = []string{}
+
+		next = dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			name := r.Question[0].Name
+			queried = append(queried, name)
+
+			m := &dns.Msg{}
+			m.SetReply(r)
+			if rcode, ok := rcodes[name]; ok {
+				m.SetRcode(r, rcode)
+			} else if ip, ok := known[name]; ok {
+				m.Answer = []dns.RR{
+					&dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 30}, A: net.ParseIP(ip)},
+					&dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 10}, A: net.ParseIP(ip).To4()},
+				}
+			} else {
+				m.SetRcode(r, dns.RcodeNameError)
+			}
+
+			Expect(w.WriteMsg(m)).To(Succeed())
+		})
+
+		handler = handlers.NewSearchHandler(next, fakeProvider, 1, &loggerfakes.FakeLogger{})
+	})
+
+	question := func(name string, qtype uint16) *dns.Msg {
+		m := &dns.Msg{}
+		m.SetQuestion(name, qtype)
+		return m
+	}
+
+	It("answers with a CNAME to the first suffix the name resolves under", func() {
+		request := question("uaa.", dns.TypeA)
+		handler.ServeDNS(fakeWriter, request)
+
+		Expect(queried).To(Equal([]string{"uaa.", "uaa.my-network.my-deployment.bosh."}))
+		Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+
+		resp := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(resp.Id).To(Equal(request.Id))
+		Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+		Expect(resp.Question).To(Equal(request.Question))
+		Expect(resp.Answer).To(HaveLen(3))
+
+		cname, ok := resp.Answer[0].(*dns.CNAME)
+		Expect(ok).To(BeTrue())
+		Expect(cname.Hdr.Name).To(Equal("uaa."))
+		Expect(cname.Hdr.Ttl).To(Equal(uint32(10)))
+		Expect(cname.Target).To(Equal("uaa.my-network.my-deployment.bosh."))
+
+		Expect(resp.Answer[1].Header().Name).To(Equal("uaa.my-network.my-deployment.bosh."))
+		Expect(resp.Answer[1].(*dns.A).A.String()).To(Equal("10.0.0.5"))
+	})
+
+	It("tries the suffixes in order", func() {
+		delete(known, "uaa.my-network.my-deployment.bosh.")
+
+		handler.ServeDNS(fakeWriter, question("uaa.", dns.TypeA))
+
+		Expect(queried).To(Equal([]string{"uaa.", "uaa.my-network.my-deployment.bosh.", "uaa.service.internal."}))
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Answer[0].(*dns.CNAME).Target).To(Equal("uaa.service.internal."))
+	})
+
+	It("returns the answer for the name as queried when there is one", func() {
+		known["uaa."] = "10.0.0.7"
+
+		handler.ServeDNS(fakeWriter, question("uaa.", dns.TypeA))
+
+		Expect(queried).To(Equal([]string{"uaa."}))
+		resp := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(resp.Answer).To(HaveLen(2))
+		Expect(resp.Answer[0].(*dns.A).A.String()).To(Equal("10.0.0.7"))
+	})
+
+	It("returns the original miss when no suffix resolves", func() {
+		handler.ServeDNS(fakeWriter, question("database.", dns.TypeA))
+
+		Expect(queried).To(Equal([]string{"database.", "database.my-network.my-deployment.bosh.", "database.service.internal."}))
+		Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+
+		resp := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(resp.Rcode).To(Equal(dns.RcodeNameError))
+		Expect(resp.Question[0].Name).To(Equal("database."))
+	})
+
+	It("searches when the name as queried has no answers", func() {
+		rcodes["uaa."] = dns.RcodeSuccess
+
+		handler.ServeDNS(fakeWriter, question("uaa.", dns.TypeA))
+
+		Expect(queried).To(Equal([]string{"uaa.", "uaa.my-network.my-deployment.bosh."}))
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Answer[0].(*dns.CNAME).Target).To(Equal("uaa.my-network.my-deployment.bosh."))
+	})
+
+	It("returns other failures of the name as queried without searching", func() {
+		rcodes["uaa."] = dns.RcodeServerFailure
+
+		handler.ServeDNS(fakeWriter, question("uaa.", dns.TypeA))
+
+		Expect(queried).To(Equal([]string{"uaa."}))
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeServerFailure))
+	})
+
+	It("does not search names with ndots or more dots", func() {
+		handler.ServeDNS(fakeWriter, question("uaa.example.", dns.TypeA))
+
+		Expect(queried).To(Equal([]string{"uaa.example."}))
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeNameError))
+	})
+
+	It("searches names with more dots when ndots allows", func() {
+		known["uaa.example.service.internal."] = "10.0.0.8"
+		handler = handlers.NewSearchHandler(next, fakeProvider, 2, &loggerfakes.FakeLogger{})
+
+		handler.ServeDNS(fakeWriter, question("uaa.example.", dns.TypeA))
+
+		Expect(queried).To(Equal([]string{"uaa.example.", "uaa.example.my-network.my-deployment.bosh.", "uaa.example.service.internal."}))
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Answer[0].(*dns.CNAME).Target).To(Equal("uaa.example.service.internal."))
+	})
+
+	It("does not search other query types", func() {
+		handler.ServeDNS(fakeWriter, question("uaa.", dns.TypeMX))
+
+		Expect(queried).To(Equal([]string{"uaa."}))
+		Expect(fakeProvider.SearchSuffixesCallCount()).To(Equal(0))
+	})
+
+	It("does not search the root", func() {
+		handler.ServeDNS(fakeWriter, question(".", dns.TypeA))
+
+		Expect(queried).To(Equal([]string{"."}))
+		Expect(fakeProvider.SearchSuffixesCallCount()).To(Equal(0))
+	})
+
+	Describe("SearchSuffixProviders", func() {
+		It("returns the suffixes of every provider in order", func() {
+			providers := handlers.SearchSuffixProviders{
+				handlers.SearchSuffixFunc(func() []string { return []string{"a.bosh."} }),
+				handlers.SearchSuffixes{"b.internal.", "c.internal."},
+			}
+
+			Expect(providers.SearchSuffixes()).To(Equal([]string{"a.bosh.", "b.internal.", "c.internal."}))
+		})
+	})
+})
@@ -3,6 +3,8 @@ package handlers
 import (
 	"net"
 
+	"bosh-dns/dns/server/records/dnsresolver"
+
 	"github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/miekg/dns"
 )
@@ -56,6 +58,8 @@ func (h UpcheckHandler) ServeDNS(resp dns.ResponseWriter, req *dns.Msg) {
 	out.SetReply(req)
 	// rcode is succeess by default
 
+	dnsresolver.TruncateIfNeeded(resp, req, out)
+
 	if err := resp.WriteMsg(out); err != nil {
 		h.logger.Error("UpcheckHandler", err.Error())
 	}
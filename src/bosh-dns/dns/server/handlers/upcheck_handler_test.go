@@ -29,6 +29,17 @@ var _ = Describe("UpcheckHandler", func() {
 	})
 
 	Describe("ServeDNS", func() {
+		It("echoes the OPT record of EDNS(0) requests", func() {
+			m := &dns.Msg{}
+			m.SetQuestion("upcheck.bosh-dns.", dns.TypeA)
+			m.SetEdns0(1232, false)
+
+			upcheckHandler.ServeDNS(fakeWriter, m)
+			message := fakeWriter.WriteMsgArgsForCall(0)
+			Expect(message.IsEdns0()).NotTo(BeNil())
+			Expect(message.IsEdns0().UDPSize()).To(Equal(uint16(1232)))
+		})
+
 		Context("when ANY record", func() {
 			It("returns success rcode", func() {
 				m := &dns.Msg{}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"code.cloudfoundry.org/clock"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+//go:generate counterfeiter . UpdateStore
+
+type UpdateStore interface {
+	Update(zone string, prerequisites, updates []dns.RR) int
+}
+
+// UpdateHandler applies RFC 2136 dynamic updates signed with one of keyNames
+// and passes every other request to next.
+type UpdateHandler struct {
+	next     dns.Handler
+	store    UpdateStore
+	keyNames []string
+	clock    clock.Clock
+	logger   logger.Logger
+	logTag   string
+}
+
+func NewUpdateHandler(next dns.Handler, store UpdateStore, keyNames []string, clock clock.Clock, logger logger.Logger) UpdateHandler {
+	return UpdateHandler{
+		next:     next,
+		store:    store,
+		keyNames: keyNames,
+		clock:    clock,
+		logger:   logger,
+		logTag:   "UpdateHandler",
+	}
+}
+
+func (h UpdateHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	if r.Opcode != dns.OpcodeUpdate {
+		h.next.ServeDNS(w, r)
+		return
+	}
+
+	remote := remoteIP(w.RemoteAddr())
+
+	if len(r.Question) != 1 || r.Question[0].Qtype != dns.TypeSOA {
+		h.logger.Info(h.logTag, "Rejecting update from %s: the zone section must hold a single SOA question", remote)
+		h.reply(w, r, dns.RcodeFormatError, nil)
+		return
+	}
+
+	zone := r.Question[0].Name
+
+	tsig := r.IsTsig()
+	if tsig == nil {
+		h.logger.Info(h.logTag, "Refusing update of %s from %s: request is not signed", zone, remote)
+		h.reply(w, r, dns.RcodeRefused, nil)
+		return
+	}
+
+	if err := w.TsigStatus(); err != nil {
+		h.logger.Info(h.logTag, "Refusing update of %s from %s: %s", zone, remote, err)
+		h.reply(w, r, dns.RcodeNotAuth, nil)
+		return
+	}
+
+	if !containsName(h.keyNames, tsig.Hdr.Name) {
+		h.logger.Info(h.logTag, "Refusing update of %s from %s: key '%s' may not update zones", zone, remote, tsig.Hdr.Name)
+		h.reply(w, r, dns.RcodeNotAuth, tsig)
+		return
+	}
+
+	rcode := h.store.Update(zone, r.Answer, r.Ns)
+	h.logger.Info(h.logTag, "Update of %s from %s with key '%s': %s", zone, remote, tsig.Hdr.Name, dns.RcodeToString[rcode])
+
+	h.reply(w, r, rcode, tsig)
+}
+
+// reply signs the response when the request carried a verified TSIG.
+func (h UpdateHandler) reply(w dns.ResponseWriter, r *dns.Msg, rcode int, tsig *dns.TSIG) {
+	m := &dns.Msg{}
+	m.SetRcode(r, rcode)
+
+	if tsig != nil {
+		m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, h.clock.Now().Unix())
+	}
+
+	if err := w.WriteMsg(m); err != nil {
+		h.logger.Error(h.logTag, err.Error())
+	}
+}
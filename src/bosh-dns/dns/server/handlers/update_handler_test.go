@@ -0,0 +1,171 @@
+package handlers_test
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/handlers/handlersfakes"
+	"bosh-dns/dns/server/internal/internalfakes"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func updateRequest(key string) *dns.Msg {
+	m := &dns.Msg{}
+	m.SetUpdate("dyn.internal.")
+	m.Insert([]dns.RR{&dns.A{
+		Hdr: dns.RR_Header{Name: "app.dyn.internal.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+		A:   net.ParseIP("10.0.0.1"),
+	}})
+	if key != "" {
+		m.SetTsig(key, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+	return m
+}
+
+var _ = Describe("UpdateHandler", func() {
+	var (
+		next       dns.Handler
+		nextCalls  int
+		store      *handlersfakes.FakeUpdateStore
+		fakeWriter *internalfakes.FakeResponseWriter
+		fakeLogger *loggerfakes.FakeLogger
+		handler    handlers.UpdateHandler
+	)
+
+	BeforeEach(func() {
+		nextCalls = 0
+		next = dns.HandlerFunc(func(dns.ResponseWriter, *dns.Msg) {
+			nextCalls++
+		})
+		store = &handlersfakes.FakeUpdateStore{}
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		fakeLogger = &loggerfakes.FakeLogger{}
+
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5353})
+		store.UpdateReturns(dns.RcodeSuccess)
+
+		handler = handlers.NewUpdateHandler(next, store, []string{"updates"}, fakeclock.NewFakeClock(time.Now()), fakeLogger)
+	})
+
+	It("passes queries on", func() {
+		m := &dns.Msg{}
+		m.SetQuestion("app.dyn.internal.", dns.TypeA)
+
+		handler.ServeDNS(fakeWriter, m)
+
+		Expect(nextCalls).To(Equal(1))
+		Expect(store.UpdateCallCount()).To(Equal(0))
+	})
+
+	It("applies signed updates and signs the response", func() {
+		store.UpdateReturns(dns.RcodeNXRrset)
+		request := updateRequest("updates.")
+
+		handler.ServeDNS(fakeWriter, request)
+
+		zone, prerequisites, updates := store.UpdateArgsForCall(0)
+		Expect(zone).To(Equal("dyn.internal."))
+		Expect(prerequisites).To(BeEmpty())
+		Expect(updates).To(Equal(request.Ns))
+
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Opcode).To(Equal(dns.OpcodeUpdate))
+		Expect(response.Rcode).To(Equal(dns.RcodeNXRrset))
+		Expect(response.IsTsig()).NotTo(BeNil())
+		Expect(response.IsTsig().Hdr.Name).To(Equal("updates."))
+	})
+
+	It("rejects updates without a single SOA zone question", func() {
+		request := updateRequest("updates.")
+		request.Question[0].Qtype = dns.TypeA
+
+		handler.ServeDNS(fakeWriter, request)
+
+		Expect(store.UpdateCallCount()).To(Equal(0))
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeFormatError))
+	})
+
+	It("refuses unsigned updates", func() {
+		handler.ServeDNS(fakeWriter, updateRequest(""))
+
+		Expect(store.UpdateCallCount()).To(Equal(0))
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeRefused))
+	})
+
+	It("refuses updates with an invalid signature", func() {
+		fakeWriter.TsigStatusReturns(errors.New("dns: bad signature"))
+
+		handler.ServeDNS(fakeWriter, updateRequest("updates."))
+
+		Expect(store.UpdateCallCount()).To(Equal(0))
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Rcode).To(Equal(dns.RcodeNotAuth))
+		Expect(response.IsTsig()).To(BeNil())
+	})
+
+	It("refuses updates signed with keys not allowed to update", func() {
+		handler.ServeDNS(fakeWriter, updateRequest("transfer."))
+
+		Expect(store.UpdateCallCount()).To(Equal(0))
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeNotAuth))
+
+		_, message, _ := fakeLogger.InfoArgsForCall(0)
+		Expect(message).To(ContainSubstring("may not update zones"))
+	})
+
+	Context("when serving clients over UDP", func() {
+		var (
+			server  *dns.Server
+			address string
+		)
+
+		BeforeEach(func() {
+			handler = handlers.NewUpdateHandler(next, store, []string{"updates."}, clock.NewClock(), fakeLogger)
+
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			address = conn.LocalAddr().String()
+
+			started := make(chan struct{})
+			server = &dns.Server{
+				PacketConn:        conn,
+				Handler:           handler,
+				TsigSecret:        map[string]string{"updates.": transferKeySecret},
+				NotifyStartedFunc: func() { close(started) },
+			}
+			go server.ActivateAndServe()
+			Eventually(started).Should(BeClosed())
+		})
+
+		AfterEach(func() {
+			server.Shutdown()
+		})
+
+		It("applies updates from a TSIG signing client", func() {
+			client := &dns.Client{TsigSecret: map[string]string{"updates.": transferKeySecret}}
+			response, _, err := client.Exchange(updateRequest("updates."), address)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(response.Rcode).To(Equal(dns.RcodeSuccess))
+			Expect(store.UpdateCallCount()).To(Equal(1))
+		})
+
+		It("rejects updates signed with the wrong secret", func() {
+			client := &dns.Client{TsigSecret: map[string]string{"updates.": "d3Jvbmctd3Jvbmctd3Jvbmc="}}
+			response, _, _ := client.Exchange(updateRequest("updates."), address)
+
+			Expect(response).NotTo(BeNil())
+			Expect(response.Rcode).To(Equal(dns.RcodeNotAuth))
+			Expect(store.UpdateCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"net"
+
+	"code.cloudfoundry.org/clock"
+
+	"bosh-dns/dns/server/zone"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+// transferChunkSize keeps every transfer message well below the 64KiB TCP
+// message limit.
+const transferChunkSize = 250
+
+//go:generate counterfeiter . ZoneSource
+
+type ZoneSource interface {
+	Zone(origin string) (zone.Zone, bool)
+	IXFR(origin string, serial uint32) ([]dns.RR, bool)
+}
+
+// ZoneTransferHandler answers AXFR and IXFR requests, as well as SOA and NS
+// queries at the apex of a zone, and passes every other request to next.
+// Transfers are refused unless they come from one of secondaries and carry a
+// valid TSIG signed with one of keyNames.
+type ZoneTransferHandler struct {
+	next        dns.Handler
+	zones       ZoneSource
+	secondaries []*net.IPNet
+	keyNames    []string
+	clock       clock.Clock
+	logger      logger.Logger
+	logTag      string
+}
+
+func NewZoneTransferHandler(next dns.Handler, zones ZoneSource, secondaries []*net.IPNet, keyNames []string, clock clock.Clock, logger logger.Logger) ZoneTransferHandler {
+	return ZoneTransferHandler{
+		next:        next,
+		zones:       zones,
+		secondaries: secondaries,
+		keyNames:    keyNames,
+		clock:       clock,
+		logger:      logger,
+		logTag:      "ZoneTransferHandler",
+	}
+}
+
+func (h ZoneTransferHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	if r.Opcode != dns.OpcodeQuery || len(r.Question) == 0 {
+		h.next.ServeDNS(w, r)
+		return
+	}
+
+	question := r.Question[0]
+
+	switch question.Qtype {
+	case dns.TypeAXFR, dns.TypeIXFR:
+		h.transfer(w, r)
+	case dns.TypeSOA, dns.TypeNS:
+		z, ok := h.zones.Zone(question.Name)
+		if !ok {
+			h.next.ServeDNS(w, r)
+			return
+		}
+
+		m := &dns.Msg{}
+		m.SetReply(r)
+		m.Authoritative = true
+		if question.Qtype == dns.TypeSOA {
+			m.Answer = []dns.RR{z.SOA}
+		} else {
+			m.Answer = z.NS()
+		}
+
+		h.write(w, m)
+	default:
+		h.next.ServeDNS(w, r)
+	}
+}
+
+func (h ZoneTransferHandler) transfer(w dns.ResponseWriter, r *dns.Msg) {
+	question := r.Question[0]
+	remote := remoteIP(w.RemoteAddr())
+
+	if !h.allowed(remote) {
+		h.logger.Info(h.logTag, "Refusing %s of %s to %s: not an allowed secondary", dns.TypeToString[question.Qtype], question.Name, remote)
+		h.refuse(w, r, dns.RcodeRefused)
+		return
+	}
+
+	tsig := r.IsTsig()
+	if tsig == nil {
+		h.logger.Info(h.logTag, "Refusing %s of %s to %s: request is not signed", dns.TypeToString[question.Qtype], question.Name, remote)
+		h.refuse(w, r, dns.RcodeRefused)
+		return
+	}
+
+	if err := w.TsigStatus(); err != nil {
+		h.logger.Info(h.logTag, "Refusing %s of %s to %s: %s", dns.TypeToString[question.Qtype], question.Name, remote, err)
+		h.refuse(w, r, dns.RcodeNotAuth)
+		return
+	}
+
+	if !containsName(h.keyNames, tsig.Hdr.Name) {
+		h.logger.Info(h.logTag, "Refusing %s of %s to %s: key '%s' may not transfer zones", dns.TypeToString[question.Qtype], question.Name, remote, tsig.Hdr.Name)
+		h.refuse(w, r, dns.RcodeNotAuth)
+		return
+	}
+
+	z, ok := h.zones.Zone(question.Name)
+	if !ok {
+		h.refuse(w, r, dns.RcodeNotAuth)
+		return
+	}
+
+	rrs := z.AXFR()
+	if question.Qtype == dns.TypeIXFR {
+		serial, found := ixfrSerial(r)
+		if !found {
+			h.refuse(w, r, dns.RcodeFormatError)
+			return
+		}
+
+		rrs, _ = h.zones.IXFR(question.Name, serial)
+
+		if _, udp := w.RemoteAddr().(*net.UDPAddr); udp && len(rrs) > 1 {
+			// tells the secondary to retry over TCP
+			rrs = []dns.RR{z.SOA}
+		}
+	} else if _, udp := w.RemoteAddr().(*net.UDPAddr); udp {
+		h.refuse(w, r, dns.RcodeFormatError)
+		return
+	}
+
+	h.logger.Info(h.logTag, "Sending %s of %s serial %d to %s, %d records", dns.TypeToString[question.Qtype], question.Name, z.SOA.Serial, remote, len(rrs))
+
+	for i, chunk := range chunkRecords(rrs) {
+		m := &dns.Msg{}
+		m.SetReply(r)
+		m.Authoritative = true
+		m.Compress = true
+		m.Answer = chunk
+		m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, h.clock.Now().Unix())
+
+		if i > 0 {
+			w.TsigTimersOnly(true)
+		}
+
+		if err := w.WriteMsg(m); err != nil {
+			h.logger.Error(h.logTag, "Failed sending %s of %s to %s: %s", dns.TypeToString[question.Qtype], question.Name, remote, err)
+			return
+		}
+	}
+}
+
+func (h ZoneTransferHandler) allowed(ip net.IP) bool {
+	for _, secondary := range h.secondaries {
+		if secondary.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h ZoneTransferHandler) refuse(w dns.ResponseWriter, r *dns.Msg, rcode int) {
+	m := &dns.Msg{}
+	m.SetRcode(r, rcode)
+	h.write(w, m)
+}
+
+func (h ZoneTransferHandler) write(w dns.ResponseWriter, m *dns.Msg) {
+	if err := w.WriteMsg(m); err != nil {
+		h.logger.Error(h.logTag, err.Error())
+	}
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if dns.Fqdn(n) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ixfrSerial(r *dns.Msg) (uint32, bool) {
+	for _, rr := range r.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return soa.Serial, true
+		}
+	}
+
+	return 0, false
+}
+
+// chunkRecords splits a transfer into messages. A message never ends on an
+// SOA other than the final one, as secondaries take that as the end of the
+// transfer.
+func chunkRecords(rrs []dns.RR) [][]dns.RR {
+	chunks := [][]dns.RR{}
+
+	for len(rrs) > 0 {
+		size := transferChunkSize
+		for size < len(rrs) && rrs[size-1].Header().Rrtype == dns.TypeSOA {
+			size++
+		}
+
+		if size > len(rrs) {
+			size = len(rrs)
+		}
+
+		chunks = append(chunks, rrs[:size])
+		rrs = rrs[size:]
+	}
+
+	return chunks
+}
+
+func remoteIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+
+	return net.ParseIP(host)
+}
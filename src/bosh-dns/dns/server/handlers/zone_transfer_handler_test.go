@@ -0,0 +1,292 @@
+package handlers_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/handlers/handlersfakes"
+	"bosh-dns/dns/server/internal/internalfakes"
+	"bosh-dns/dns/server/zone"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const transferKeySecret = "c2VjcmV0LXNlY3JldC1zZWNyZXQ="
+
+func testZone(serial uint32, records int) zone.Zone {
+	z := zone.Zone{
+		Origin: "bosh.",
+		SOA: &dns.SOA{
+			Hdr:    dns.RR_Header{Name: "bosh.", Rrtype: dns.TypeSOA, Class: dns.ClassINET},
+			Ns:     "ns1.example.com.",
+			Mbox:   "hostmaster.bosh.",
+			Serial: serial,
+		},
+		Records: []dns.RR{&dns.NS{
+			Hdr: dns.RR_Header{Name: "bosh.", Rrtype: dns.TypeNS, Class: dns.ClassINET},
+			Ns:  "ns1.example.com.",
+		}},
+	}
+
+	for i := 0; i < records; i++ {
+		z.Records = append(z.Records, &dns.A{
+			Hdr: dns.RR_Header{Name: fmt.Sprintf("instance%d.web.default.cf.bosh.", i), Rrtype: dns.TypeA, Class: dns.ClassINET},
+			A:   net.IPv4(10, 0, byte(i/256), byte(i%256)),
+		})
+	}
+
+	return z
+}
+
+func signedRequest(m *dns.Msg) *dns.Msg {
+	m.SetTsig("transfer.", dns.HmacSHA256, 300, time.Now().Unix())
+	return m
+}
+
+var _ = Describe("ZoneTransferHandler", func() {
+	var (
+		next        dns.Handler
+		nextCalls   int
+		zones       *handlersfakes.FakeZoneSource
+		fakeWriter  *internalfakes.FakeResponseWriter
+		fakeLogger  *loggerfakes.FakeLogger
+		fakeClock   *fakeclock.FakeClock
+		handler     handlers.ZoneTransferHandler
+		secondaries []*net.IPNet
+	)
+
+	BeforeEach(func() {
+		nextCalls = 0
+		next = dns.HandlerFunc(func(dns.ResponseWriter, *dns.Msg) {
+			nextCalls++
+		})
+		zones = &handlersfakes.FakeZoneSource{}
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		fakeLogger = &loggerfakes.FakeLogger{}
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+
+		_, network, err := net.ParseCIDR("10.1.0.0/16")
+		Expect(err).NotTo(HaveOccurred())
+		secondaries = []*net.IPNet{network}
+
+		zones.ZoneStub = func(origin string) (zone.Zone, bool) {
+			if origin == "bosh." {
+				return testZone(7, 2), true
+			}
+			return zone.Zone{}, false
+		}
+
+		fakeWriter.RemoteAddrReturns(&net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5353})
+
+		handler = handlers.NewZoneTransferHandler(next, zones, secondaries, []string{"transfer."}, fakeClock, fakeLogger)
+	})
+
+	It("passes ordinary queries on", func() {
+		m := &dns.Msg{}
+		m.SetQuestion("instance0.web.default.cf.bosh.", dns.TypeA)
+
+		handler.ServeDNS(fakeWriter, m)
+
+		Expect(nextCalls).To(Equal(1))
+		Expect(fakeWriter.WriteMsgCallCount()).To(Equal(0))
+	})
+
+	It("answers SOA queries at the zone apex", func() {
+		m := &dns.Msg{}
+		m.SetQuestion("bosh.", dns.TypeSOA)
+
+		handler.ServeDNS(fakeWriter, m)
+
+		Expect(nextCalls).To(Equal(0))
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Authoritative).To(BeTrue())
+		Expect(response.Answer).To(HaveLen(1))
+		Expect(response.Answer[0].(*dns.SOA).Serial).To(Equal(uint32(7)))
+	})
+
+	It("answers NS queries at the zone apex", func() {
+		m := &dns.Msg{}
+		m.SetQuestion("bosh.", dns.TypeNS)
+
+		handler.ServeDNS(fakeWriter, m)
+
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Answer).To(HaveLen(1))
+		Expect(response.Answer[0].(*dns.NS).Ns).To(Equal("ns1.example.com."))
+	})
+
+	It("passes SOA queries below the apex on", func() {
+		m := &dns.Msg{}
+		m.SetQuestion("web.bosh.", dns.TypeSOA)
+
+		handler.ServeDNS(fakeWriter, m)
+
+		Expect(nextCalls).To(Equal(1))
+	})
+
+	It("sends the zone in signed messages", func() {
+		handler.ServeDNS(fakeWriter, signedRequest((&dns.Msg{}).SetAxfr("bosh.")))
+
+		Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Rcode).To(Equal(dns.RcodeSuccess))
+		Expect(response.Answer).To(Equal(testZone(7, 2).AXFR()))
+		Expect(response.IsTsig()).NotTo(BeNil())
+		Expect(response.IsTsig().Hdr.Name).To(Equal("transfer."))
+	})
+
+	It("splits large zones across messages without ending one on an inner SOA", func() {
+		zones.ZoneStub = func(string) (zone.Zone, bool) { return testZone(7, 600), true }
+
+		handler.ServeDNS(fakeWriter, signedRequest((&dns.Msg{}).SetAxfr("bosh.")))
+
+		Expect(fakeWriter.WriteMsgCallCount()).To(Equal(3))
+		total := 0
+		for i := 0; i < 3; i++ {
+			total += len(fakeWriter.WriteMsgArgsForCall(i).Answer)
+		}
+		Expect(total).To(Equal(603))
+
+		Expect(fakeWriter.TsigTimersOnlyCallCount()).To(Equal(2))
+	})
+
+	It("sends the changes since the requested serial for IXFR", func() {
+		zones.IXFRReturns([]dns.RR{testZone(7, 0).SOA}, true)
+
+		handler.ServeDNS(fakeWriter, signedRequest((&dns.Msg{}).SetIxfr("bosh.", 7, "ns1.example.com.", "hostmaster.bosh.")))
+
+		origin, serial := zones.IXFRArgsForCall(0)
+		Expect(origin).To(Equal("bosh."))
+		Expect(serial).To(Equal(uint32(7)))
+
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Answer).To(HaveLen(1))
+	})
+
+	It("sends only the SOA for IXFR over UDP when there are changes", func() {
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5353})
+		zones.IXFRReturns(testZone(7, 2).AXFR(), true)
+
+		handler.ServeDNS(fakeWriter, signedRequest((&dns.Msg{}).SetIxfr("bosh.", 3, "ns1.example.com.", "hostmaster.bosh.")))
+
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Answer).To(HaveLen(1))
+		Expect(response.Answer[0].Header().Rrtype).To(Equal(dns.TypeSOA))
+	})
+
+	It("rejects AXFR over UDP", func() {
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5353})
+
+		handler.ServeDNS(fakeWriter, signedRequest((&dns.Msg{}).SetAxfr("bosh.")))
+
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeFormatError))
+	})
+
+	It("refuses transfers to hosts which are not secondaries", func() {
+		fakeWriter.RemoteAddrReturns(&net.TCPAddr{IP: net.ParseIP("10.2.0.1"), Port: 5353})
+
+		handler.ServeDNS(fakeWriter, signedRequest((&dns.Msg{}).SetAxfr("bosh.")))
+
+		response := fakeWriter.WriteMsgArgsForCall(0)
+		Expect(response.Rcode).To(Equal(dns.RcodeRefused))
+		Expect(response.Answer).To(BeEmpty())
+
+		_, message, _ := fakeLogger.InfoArgsForCall(0)
+		Expect(message).To(ContainSubstring("not an allowed secondary"))
+	})
+
+	It("refuses unsigned transfers", func() {
+		handler.ServeDNS(fakeWriter, (&dns.Msg{}).SetAxfr("bosh."))
+
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeRefused))
+	})
+
+	It("refuses transfers with an invalid signature", func() {
+		fakeWriter.TsigStatusReturns(errors.New("dns: bad signature"))
+
+		handler.ServeDNS(fakeWriter, signedRequest((&dns.Msg{}).SetAxfr("bosh.")))
+
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeNotAuth))
+	})
+
+	It("refuses transfers signed with keys not allowed to transfer", func() {
+		m := (&dns.Msg{}).SetAxfr("bosh.")
+		m.SetTsig("updates.", dns.HmacSHA256, 300, time.Now().Unix())
+
+		handler.ServeDNS(fakeWriter, m)
+
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeNotAuth))
+	})
+
+	It("refuses transfers of unknown zones", func() {
+		handler.ServeDNS(fakeWriter, signedRequest((&dns.Msg{}).SetAxfr("example.com.")))
+
+		Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeNotAuth))
+	})
+
+	Context("when serving secondaries over TCP", func() {
+		var (
+			server  *dns.Server
+			address string
+		)
+
+		BeforeEach(func() {
+			zones.ZoneStub = func(string) (zone.Zone, bool) { return testZone(7, 600), true }
+
+			_, loopback, err := net.ParseCIDR("127.0.0.0/8")
+			Expect(err).NotTo(HaveOccurred())
+			handler = handlers.NewZoneTransferHandler(next, zones, []*net.IPNet{loopback}, []string{"transfer."}, clock.NewClock(), fakeLogger)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			address = listener.Addr().String()
+
+			started := make(chan struct{})
+			server = &dns.Server{
+				Listener:          listener,
+				Handler:           handler,
+				TsigSecret:        map[string]string{"transfer.": transferKeySecret},
+				NotifyStartedFunc: func() { close(started) },
+			}
+			go server.ActivateAndServe()
+			Eventually(started).Should(BeClosed())
+		})
+
+		AfterEach(func() {
+			server.Shutdown()
+		})
+
+		It("transfers the whole zone to a TSIG verifying client", func() {
+			transfer := &dns.Transfer{TsigSecret: map[string]string{"transfer.": transferKeySecret}}
+			envelopes, err := transfer.In(signedRequest((&dns.Msg{}).SetAxfr("bosh.")), address)
+			Expect(err).NotTo(HaveOccurred())
+
+			received := []dns.RR{}
+			for envelope := range envelopes {
+				Expect(envelope.Error).NotTo(HaveOccurred())
+				received = append(received, envelope.RR...)
+			}
+
+			Expect(received).To(HaveLen(603))
+			Expect(received[0].(*dns.SOA).Serial).To(Equal(uint32(7)))
+		})
+
+		It("fails verification with the wrong key", func() {
+			transfer := &dns.Transfer{TsigSecret: map[string]string{"transfer.": "d3Jvbmctd3Jvbmctd3Jvbmc="}}
+			envelopes, err := transfer.In(signedRequest((&dns.Msg{}).SetAxfr("bosh.")), address)
+			Expect(err).NotTo(HaveOccurred())
+
+			envelope := <-envelopes
+			Expect(envelope.Error).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,16 @@
+package server
+
+//go:generate counterfeiter . Handoff
+
+// Handoff passes the listening sockets of a server to the process replacing
+// it, so that restarts do not drop queries.
+type Handoff interface {
+	// Release tells the process the sockets were inherited from that this
+	// process serves, so that it can drain and exit.
+	Release() error
+
+	// Accept blocks until a successor has taken over the sockets of the
+	// servers currently serving, which returns nil, or until signal is
+	// closed.
+	Accept(signal <-chan struct{}, servers func() []DNSServer) error
+}
@@ -0,0 +1,106 @@
+package healthiness
+
+import "sync"
+
+// checkQueue hands out IPs to check, first-seen IPs ahead of periodic
+// re-checks. An IP is never queued twice nor queued while it is being
+// checked.
+type checkQueue struct {
+	cond *sync.Cond
+
+	priority []string
+	regular  []string
+	pending  map[string]struct{}
+	inFlight map[string]struct{}
+	closed   bool
+}
+
+func newCheckQueue() *checkQueue {
+	return &checkQueue{
+		cond:     sync.NewCond(&sync.Mutex{}),
+		pending:  map[string]struct{}{},
+		inFlight: map[string]struct{}{},
+	}
+}
+
+func (q *checkQueue) push(ip string, priority bool) bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	if _, ok := q.pending[ip]; ok {
+		return false
+	}
+
+	if _, ok := q.inFlight[ip]; ok {
+		return false
+	}
+
+	q.pending[ip] = struct{}{}
+	if priority {
+		q.priority = append(q.priority, ip)
+	} else {
+		q.regular = append(q.regular, ip)
+	}
+
+	q.cond.Signal()
+
+	return true
+}
+
+// pop blocks until an IP is available or the queue is closed.
+func (q *checkQueue) pop() (string, bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	for len(q.priority) == 0 && len(q.regular) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+
+	if q.closed {
+		return "", false
+	}
+
+	var ip string
+	if len(q.priority) > 0 {
+		ip, q.priority = q.priority[0], q.priority[1:]
+	} else {
+		ip, q.regular = q.regular[0], q.regular[1:]
+	}
+
+	delete(q.pending, ip)
+	q.inFlight[ip] = struct{}{}
+
+	return ip, true
+}
+
+func (q *checkQueue) done(ip string) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	delete(q.inFlight, ip)
+}
+
+func (q *checkQueue) queued(ip string) bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	_, pending := q.pending[ip]
+	_, inFlight := q.inFlight[ip]
+
+	return pending || inFlight
+}
+
+func (q *checkQueue) depth() (int, int) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	return len(q.priority) + len(q.regular), len(q.inFlight)
+}
+
+func (q *checkQueue) close() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
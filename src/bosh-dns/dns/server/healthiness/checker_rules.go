@@ -0,0 +1,106 @@
+package healthiness
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"bosh-dns/dns/config"
+
+	"github.com/miekg/dns"
+)
+
+const defaultProbeTimeout = 5 * time.Second
+
+// NewCheckerRules builds the per alias and per instance group checkers
+// described by the health.checkers configuration. mtlsClient is used by
+// "mtls" checkers, which talk to the healthcheck job on a custom port, and may
+// be nil when there are none.
+func NewCheckerRules(configs []config.HealthCheckerConfig, mtlsClient HTTPClientGetter) ([]CheckerRule, error) {
+	rules := []CheckerRule{}
+
+	for _, c := range configs {
+		checker, err := newChecker(c, mtlsClient)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, CheckerRule{
+			Alias:         dnsFqdnOrEmpty(c.Alias),
+			InstanceGroup: c.InstanceGroup,
+			Checker:       checker,
+		})
+	}
+
+	return rules, nil
+}
+
+func newChecker(c config.HealthCheckerConfig, mtlsClient HTTPClientGetter) (HealthChecker, error) {
+	timeout := time.Duration(c.Timeout)
+	if timeout == 0 {
+		timeout = defaultProbeTimeout
+	}
+
+	switch c.Type {
+	case config.HealthCheckerMTLS:
+		return NewHealthChecker(mtlsClient, c.Port), nil
+	case config.HealthCheckerTCP:
+		return NewTCPHealthChecker(NewNetDialer(), c.Port, timeout), nil
+	case config.HealthCheckerHTTP, config.HealthCheckerHTTPS:
+		path := c.Path
+		if path == "" {
+			path = "/"
+		}
+
+		expectedStatus := c.ExpectedStatus
+		if expectedStatus == 0 {
+			expectedStatus = http.StatusOK
+		}
+
+		client := &http.Client{Timeout: timeout}
+		if c.CAFile != "" || c.ServerName != "" {
+			tlsConfig, err := httpsClientConfig(c)
+			if err != nil {
+				return nil, err
+			}
+			client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+		}
+
+		return NewHTTPHealthChecker(client, c.Type, c.Port, path, expectedStatus), nil
+	case config.HealthCheckerDNS:
+		return NewDNSHealthChecker(&dns.Client{Net: "udp", Timeout: timeout}, c.Port, c.Query), nil
+	}
+
+	return nil, fmt.Errorf("unknown health checker type '%s'", c.Type)
+}
+
+// httpsClientConfig verifies servers against the CA of c instead of the
+// system roots, and against its server name instead of the checked IP.
+func httpsClientConfig(c config.HealthCheckerConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: c.ServerName}
+
+	if c.CAFile != "" {
+		caCert, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in CA file '%s'", c.CAFile)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+func dnsFqdnOrEmpty(domain string) string {
+	if domain == "" {
+		return ""
+	}
+
+	return dns.Fqdn(domain)
+}
@@ -0,0 +1,84 @@
+package healthiness_test
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/healthiness"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewCheckerRules", func() {
+	Describe("https checkers", func() {
+		var (
+			server *httptest.Server
+			port   int
+			dir    string
+			caFile string
+		)
+
+		BeforeEach(func() {
+			server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			_, portString, err := net.SplitHostPort(server.Listener.Addr().String())
+			Expect(err).NotTo(HaveOccurred())
+			port, err = strconv.Atoi(portString)
+			Expect(err).NotTo(HaveOccurred())
+
+			dir, err = ioutil.TempDir("", "checker-rules")
+			Expect(err).NotTo(HaveOccurred())
+
+			caFile = filepath.Join(dir, "ca.pem")
+			caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]})
+			Expect(ioutil.WriteFile(caFile, caPEM, 0644)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			server.Close()
+			os.RemoveAll(dir)
+		})
+
+		rulesFor := func(c config.HealthCheckerConfig) []healthiness.CheckerRule {
+			rules, err := healthiness.NewCheckerRules([]config.HealthCheckerConfig{c}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rules).To(HaveLen(1))
+			return rules
+		}
+
+		It("verifies the server against the configured CA and server name", func() {
+			rules := rulesFor(config.HealthCheckerConfig{InstanceGroup: "db", Type: "https", Port: port, CAFile: caFile, ServerName: "example.com"})
+
+			Expect(rules[0].Checker.GetStatus("127.0.0.1")).To(BeTrue())
+		})
+
+		It("rejects servers not signed by the configured CA", func() {
+			rules := rulesFor(config.HealthCheckerConfig{InstanceGroup: "db", Type: "https", Port: port})
+
+			Expect(rules[0].Checker.GetStatus("127.0.0.1")).To(BeFalse())
+		})
+
+		It("rejects servers with another name", func() {
+			rules := rulesFor(config.HealthCheckerConfig{InstanceGroup: "db", Type: "https", Port: port, CAFile: caFile, ServerName: "example.org"})
+
+			Expect(rules[0].Checker.GetStatus("127.0.0.1")).To(BeFalse())
+		})
+
+		It("errors when the CA file cannot be read", func() {
+			_, err := healthiness.NewCheckerRules([]config.HealthCheckerConfig{
+				{InstanceGroup: "db", Type: "https", Port: port, CAFile: filepath.Join(dir, "missing.pem")},
+			}, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,43 @@
+package healthiness
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+//go:generate counterfeiter . DNSExchanger
+
+type DNSExchanger interface {
+	Exchange(m *dns.Msg, address string) (*dns.Msg, time.Duration, error)
+}
+
+type dnsHealthChecker struct {
+	exchanger DNSExchanger
+	port      int
+	question  string
+}
+
+// NewDNSHealthChecker considers an instance healthy when it successfully
+// answers an A query for the given name on the given port.
+func NewDNSHealthChecker(exchanger DNSExchanger, port int, question string) HealthChecker {
+	return &dnsHealthChecker{
+		exchanger: exchanger,
+		port:      port,
+		question:  dns.Fqdn(question),
+	}
+}
+
+func (hc *dnsHealthChecker) GetStatus(ip string) bool {
+	m := &dns.Msg{}
+	m.SetQuestion(hc.question, dns.TypeA)
+
+	response, _, err := hc.exchanger.Exchange(m, net.JoinHostPort(ip, strconv.Itoa(hc.port)))
+	if err != nil {
+		return false
+	}
+
+	return response.Rcode == dns.RcodeSuccess
+}
@@ -0,0 +1,47 @@
+package healthiness_test
+
+import (
+	"errors"
+
+	"bosh-dns/dns/server/healthiness"
+	"bosh-dns/dns/server/healthiness/healthinessfakes"
+
+	"github.com/miekg/dns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DNSHealthChecker", func() {
+	var (
+		exchanger     *healthinessfakes.FakeDNSExchanger
+		healthChecker healthiness.HealthChecker
+	)
+
+	BeforeEach(func() {
+		exchanger = &healthinessfakes.FakeDNSExchanger{}
+		healthChecker = healthiness.NewDNSHealthChecker(exchanger, 53, "upcheck.bosh-dns")
+	})
+
+	It("is healthy when the query succeeds", func() {
+		exchanger.ExchangeReturns(&dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeSuccess}}, 0, nil)
+
+		Expect(healthChecker.GetStatus("10.0.0.1")).To(BeTrue())
+
+		msg, address := exchanger.ExchangeArgsForCall(0)
+		Expect(address).To(Equal("10.0.0.1:53"))
+		Expect(msg.Question).To(Equal([]dns.Question{{Name: "upcheck.bosh-dns.", Qtype: dns.TypeA, Qclass: dns.ClassINET}}))
+	})
+
+	It("is unhealthy when the query fails", func() {
+		exchanger.ExchangeReturns(&dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeServerFailure}}, 0, nil)
+
+		Expect(healthChecker.GetStatus("10.0.0.1")).To(BeFalse())
+	})
+
+	It("is unhealthy when the exchange errors", func() {
+		exchanger.ExchangeReturns(nil, 0, errors.New("i/o timeout"))
+
+		Expect(healthChecker.GetStatus("10.0.0.1")).To(BeFalse())
+	})
+})
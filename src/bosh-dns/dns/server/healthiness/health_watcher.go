@@ -1,11 +1,13 @@
 package healthiness
 
 import (
+	"expvar"
+	"math/rand"
 	"sync"
 	"time"
 
 	"code.cloudfoundry.org/clock"
-	"code.cloudfoundry.org/workpool"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
 //go:generate counterfeiter . HealthChecker
@@ -20,43 +22,103 @@ type HealthWatcher interface {
 	IsHealthy(ip string) bool
 	Untrack(ip string)
 	Run(signal <-chan struct{})
+	Stats() HealthWatcherStats
+}
+
+const (
+	healthWatcherLogTag = "HealthWatcher"
+
+	// checks are scheduled up to this fraction of the interval early so that
+	// IPs first seen together do not stay in lock step
+	jitterFraction = 0.1
+
+	// how often Run logs the watcher stats at info level
+	statsLogInterval = time.Minute
+)
+
+// HealthWatcherStats are published as JSON by PublishStats, with latencies
+// in nanoseconds.
+type HealthWatcherStats struct {
+	Tracked        int           `json:"tracked"`
+	QueueDepth     int           `json:"queue_depth"`
+	InFlight       int           `json:"in_flight"`
+	Checks         uint64        `json:"checks"`
+	LastLatency    time.Duration `json:"last_latency_ns"`
+	AverageLatency time.Duration `json:"average_latency_ns"`
+	MaxLatency     time.Duration `json:"max_latency_ns"`
+}
+
+// PublishStats publishes the current stats of watcher as the expvar name,
+// which must not be published yet.
+func PublishStats(name string, watcher HealthWatcher) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return watcher.Stats()
+	}))
+}
+
+type ipState struct {
+	healthy   bool
+	checked   bool
+	nextCheck time.Time
 }
 
 type healthWatcher struct {
 	checker       HealthChecker
 	checkInterval time.Duration
+	concurrency   int
 	clock         clock.Clock
+	logger        boshlog.Logger
+
+	queue      *checkQueue
+	state      map[string]*ipState
+	stateMutex *sync.RWMutex
 
-	checkWorkPool *workpool.WorkPool
-	state         map[string]bool
-	stateMutex    *sync.RWMutex
+	statsMutex   *sync.Mutex
+	checks       uint64
+	totalLatency time.Duration
+	lastLatency  time.Duration
+	maxLatency   time.Duration
 }
 
-func NewHealthWatcher(checker HealthChecker, clock clock.Clock, checkInterval time.Duration) *healthWatcher {
-	wp, _ := workpool.NewWorkPool(1000)
+func NewHealthWatcher(checker HealthChecker, clock clock.Clock, checkInterval time.Duration, concurrency int, logger boshlog.Logger) *healthWatcher {
+	if concurrency < 1 {
+		concurrency = 1
+	}
 
 	return &healthWatcher{
 		checker:       checker,
 		checkInterval: checkInterval,
+		concurrency:   concurrency,
 		clock:         clock,
+		logger:        logger,
 
-		checkWorkPool: wp,
-		state:         map[string]bool{},
-		stateMutex:    &sync.RWMutex{},
+		queue:      newCheckQueue(),
+		state:      map[string]*ipState{},
+		stateMutex: &sync.RWMutex{},
+		statsMutex: &sync.Mutex{},
 	}
 }
 
+// IsHealthy optimistically reports unknown IPs as healthy and queues their
+// first check ahead of any periodic re-checks.
 func (hw *healthWatcher) IsHealthy(ip string) bool {
 	hw.stateMutex.RLock()
-	defer hw.stateMutex.RUnlock()
+	state, found := hw.state[ip]
+	if found && state.checked {
+		defer hw.stateMutex.RUnlock()
+		return state.healthy
+	}
+	hw.stateMutex.RUnlock()
 
-	if health, found := hw.state[ip]; found {
-		return health
+	if !found {
+		hw.stateMutex.Lock()
+		if _, found = hw.state[ip]; !found {
+			hw.state[ip] = &ipState{}
+		}
+		hw.stateMutex.Unlock()
 	}
 
-	hw.checkWorkPool.Submit(func() {
-		hw.runCheck(ip)
-	})
+	hw.queue.push(ip, true)
 
 	return true
 }
@@ -67,35 +129,156 @@ func (hw *healthWatcher) Untrack(ip string) {
 	hw.stateMutex.Unlock()
 }
 
+func (hw *healthWatcher) Stats() HealthWatcherStats {
+	hw.stateMutex.RLock()
+	tracked := len(hw.state)
+	hw.stateMutex.RUnlock()
+
+	queueDepth, inFlight := hw.queue.depth()
+
+	hw.statsMutex.Lock()
+	defer hw.statsMutex.Unlock()
+
+	stats := HealthWatcherStats{
+		Tracked:     tracked,
+		QueueDepth:  queueDepth,
+		InFlight:    inFlight,
+		Checks:      hw.checks,
+		LastLatency: hw.lastLatency,
+		MaxLatency:  hw.maxLatency,
+	}
+
+	if hw.checks > 0 {
+		stats.AverageLatency = hw.totalLatency / time.Duration(hw.checks)
+	}
+
+	return stats
+}
+
 func (hw *healthWatcher) Run(signal <-chan struct{}) {
+	defer hw.queue.close()
+
+	for i := 0; i < hw.concurrency; i++ {
+		go hw.work()
+	}
+
 	timer := hw.clock.NewTimer(hw.checkInterval)
 	defer timer.Stop()
 
+	lastStatsLog := hw.clock.Now()
+
 	for {
 		select {
 		case <-timer.C():
-			hw.stateMutex.RLock()
-			for ip := range hw.state {
-				// closing on ip, we need to ensure it's fixed within this context
-				ip := ip
-				hw.checkWorkPool.Submit(func() {
-					hw.runCheck(ip)
-				})
+			enqueued := hw.enqueueDue()
+
+			stats := hw.Stats()
+			hw.logger.Debug(healthWatcherLogTag, "queued %d re-checks; tracked %d, queue depth %d, in flight %d, average check latency %s, max %s", enqueued, stats.Tracked, stats.QueueDepth, stats.InFlight, stats.AverageLatency, stats.MaxLatency)
+
+			if now := hw.clock.Now(); now.Sub(lastStatsLog) >= statsLogInterval {
+				hw.logger.Info(healthWatcherLogTag, "tracked %d, queue depth %d, in flight %d, %d checks, last check latency %s, average %s, max %s", stats.Tracked, stats.QueueDepth, stats.InFlight, stats.Checks, stats.LastLatency, stats.AverageLatency, stats.MaxLatency)
+				lastStatsLog = now
 			}
-			hw.stateMutex.RUnlock()
 
-			timer.Reset(hw.checkInterval)
+			timer.Reset(hw.untilNextDue())
 		case <-signal:
 			return
 		}
 	}
 }
 
+func (hw *healthWatcher) enqueueDue() int {
+	now := hw.clock.Now()
+	due := []string{}
+
+	hw.stateMutex.RLock()
+	for ip, state := range hw.state {
+		if state.checked && !now.Before(state.nextCheck) {
+			due = append(due, ip)
+		}
+	}
+	hw.stateMutex.RUnlock()
+
+	enqueued := 0
+	for _, ip := range due {
+		if hw.queue.push(ip, false) {
+			enqueued++
+		}
+	}
+
+	return enqueued
+}
+
+func (hw *healthWatcher) untilNextDue() time.Duration {
+	now := hw.clock.Now()
+	next := hw.checkInterval
+	minimum := hw.checkInterval / 20
+
+	hw.stateMutex.RLock()
+	defer hw.stateMutex.RUnlock()
+
+	for ip, state := range hw.state {
+		if !state.checked || hw.queue.queued(ip) {
+			continue
+		}
+
+		if until := state.nextCheck.Sub(now); until < next {
+			next = until
+		}
+	}
+
+	if next < minimum {
+		return minimum
+	}
+
+	return next
+}
+
+func (hw *healthWatcher) work() {
+	for {
+		ip, ok := hw.queue.pop()
+		if !ok {
+			return
+		}
+
+		hw.runCheck(ip)
+		hw.queue.done(ip)
+	}
+}
+
 func (hw *healthWatcher) runCheck(ip string) {
+	start := hw.clock.Now()
 	status := hw.checker.GetStatus(ip)
+	finish := hw.clock.Now()
+
+	hw.recordLatency(finish.Sub(start))
 
 	hw.stateMutex.Lock()
 	defer hw.stateMutex.Unlock()
 
-	hw.state[ip] = status
+	state, found := hw.state[ip]
+	if !found {
+		return
+	}
+
+	state.healthy = status
+	state.checked = true
+	state.nextCheck = finish.Add(hw.jitteredInterval())
+}
+
+func (hw *healthWatcher) jitteredInterval() time.Duration {
+	jitter := time.Duration(rand.Int63n(int64(float64(hw.checkInterval)*jitterFraction) + 1))
+	return hw.checkInterval - jitter
+}
+
+func (hw *healthWatcher) recordLatency(latency time.Duration) {
+	hw.statsMutex.Lock()
+	defer hw.statsMutex.Unlock()
+
+	hw.checks++
+	hw.totalLatency += latency
+	hw.lastLatency = latency
+	if latency > hw.maxLatency {
+		hw.maxLatency = latency
+	}
 }
@@ -1,12 +1,16 @@
 package healthiness_test
 
 import (
+	"encoding/json"
+	"expvar"
+	"fmt"
 	"time"
 
 	"bosh-dns/dns/server/healthiness"
 	"bosh-dns/dns/server/healthiness/healthinessfakes"
 
 	"code.cloudfoundry.org/clock/fakeclock"
+	loggerfakes "github.com/cloudfoundry/bosh-utils/logger/fakes"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -16,7 +20,9 @@ var _ = Describe("HealthWatcher", func() {
 	var (
 		fakeChecker *healthinessfakes.FakeHealthChecker
 		fakeClock   *fakeclock.FakeClock
+		fakeLogger  *loggerfakes.FakeLogger
 		interval    time.Duration
+		concurrency int
 		signal      chan struct{}
 		stopped     chan struct{}
 
@@ -26,10 +32,15 @@ var _ = Describe("HealthWatcher", func() {
 	BeforeEach(func() {
 		fakeChecker = &healthinessfakes.FakeHealthChecker{}
 		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeLogger = &loggerfakes.FakeLogger{}
 		interval = time.Second
-		healthWatcher = healthiness.NewHealthWatcher(fakeChecker, fakeClock, interval)
+		concurrency = 5
 		signal = make(chan struct{})
 		stopped = make(chan struct{})
+	})
+
+	JustBeforeEach(func() {
+		healthWatcher = healthiness.NewHealthWatcher(fakeChecker, fakeClock, interval, concurrency, fakeLogger)
 
 		go func() {
 			healthWatcher.Run(signal)
@@ -113,7 +124,7 @@ var _ = Describe("HealthWatcher", func() {
 	Describe("Untrack", func() {
 		var ip string
 
-		BeforeEach(func() {
+		JustBeforeEach(func() {
 			ip = "127.0.0.2"
 			healthWatcher.IsHealthy(ip)
 			Eventually(fakeChecker.GetStatusCallCount).Should(Equal(1))
@@ -126,4 +137,124 @@ var _ = Describe("HealthWatcher", func() {
 			Consistently(fakeChecker.GetStatusCallCount).Should(Equal(1))
 		})
 	})
+
+	Describe("scheduling", func() {
+		var release chan struct{}
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			fakeChecker.GetStatusStub = func(string) bool {
+				<-release
+				return true
+			}
+		})
+
+		AfterEach(func() {
+			close(release)
+		})
+
+		It("checks an IP only once while its check is pending", func() {
+			for i := 0; i < 10; i++ {
+				Expect(healthWatcher.IsHealthy("127.0.0.1")).To(BeTrue())
+			}
+
+			Eventually(fakeChecker.GetStatusCallCount).Should(Equal(1))
+			Consistently(fakeChecker.GetStatusCallCount).Should(Equal(1))
+		})
+
+		Context("with limited concurrency", func() {
+			BeforeEach(func() {
+				concurrency = 2
+			})
+
+			It("runs no more checks at once than allowed and exposes the queue depth", func() {
+				for i := 0; i < 5; i++ {
+					healthWatcher.IsHealthy(fmt.Sprintf("127.0.0.%d", i))
+				}
+
+				Eventually(fakeChecker.GetStatusCallCount).Should(Equal(2))
+				Consistently(fakeChecker.GetStatusCallCount).Should(Equal(2))
+
+				Expect(healthWatcher.Stats().InFlight).To(Equal(2))
+				Expect(healthWatcher.Stats().QueueDepth).To(Equal(3))
+				Expect(healthWatcher.Stats().Tracked).To(Equal(5))
+			})
+		})
+
+		Context("when first-seen IPs arrive while re-checks are queued", func() {
+			BeforeEach(func() {
+				concurrency = 1
+			})
+
+			It("checks the first-seen IPs first", func() {
+				healthWatcher.IsHealthy("127.0.0.1")
+				healthWatcher.IsHealthy("127.0.0.2")
+				release <- struct{}{}
+				release <- struct{}{}
+				Eventually(func() uint64 { return healthWatcher.Stats().Checks }).Should(Equal(uint64(2)))
+
+				healthWatcher.IsHealthy("127.0.0.3")
+				Eventually(fakeChecker.GetStatusCallCount).Should(Equal(3))
+
+				fakeClock.WaitForWatcherAndIncrement(interval)
+				Eventually(func() int { return healthWatcher.Stats().QueueDepth }).Should(Equal(2))
+
+				healthWatcher.IsHealthy("127.0.0.4")
+
+				release <- struct{}{}
+				Eventually(fakeChecker.GetStatusCallCount).Should(Equal(4))
+				Expect(fakeChecker.GetStatusArgsForCall(3)).To(Equal("127.0.0.4"))
+			})
+		})
+	})
+
+	Describe("PublishStats", func() {
+		It("publishes the current stats as an expvar", func() {
+			watcher := &healthinessfakes.FakeHealthWatcher{}
+			watcher.StatsReturns(healthiness.HealthWatcherStats{
+				Tracked:        3,
+				QueueDepth:     2,
+				InFlight:       1,
+				Checks:         10,
+				LastLatency:    time.Millisecond,
+				AverageLatency: 2 * time.Millisecond,
+				MaxLatency:     5 * time.Millisecond,
+			})
+
+			healthiness.PublishStats("health_watcher_test", watcher)
+
+			var published map[string]interface{}
+			Expect(json.Unmarshal([]byte(expvar.Get("health_watcher_test").String()), &published)).To(Succeed())
+			Expect(published).To(Equal(map[string]interface{}{
+				"tracked":            3.0,
+				"queue_depth":        2.0,
+				"in_flight":          1.0,
+				"checks":             10.0,
+				"last_latency_ns":    1e6,
+				"average_latency_ns": 2e6,
+				"max_latency_ns":     5e6,
+			}))
+
+			watcher.StatsReturns(healthiness.HealthWatcherStats{QueueDepth: 7})
+			Expect(expvar.Get("health_watcher_test").String()).To(ContainSubstring(`"queue_depth":7`))
+		})
+	})
+
+	Describe("Run", func() {
+		BeforeEach(func() {
+			interval = time.Minute
+			fakeChecker.GetStatusReturns(true)
+		})
+
+		It("logs the stats every minute", func() {
+			healthWatcher.IsHealthy("127.0.0.1")
+			Eventually(func() uint64 { return healthWatcher.Stats().Checks }).Should(Equal(uint64(1)))
+
+			fakeClock.WaitForWatcherAndIncrement(interval)
+
+			Eventually(fakeLogger.InfoCallCount).Should(Equal(1))
+			_, template, args := fakeLogger.InfoArgsForCall(0)
+			Expect(fmt.Sprintf(template, args...)).To(HavePrefix("tracked 1, queue depth "))
+		})
+	})
 })
@@ -7,10 +7,10 @@ import (
 )
 
 type FakeHealthWatcher struct {
-	IsHealthyStub        func(ip string) bool
+	IsHealthyStub        func(string) bool
 	isHealthyMutex       sync.RWMutex
 	isHealthyArgsForCall []struct {
-		ip string
+		arg1 string
 	}
 	isHealthyReturns struct {
 		result1 bool
@@ -18,35 +18,47 @@ type FakeHealthWatcher struct {
 	isHealthyReturnsOnCall map[int]struct {
 		result1 bool
 	}
-	UntrackStub        func(ip string)
-	untrackMutex       sync.RWMutex
-	untrackArgsForCall []struct {
-		ip string
-	}
-	RunStub        func(signal <-chan struct{})
+	RunStub        func(<-chan struct{})
 	runMutex       sync.RWMutex
 	runArgsForCall []struct {
-		signal <-chan struct{}
+		arg1 <-chan struct{}
+	}
+	StatsStub        func() healthiness.HealthWatcherStats
+	statsMutex       sync.RWMutex
+	statsArgsForCall []struct {
+	}
+	statsReturns struct {
+		result1 healthiness.HealthWatcherStats
+	}
+	statsReturnsOnCall map[int]struct {
+		result1 healthiness.HealthWatcherStats
+	}
+	UntrackStub        func(string)
+	untrackMutex       sync.RWMutex
+	untrackArgsForCall []struct {
+		arg1 string
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeHealthWatcher) IsHealthy(ip string) bool {
+func (fake *FakeHealthWatcher) IsHealthy(arg1 string) bool {
 	fake.isHealthyMutex.Lock()
 	ret, specificReturn := fake.isHealthyReturnsOnCall[len(fake.isHealthyArgsForCall)]
 	fake.isHealthyArgsForCall = append(fake.isHealthyArgsForCall, struct {
-		ip string
-	}{ip})
-	fake.recordInvocation("IsHealthy", []interface{}{ip})
+		arg1 string
+	}{arg1})
+	stub := fake.IsHealthyStub
+	fakeReturns := fake.isHealthyReturns
+	fake.recordInvocation("IsHealthy", []interface{}{arg1})
 	fake.isHealthyMutex.Unlock()
-	if fake.IsHealthyStub != nil {
-		return fake.IsHealthyStub(ip)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	return fake.isHealthyReturns.result1
+	return fakeReturns.result1
 }
 
 func (fake *FakeHealthWatcher) IsHealthyCallCount() int {
@@ -55,13 +67,22 @@ func (fake *FakeHealthWatcher) IsHealthyCallCount() int {
 	return len(fake.isHealthyArgsForCall)
 }
 
+func (fake *FakeHealthWatcher) IsHealthyCalls(stub func(string) bool) {
+	fake.isHealthyMutex.Lock()
+	defer fake.isHealthyMutex.Unlock()
+	fake.IsHealthyStub = stub
+}
+
 func (fake *FakeHealthWatcher) IsHealthyArgsForCall(i int) string {
 	fake.isHealthyMutex.RLock()
 	defer fake.isHealthyMutex.RUnlock()
-	return fake.isHealthyArgsForCall[i].ip
+	argsForCall := fake.isHealthyArgsForCall[i]
+	return argsForCall.arg1
 }
 
 func (fake *FakeHealthWatcher) IsHealthyReturns(result1 bool) {
+	fake.isHealthyMutex.Lock()
+	defer fake.isHealthyMutex.Unlock()
 	fake.IsHealthyStub = nil
 	fake.isHealthyReturns = struct {
 		result1 bool
@@ -69,6 +90,8 @@ func (fake *FakeHealthWatcher) IsHealthyReturns(result1 bool) {
 }
 
 func (fake *FakeHealthWatcher) IsHealthyReturnsOnCall(i int, result1 bool) {
+	fake.isHealthyMutex.Lock()
+	defer fake.isHealthyMutex.Unlock()
 	fake.IsHealthyStub = nil
 	if fake.isHealthyReturnsOnCall == nil {
 		fake.isHealthyReturnsOnCall = make(map[int]struct {
@@ -80,39 +103,16 @@ func (fake *FakeHealthWatcher) IsHealthyReturnsOnCall(i int, result1 bool) {
 	}{result1}
 }
 
-func (fake *FakeHealthWatcher) Untrack(ip string) {
-	fake.untrackMutex.Lock()
-	fake.untrackArgsForCall = append(fake.untrackArgsForCall, struct {
-		ip string
-	}{ip})
-	fake.recordInvocation("Untrack", []interface{}{ip})
-	fake.untrackMutex.Unlock()
-	if fake.UntrackStub != nil {
-		fake.UntrackStub(ip)
-	}
-}
-
-func (fake *FakeHealthWatcher) UntrackCallCount() int {
-	fake.untrackMutex.RLock()
-	defer fake.untrackMutex.RUnlock()
-	return len(fake.untrackArgsForCall)
-}
-
-func (fake *FakeHealthWatcher) UntrackArgsForCall(i int) string {
-	fake.untrackMutex.RLock()
-	defer fake.untrackMutex.RUnlock()
-	return fake.untrackArgsForCall[i].ip
-}
-
-func (fake *FakeHealthWatcher) Run(signal <-chan struct{}) {
+func (fake *FakeHealthWatcher) Run(arg1 <-chan struct{}) {
 	fake.runMutex.Lock()
 	fake.runArgsForCall = append(fake.runArgsForCall, struct {
-		signal <-chan struct{}
-	}{signal})
-	fake.recordInvocation("Run", []interface{}{signal})
+		arg1 <-chan struct{}
+	}{arg1})
+	stub := fake.RunStub
+	fake.recordInvocation("Run", []interface{}{arg1})
 	fake.runMutex.Unlock()
-	if fake.RunStub != nil {
-		fake.RunStub(signal)
+	if stub != nil {
+		fake.RunStub(arg1)
 	}
 }
 
@@ -122,10 +122,102 @@ func (fake *FakeHealthWatcher) RunCallCount() int {
 	return len(fake.runArgsForCall)
 }
 
+func (fake *FakeHealthWatcher) RunCalls(stub func(<-chan struct{})) {
+	fake.runMutex.Lock()
+	defer fake.runMutex.Unlock()
+	fake.RunStub = stub
+}
+
 func (fake *FakeHealthWatcher) RunArgsForCall(i int) <-chan struct{} {
 	fake.runMutex.RLock()
 	defer fake.runMutex.RUnlock()
-	return fake.runArgsForCall[i].signal
+	argsForCall := fake.runArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeHealthWatcher) Stats() healthiness.HealthWatcherStats {
+	fake.statsMutex.Lock()
+	ret, specificReturn := fake.statsReturnsOnCall[len(fake.statsArgsForCall)]
+	fake.statsArgsForCall = append(fake.statsArgsForCall, struct {
+	}{})
+	stub := fake.StatsStub
+	fakeReturns := fake.statsReturns
+	fake.recordInvocation("Stats", []interface{}{})
+	fake.statsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeHealthWatcher) StatsCallCount() int {
+	fake.statsMutex.RLock()
+	defer fake.statsMutex.RUnlock()
+	return len(fake.statsArgsForCall)
+}
+
+func (fake *FakeHealthWatcher) StatsCalls(stub func() healthiness.HealthWatcherStats) {
+	fake.statsMutex.Lock()
+	defer fake.statsMutex.Unlock()
+	fake.StatsStub = stub
+}
+
+func (fake *FakeHealthWatcher) StatsReturns(result1 healthiness.HealthWatcherStats) {
+	fake.statsMutex.Lock()
+	defer fake.statsMutex.Unlock()
+	fake.StatsStub = nil
+	fake.statsReturns = struct {
+		result1 healthiness.HealthWatcherStats
+	}{result1}
+}
+
+func (fake *FakeHealthWatcher) StatsReturnsOnCall(i int, result1 healthiness.HealthWatcherStats) {
+	fake.statsMutex.Lock()
+	defer fake.statsMutex.Unlock()
+	fake.StatsStub = nil
+	if fake.statsReturnsOnCall == nil {
+		fake.statsReturnsOnCall = make(map[int]struct {
+			result1 healthiness.HealthWatcherStats
+		})
+	}
+	fake.statsReturnsOnCall[i] = struct {
+		result1 healthiness.HealthWatcherStats
+	}{result1}
+}
+
+func (fake *FakeHealthWatcher) Untrack(arg1 string) {
+	fake.untrackMutex.Lock()
+	fake.untrackArgsForCall = append(fake.untrackArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.UntrackStub
+	fake.recordInvocation("Untrack", []interface{}{arg1})
+	fake.untrackMutex.Unlock()
+	if stub != nil {
+		fake.UntrackStub(arg1)
+	}
+}
+
+func (fake *FakeHealthWatcher) UntrackCallCount() int {
+	fake.untrackMutex.RLock()
+	defer fake.untrackMutex.RUnlock()
+	return len(fake.untrackArgsForCall)
+}
+
+func (fake *FakeHealthWatcher) UntrackCalls(stub func(string)) {
+	fake.untrackMutex.Lock()
+	defer fake.untrackMutex.Unlock()
+	fake.UntrackStub = stub
+}
+
+func (fake *FakeHealthWatcher) UntrackArgsForCall(i int) string {
+	fake.untrackMutex.RLock()
+	defer fake.untrackMutex.RUnlock()
+	argsForCall := fake.untrackArgsForCall[i]
+	return argsForCall.arg1
 }
 
 func (fake *FakeHealthWatcher) Invocations() map[string][][]interface{} {
@@ -133,10 +225,12 @@ func (fake *FakeHealthWatcher) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.isHealthyMutex.RLock()
 	defer fake.isHealthyMutex.RUnlock()
-	fake.untrackMutex.RLock()
-	defer fake.untrackMutex.RUnlock()
 	fake.runMutex.RLock()
 	defer fake.runMutex.RUnlock()
+	fake.statsMutex.RLock()
+	defer fake.statsMutex.RUnlock()
+	fake.untrackMutex.RLock()
+	defer fake.untrackMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,46 @@
+package healthiness
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+)
+
+type httpHealthChecker struct {
+	client         HTTPClientGetter
+	scheme         string
+	port           int
+	path           string
+	expectedStatus int
+}
+
+// NewHTTPHealthChecker considers an instance healthy when a GET against the
+// given path answers with the expected status code. Unlike the default
+// checker it needs neither the healthcheck job nor mutual TLS.
+func NewHTTPHealthChecker(client HTTPClientGetter, scheme string, port int, path string, expectedStatus int) HealthChecker {
+	return &httpHealthChecker{
+		client:         client,
+		scheme:         scheme,
+		port:           port,
+		path:           path,
+		expectedStatus: expectedStatus,
+	}
+}
+
+func (hc *httpHealthChecker) GetStatus(ip string) bool {
+	endpoint := fmt.Sprintf("%s://%s%s", hc.scheme, net.JoinHostPort(ip, strconv.Itoa(hc.port)), hc.path)
+
+	response, err := hc.client.Get(endpoint)
+	if err != nil {
+		return false
+	}
+
+	if response.Body != nil {
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+		_ = response.Body.Close()
+	}
+
+	return response.StatusCode == hc.expectedStatus
+}
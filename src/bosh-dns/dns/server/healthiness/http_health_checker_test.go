@@ -0,0 +1,59 @@
+package healthiness_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"bosh-dns/dns/server/healthiness"
+	"bosh-dns/dns/server/healthiness/healthinessfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTPHealthChecker", func() {
+	var (
+		fakeClient    *healthinessfakes.FakeHTTPClientGetter
+		healthChecker healthiness.HealthChecker
+	)
+
+	respond := func(code int) {
+		fakeClient.GetReturns(&http.Response{
+			StatusCode: code,
+			Body:       ioutil.NopCloser(bytes.NewBufferString("ok")),
+		}, nil)
+	}
+
+	BeforeEach(func() {
+		fakeClient = &healthinessfakes.FakeHTTPClientGetter{}
+		healthChecker = healthiness.NewHTTPHealthChecker(fakeClient, "https", 8443, "/healthz", http.StatusNoContent)
+	})
+
+	It("is healthy when the expected status is returned", func() {
+		respond(http.StatusNoContent)
+
+		Expect(healthChecker.GetStatus("10.0.0.1")).To(BeTrue())
+		Expect(fakeClient.GetArgsForCall(0)).To(Equal("https://10.0.0.1:8443/healthz"))
+	})
+
+	It("brackets IPv6 addresses", func() {
+		respond(http.StatusNoContent)
+
+		Expect(healthChecker.GetStatus("::1")).To(BeTrue())
+		Expect(fakeClient.GetArgsForCall(0)).To(Equal("https://[::1]:8443/healthz"))
+	})
+
+	It("is unhealthy when another status is returned", func() {
+		respond(http.StatusOK)
+
+		Expect(healthChecker.GetStatus("10.0.0.1")).To(BeFalse())
+	})
+
+	It("is unhealthy when the request fails", func() {
+		fakeClient.GetReturns(nil, errors.New("fake-err"))
+
+		Expect(healthChecker.GetStatus("10.0.0.1")).To(BeFalse())
+	})
+})
@@ -0,0 +1,14 @@
+package healthiness
+
+type nopHealthChecker struct{}
+
+// NewNopHealthChecker considers every instance healthy. It is the default
+// checker when only health.checkers are configured, without the healthcheck
+// job.
+func NewNopHealthChecker() HealthChecker {
+	return &nopHealthChecker{}
+}
+
+func (hc *nopHealthChecker) GetStatus(ip string) bool {
+	return true
+}
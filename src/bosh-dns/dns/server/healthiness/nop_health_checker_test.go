@@ -0,0 +1,14 @@
+package healthiness_test
+
+import (
+	"bosh-dns/dns/server/healthiness"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NopHealthChecker", func() {
+	It("is always healthy", func() {
+		Expect(healthiness.NewNopHealthChecker().GetStatus("127.0.0.1")).To(BeTrue())
+	})
+})
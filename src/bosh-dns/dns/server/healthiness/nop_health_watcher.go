@@ -12,6 +12,10 @@ func (hw *nopHealthWatcher) IsHealthy(ip string) bool {
 
 func (hw *nopHealthWatcher) Untrack(ip string) {}
 
+func (hw *nopHealthWatcher) Stats() HealthWatcherStats {
+	return HealthWatcherStats{}
+}
+
 func (hw *nopHealthWatcher) Run(signal <-chan struct{}) {
 	<-signal
 }
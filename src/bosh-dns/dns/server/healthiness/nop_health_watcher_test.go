@@ -42,4 +42,10 @@ var _ = Describe("NopHealthWatcher", func() {
 			Expect(healthWatcher.IsHealthy(ip)).To(BeTrue())
 		})
 	})
+
+	Describe("Stats", func() {
+		It("tracks nothing", func() {
+			Expect(healthWatcher.Stats()).To(Equal(healthiness.HealthWatcherStats{}))
+		})
+	})
 })
@@ -0,0 +1,82 @@
+package healthiness
+
+import "sync"
+
+//go:generate counterfeiter . HealthCheckTargets
+
+// HealthCheckTargets tells which instance groups and aliases an IP belongs
+// to, so that a checker can be chosen for it.
+type HealthCheckTargets interface {
+	InstanceGroupsForIP(ip string) []string
+	AliasResolvesToIP(alias, ip string) bool
+}
+
+type CheckerRule struct {
+	Alias         string
+	InstanceGroup string
+	Checker       HealthChecker
+}
+
+type RoutingHealthChecker struct {
+	defaultChecker HealthChecker
+	rules          []CheckerRule
+
+	targets      HealthCheckTargets
+	targetsMutex *sync.RWMutex
+}
+
+// NewRoutingHealthChecker delegates to the checker of the first rule whose
+// alias or instance group matches the IP, falling back to defaultChecker.
+// Rules only start matching once targets have been set.
+func NewRoutingHealthChecker(defaultChecker HealthChecker, rules []CheckerRule) *RoutingHealthChecker {
+	return &RoutingHealthChecker{
+		defaultChecker: defaultChecker,
+		rules:          rules,
+		targetsMutex:   &sync.RWMutex{},
+	}
+}
+
+func (hc *RoutingHealthChecker) SetTargets(targets HealthCheckTargets) {
+	hc.targetsMutex.Lock()
+	defer hc.targetsMutex.Unlock()
+
+	hc.targets = targets
+}
+
+func (hc *RoutingHealthChecker) GetStatus(ip string) bool {
+	return hc.checkerFor(ip).GetStatus(ip)
+}
+
+func (hc *RoutingHealthChecker) checkerFor(ip string) HealthChecker {
+	hc.targetsMutex.RLock()
+	targets := hc.targets
+	hc.targetsMutex.RUnlock()
+
+	if targets == nil || len(hc.rules) == 0 {
+		return hc.defaultChecker
+	}
+
+	var groups map[string]struct{}
+
+	for _, rule := range hc.rules {
+		if rule.Alias != "" {
+			if targets.AliasResolvesToIP(rule.Alias, ip) {
+				return rule.Checker
+			}
+			continue
+		}
+
+		if groups == nil {
+			groups = map[string]struct{}{}
+			for _, group := range targets.InstanceGroupsForIP(ip) {
+				groups[group] = struct{}{}
+			}
+		}
+
+		if _, ok := groups[rule.InstanceGroup]; ok {
+			return rule.Checker
+		}
+	}
+
+	return hc.defaultChecker
+}
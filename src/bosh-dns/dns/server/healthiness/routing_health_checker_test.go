@@ -0,0 +1,100 @@
+package healthiness_test
+
+import (
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/healthiness"
+	"bosh-dns/dns/server/healthiness/healthinessfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RoutingHealthChecker", func() {
+	var (
+		defaultChecker *healthinessfakes.FakeHealthChecker
+		groupChecker   *healthinessfakes.FakeHealthChecker
+		aliasChecker   *healthinessfakes.FakeHealthChecker
+		targets        *healthinessfakes.FakeHealthCheckTargets
+		healthChecker  *healthiness.RoutingHealthChecker
+	)
+
+	BeforeEach(func() {
+		defaultChecker = &healthinessfakes.FakeHealthChecker{}
+		groupChecker = &healthinessfakes.FakeHealthChecker{}
+		aliasChecker = &healthinessfakes.FakeHealthChecker{}
+		targets = &healthinessfakes.FakeHealthCheckTargets{}
+
+		defaultChecker.GetStatusReturns(true)
+
+		healthChecker = healthiness.NewRoutingHealthChecker(defaultChecker, []healthiness.CheckerRule{
+			{Alias: "legacy.internal.", Checker: aliasChecker},
+			{InstanceGroup: "db", Checker: groupChecker},
+		})
+	})
+
+	It("uses the default checker until targets are set", func() {
+		Expect(healthChecker.GetStatus("10.0.0.1")).To(BeTrue())
+		Expect(defaultChecker.GetStatusCallCount()).To(Equal(1))
+		Expect(targets.InstanceGroupsForIPCallCount()).To(Equal(0))
+	})
+
+	Context("when targets are set", func() {
+		BeforeEach(func() {
+			healthChecker.SetTargets(targets)
+		})
+
+		It("uses the checker of a matching alias", func() {
+			targets.AliasResolvesToIPReturns(true)
+			aliasChecker.GetStatusReturns(false)
+
+			Expect(healthChecker.GetStatus("10.0.0.1")).To(BeFalse())
+			Expect(aliasChecker.GetStatusArgsForCall(0)).To(Equal("10.0.0.1"))
+
+			alias, ip := targets.AliasResolvesToIPArgsForCall(0)
+			Expect(alias).To(Equal("legacy.internal."))
+			Expect(ip).To(Equal("10.0.0.1"))
+		})
+
+		It("uses the checker of a matching instance group", func() {
+			targets.InstanceGroupsForIPReturns([]string{"web", "db"})
+			groupChecker.GetStatusReturns(true)
+
+			Expect(healthChecker.GetStatus("10.0.0.1")).To(BeTrue())
+			Expect(groupChecker.GetStatusCallCount()).To(Equal(1))
+			Expect(defaultChecker.GetStatusCallCount()).To(Equal(0))
+		})
+
+		It("falls back to the default checker", func() {
+			targets.InstanceGroupsForIPReturns([]string{"web"})
+
+			Expect(healthChecker.GetStatus("10.0.0.1")).To(BeTrue())
+			Expect(defaultChecker.GetStatusCallCount()).To(Equal(1))
+		})
+	})
+})
+
+var _ = Describe("NewCheckerRules", func() {
+	It("builds a rule per configuration", func() {
+		rules, err := healthiness.NewCheckerRules([]config.HealthCheckerConfig{
+			{Alias: "legacy.internal", Type: "http", Port: 80},
+			{InstanceGroup: "db", Type: "tcp", Port: 5432},
+			{InstanceGroup: "dns", Type: "dns", Port: 53, Query: "upcheck.bosh-dns."},
+			{InstanceGroup: "other", Type: "mtls", Port: 8853},
+		}, &healthinessfakes.FakeHTTPClientGetter{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rules).To(HaveLen(4))
+		Expect(rules[0].Alias).To(Equal("legacy.internal."))
+		Expect(rules[1].InstanceGroup).To(Equal("db"))
+		for _, rule := range rules {
+			Expect(rule.Checker).NotTo(BeNil())
+		}
+	})
+
+	It("errors on unknown types", func() {
+		_, err := healthiness.NewCheckerRules([]config.HealthCheckerConfig{
+			{InstanceGroup: "db", Type: "icmp", Port: 1},
+		}, &healthinessfakes.FakeHTTPClientGetter{})
+		Expect(err).To(MatchError("unknown health checker type 'icmp'"))
+	})
+})
@@ -0,0 +1,50 @@
+package healthiness
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+//go:generate counterfeiter . Dialer
+
+type Dialer interface {
+	DialTimeout(network, address string, timeout time.Duration) (net.Conn, error)
+}
+
+type netDialer struct{}
+
+func NewNetDialer() Dialer {
+	return netDialer{}
+}
+
+func (netDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout(network, address, timeout)
+}
+
+type tcpHealthChecker struct {
+	dialer  Dialer
+	port    int
+	timeout time.Duration
+}
+
+// NewTCPHealthChecker considers an instance healthy when a TCP connection to
+// the given port can be established.
+func NewTCPHealthChecker(dialer Dialer, port int, timeout time.Duration) HealthChecker {
+	return &tcpHealthChecker{
+		dialer:  dialer,
+		port:    port,
+		timeout: timeout,
+	}
+}
+
+func (hc *tcpHealthChecker) GetStatus(ip string) bool {
+	conn, err := hc.dialer.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(hc.port)), hc.timeout)
+	if err != nil {
+		return false
+	}
+
+	_ = conn.Close()
+
+	return true
+}
@@ -0,0 +1,71 @@
+package healthiness_test
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"bosh-dns/dns/server/healthiness"
+	"bosh-dns/dns/server/healthiness/healthinessfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TCPHealthChecker", func() {
+	var (
+		dialer        *healthinessfakes.FakeDialer
+		healthChecker healthiness.HealthChecker
+	)
+
+	BeforeEach(func() {
+		dialer = &healthinessfakes.FakeDialer{}
+		healthChecker = healthiness.NewTCPHealthChecker(dialer, 5432, 2*time.Second)
+	})
+
+	It("is healthy when the port accepts connections", func() {
+		client, server := net.Pipe()
+		defer server.Close()
+		dialer.DialTimeoutReturns(client, nil)
+
+		Expect(healthChecker.GetStatus("10.0.0.1")).To(BeTrue())
+
+		network, address, timeout := dialer.DialTimeoutArgsForCall(0)
+		Expect(network).To(Equal("tcp"))
+		Expect(address).To(Equal("10.0.0.1:5432"))
+		Expect(timeout).To(Equal(2 * time.Second))
+	})
+
+	It("brackets IPv6 addresses", func() {
+		client, server := net.Pipe()
+		defer server.Close()
+		dialer.DialTimeoutReturns(client, nil)
+
+		Expect(healthChecker.GetStatus("::1")).To(BeTrue())
+
+		_, address, _ := dialer.DialTimeoutArgsForCall(0)
+		Expect(address).To(Equal("[::1]:5432"))
+	})
+
+	It("is unhealthy when the connection fails", func() {
+		dialer.DialTimeoutReturns(nil, errors.New("connection refused"))
+
+		Expect(healthChecker.GetStatus("10.0.0.1")).To(BeFalse())
+	})
+
+	Context("with a real listener", func() {
+		It("connects to it", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+
+			port := listener.Addr().(*net.TCPAddr).Port
+			healthChecker = healthiness.NewTCPHealthChecker(healthiness.NewNetDialer(), port, time.Second)
+
+			Expect(healthChecker.GetStatus("127.0.0.1")).To(BeTrue())
+
+			listener.Close()
+			Expect(healthChecker.GetStatus("127.0.0.1")).To(BeFalse())
+		})
+	})
+})
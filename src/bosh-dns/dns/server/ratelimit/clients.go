@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"net"
+)
+
+// Clients groups client addresses into the prefixes limits apply to.
+type Clients struct {
+	ipv4Mask net.IPMask
+	ipv6Mask net.IPMask
+	exempt   []*net.IPNet
+}
+
+func NewClients(ipv4PrefixLength, ipv6PrefixLength int, exempt []*net.IPNet) Clients {
+	return Clients{
+		ipv4Mask: net.CIDRMask(ipv4PrefixLength, 32),
+		ipv6Mask: net.CIDRMask(ipv6PrefixLength, 128),
+		exempt:   exempt,
+	}
+}
+
+// Prefix returns the prefix of the client at addr, or false when the client
+// is exempt from limits.
+func (c Clients) Prefix(addr net.Addr) (string, bool) {
+	var ip net.IP
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		ip = addr.IP
+	case *net.TCPAddr:
+		ip = addr.IP
+	default:
+		return "", false
+	}
+
+	for _, network := range c.exempt {
+		if network.Contains(ip) {
+			return "", false
+		}
+	}
+
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return ipv4.Mask(c.ipv4Mask).String(), true
+	}
+
+	return ip.Mask(c.ipv6Mask).String(), true
+}
@@ -0,0 +1,42 @@
+package ratelimit_test
+
+import (
+	"net"
+
+	"bosh-dns/dns/server/ratelimit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Clients", func() {
+	var clients ratelimit.Clients
+
+	BeforeEach(func() {
+		_, exempt, err := net.ParseCIDR("192.168.0.0/16")
+		Expect(err).NotTo(HaveOccurred())
+
+		clients = ratelimit.NewClients(24, 56, []*net.IPNet{exempt})
+	})
+
+	It("groups IPv4 clients by prefix", func() {
+		prefix, limited := clients.Prefix(&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 53})
+		Expect(limited).To(BeTrue())
+		Expect(prefix).To(Equal("10.1.2.0"))
+
+		prefix, limited = clients.Prefix(&net.TCPAddr{IP: net.ParseIP("10.1.2.200"), Port: 53})
+		Expect(limited).To(BeTrue())
+		Expect(prefix).To(Equal("10.1.2.0"))
+	})
+
+	It("groups IPv6 clients by prefix", func() {
+		prefix, limited := clients.Prefix(&net.UDPAddr{IP: net.ParseIP("2001:db8:1:2:3::1"), Port: 53})
+		Expect(limited).To(BeTrue())
+		Expect(prefix).To(Equal("2001:db8:1::"))
+	})
+
+	It("does not limit exempt clients", func() {
+		_, limited := clients.Prefix(&net.UDPAddr{IP: net.ParseIP("192.168.5.5"), Port: 53})
+		Expect(limited).To(BeFalse())
+	})
+})
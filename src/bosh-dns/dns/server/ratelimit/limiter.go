@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	logTag = "RateLimit"
+
+	reportInterval = time.Minute
+
+	// maxTrackedKeys bounds the memory used by a limiter under a flood of
+	// spoofed sources.
+	maxTrackedKeys = 100000
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	denied int
+}
+
+// Limiter keeps a token bucket per key, which refills at rate tokens per
+// second and holds at most one second worth of tokens.
+type Limiter struct {
+	name   string
+	rate   int
+	clock  clock.Clock
+	logger boshlog.Logger
+
+	mutex     *sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	dropped   uint64
+}
+
+// NewLimiter returns a limiter allowing rate events per second and key. The
+// name describes the limited events in the log.
+func NewLimiter(name string, rate int, clock clock.Clock, logger boshlog.Logger) *Limiter {
+	return &Limiter{
+		name:   name,
+		rate:   rate,
+		clock:  clock,
+		logger: logger,
+
+		mutex:   &sync.Mutex{},
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow takes a token from the bucket of key and reports whether there was
+// one left.
+func (l *Limiter) Allow(key string) bool {
+	allowed, _ := l.take(key)
+	return allowed
+}
+
+// take returns, along with whether the event is allowed, how many events of
+// key have been denied in a row.
+func (l *Limiter) take(key string) (bool, int) {
+	now := l.clock.Now()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		l.makeRoom(now)
+
+		b = &bucket{tokens: float64(l.rate), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * float64(l.rate)
+	if b.tokens > float64(l.rate) {
+		b.tokens = float64(l.rate)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.denied = 0
+		return true, 0
+	}
+
+	b.denied++
+	l.dropped++
+	return false, b.denied
+}
+
+// makeRoom forgets buckets which have refilled completely once the limiter
+// tracks too many keys, or any bucket when it pruned just before.
+func (l *Limiter) makeRoom(now time.Time) {
+	if len(l.buckets) < maxTrackedKeys {
+		return
+	}
+
+	if now.Sub(l.lastPrune) >= time.Second {
+		l.lastPrune = now
+
+		for key, b := range l.buckets {
+			if now.Sub(b.last) >= time.Second {
+				delete(l.buckets, key)
+			}
+		}
+	}
+
+	for key := range l.buckets {
+		if len(l.buckets) < maxTrackedKeys {
+			break
+		}
+
+		delete(l.buckets, key)
+	}
+}
+
+// Run logs how many events the limiter denied, once a minute.
+func (l *Limiter) Run(signal <-chan struct{}) {
+	ticker := l.clock.NewTicker(reportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-signal:
+			return
+		case <-ticker.C():
+			l.mutex.Lock()
+			dropped := l.dropped
+			l.dropped = 0
+			l.mutex.Unlock()
+
+			if dropped > 0 {
+				l.logger.Info(logTag, "Dropped %d %s over the limit of %d per second", dropped, l.name, l.rate)
+			}
+		}
+	}
+}
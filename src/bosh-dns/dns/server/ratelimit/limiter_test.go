@@ -0,0 +1,97 @@
+package ratelimit_test
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/ratelimit"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Limiter", func() {
+	var (
+		fakeClock  *fakeclock.FakeClock
+		fakeLogger *loggerfakes.FakeLogger
+		limiter    *ratelimit.Limiter
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeLogger = &loggerfakes.FakeLogger{}
+		limiter = ratelimit.NewLimiter("queries", 3, fakeClock, fakeLogger)
+	})
+
+	It("allows a burst of one second worth of events per key", func() {
+		for i := 0; i < 3; i++ {
+			Expect(limiter.Allow("10.0.0.0")).To(BeTrue())
+		}
+		Expect(limiter.Allow("10.0.0.0")).To(BeFalse())
+
+		By("tracking keys separately")
+		Expect(limiter.Allow("10.0.1.0")).To(BeTrue())
+	})
+
+	It("refills buckets over time", func() {
+		for i := 0; i < 3; i++ {
+			Expect(limiter.Allow("10.0.0.0")).To(BeTrue())
+		}
+		Expect(limiter.Allow("10.0.0.0")).To(BeFalse())
+
+		fakeClock.Increment(time.Second / 2)
+		Expect(limiter.Allow("10.0.0.0")).To(BeTrue())
+		Expect(limiter.Allow("10.0.0.0")).To(BeFalse())
+
+		fakeClock.Increment(time.Hour)
+		for i := 0; i < 3; i++ {
+			Expect(limiter.Allow("10.0.0.0")).To(BeTrue())
+		}
+		Expect(limiter.Allow("10.0.0.0")).To(BeFalse())
+	})
+
+	It("keeps limiting when many keys are tracked", func() {
+		for i := 0; i < 100001; i++ {
+			limiter.Allow(fmt.Sprintf("key-%d", i))
+		}
+
+		for i := 0; i < 3; i++ {
+			Expect(limiter.Allow("10.0.0.0")).To(BeTrue())
+		}
+		Expect(limiter.Allow("10.0.0.0")).To(BeFalse())
+	})
+
+	Describe("Run", func() {
+		var signal chan struct{}
+
+		BeforeEach(func() {
+			signal = make(chan struct{})
+			go limiter.Run(signal)
+		})
+
+		AfterEach(func() {
+			close(signal)
+		})
+
+		It("logs the number of denied events every minute", func() {
+			for i := 0; i < 5; i++ {
+				limiter.Allow("10.0.0.0")
+			}
+
+			fakeClock.WaitForWatcherAndIncrement(time.Minute)
+			Eventually(fakeLogger.InfoCallCount).Should(Equal(1))
+
+			tag, message, args := fakeLogger.InfoArgsForCall(0)
+			Expect(tag).To(Equal("RateLimit"))
+			Expect(fmt.Sprintf(message, args...)).To(Equal("Dropped 2 queries over the limit of 3 per second"))
+
+			By("not logging when nothing was denied")
+			fakeClock.Increment(time.Minute)
+			Consistently(fakeLogger.InfoCallCount).Should(Equal(1))
+		})
+	})
+})
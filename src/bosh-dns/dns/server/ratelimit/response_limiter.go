@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/clock"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+type Action int
+
+const (
+	Send Action = iota
+	Slip
+	Drop
+)
+
+// ResponseLimiter limits how many identical responses each client prefix
+// receives per second, as response rate limiting (RRL) does. Every slip-th
+// response over the limit is sent truncated, so that legitimate clients
+// retry over TCP, while the others are dropped.
+type ResponseLimiter struct {
+	limiter *Limiter
+	slip    int
+}
+
+func NewResponseLimiter(rate, slip int, clock clock.Clock, logger boshlog.Logger) *ResponseLimiter {
+	return &ResponseLimiter{
+		limiter: NewLimiter("identical responses", rate, clock, logger),
+		slip:    slip,
+	}
+}
+
+// Limit tells what to do with response m to the client at prefix.
+func (r *ResponseLimiter) Limit(prefix string, m *dns.Msg) Action {
+	allowed, denied := r.limiter.take(prefix + " " + responseKey(m))
+	if allowed {
+		return Send
+	}
+
+	if r.slip > 0 && denied%r.slip == 0 {
+		return Slip
+	}
+
+	return Drop
+}
+
+// Run logs how many responses were dropped or slipped, once a minute.
+func (r *ResponseLimiter) Run(signal <-chan struct{}) {
+	r.limiter.Run(signal)
+}
+
+// responseKey tells which responses are identical: answers by name and
+// type, name errors by the zone they come from and other errors by rcode.
+func responseKey(m *dns.Msg) string {
+	name, qtype := "", uint16(0)
+	if len(m.Question) > 0 {
+		name, qtype = strings.ToLower(m.Question[0].Name), m.Question[0].Qtype
+	}
+
+	switch m.Rcode {
+	case dns.RcodeSuccess:
+		return fmt.Sprintf("%s %d", name, qtype)
+	case dns.RcodeNameError:
+		for _, rr := range m.Ns {
+			if rr.Header().Rrtype == dns.TypeSOA {
+				return "nxdomain " + strings.ToLower(rr.Header().Name)
+			}
+		}
+
+		return "nxdomain " + name
+	default:
+		return fmt.Sprintf("error %d", m.Rcode)
+	}
+}
@@ -0,0 +1,85 @@
+package ratelimit_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/ratelimit"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResponseLimiter", func() {
+	var (
+		limiter *ratelimit.ResponseLimiter
+		slip    int
+	)
+
+	JustBeforeEach(func() {
+		limiter = ratelimit.NewResponseLimiter(1, slip, fakeclock.NewFakeClock(time.Now()), &loggerfakes.FakeLogger{})
+	})
+
+	answer := func(name string, qtype uint16) *dns.Msg {
+		m := &dns.Msg{}
+		m.SetQuestion(name, qtype)
+		return m
+	}
+
+	nameError := func(name, zone string) *dns.Msg {
+		m := answer(name, dns.TypeA)
+		m.Rcode = dns.RcodeNameError
+		m.Ns = []dns.RR{&dns.SOA{Hdr: dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET}}}
+		return m
+	}
+
+	Context("when slipping every second response", func() {
+		BeforeEach(func() {
+			slip = 2
+		})
+
+		It("drops and slips identical responses over the limit in turn", func() {
+			Expect(limiter.Limit("10.0.0.0", answer("a.bosh.", dns.TypeA))).To(Equal(ratelimit.Send))
+			Expect(limiter.Limit("10.0.0.0", answer("a.bosh.", dns.TypeA))).To(Equal(ratelimit.Drop))
+			Expect(limiter.Limit("10.0.0.0", answer("A.bosh.", dns.TypeA))).To(Equal(ratelimit.Slip))
+			Expect(limiter.Limit("10.0.0.0", answer("a.bosh.", dns.TypeA))).To(Equal(ratelimit.Drop))
+
+			By("limiting other responses and clients separately")
+			Expect(limiter.Limit("10.0.0.0", answer("a.bosh.", dns.TypeAAAA))).To(Equal(ratelimit.Send))
+			Expect(limiter.Limit("10.0.1.0", answer("a.bosh.", dns.TypeA))).To(Equal(ratelimit.Send))
+		})
+
+		It("treats name errors from the same zone as identical", func() {
+			Expect(limiter.Limit("10.0.0.0", nameError("random-1.bosh.", "bosh."))).To(Equal(ratelimit.Send))
+			Expect(limiter.Limit("10.0.0.0", nameError("random-2.bosh.", "bosh."))).To(Equal(ratelimit.Drop))
+			Expect(limiter.Limit("10.0.0.0", nameError("random-3.other.", "other."))).To(Equal(ratelimit.Send))
+		})
+
+		It("treats errors with the same rcode as identical", func() {
+			refused := answer("a.bosh.", dns.TypeA)
+			refused.Rcode = dns.RcodeRefused
+			otherRefused := answer("b.bosh.", dns.TypeA)
+			otherRefused.Rcode = dns.RcodeRefused
+
+			Expect(limiter.Limit("10.0.0.0", refused)).To(Equal(ratelimit.Send))
+			Expect(limiter.Limit("10.0.0.0", otherRefused)).To(Equal(ratelimit.Drop))
+		})
+	})
+
+	Context("when never slipping", func() {
+		BeforeEach(func() {
+			slip = 0
+		})
+
+		It("drops every response over the limit", func() {
+			Expect(limiter.Limit("10.0.0.0", answer("a.bosh.", dns.TypeA))).To(Equal(ratelimit.Send))
+			for i := 0; i < 4; i++ {
+				Expect(limiter.Limit("10.0.0.0", answer("a.bosh.", dns.TypeA))).To(Equal(ratelimit.Drop))
+			}
+		})
+	})
+})
@@ -68,6 +68,20 @@ func Field(field string, values []string) Matcher {
 	return FieldMatcher("", "")
 }
 
+// matcherFor matches the records a query with criteria c resolves to.
+func matcherFor(c criteria) Matcher {
+	matcher := new(AndMatcher)
+	for field, values := range c {
+		// healthiness is not handled by the normal recordset
+		if field == "s" {
+			continue
+		}
+		matcher.Append(Field(field, values))
+	}
+
+	return matcher
+}
+
 type MatcherFunc func(r *Record) bool
 
 func (m MatcherFunc) Match(r *Record) bool {
@@ -5,12 +5,30 @@ import (
 	"net"
 )
 
-func TruncateIfNeeded(responseWriter dns.ResponseWriter, resp *dns.Msg) {
+// TruncateIfNeeded echoes the OPT record of request in resp and drops
+// answers from resp until it fits the payload size the client advertised,
+// or 512 bytes for clients without EDNS(0). Names are compressed before any
+// answer is dropped.
+func TruncateIfNeeded(responseWriter dns.ResponseWriter, request *dns.Msg, resp *dns.Msg) {
 	maxLength := dns.MaxMsgSize
 	_, isUDP := responseWriter.RemoteAddr().(*net.UDPAddr)
 
 	if isUDP {
-		maxLength = 512
+		maxLength = dns.MinMsgSize
+	}
+
+	if opt := request.IsEdns0(); opt != nil {
+		if resp.IsEdns0() == nil {
+			resp.SetEdns0(opt.UDPSize(), false)
+		}
+
+		if isUDP && int(opt.UDPSize()) > maxLength {
+			maxLength = int(opt.UDPSize())
+		}
+	}
+
+	if resp.Len() > maxLength {
+		resp.Compress = true
 	}
 
 	numAnswers := len(resp.Answer)
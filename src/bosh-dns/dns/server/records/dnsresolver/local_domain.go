@@ -44,7 +44,7 @@ func (d LocalDomain) Resolve(questionDomains []string, responseWriter dns.Respon
 	responseMsg.Answer = answers
 	responseMsg.SetRcode(requestMsg, rCode)
 
-	TruncateIfNeeded(responseWriter, responseMsg)
+	TruncateIfNeeded(responseWriter, requestMsg, responseMsg)
 
 	return responseMsg
 }
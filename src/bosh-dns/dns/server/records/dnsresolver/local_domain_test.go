@@ -2,6 +2,7 @@ package dnsresolver_test
 
 import (
 	"errors"
+	"fmt"
 	"net"
 
 	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
@@ -136,7 +137,7 @@ var _ = Describe("LocalDomain", func() {
 			})
 
 			Context("when the request is udp", func() {
-				It("truncates the response", func() {
+				It("compresses the response", func() {
 					responseMsg := localDomain.Resolve(
 						[]string{"my-instance.my-group.my-network.my-deployment.bosh."},
 						fakeWriter,
@@ -144,9 +145,68 @@ var _ = Describe("LocalDomain", func() {
 					)
 
 					Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
-					Expect(len(responseMsg.Answer)).To(Equal(6))
-					Expect(responseMsg.Truncated).To(Equal(true))
-					Expect(responseMsg.Len()).To(BeNumerically("<", 512))
+					Expect(responseMsg.Compress).To(BeTrue())
+					Expect(len(responseMsg.Answer)).To(Equal(7))
+					Expect(responseMsg.Truncated).To(Equal(false))
+					Expect(responseMsg.Len()).To(BeNumerically("<=", 512))
+				})
+
+				Context("and the compressed message is still too long", func() {
+					BeforeEach(func() {
+						ips := []string{}
+						for i := 0; i < 40; i++ {
+							ips = append(ips, fmt.Sprintf("127.0.0.%d", i))
+						}
+						fakeRecordSet.ResolveReturns(ips, nil)
+					})
+
+					It("truncates the response", func() {
+						responseMsg := localDomain.Resolve(
+							[]string{"my-instance.my-group.my-network.my-deployment.bosh."},
+							fakeWriter,
+							req,
+						)
+
+						Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+						Expect(len(responseMsg.Answer)).To(BeNumerically("<", 40))
+						Expect(responseMsg.Truncated).To(Equal(true))
+						Expect(responseMsg.Len()).To(BeNumerically("<=", 512))
+						Expect(responseMsg.IsEdns0()).To(BeNil())
+					})
+
+					Context("when the request uses EDNS(0)", func() {
+						BeforeEach(func() {
+							req.SetEdns0(1232, false)
+						})
+
+						It("echoes the OPT record and fits the advertised payload size", func() {
+							responseMsg := localDomain.Resolve(
+								[]string{"my-instance.my-group.my-network.my-deployment.bosh."},
+								fakeWriter,
+								req,
+							)
+
+							Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+							Expect(len(responseMsg.Answer)).To(Equal(40))
+							Expect(responseMsg.Truncated).To(Equal(false))
+							Expect(responseMsg.IsEdns0()).NotTo(BeNil())
+							Expect(responseMsg.IsEdns0().UDPSize()).To(Equal(uint16(1232)))
+						})
+
+						It("treats advertised sizes below 512 bytes as 512", func() {
+							req.IsEdns0().SetUDPSize(100)
+
+							responseMsg := localDomain.Resolve(
+								[]string{"my-instance.my-group.my-network.my-deployment.bosh."},
+								fakeWriter,
+								req,
+							)
+
+							Expect(responseMsg.Truncated).To(Equal(true))
+							Expect(responseMsg.Len()).To(BeNumerically("<=", 512))
+							Expect(responseMsg.Len()).To(BeNumerically(">", 100))
+						})
+					})
 				})
 			})
 
@@ -1,11 +1,16 @@
 package records
 
 import (
+	"encoding/json"
+	"expvar"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"code.cloudfoundry.org/clock"
 
+	"bosh-dns/dns/filewatcher"
+
 	"github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/cloudfoundry/bosh-utils/system"
 
@@ -23,9 +28,25 @@ const logTag string = "RecordsRepo"
 type FileReader interface {
 	Get() ([]byte, error)
 	Subscribe() <-chan bool
+	// Rejections is how many changes of the records file were rejected as
+	// invalid while previously loaded records were kept
+	Rejections() uint64
 }
 
+const (
+	// pollInterval is how often the records file is checked when it cannot be
+	// watched for changes
+	pollInterval = time.Second
+
+	// watchedPollInterval is how often a watched records file is checked in
+	// case a change notification was missed
+	watchedPollInterval = 30 * time.Second
+)
+
 type autoUpdatingRepo struct {
+	// rejections is first to keep it aligned for atomic access
+	rejections uint64
+
 	// updateImmediately chan struct{}
 	recordsFilePath string
 	fileSystem      system.FileSystem
@@ -51,40 +72,90 @@ func NewFileReader(recordsFilePath string, fileSys system.FileSystem, clock cloc
 	}
 
 	_, fileContents, err := repo.needNewFromDisk()
+	if err == nil {
+		err = validateRecordsJSON(fileContents)
+	}
 	repo.atomicallyUpdateCache(fileContents, err)
 
 	if repo.cacheErr != nil {
 		logger.Error(logTag, fmt.Sprintf("Unable to open records file at: %s", recordsFilePath))
 	}
 
+	interval := pollInterval
+	var events <-chan string
+
+	watcher, err := filewatcher.New([]string{recordsFilePath})
+	if err != nil {
+		logger.Info(logTag, "Polling records file every %s, unable to watch it: %s", interval, err)
+	} else {
+		interval = watchedPollInterval
+		events = watcher.Events()
+	}
+
 	go func() {
+		if watcher != nil {
+			defer watcher.Close()
+		}
+
 		for {
+			timer := clock.NewTimer(interval)
+
 			select {
 			case <-shutdownChan:
-				break
-			default:
-				clock.Sleep(time.Second)
-
-				newData, data, err := repo.needNewFromDisk()
-				if newData && err == nil {
-					repo.atomicallyUpdateCache(data, err)
-					for _, c := range repo.subscribers {
-						c <- true
-					}
+				timer.Stop()
+				return
+			case _, ok := <-events:
+				if !ok {
+					logger.Error(logTag, "Stopped watching records file, polling every %s", pollInterval)
+					events = nil
+					interval = pollInterval
 				}
+			case <-timer.C():
 			}
+
+			timer.Stop()
+			repo.reload()
 		}
 	}()
 
 	return repo
 }
 
+func (r *autoUpdatingRepo) reload() {
+	newData, data, err := r.needNewFromDisk()
+	if !newData || err != nil {
+		return
+	}
+
+	if err := validateRecordsJSON(data); err != nil {
+		atomic.AddUint64(&r.rejections, 1)
+		r.logger.Error(logTag, "Rejected records file '%s', keeping previously loaded records: %s", r.recordsFilePath, err)
+		return
+	}
+
+	r.atomicallyUpdateCache(data, nil)
+	for _, c := range r.subscribers {
+		c <- true
+	}
+}
+
+func (r *autoUpdatingRepo) Rejections() uint64 {
+	return atomic.LoadUint64(&r.rejections)
+}
+
 func (r *autoUpdatingRepo) Subscribe() <-chan bool {
 	c := make(chan bool)
 	r.subscribers = append(r.subscribers, c)
 	return c
 }
 
+// PublishRejections exports the rejections of reader as an expvar.
+func PublishRejections(name string, reader FileReader) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return reader.Rejections()
+	}))
+}
+
 func (r *autoUpdatingRepo) needNewFromDisk() (bool, []byte, error) {
 	newStat, err := r.fileSystem.Stat(r.recordsFilePath)
 	if err != nil {
@@ -129,3 +200,29 @@ func (r *autoUpdatingRepo) atomicallyFetchCache() ([]byte, error) {
 	r.rwlock.RUnlock()
 	return set, err
 }
+
+// validateRecordsJSON rejects records files which are not complete records
+// documents, e.g. because they were read while still being written, or which
+// use a newer schema than this release understands. Individual malformed rows
+// are left to createFromJSON, which skips and reports them.
+func validateRecordsJSON(contents []byte) error {
+	var swap recordsJSON
+
+	if err := json.Unmarshal(contents, &swap); err != nil {
+		return bosherr.WrapError(err, "Parsing records file")
+	}
+
+	if swap.SchemaVersion > SchemaVersion {
+		return bosherr.Errorf("Unsupported records schema_version %d, newest supported is %d", swap.SchemaVersion, SchemaVersion)
+	}
+
+	if swap.Keys == nil {
+		return bosherr.Error("Missing record_keys")
+	}
+
+	if swap.Infos == nil {
+		return bosherr.Error("Missing record_infos")
+	}
+
+	return nil
+}
@@ -1,15 +1,19 @@
 package records_test
 
 import (
+	"encoding/json"
+	"expvar"
 	"time"
 
 	"code.cloudfoundry.org/clock/fakeclock"
 
 	"bosh-dns/dns/server/records"
+	"bosh-dns/dns/server/records/recordsfakes"
 
 	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
 	"github.com/cloudfoundry/bosh-utils/system/fakes"
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 
 	"errors"
@@ -145,6 +149,104 @@ var _ = Describe("RecordsFileReader", func() {
 				})
 			})
 
+			Context("when the new file is incomplete", func() {
+				BeforeEach(func() {
+					err := fakeFileSystem.WriteFile(recordsFile.Name(), []byte(`{
+						"record_keys": ["id", "instance_group", "az", "network", "deployment", "domain", "ip"],
+						"record_in`))
+					Expect(err).NotTo(HaveOccurred())
+
+					fakeFileSystem.RegisterOpenFile(recordsFile.Name(), &fakes.FakeFile{
+						Stats: &fakes.FakeFileStats{
+							ModTime: fakeClock.Now(),
+						},
+					})
+
+					fakeClock.WaitForWatcherAndIncrement(time.Second)
+					fakeClock.WaitForWatcherAndIncrement(0)
+				})
+
+				It("keeps the previous contents", func() {
+					contents, err := fileReader.Get()
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(string(contents)).To(Equal(fileContents))
+				})
+
+				It("logs the rejection", func() {
+					Expect(fakeLogger.ErrorCallCount()).To(Equal(1))
+					tag, message, args := fakeLogger.ErrorArgsForCall(0)
+					Expect(tag).To(Equal("RecordsRepo"))
+					Expect(message).To(Equal("Rejected records file '%s', keeping previously loaded records: %s"))
+					Expect(args[0]).To(Equal("/fake/file"))
+				})
+
+				It("counts the rejection", func() {
+					Expect(fileReader.Rejections()).To(Equal(uint64(1)))
+				})
+
+				It("does not notify subscribers", func() {
+					c := fileReader.Subscribe()
+
+					fakeClock.WaitForWatcherAndIncrement(time.Second)
+					Consistently(c).ShouldNot(Receive())
+				})
+			})
+
+			DescribeTable("when the new file is not a complete records document",
+				func(contents string, reason string) {
+					err := fakeFileSystem.WriteFile(recordsFile.Name(), []byte(contents))
+					Expect(err).NotTo(HaveOccurred())
+
+					fakeFileSystem.RegisterOpenFile(recordsFile.Name(), &fakes.FakeFile{
+						Stats: &fakes.FakeFileStats{
+							ModTime: fakeClock.Now(),
+						},
+					})
+
+					fakeClock.WaitForWatcherAndIncrement(time.Second)
+					fakeClock.WaitForWatcherAndIncrement(0)
+
+					current, err := fileReader.Get()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(current)).To(Equal(fileContents))
+
+					Expect(fakeLogger.ErrorCallCount()).To(Equal(1))
+					_, _, args := fakeLogger.ErrorArgsForCall(0)
+					Expect(args[1]).To(MatchError(reason))
+				},
+				Entry("without record_keys", `{"record_infos": []}`, "Missing record_keys"),
+				Entry("without record_infos", `{"record_keys": ["id"]}`, "Missing record_infos"),
+				Entry("with a newer schema_version", `{"schema_version": 99, "record_keys": ["id"], "record_infos": []}`, "Unsupported records schema_version 99, newest supported is 1"),
+			)
+
+			Context("when the new file has a row of the wrong length", func() {
+				var newFileContents string
+
+				BeforeEach(func() {
+					newFileContents = `{"record_keys": ["id", "ip"], "record_infos": [["a", "1.1.1.1"], ["b"]]}`
+					err := fakeFileSystem.WriteFile(recordsFile.Name(), []byte(newFileContents))
+					Expect(err).NotTo(HaveOccurred())
+
+					fakeFileSystem.RegisterOpenFile(recordsFile.Name(), &fakes.FakeFile{
+						Stats: &fakes.FakeFileStats{
+							ModTime: fakeClock.Now(),
+						},
+					})
+
+					fakeClock.WaitForWatcherAndIncrement(time.Second)
+					fakeClock.WaitForWatcherAndIncrement(0)
+				})
+
+				It("leaves the row to the record set and serves the new contents", func() {
+					contents, err := fileReader.Get()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(contents)).To(Equal(newFileContents))
+					Expect(fakeLogger.ErrorCallCount()).To(Equal(0))
+					Expect(fileReader.Rejections()).To(BeZero())
+				})
+			})
+
 			// Context("when the file changes", func() {
 			// 	var initialTime time.Time
 			// 	BeforeEach(func() {
@@ -387,6 +489,31 @@ var _ = Describe("RecordsFileReader", func() {
 		})
 	})
 
+	Describe("PublishRejections", func() {
+		It("publishes the rejections of the reader as an expvar", func() {
+			reader := &recordsfakes.FakeFileReader{}
+			reader.RejectionsReturns(3)
+
+			records.PublishRejections("records_file_rejections_test", reader)
+
+			var rejections uint64
+			Expect(json.Unmarshal([]byte(expvar.Get("records_file_rejections_test").String()), &rejections)).To(Succeed())
+			Expect(rejections).To(Equal(uint64(3)))
+		})
+	})
+
+	Describe("shutdown", func() {
+		It("stops checking the file", func() {
+			readerShutdown := make(chan struct{})
+			otherClock := fakeclock.NewFakeClock(time.Now())
+			records.NewFileReader(recordsFile.Name(), fakeFileSystem, otherClock, fakeLogger, readerShutdown)
+
+			Eventually(otherClock.WatcherCount).Should(Equal(1))
+			close(readerShutdown)
+			Eventually(otherClock.WatcherCount).Should(Equal(0))
+		})
+	})
+
 	Describe("Subscribe", func() {
 		It("notifies when changes occur", func() {
 			c := fileReader.Subscribe()
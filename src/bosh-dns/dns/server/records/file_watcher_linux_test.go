@@ -0,0 +1,85 @@
+//go:build linux
+// +build linux
+
+package records_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/records"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecordsFileReader watching", func() {
+	var (
+		shutdownChan chan struct{}
+		dir          string
+		recordsPath  string
+		fileReader   records.FileReader
+		fakeClock    *fakeclock.FakeClock
+		fakeLogger   *loggerfakes.FakeLogger
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "records")
+		Expect(err).NotTo(HaveOccurred())
+
+		recordsPath = filepath.Join(dir, "records.json")
+		Expect(ioutil.WriteFile(recordsPath, []byte(`{"record_keys":["id"],"record_infos":[["a"]]}`), 0644)).To(Succeed())
+
+		shutdownChan = make(chan struct{})
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeLogger = &loggerfakes.FakeLogger{}
+		fileSystem := boshsys.NewOsFileSystem(boshlog.NewLogger(boshlog.LevelNone))
+
+		fileReader = records.NewFileReader(recordsPath, fileSystem, fakeClock, fakeLogger, shutdownChan)
+	})
+
+	AfterEach(func() {
+		close(shutdownChan)
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("picks up files renamed into place without waiting for the poll interval", func() {
+		c := fileReader.Subscribe()
+
+		tmpPath := filepath.Join(dir, "records.json.tmp")
+		Expect(ioutil.WriteFile(tmpPath, []byte(`{"record_keys":["id"],"record_infos":[["b"]]}`), 0644)).To(Succeed())
+		Expect(os.Rename(tmpPath, recordsPath)).To(Succeed())
+
+		Eventually(c).Should(Receive())
+		contents, err := fileReader.Get()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal(`{"record_keys":["id"],"record_infos":[["b"]]}`))
+	})
+
+	It("picks up files written in place", func() {
+		c := fileReader.Subscribe()
+
+		Expect(ioutil.WriteFile(recordsPath, []byte(`{"record_keys":["id"],"record_infos":[["c"]]}`), 0644)).To(Succeed())
+
+		Eventually(c).Should(Receive())
+		contents, err := fileReader.Get()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal(`{"record_keys":["id"],"record_infos":[["c"]]}`))
+	})
+
+	It("ignores other files in the directory", func() {
+		c := fileReader.Subscribe()
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "other.json"), []byte(`{}`), 0644)).To(Succeed())
+
+		Consistently(c).ShouldNot(Receive())
+	})
+})
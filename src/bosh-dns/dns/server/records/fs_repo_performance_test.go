@@ -41,7 +41,7 @@ var _ = Describe("FsRepoPerformance", func() {
 		Expect(err).NotTo(HaveOccurred())
 		recordsFilePath = recordsFile.Name()
 
-		recordsFile.Write([]byte(`{"zones":["my.domain.","your.domain.","example.com."]}`))
+		recordsFile.Write([]byte(`{"record_keys":["id","domain"],"record_infos":[["my-instance","my.domain."]]}`))
 
 		logger = &blogfakes.FakeLogger{}
 		fileSys = boshsys.NewOsFileSystem(logger)
@@ -93,7 +93,7 @@ var _ = Describe("FsRepoPerformance", func() {
 				case <-done:
 					break dance
 				default:
-					Expect(fileSys.WriteFileString(recordsFilePath, `{"zones":["my.domain.","your.domain.","example.com."]}`)).To(Succeed())
+					Expect(fileSys.WriteFileString(recordsFilePath, `{"record_keys":["id","domain"],"record_infos":[["my-instance","my.domain."]]}`)).To(Succeed())
 				}
 			}
 
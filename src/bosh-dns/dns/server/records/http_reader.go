@@ -0,0 +1,315 @@
+package records
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	httpReaderLogTag = "RecordsHTTPReader"
+
+	// the first poll happens before the server starts, so an endpoint that
+	// accepts connections but never answers must not hold up startup
+	initialPollTimeout = 2 * time.Second
+)
+
+//go:generate counterfeiter . HTTPDoer
+
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// RecordsSnapshot is the body served by a records endpoint. Records holds the
+// same document as records.json and Checksum is "sha256:" followed by the hex
+// digest of exactly those bytes.
+type RecordsSnapshot struct {
+	Version  string          `json:"version"`
+	Checksum string          `json:"checksum"`
+	Records  json.RawMessage `json:"records"`
+}
+
+func RecordsChecksum(records []byte) string {
+	sum := sha256.Sum256(records)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+type httpReader struct {
+	endpoint      string
+	client        HTTPDoer
+	fallback      FileReader
+	pollWait      time.Duration
+	retryInterval time.Duration
+	clock         clock.Clock
+	logger        logger.Logger
+
+	mutex     *sync.RWMutex
+	available bool
+	version   string
+	snapshot  []byte
+
+	subscribersMutex *sync.Mutex
+	subscribers      []chan bool
+}
+
+// NewHTTPReader streams records from a local endpoint using long-polling:
+//
+//	GET <endpoint>?version=<current version>&wait=<pollWait>
+//
+// The endpoint answers 200 with a RecordsSnapshot as soon as its version
+// differs from the given one, or 304 once wait elapses without a change.
+// While the endpoint is unreachable or serves invalid snapshots, records are
+// read from the fallback reader instead.
+func NewHTTPReader(
+	endpoint string,
+	client HTTPDoer,
+	fallback FileReader,
+	pollWait time.Duration,
+	retryInterval time.Duration,
+	clock clock.Clock,
+	logger logger.Logger,
+	shutdownChan chan struct{},
+) FileReader {
+	r := &httpReader{
+		endpoint:      endpoint,
+		client:        client,
+		fallback:      fallback,
+		pollWait:      pollWait,
+		retryInterval: retryInterval,
+		clock:         clock,
+		logger:        logger,
+
+		mutex:            &sync.RWMutex{},
+		subscribersMutex: &sync.Mutex{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), initialPollTimeout)
+	_, err := r.poll(ctx, 0)
+	cancel()
+	if err != nil {
+		logger.Error(httpReaderLogTag, "Records endpoint '%s' unavailable, using records file: %s", endpoint, err)
+	}
+
+	go r.forwardFallback(fallback.Subscribe(), shutdownChan)
+	go r.run(shutdownChan)
+
+	return r
+}
+
+func (r *httpReader) Get() ([]byte, error) {
+	r.mutex.RLock()
+	available, snapshot := r.available, r.snapshot
+	r.mutex.RUnlock()
+
+	if available {
+		return snapshot, nil
+	}
+
+	return r.fallback.Get()
+}
+
+// Rejections reports those of the records file. An invalid snapshot is not
+// counted, as it makes the reader fall back to the records file instead.
+func (r *httpReader) Rejections() uint64 {
+	return r.fallback.Rejections()
+}
+
+// Subscribe receives after every change of the served records. Changes a
+// subscriber has not caught up with yet are coalesced into a single receive.
+func (r *httpReader) Subscribe() <-chan bool {
+	r.subscribersMutex.Lock()
+	defer r.subscribersMutex.Unlock()
+
+	c := make(chan bool, 1)
+	r.subscribers = append(r.subscribers, c)
+	return c
+}
+
+func (r *httpReader) run(shutdownChan chan struct{}) {
+	// cancel an outstanding long-poll as soon as shutdown starts
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-shutdownChan
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-shutdownChan:
+			return
+		default:
+		}
+
+		changed, err := r.poll(ctx, r.pollWait)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			r.logger.Error(httpReaderLogTag, "Polling records endpoint '%s' failed: %s", r.endpoint, err)
+			if r.markUnavailable() {
+				r.logger.Info(httpReaderLogTag, "Falling back to records file")
+				r.notify()
+			}
+
+			timer := r.clock.NewTimer(r.retryInterval)
+			select {
+			case <-shutdownChan:
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+			continue
+		}
+
+		if changed {
+			r.notify()
+		}
+	}
+}
+
+func (r *httpReader) forwardFallback(fallbackChan <-chan bool, shutdownChan chan struct{}) {
+	for {
+		select {
+		case <-shutdownChan:
+			return
+		case ok := <-fallbackChan:
+			if !ok {
+				return
+			}
+
+			r.mutex.RLock()
+			available := r.available
+			r.mutex.RUnlock()
+
+			if !available {
+				r.notify()
+			}
+		}
+	}
+}
+
+func (r *httpReader) poll(ctx context.Context, wait time.Duration) (bool, error) {
+	r.mutex.RLock()
+	version, available := r.version, r.available
+	r.mutex.RUnlock()
+
+	if !available {
+		// always take a full snapshot when coming back from the fallback
+		version = ""
+	}
+
+	query := url.Values{}
+	query.Set("version", version)
+	query.Set("wait", wait.String())
+
+	separator := "?"
+	if strings.Contains(r.endpoint, "?") {
+		separator = "&"
+	}
+
+	req, err := http.NewRequest("GET", r.endpoint+separator+query.Encode(), nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return r.markAvailable(), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+
+	var snapshot RecordsSnapshot
+	if err := json.Unmarshal(body, &snapshot); err != nil {
+		return false, fmt.Errorf("decoding snapshot: %s", err)
+	}
+
+	if checksum := RecordsChecksum(snapshot.Records); checksum != snapshot.Checksum {
+		return false, fmt.Errorf("checksum mismatch for version '%s': expected '%s', got '%s'", snapshot.Version, snapshot.Checksum, checksum)
+	}
+
+	if err := validateRecordsJSON(snapshot.Records); err != nil {
+		return false, fmt.Errorf("invalid records in version '%s': %s", snapshot.Version, err)
+	}
+
+	r.mutex.Lock()
+	changed := !r.available || r.version != snapshot.Version
+	r.available = true
+	r.version = snapshot.Version
+	r.snapshot = []byte(snapshot.Records)
+	r.mutex.Unlock()
+
+	if changed {
+		r.logger.Debug(httpReaderLogTag, "Received records version '%s'", snapshot.Version)
+	}
+
+	return changed, nil
+}
+
+// markAvailable returns true when the reader switched back from the fallback
+func (r *httpReader) markAvailable() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.available || r.snapshot == nil {
+		return false
+	}
+
+	r.available = true
+	return true
+}
+
+// markUnavailable returns true when the reader switched to the fallback. The
+// cached snapshot is dropped so that a 304 for the empty version cannot bring
+// it back once the endpoint recovers.
+func (r *httpReader) markUnavailable() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.version = ""
+	r.snapshot = nil
+
+	if !r.available {
+		return false
+	}
+
+	r.available = false
+	return true
+}
+
+func (r *httpReader) notify() {
+	r.subscribersMutex.Lock()
+	defer r.subscribersMutex.Unlock()
+
+	for _, c := range r.subscribers {
+		select {
+		case c <- true:
+		default:
+		}
+	}
+}
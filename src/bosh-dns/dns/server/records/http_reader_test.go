@@ -0,0 +1,363 @@
+package records_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/server/records"
+	"bosh-dns/dns/server/records/recordsfakes"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordsEndpoint struct {
+	mutex    sync.Mutex
+	version  string
+	records  string
+	checksum string
+	down     bool
+	requests []*http.Request
+}
+
+func (e *recordsEndpoint) publish(version, records string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.version = version
+	e.records = records
+	e.checksum = records
+}
+
+func (e *recordsEndpoint) corrupt(checksum string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.checksum = checksum
+}
+
+func (e *recordsEndpoint) setDown(down bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.down = down
+}
+
+func (e *recordsEndpoint) lastRequest() *http.Request {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.requests[len(e.requests)-1]
+}
+
+func (e *recordsEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	wait, err := time.ParseDuration(r.URL.Query().Get("wait"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	e.mutex.Lock()
+	e.requests = append(e.requests, r)
+	e.mutex.Unlock()
+
+	deadline := time.Now().Add(wait)
+	for {
+		e.mutex.Lock()
+		down, version := e.down, e.version
+		snapshot := records.RecordsSnapshot{
+			Version:  e.version,
+			Checksum: records.RecordsChecksum([]byte(e.checksum)),
+			Records:  json.RawMessage(e.records),
+		}
+		e.mutex.Unlock()
+
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if version != r.URL.Query().Get("version") {
+			json.NewEncoder(w).Encode(snapshot)
+			return
+		}
+
+		if time.Now().After(deadline) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+var _ = Describe("HTTPReader", func() {
+	var (
+		shutdownChan chan struct{}
+		endpoint     *recordsEndpoint
+		server       *httptest.Server
+		fallback     *recordsfakes.FakeFileReader
+		fallbackChan chan bool
+		fakeClock    *fakeclock.FakeClock
+		fakeLogger   *loggerfakes.FakeLogger
+	)
+
+	newReader := func() records.FileReader {
+		return records.NewHTTPReader(server.URL+"/records", http.DefaultClient, fallback, 50*time.Millisecond, time.Second, fakeClock, fakeLogger, shutdownChan)
+	}
+
+	BeforeEach(func() {
+		shutdownChan = make(chan struct{})
+		endpoint = &recordsEndpoint{}
+		endpoint.publish("1", `{"record_keys":["id"],"record_infos":[["a"]]}`)
+		server = httptest.NewServer(endpoint)
+
+		fallbackChan = make(chan bool)
+		fallback = &recordsfakes.FakeFileReader{}
+		fallback.SubscribeReturns(fallbackChan)
+		fallback.GetReturns([]byte("from-file"), nil)
+
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeLogger = &loggerfakes.FakeLogger{}
+	})
+
+	AfterEach(func() {
+		close(shutdownChan)
+		endpoint.setDown(true)
+		server.Close()
+	})
+
+	It("serves the snapshot fetched from the endpoint", func() {
+		reader := newReader()
+
+		contents, err := reader.Get()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal(`{"record_keys":["id"],"record_infos":[["a"]]}`))
+		Expect(fallback.GetCallCount()).To(Equal(0))
+	})
+
+	It("reports the rejections of the records file", func() {
+		fallback.RejectionsReturns(2)
+
+		Expect(newReader().Rejections()).To(Equal(uint64(2)))
+	})
+
+	It("long-polls with the current version", func() {
+		newReader()
+
+		Eventually(func() string {
+			return endpoint.lastRequest().URL.Query().Get("version")
+		}).Should(Equal("1"))
+		Expect(endpoint.lastRequest().URL.Query().Get("wait")).To(Equal("50ms"))
+	})
+
+	It("notifies subscribers as soon as a new version is published", func() {
+		reader := newReader()
+		subscription := reader.Subscribe()
+
+		endpoint.publish("2", `{"record_keys":["id"],"record_infos":[["b"]]}`)
+
+		Eventually(subscription).Should(Receive(BeTrue()))
+		contents, err := reader.Get()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal(`{"record_keys":["id"],"record_infos":[["b"]]}`))
+	})
+
+	It("does not block on subscribers which are not receiving", func() {
+		reader := newReader()
+		subscription := reader.Subscribe()
+
+		endpoint.publish("2", `{"record_keys":["id"],"record_infos":[["b"]]}`)
+		Eventually(func() string {
+			return endpoint.lastRequest().URL.Query().Get("version")
+		}).Should(Equal("2"))
+
+		endpoint.publish("3", `{"record_keys":["id"],"record_infos":[["c"]]}`)
+		Eventually(func() string {
+			return endpoint.lastRequest().URL.Query().Get("version")
+		}).Should(Equal("3"))
+
+		Expect(subscription).To(Receive(BeTrue()))
+		Expect(subscription).NotTo(Receive())
+	})
+
+	Context("when the endpoint is unavailable", func() {
+		BeforeEach(func() {
+			endpoint.setDown(true)
+		})
+
+		It("reads records from the fallback", func() {
+			reader := newReader()
+
+			contents, err := reader.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("from-file"))
+			Expect(fakeLogger.ErrorCallCount()).To(BeNumerically(">", 0))
+		})
+
+		It("forwards fallback updates", func() {
+			reader := newReader()
+			subscription := reader.Subscribe()
+
+			fallbackChan <- true
+
+			Eventually(subscription).Should(Receive(BeTrue()))
+		})
+
+		It("switches over once the endpoint recovers", func() {
+			reader := newReader()
+			subscription := reader.Subscribe()
+
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+			endpoint.setDown(false)
+			fakeClock.Increment(time.Second)
+
+			Eventually(subscription).Should(Receive(BeTrue()))
+			contents, err := reader.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(`{"record_keys":["id"],"record_infos":[["a"]]}`))
+		})
+	})
+
+	Context("when shutting down", func() {
+		var readerShutdown chan struct{}
+
+		BeforeEach(func() {
+			readerShutdown = make(chan struct{})
+		})
+
+		It("stops waiting to retry the endpoint", func() {
+			endpoint.setDown(true)
+			records.NewHTTPReader(server.URL+"/records", http.DefaultClient, fallback, 50*time.Millisecond, time.Second, fakeClock, fakeLogger, readerShutdown)
+
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+			close(readerShutdown)
+			Eventually(fakeClock.WatcherCount).Should(Equal(0))
+		})
+
+		It("abandons the outstanding long-poll", func() {
+			records.NewHTTPReader(server.URL+"/records", http.DefaultClient, fallback, time.Minute, time.Second, fakeClock, fakeLogger, readerShutdown)
+
+			Eventually(func() string {
+				return endpoint.lastRequest().URL.Query().Get("wait")
+			}).Should(Equal("1m0s"))
+			request := endpoint.lastRequest()
+
+			close(readerShutdown)
+			Eventually(request.Context().Done()).Should(BeClosed())
+		})
+	})
+
+	Context("when the endpoint goes away", func() {
+		It("falls back to the file and notifies subscribers", func() {
+			reader := newReader()
+			subscription := reader.Subscribe()
+
+			endpoint.setDown(true)
+
+			Eventually(subscription).Should(Receive(BeTrue()))
+			contents, err := reader.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("from-file"))
+		})
+	})
+
+	Context("when the checksum does not match", func() {
+		BeforeEach(func() {
+			endpoint.corrupt("something else")
+		})
+
+		It("rejects the snapshot", func() {
+			reader := newReader()
+
+			contents, err := reader.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("from-file"))
+
+			_, message, args := fakeLogger.ErrorArgsForCall(0)
+			Expect(message).To(ContainSubstring("unavailable"))
+			Expect(args[1]).To(MatchError(ContainSubstring("checksum mismatch for version '1'")))
+		})
+	})
+	Context("when the snapshot is not a valid records document", func() {
+		BeforeEach(func() {
+			endpoint.publish("1", `{"schema_version":99,"record_keys":["id"],"record_infos":[["a"]]}`)
+		})
+
+		It("rejects the snapshot", func() {
+			reader := newReader()
+
+			contents, err := reader.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("from-file"))
+
+			_, _, args := fakeLogger.ErrorArgsForCall(0)
+			Expect(args[1]).To(MatchError(ContainSubstring("invalid records in version '1'")))
+		})
+	})
+
+	Context("when the endpoint comes back without a version", func() {
+		It("does not return to the snapshot cached before the fallback", func() {
+			reader := newReader()
+			subscription := reader.Subscribe()
+
+			endpoint.setDown(true)
+			Eventually(subscription).Should(Receive(BeTrue()))
+
+			endpoint.publish("", `{"record_keys":["id"],"record_infos":[["b"]]}`)
+			endpoint.setDown(false)
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+			Eventually(func() string { return endpoint.lastRequest().URL.Query().Get("version") }).Should(Equal(""))
+			Consistently(subscription, 200*time.Millisecond).ShouldNot(Receive())
+
+			contents, err := reader.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("from-file"))
+		})
+	})
+
+	Context("when the endpoint does not answer", func() {
+		var (
+			hanging *httptest.Server
+			release chan struct{}
+		)
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			hanging = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-release:
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			close(release)
+			hanging.Close()
+		})
+
+		It("does not hold up startup", func() {
+			started := make(chan records.FileReader)
+			go func() {
+				defer GinkgoRecover()
+				started <- records.NewHTTPReader(hanging.URL+"/records", http.DefaultClient, fallback, time.Minute, time.Second, fakeClock, fakeLogger, shutdownChan)
+			}()
+
+			var reader records.FileReader
+			Eventually(started, 5*time.Second).Should(Receive(&reader))
+
+			contents, err := reader.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("from-file"))
+		})
+	})
+})
@@ -12,4 +12,8 @@ type Record struct {
 	Domain        string
 	AZID          string
 	InstanceIndex string
+
+	// Attributes holds the values of records file columns this release does
+	// not know about, keyed by column name.
+	Attributes map[string]interface{}
 }
@@ -0,0 +1,196 @@
+package records
+
+import (
+	"sort"
+	"strings"
+)
+
+// recordIndex maps every criteria field value to the positions of the
+// records carrying it so that queries only look at records which can match
+// instead of scanning every record. Positions are kept in ascending order so
+// results come back in records file order.
+type recordIndex struct {
+	records []Record
+	fields  map[string]map[string][]int
+	ips     map[string][]int
+	domains map[string]struct{}
+}
+
+func newRecordIndex(records []Record) *recordIndex {
+	index := &recordIndex{
+		records: records,
+		fields:  map[string]map[string][]int{},
+		ips:     map[string][]int{},
+		domains: map[string]struct{}{},
+	}
+
+	for i, record := range records {
+		index.add("instanceName", record.ID, i)
+		index.add("instanceGroupName", record.Group, i)
+		index.add("network", record.Network, i)
+		index.add("deployment", record.Deployment, i)
+		index.add("domain", record.Domain, i)
+		index.add("m", record.NumId, i)
+		index.add("n", record.NetworkID, i)
+		index.add("a", record.AZID, i)
+		index.add("i", record.InstanceIndex, i)
+
+		for _, groupID := range record.GroupIDs {
+			index.add("g", groupID, i)
+		}
+
+		index.ips[record.IP] = append(index.ips[record.IP], i)
+		index.domains[record.Domain] = struct{}{}
+	}
+
+	return index
+}
+
+func (idx *recordIndex) add(field, value string, position int) {
+	values, ok := idx.fields[field]
+	if !ok {
+		values = map[string][]int{}
+		idx.fields[field] = values
+	}
+
+	positions := values[value]
+	if len(positions) > 0 && positions[len(positions)-1] == position {
+		// a record may list the same group id twice
+		return
+	}
+
+	values[value] = append(positions, position)
+}
+
+func (idx *recordIndex) Domains() []string {
+	domains := make([]string, 0, len(idx.domains))
+	for domain := range idx.domains {
+		domains = append(domains, domain)
+	}
+
+	return domains
+}
+
+// DomainFor returns the longest known domain which fqdn ends with, matching
+// on label boundaries only.
+func (idx *recordIndex) DomainFor(fqdn string) string {
+	for suffix := fqdn; suffix != ""; {
+		if _, ok := idx.domains[suffix]; ok {
+			return suffix
+		}
+
+		dot := strings.Index(suffix, ".")
+		if dot < 0 {
+			break
+		}
+		suffix = suffix[dot+1:]
+	}
+
+	return ""
+}
+
+// IPsMatching returns the IPs of all records satisfying every field of c.
+// Multiple values for one field match records carrying any of them. The
+// positions of every field are looked up in the index and intersected,
+// starting from the shortest list, so the cost follows the number of
+// candidate records rather than the size of the deployment.
+func (idx *recordIndex) IPsMatching(c criteria) []string {
+	lists := [][]int{}
+	for field, values := range c {
+		// healthiness is not handled by the normal recordset
+		if field == "s" {
+			continue
+		}
+
+		positions := idx.union(field, values)
+		if len(positions) == 0 {
+			return []string{}
+		}
+
+		lists = append(lists, positions)
+	}
+
+	var positions []int
+	if len(lists) == 0 {
+		positions = idx.all()
+	} else {
+		sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+
+		positions = lists[0]
+		for _, list := range lists[1:] {
+			positions = intersect(positions, list)
+			if len(positions) == 0 {
+				return []string{}
+			}
+		}
+	}
+
+	ips := make([]string, 0, len(positions))
+	for _, position := range positions {
+		ips = append(ips, idx.records[position].IP)
+	}
+
+	return ips
+}
+
+// RecordsWithIP returns the records with the given IP.
+func (idx *recordIndex) RecordsWithIP(ip string) []Record {
+	records := make([]Record, 0, len(idx.ips[ip]))
+	for _, position := range idx.ips[ip] {
+		records = append(records, idx.records[position])
+	}
+
+	return records
+}
+
+func (idx *recordIndex) union(field string, values []string) []int {
+	postings := idx.fields[field]
+
+	if len(values) == 1 {
+		return postings[values[0]]
+	}
+
+	seen := map[int]struct{}{}
+	positions := []int{}
+	for _, value := range values {
+		for _, position := range postings[value] {
+			if _, ok := seen[position]; ok {
+				continue
+			}
+			seen[position] = struct{}{}
+			positions = append(positions, position)
+		}
+	}
+
+	sort.Ints(positions)
+
+	return positions
+}
+
+// intersect returns the positions in both of the ascending lists short and
+// long, searching long for each position of short.
+func intersect(short, long []int) []int {
+	positions := []int{}
+	for _, position := range short {
+		i := sort.SearchInts(long, position)
+		if i == len(long) {
+			break
+		}
+
+		if long[i] == position {
+			positions = append(positions, position)
+		}
+		long = long[i:]
+	}
+
+	return positions
+}
+
+func (idx *recordIndex) all() []int {
+	positions := make([]int, len(idx.records))
+	for i := range positions {
+		positions[i] = i
+	}
+
+	return positions
+}
@@ -1,7 +1,6 @@
 package records
 
 import (
-	"encoding/json"
 	"fmt"
 	"net"
 	"strings"
@@ -9,8 +8,6 @@ import (
 
 	"errors"
 
-	"strconv"
-
 	"bosh-dns/dns/server/aliases"
 	"bosh-dns/dns/server/healthiness"
 	"bosh-dns/dns/server/records/internal"
@@ -19,6 +16,10 @@ import (
 	"github.com/miekg/dns"
 )
 
+// changeSubscriberBuffer is how many records changes a SubscribeChanges
+// subscriber may fall behind before changes are dropped for it
+const changeSubscriberBuffer = 16
+
 type recordGroup map[*Record]struct{}
 
 type RecordSet struct {
@@ -26,6 +27,7 @@ type RecordSet struct {
 	recordsMutex      sync.RWMutex
 	subscriberssMutex sync.RWMutex
 	subscribers       []chan bool
+	changeSubscribers []chan RecordsDiff
 	logger            boshlog.Logger
 	aliasList         aliases.Config
 	healthWatcher     healthiness.HealthWatcher
@@ -35,6 +37,8 @@ type RecordSet struct {
 	trackedIPs      map[string]map[string]struct{}
 	trackedIPsMutex *sync.Mutex
 
+	index   *recordIndex
+	loaded  bool
 	domains []string
 	Records []Record
 }
@@ -55,17 +59,24 @@ func NewRecordSet(
 		trackedDomains:   internal.NewPriorityLimitedTranscript(maximumTrackedDomains),
 		trackedIPs:       map[string]map[string]struct{}{},
 		trackedIPsMutex:  &sync.Mutex{},
+		index:            newRecordIndex(nil),
 	}
 
 	r.update()
 
+	// subscribe before returning so that no change is missed while the
+	// goroutine starts
+	subscriptionChan := recordFileReader.Subscribe()
+
 	go func() {
-		subscriptionChan := recordFileReader.Subscribe()
 		defer func() {
 			r.subscriberssMutex.RLock()
 			for _, subscriber := range r.subscribers {
 				close(subscriber)
 			}
+			for _, subscriber := range r.changeSubscribers {
+				close(subscriber)
+			}
 			r.subscriberssMutex.RUnlock()
 		}()
 
@@ -78,12 +89,26 @@ func NewRecordSet(
 					return
 				}
 
-				r.update()
-				r.refreshTrackedIPs()
+				diff := r.update()
+				if !diff.Empty() {
+					r.refreshTrackedIPs(diff)
+				}
 
 				r.subscriberssMutex.RLock()
 				for _, subscriber := range r.subscribers {
-					subscriber <- true
+					select {
+					case subscriber <- true:
+					default:
+					}
+				}
+				if !diff.Empty() {
+					for _, subscriber := range r.changeSubscribers {
+						select {
+						case subscriber <- diff:
+						default:
+							r.logger.Warn("RecordSet", "Dropped records change for a subscriber which is %d changes behind", len(subscriber))
+						}
+					}
 				}
 				r.subscriberssMutex.RUnlock()
 			}
@@ -93,14 +118,27 @@ func NewRecordSet(
 	return r, nil
 }
 
+// Subscribe receives after every reload. Reloads a subscriber has not caught
+// up with yet are coalesced into a single receive.
 func (r *RecordSet) Subscribe() <-chan bool {
 	r.subscriberssMutex.Lock()
 	defer r.subscriberssMutex.Unlock()
-	c := make(chan bool)
+	c := make(chan bool, 1)
 	r.subscribers = append(r.subscribers, c)
 	return c
 }
 
+// SubscribeChanges receives the records added, removed and changed by every
+// reload which altered the record set. Changes are dropped for subscribers
+// which fall more than changeSubscriberBuffer changes behind.
+func (r *RecordSet) SubscribeChanges() <-chan RecordsDiff {
+	r.subscriberssMutex.Lock()
+	defer r.subscriberssMutex.Unlock()
+	c := make(chan RecordsDiff, changeSubscriberBuffer)
+	r.changeSubscribers = append(r.changeSubscribers, c)
+	return c
+}
+
 func (r *RecordSet) Resolve(fqdn string) ([]string, error) {
 	r.recordsMutex.RLock()
 	defer r.recordsMutex.RUnlock()
@@ -159,6 +197,12 @@ func filterByHealthStrategy(healthyIPs, unhealthyIPs []string, crit criteria) []
 		healthStrategy = crit["s"][0]
 	}
 
+	return FilterByHealthStrategy(healthyIPs, unhealthyIPs, healthStrategy)
+}
+
+// FilterByHealthStrategy returns the IPs a query with the s<healthStrategy>
+// criterion resolves to.
+func FilterByHealthStrategy(healthyIPs, unhealthyIPs []string, healthStrategy string) []string {
 	switch healthStrategy {
 	case "1": // unhealthy ones
 		return unhealthyIPs
@@ -175,11 +219,15 @@ func filterByHealthStrategy(healthyIPs, unhealthyIPs []string, crit criteria) []
 	}
 }
 
+// IsHealthy reports whether the health watcher considers ip healthy.
+func (r *RecordSet) IsHealthy(ip string) bool {
+	return r.healthWatcher.IsHealthy(ip)
+}
+
 func (r *RecordSet) segregateIPs(ips []string, fqdn string) ([]string, []string) {
 	var healthyIPs, unhealthyIPs []string
 	for _, ip := range ips {
 		r.trackedIPsMutex.Lock()
-		r.trackedIPs[ip] = map[string]struct{}{}
 		if _, ok := r.trackedIPs[ip]; !ok {
 			r.trackedIPs[ip] = map[string]struct{}{}
 		}
@@ -196,35 +244,67 @@ func (r *RecordSet) segregateIPs(ips []string, fqdn string) ([]string, []string)
 	return healthyIPs, unhealthyIPs
 }
 
-func (r *RecordSet) refreshTrackedIPs() {
-	newTrackedIPs := map[string]map[string]struct{}{}
+// refreshTrackedIPs re-resolves the tracked domains, or the names they are
+// aliases for, whose answers diff may have changed. IPs which no tracked name
+// resolves to any more stop being health checked; newly resolved IPs start
+// being checked.
+func (r *RecordSet) refreshTrackedIPs(diff RecordsDiff) {
+	r.recordsMutex.RLock()
+	defer r.recordsMutex.RUnlock()
+
 	r.trackedIPsMutex.Lock()
 	defer r.trackedIPsMutex.Unlock()
+
 	for _, domain := range r.trackedDomains.Registry() {
-		ips, _, err := r.resolveQuery(domain)
-		if err != nil {
-			continue
+		names := r.aliasList.Resolutions(domain)
+		if len(names) == 0 {
+			names = []string{domain}
 		}
 
-		for _, ip := range ips {
-			if _, ok := newTrackedIPs[ip]; !ok {
-				newTrackedIPs[ip] = map[string]struct{}{}
+		for _, name := range names {
+			if net.ParseIP(name) != nil {
+				continue
 			}
-			newTrackedIPs[ip][domain] = struct{}{}
 
-			if _, found := r.trackedIPs[ip]; found {
-				delete(r.trackedIPs, ip)
-			} else {
-				r.healthWatcher.IsHealthy(ip)
+			c, err := r.parseQuery(name)
+			if err != nil || c == nil || !diff.Affects(matcherFor(c)) {
+				continue
 			}
+
+			r.retrackName(name, r.index.IPsMatching(c))
 		}
 	}
+}
+
+// retrackName makes exactly ips track name. The caller must hold
+// trackedIPsMutex.
+func (r *RecordSet) retrackName(name string, ips []string) {
+	resolved := make(map[string]struct{}, len(ips))
+	for _, ip := range ips {
+		resolved[ip] = struct{}{}
 
-	for oldIP := range r.trackedIPs {
-		r.healthWatcher.Untrack(oldIP)
+		if _, found := r.trackedIPs[ip]; !found {
+			r.trackedIPs[ip] = map[string]struct{}{}
+			r.healthWatcher.IsHealthy(ip)
+		}
+		r.trackedIPs[ip][name] = struct{}{}
 	}
 
-	r.trackedIPs = newTrackedIPs
+	for ip, names := range r.trackedIPs {
+		if _, ok := resolved[ip]; ok {
+			continue
+		}
+
+		if _, ok := names[name]; !ok {
+			continue
+		}
+
+		delete(names, name)
+		if len(names) == 0 {
+			delete(r.trackedIPs, ip)
+			r.healthWatcher.Untrack(ip)
+		}
+	}
 }
 
 func (r *RecordSet) untrackDomain(removedDomain string) {
@@ -235,6 +315,7 @@ func (r *RecordSet) untrackDomain(removedDomain string) {
 		if _, ok := domains[removedDomain]; ok {
 			delete(domains, removedDomain)
 			if len(domains) == 0 {
+				delete(r.trackedIPs, ip)
 				r.healthWatcher.Untrack(ip)
 			}
 		}
@@ -248,256 +329,196 @@ func (r *RecordSet) Domains() []string {
 	return append(r.domains, r.aliasList.AliasHosts()...)
 }
 
-func (r *RecordSet) update() {
-	contents, err := r.recordFileReader.Get()
-	if err != nil {
-		return
-	}
-	records, err := createFromJSON(contents, r.logger)
-	if err != nil {
-		return
-	}
+// RecordsWithIP returns the records with the given IP.
+func (r *RecordSet) RecordsWithIP(ip string) []Record {
+	r.recordsMutex.RLock()
+	defer r.recordsMutex.RUnlock()
 
-	r.recordsMutex.Lock()
-	defer r.recordsMutex.Unlock()
+	return r.index.RecordsWithIP(ip)
+}
 
-	r.Records = records
+// InstanceGroupsForIP returns the instance groups of all records with the
+// given IP.
+func (r *RecordSet) InstanceGroupsForIP(ip string) []string {
+	r.recordsMutex.RLock()
+	defer r.recordsMutex.RUnlock()
 
-	domains := make(map[string]struct{})
-	for _, record := range r.Records {
-		domains[record.Domain] = struct{}{}
-	}
-	for domain := range domains {
-		r.domains = append(r.domains, domain)
+	groups := []string{}
+	for _, record := range r.index.RecordsWithIP(ip) {
+		groups = append(groups, record.Group)
 	}
-}
 
-func (r *RecordSet) ipsMatching(matcher Matcher) []string {
-	ips := []string{}
+	return groups
+}
 
-	for _, record := range r.Records {
-		if matcher.Match(&record) {
-			ips = append(ips, record.IP)
+// AliasResolvesToIP reports whether resolving alias, ignoring health, can
+// yield ip.
+func (r *RecordSet) AliasResolvesToIP(alias, ip string) bool {
+	for _, resolvedIP := range r.AliasIPs(alias) {
+		if resolvedIP == ip {
+			return true
 		}
 	}
 
-	return ips
+	return false
 }
 
-func (r *RecordSet) resolveQuery(fqdn string) ([]string, criteria, error) {
-	var ips []string
-
-	segments := strings.SplitN(fqdn, ".", 2) // [q-s0, q-g7.x.y.bosh]
-
-	if len(segments) < 2 {
-		return ips, criteria{}, errors.New("domain is malformed")
-	}
+// AliasIPs returns every IP alias resolves to, ignoring health.
+func (r *RecordSet) AliasIPs(alias string) []string {
+	r.recordsMutex.RLock()
+	defer r.recordsMutex.RUnlock()
 
-	var tld string
-	for _, possible := range r.domains { // do these/do these have to end in a . ?
-		if strings.HasSuffix(fqdn, possible) {
-			tld = possible
-			break
+	ips := []string{}
+	for _, resolution := range r.aliasList.Resolutions(alias) {
+		if net.ParseIP(resolution) != nil {
+			ips = append(ips, resolution)
+			continue
 		}
-	}
 
-	if tld == "" {
-		return []string{}, criteria{}, nil
-	}
-
-	groupQuery := strings.TrimSuffix(segments[1], "."+tld)
-	groupSegments := strings.Split(groupQuery, ".")
-	var c criteria
-	var err error
-	if len(groupSegments) == 1 {
-		c, err = parseCriteria(segments[0], groupQuery, "", "", "", tld)
-		if err != nil {
-			return ips, c, err
-		}
-	} else if len(groupSegments) == 3 {
-		c, err = parseCriteria(segments[0], "", groupSegments[0], groupSegments[1], groupSegments[2], tld)
+		resolvedIPs, _, err := r.resolveQuery(resolution)
 		if err != nil {
-			return ips, c, err
-		}
-	} else {
-		panic(fmt.Sprintf("Bad group segment query had %d values %#v\n", len(groupSegments), groupSegments))
-	}
-
-	matcher := new(AndMatcher)
-	for field, values := range c {
-		// healthiness is not handled by the normal recordset
-		if field == "s" {
 			continue
 		}
-		matcher.Append(Field(field, values))
+
+		ips = append(ips, resolvedIPs...)
 	}
 
-	return r.ipsMatching(matcher), c, nil
+	return ips
 }
 
-func createFromJSON(j []byte, logger boshlog.Logger) ([]Record, error) {
-	swap := struct {
-		Keys  []string        `json:"record_keys"`
-		Infos [][]interface{} `json:"record_infos"`
-	}{}
+// Aliases returns the names of all configured aliases.
+func (r *RecordSet) Aliases() []string {
+	return r.aliasList.Aliases()
+}
 
-	err := json.Unmarshal(j, &swap)
-	if err != nil {
-		return nil, err
-	}
+// SearchSuffixesForIPs returns the <network>.<deployment>.<domain> names of
+// the records with any of ips, under which the names of their instance groups
+// resolve.
+func (r *RecordSet) SearchSuffixesForIPs(ips []string) []string {
+	r.recordsMutex.RLock()
+	defer r.recordsMutex.RUnlock()
 
-	records := make([]Record, 0, len(swap.Infos))
-
-	idIndex := -1
-	numIDIndex := -1
-	groupIndex := -1
-	networkIndex := -1
-	networkIDIndex := -1
-	deploymentIndex := -1
-	ipIndex := -1
-	domainIndex := -1
-	azIDIndex := -1
-	instanceIndexIndex := -1
-	groupIdsIndex := -1
-
-	for i, k := range swap.Keys {
-		switch k {
-		case "id":
-			idIndex = i
-		case "num_id":
-			numIDIndex = i
-		case "instance_group":
-			groupIndex = i
-		case "group_ids":
-			groupIdsIndex = i
-		case "network":
-			networkIndex = i
-		case "network_id":
-			networkIDIndex = i
-		case "deployment":
-			deploymentIndex = i
-		case "ip":
-			ipIndex = i
-		case "domain":
-			domainIndex = i
-		case "az_id":
-			azIDIndex = i
-		case "instance_index":
-			instanceIndexIndex = i
-		default:
-			continue
+	suffixes := []string{}
+	seen := map[string]bool{}
+	for _, ip := range ips {
+		for _, record := range r.index.RecordsWithIP(ip) {
+			suffix := dns.Fqdn(fmt.Sprintf("%s.%s.%s", record.Network, record.Deployment, record.Domain))
+			if !seen[suffix] {
+				seen[suffix] = true
+				suffixes = append(suffixes, suffix)
+			}
 		}
 	}
 
-	countKeys := len(swap.Keys)
+	return suffixes
+}
 
-	for index, info := range swap.Infos {
-		countInfo := len(info)
-		if countInfo != countKeys {
-			logger.Warn("RecordSet", "Unbalanced records structure. Found %d fields of an expected %d at record #%d", countInfo, countKeys, index)
-			continue
-		}
+// AllRecords returns the currently loaded records. Unlike reading Records
+// directly it is safe while reloads happen.
+func (r *RecordSet) AllRecords() []Record {
+	r.recordsMutex.RLock()
+	defer r.recordsMutex.RUnlock()
 
-		var domainIndexStr string
-		if !requiredStringValue(&domainIndexStr, info, domainIndex, "domain", index, logger) {
-			continue
-		}
+	return r.Records
+}
 
-		domain := dns.Fqdn(domainIndexStr)
+func (r *RecordSet) update() RecordsDiff {
+	contents, err := r.recordFileReader.Get()
+	if err != nil {
+		r.logger.Error("RecordSet", "Unable to read records, keeping previous records: %s", err)
+		return RecordsDiff{}
+	}
+	records, report, err := createFromJSON(contents, r.logger)
+	if err != nil {
+		r.logger.Error("RecordSet", "Unable to parse records, keeping previous records: %s", err)
+		return RecordsDiff{}
+	}
 
-		record := Record{Domain: domain}
+	if !report.Valid() {
+		r.logger.Info("RecordSet", "Records failed validation: %s", report.Summary())
+	}
 
-		if !requiredStringValue(&record.ID, info, idIndex, "id", index, logger) {
-			continue
-		} else if !requiredStringValue(&record.Group, info, groupIndex, "group", index, logger) {
-			continue
-		} else if !requiredStringValue(&record.Network, info, networkIndex, "network", index, logger) {
-			continue
-		} else if !requiredStringValue(&record.Deployment, info, deploymentIndex, "deployment", index, logger) {
-			continue
-		} else if !requiredStringValue(&record.IP, info, ipIndex, "ip", index, logger) {
-			continue
-		} else if !optionalStringValue(&record.AZID, info, azIDIndex, "az_id", index, logger) {
-			continue
-		} else if !optionalStringValue(&record.NetworkID, info, networkIDIndex, "network_id", index, logger) {
-			continue
-		} else if !optionalStringValue(&record.NumId, info, numIDIndex, "num_id", index, logger) {
-			continue
-		} else if groupIdsIndex >= 0 && !assertStringArrayOfStringValue(&record.GroupIDs, info, groupIdsIndex, "group_ids", index, logger) {
-			continue
-		}
+	index := newRecordIndex(records)
 
-		assertStringIntegerValue(&record.InstanceIndex, info, instanceIndexIndex, "instance_index", index, logger)
+	r.recordsMutex.Lock()
+	diff := diffRecords(r.Records, records)
+	loaded := r.loaded
+	r.Records = records
+	r.index = index
+	r.domains = index.Domains()
+	r.loaded = true
+	r.recordsMutex.Unlock()
 
-		records = append(records, record)
+	if loaded {
+		r.logChanges(diff)
 	}
 
-	return records, nil
+	return diff
 }
 
-func assertStringIntegerValue(field *string, info []interface{}, fieldIdx int, fieldName string, infoIdx int, logger boshlog.Logger) bool {
-	if fieldIdx < 0 {
-		return false
+func (r *RecordSet) logChanges(diff RecordsDiff) {
+	for _, record := range diff.Added {
+		r.logger.Info("RecordSet", "Instance %s added with IP %s", describeRecord(record), record.IP)
 	}
 
-	float64Value, ok := info[fieldIdx].(float64) // golang default type for numeric fields
-	if !ok {
-		logger.Warn("RecordSet", "Value %d (%s) of record %d is not expected type of %s: %#+v", fieldIdx, fieldName, infoIdx, "numeric", info[fieldIdx])
+	for _, record := range diff.Removed {
+		r.logger.Info("RecordSet", "Instance %s removed, had IP %s", describeRecord(record), record.IP)
 	}
 
-	*field = strconv.Itoa(int(float64Value))
-	return ok
-}
-
-func convertToStringValue(field *string, info []interface{}, fieldIdx int, fieldName string, infoIdx int, logger boshlog.Logger) bool {
-	var ok bool
-	*field, ok = info[fieldIdx].(string)
-
-	if !ok {
-		logger.Warn("RecordSet", "Value %d (%s) of record %d is not expected type of %s: %#+v", fieldIdx, fieldName, infoIdx, "string", info[fieldIdx])
+	for _, change := range diff.Changed {
+		if change.Old.IP != change.New.IP {
+			r.logger.Info("RecordSet", "Instance %s moved from IP %s to %s", describeRecord(change.New), change.Old.IP, change.New.IP)
+		} else {
+			r.logger.Info("RecordSet", "Instance %s with IP %s changed", describeRecord(change.New), change.New.IP)
+		}
 	}
+}
 
-	return ok
+func describeRecord(record Record) string {
+	return fmt.Sprintf("%s.%s.%s.%s.%s", record.ID, record.Group, record.Network, record.Deployment, record.Domain)
 }
 
-func optionalStringValue(field *string, info []interface{}, fieldIdx int, fieldName string, infoIdx int, logger boshlog.Logger) bool {
-	if fieldIdx >= 0 {
-		if info[fieldIdx] == nil {
-			info[fieldIdx] = ""
-			return true
-		}
-		return convertToStringValue(field, info, fieldIdx, fieldName, infoIdx, logger)
+func (r *RecordSet) resolveQuery(fqdn string) ([]string, criteria, error) {
+	c, err := r.parseQuery(fqdn)
+	if err != nil {
+		return nil, c, err
 	}
 
-	return true
-}
-
-func requiredStringValue(field *string, info []interface{}, fieldIdx int, fieldName string, infoIdx int, logger boshlog.Logger) bool {
-	if fieldIdx < 0 {
-		return false
+	if c == nil {
+		return []string{}, criteria{}, nil
 	}
 
-	return convertToStringValue(field, info, fieldIdx, fieldName, infoIdx, logger)
+	return r.index.IPsMatching(c), c, nil
 }
 
-func assertStringArrayOfStringValue(field *[]string, info []interface{}, fieldIdx int, fieldName string, infoIdx int, logger boshlog.Logger) bool {
-	var ok bool
-	var intermediateField []interface{}
+// parseQuery returns nil criteria when fqdn is not under any known domain.
+func (r *RecordSet) parseQuery(fqdn string) (criteria, error) {
+	segments := strings.SplitN(fqdn, ".", 2) // [q-s0, q-g7.x.y.bosh]
 
-	intermediateField, ok = info[fieldIdx].([]interface{})
-	if !ok {
-		logger.Warn("RecordSet", "Value %d (%s) of record %d is not expected type of %s: %#+v", fieldIdx, fieldName, infoIdx, "array of string", info[fieldIdx])
+	if len(segments) < 2 {
+		return criteria{}, errors.New("domain is malformed")
 	}
-	out := make([]string, len(intermediateField))
-	for i, v := range intermediateField {
-		out[i], ok = v.(string)
-		if !ok {
-			logger.Warn("RecordSet", "Value %d (%s) of record %d is not expected type of %s: %#+v", fieldIdx, fieldName, infoIdx, "array of string", info[fieldIdx])
-			return ok
-		}
+
+	tld := r.index.DomainFor(segments[1])
+	if tld == "" {
+		return nil, nil
 	}
 
-	*field = out
+	groupQuery := strings.TrimSuffix(segments[1], "."+tld)
+	groupSegments := strings.Split(groupQuery, ".")
+	var c criteria
+	var err error
+	if len(groupSegments) == 1 {
+		c, err = parseCriteria(segments[0], groupQuery, "", "", "", tld)
+	} else if len(groupSegments) == 2 {
+		// <group>.<network>.<deployment>.<domain> is every healthy instance of
+		// the group, like q-s0.<group>.<network>.<deployment>.<domain>
+		c, err = parseCriteria("q-s0", "", segments[0], groupSegments[0], groupSegments[1], tld)
+	} else if len(groupSegments) == 3 {
+		c, err = parseCriteria(segments[0], "", groupSegments[0], groupSegments[1], groupSegments[2], tld)
+	} else {
+		return criteria{}, errors.New("domain is malformed")
+	}
 
-	return ok
+	return c, err
 }
@@ -12,7 +12,9 @@ import (
 
 	"fmt"
 
+	"code.cloudfoundry.org/clock/fakeclock"
 	"github.com/cloudfoundry/bosh-utils/logger/fakes"
+	sysfakes "github.com/cloudfoundry/bosh-utils/system/fakes"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
@@ -244,6 +246,50 @@ var _ = Describe("RecordSet", func() {
 		})
 	})
 
+	Context("when the records file has a row of the wrong length", func() {
+		BeforeEach(func() {
+			fakeFileSystem := sysfakes.NewFakeFileSystem()
+			err := fakeFileSystem.WriteFileString("/fake/records.json", `{
+				"record_keys": ["id", "instance_group", "az", "network", "deployment", "ip", "domain"],
+				"record_infos": [
+					["instance0", "my-group", "az1", "my-network", "my-deployment", "123.123.123.123", "bosh."],
+					["instance1", "my-group", "az1", "my-network", "my-deployment"],
+					["instance2", "my-group", "az1", "my-network", "my-deployment", "123.123.123.125", "bosh."]
+				]
+			}`)
+			Expect(err).NotTo(HaveOccurred())
+
+			fileReader := records.NewFileReader("/fake/records.json", fakeFileSystem, fakeclock.NewFakeClock(time.Now()), fakeLogger, shutdownChan)
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("still resolves the good rows", func() {
+			ips, err := recordSet.Resolve("instance0.my-group.my-network.my-deployment.bosh.")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]string{"123.123.123.123"}))
+
+			ips, err = recordSet.Resolve("instance2.my-group.my-network.my-deployment.bosh.")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]string{"123.123.123.125"}))
+
+			ips, err = recordSet.Resolve("instance1.my-group.my-network.my-deployment.bosh.")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(BeEmpty())
+		})
+
+		It("reports the bad row", func() {
+			messages := []string{}
+			for i := 0; i < fakeLogger.InfoCallCount(); i++ {
+				tag, message, args := fakeLogger.InfoArgsForCall(i)
+				if tag == "RecordSet" {
+					messages = append(messages, fmt.Sprintf(message, args...))
+				}
+			}
+			Expect(messages).To(Equal([]string{"Records failed validation: 2 of 3 rows valid, 1 row problems, first row 1: found 5 fields, expected 7"}))
+		})
+	})
+
 	Describe("Domains", func() {
 		BeforeEach(func() {
 			aliasList = aliases.MustNewConfigFromMap(map[string][]string{
@@ -308,9 +354,8 @@ var _ = Describe("RecordSet", func() {
 				]
 			}`)
 				fileReader.GetReturns(jsonBytes, nil)
+				subscribers = []<-chan bool{recordSet.Subscribe(), recordSet.Subscribe()}
 				subscriptionChan <- true
-				subscribers = append(subscribers, recordSet.Subscribe())
-				subscribers = append(subscribers, recordSet.Subscribe())
 			})
 
 			It("updates its set of records", func() {
@@ -326,6 +371,109 @@ var _ = Describe("RecordSet", func() {
 					Eventually(subscriber).Should(Receive(BeTrue()))
 				}
 			})
+
+			It("does not wait for subscribers which are not receiving", func() {
+				reloaded := make(chan struct{})
+				go func() {
+					defer close(reloaded)
+					for i := 0; i < 20; i++ {
+						subscriptionChan <- true
+					}
+				}()
+
+				Eventually(reloaded).Should(BeClosed())
+				for _, subscriber := range subscribers {
+					Eventually(subscriber).Should(Receive(BeTrue()))
+				}
+			})
+
+			It("does not accumulate domains across updates", func() {
+				Eventually(func() []string {
+					ips, _ := recordSet.Resolve("instance0.my-group.my-network.my-deployment.bosh.")
+					return ips
+				}).Should(Equal([]string{"234.234.234.234"}))
+
+				Expect(recordSet.Domains()).To(Equal([]string{"bosh."}))
+			})
+		})
+
+		Context("when records are added, removed and changed", func() {
+			var changes <-chan records.RecordsDiff
+
+			BeforeEach(func() {
+				changes = recordSet.SubscribeChanges()
+
+				jsonBytes := []byte(`{
+				"record_keys": ["id", "num_id", "instance_group", "az", "az_id", "network", "network_id", "deployment", "ip", "domain"],
+				"record_infos": [
+					["instance0", "0", "my-group", "az1", "1", "my-network", "1", "my-deployment", "234.234.234.234", "bosh."],
+					["instance1", "1", "my-group", "az1", "1", "my-network", "1", "my-deployment", "123.123.123.124", "bosh."]
+				]
+			}`)
+				fileReader.GetReturns(jsonBytes, nil)
+				subscriptionChan <- true
+			})
+
+			It("publishes the differences", func() {
+				var diff records.RecordsDiff
+				Eventually(changes).Should(Receive(&diff))
+
+				Expect(diff.Added).To(HaveLen(1))
+				Expect(diff.Added[0].ID).To(Equal("instance1"))
+				Expect(diff.Removed).To(BeEmpty())
+				Expect(diff.Changed).To(HaveLen(1))
+				Expect(diff.Changed[0].Old.IP).To(Equal("123.123.123.123"))
+				Expect(diff.Changed[0].New.IP).To(Equal("234.234.234.234"))
+			})
+
+			It("logs an audit trail of the changes", func() {
+				Eventually(changes).Should(Receive())
+
+				messages := []string{}
+				for i := 0; i < fakeLogger.InfoCallCount(); i++ {
+					_, message, args := fakeLogger.InfoArgsForCall(i)
+					messages = append(messages, fmt.Sprintf(message, args...))
+				}
+
+				Expect(messages).To(ConsistOf(
+					"Instance instance1.my-group.my-network.my-deployment.bosh. added with IP 123.123.123.124",
+					"Instance instance0.my-group.my-network.my-deployment.bosh. moved from IP 123.123.123.123 to 234.234.234.234",
+				))
+			})
+
+			Context("when an instance is then removed", func() {
+				BeforeEach(func() {
+					Eventually(changes).Should(Receive())
+
+					jsonBytes := []byte(`{
+					"record_keys": ["id", "num_id", "instance_group", "az", "az_id", "network", "network_id", "deployment", "ip", "domain"],
+					"record_infos": [
+						["instance1", "1", "my-group", "az1", "1", "my-network", "1", "my-deployment", "123.123.123.124", "bosh."]
+					]
+				}`)
+					fileReader.GetReturns(jsonBytes, nil)
+					subscriptionChan <- true
+				})
+
+				It("publishes the removal", func() {
+					var diff records.RecordsDiff
+					Eventually(changes).Should(Receive(&diff))
+
+					Expect(diff.Added).To(BeEmpty())
+					Expect(diff.Changed).To(BeEmpty())
+					Expect(diff.Removed).To(HaveLen(1))
+					Expect(diff.Removed[0].IP).To(Equal("234.234.234.234"))
+				})
+			})
+		})
+
+		Context("when the records did not change", func() {
+			It("does not publish a change", func() {
+				changes := recordSet.SubscribeChanges()
+				subscriptionChan <- true
+
+				Consistently(changes).ShouldNot(Receive())
+			})
 		})
 
 		Context("when the subscription is closed", func() {
@@ -353,6 +501,13 @@ var _ = Describe("RecordSet", func() {
 				subscriptionChan <- true
 			})
 
+			It("logs the failure", func() {
+				Eventually(fakeLogger.ErrorCallCount).Should(Equal(1))
+				tag, message, _ := fakeLogger.ErrorArgsForCall(0)
+				Expect(tag).To(Equal("RecordSet"))
+				Expect(message).To(Equal("Unable to parse records, keeping previous records: %s"))
+			})
+
 			It("keeps the original set of records", func() {
 				Consistently(func() []string {
 					ips, err := recordSet.Resolve("instance0.my-group.my-network.my-deployment.bosh.")
@@ -368,6 +523,14 @@ var _ = Describe("RecordSet", func() {
 				subscriptionChan <- true
 			})
 
+			It("logs the failure", func() {
+				Eventually(fakeLogger.ErrorCallCount).Should(Equal(1))
+				tag, message, args := fakeLogger.ErrorArgsForCall(0)
+				Expect(tag).To(Equal("RecordSet"))
+				Expect(message).To(Equal("Unable to read records, keeping previous records: %s"))
+				Expect(args).To(Equal([]interface{}{errors.New("no read")}))
+			})
+
 			It("keeps the original set of records", func() {
 				Consistently(func() []string {
 					ips, err := recordSet.Resolve("instance0.my-group.my-network.my-deployment.bosh.")
@@ -491,6 +654,46 @@ var _ = Describe("RecordSet", func() {
 				Entry("missing domain", "domain"),
 			)
 
+			It("keeps unknown columns as attributes", func() {
+				jsonBytes := []byte(`{
+					"record_keys": ["id", "instance_group", "network", "deployment", "ip", "domain", "weight", "txt"],
+					"record_infos": [
+						["id", "instance_group", "network", "deployment", "ip", "domain", 10, {"owner": "team"}]
+					]
+				}`)
+				fileReader.GetReturns(jsonBytes, nil)
+
+				var err error
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(recordSet.Records).To(HaveLen(1))
+				Expect(recordSet.Records[0].Attributes).To(Equal(map[string]interface{}{
+					"weight": float64(10),
+					"txt":    map[string]interface{}{"owner": "team"},
+				}))
+			})
+
+			It("logs a validation summary when rows are dropped", func() {
+				jsonBytes := []byte(`{
+					"record_keys": ["id", "instance_group", "network", "deployment", "ip", "domain"],
+					"record_infos": [
+						["id", "instance_group", "network", "deployment", "ip", "domain"],
+						["id", "instance_group", "network", "deployment", "ip"]
+					]
+				}`)
+				fileReader.GetReturns(jsonBytes, nil)
+
+				var err error
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeLogger.InfoCallCount()).To(Equal(1))
+				tag, message, args := fakeLogger.InfoArgsForCall(0)
+				Expect(tag).To(Equal("RecordSet"))
+				Expect(message).To(Equal("Records failed validation: %s"))
+				Expect(args).To(Equal([]interface{}{"1 of 2 rows valid, 1 row problems, first row 1: found 5 fields, expected 6"}))
+			})
+
 			It("includes records that are well-formed but missing individual group_ids values", func() {
 				jsonBytes := []byte(`{
 					"record_keys": ["id", "instance_group", "group_ids", "network", "deployment", "ip", "domain"],
@@ -622,6 +825,63 @@ var _ = Describe("RecordSet", func() {
 		})
 	})
 
+	Describe("SearchSuffixesForIPs", func() {
+		BeforeEach(func() {
+			jsonBytes := []byte(`{
+				"record_keys": ["id", "instance_group", "network", "deployment", "ip", "domain"],
+				"record_infos": [
+					["instance0", "my-group", "my-network", "my-deployment", "123.123.123.123", "my-domain"],
+					["instance0", "my-group", "my-network", "my-deployment", "123.123.123.123", "other-domain"],
+					["instance1", "my-group", "other-network", "my-deployment", "123.123.123.124", "my-domain"],
+					["instance2", "other-group", "my-network", "other-deployment", "123.123.123.125", "my-domain"]
+				]
+			}`)
+			fileReader.GetReturns(jsonBytes, nil)
+
+			var err error
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the network, deployment and domain of the records with the ips in order", func() {
+			Expect(recordSet.SearchSuffixesForIPs([]string{"123.123.123.124", "123.123.123.123", "123.123.123.124"})).To(Equal([]string{
+				"other-network.my-deployment.my-domain.",
+				"my-network.my-deployment.my-domain.",
+				"my-network.my-deployment.other-domain.",
+			}))
+		})
+
+		It("returns nothing for unknown ips", func() {
+			Expect(recordSet.SearchSuffixesForIPs([]string{"10.0.0.1"})).To(BeEmpty())
+		})
+	})
+
+	Describe("RecordsWithIP", func() {
+		BeforeEach(func() {
+			jsonBytes := []byte(`{
+				"record_keys": ["id", "instance_group", "network", "deployment", "ip", "domain"],
+				"record_infos": [
+					["instance0", "my-group", "my-network", "my-deployment", "123.123.123.123", "my-domain"],
+					["instance1", "my-group", "my-network", "my-deployment", "123.123.123.124", "my-domain"]
+				]
+			}`)
+			fileReader.GetReturns(jsonBytes, nil)
+
+			var err error
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the records with the ip", func() {
+			matches := recordSet.RecordsWithIP("123.123.123.124")
+			Expect(matches).To(HaveLen(1))
+			Expect(matches[0].ID).To(Equal("instance1"))
+			Expect(matches[0].Domain).To(Equal("my-domain."))
+
+			Expect(recordSet.RecordsWithIP("10.0.0.1")).To(BeEmpty())
+		})
+	})
+
 	Describe("Resolve", func() {
 		Context("when there are records matching the query based fqdn", func() {
 			BeforeEach(func() {
@@ -672,6 +932,18 @@ var _ = Describe("RecordSet", func() {
 				Expect(ips).To(ContainElement("123.123.123.123"))
 			})
 
+			It("resolves group names to the healthy instances of the group", func() {
+				ips, err := recordSet.Resolve("my-group.my-network.my-deployment.my-domain.")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ips).To(ConsistOf("123.123.123.123", "123.123.123.124", "123.123.123.126"))
+			})
+
+			It("returns an error for names with too many labels", func() {
+				ips, err := recordSet.Resolve("a.b.instance0.my-group.my-network.my-deployment.my-domain.")
+				Expect(err).To(HaveOccurred())
+				Expect(ips).To(BeEmpty())
+			})
+
 			Context("when the query contains poorly formed contents", func() {
 				It("returns an empty set", func() {
 					ips, err := recordSet.Resolve("q-missingvalue.my-group.my-network.my-deployment.my-domain.")
@@ -934,6 +1206,15 @@ var _ = Describe("RecordSet", func() {
 							Expect(records).To(HaveLen(0))
 						})
 					})
+
+					Context("when the fqdn only shares a partial label with a domain", func() {
+						It("returns an empty set of records", func() {
+							records, err := recordSet.Resolve("my-instance.my-group.my-network.my-deployment.not-my-domain.")
+							Expect(err).NotTo(HaveOccurred())
+
+							Expect(records).To(HaveLen(0))
+						})
+					})
 				})
 
 				Context("when fqdn is already an IP address", func() {
@@ -1236,10 +1517,107 @@ var _ = Describe("RecordSet", func() {
 				})
 			})
 
+			Context("when records which no tracked name resolves to change", func() {
+				var updated <-chan bool
+
+				BeforeEach(func() {
+					recordSet.Resolve("q-s0.my-group.my-network.my-deployment.my-domain.")
+					Expect(fakeHealthWatcher.IsHealthyCallCount()).To(Equal(2))
+
+					jsonBytes := []byte(`{
+					"record_keys":
+						["id", "num_id", "instance_group", "group_ids", "az", "az_id", "network", "network_id", "deployment", "ip", "domain", "instance_index"],
+					"record_infos": [
+						["instance0", "0", "my-group", ["1"], "az1", "1", "my-network", "1", "my-deployment", "123.123.123.123", "my-domain", 1],
+						["instance1", "1", "my-group", ["1"], "az2", "2", "my-network", "1", "my-deployment", "123.123.123.246", "my-domain", 2],
+						["instance1", "1", "my-group", ["1"], "az2", "2", "my-network", "1", "my-deployment", "246.246.246.1", "a1_domain1", 1]
+					]
+				}`)
+
+					fileReader.GetReturns(jsonBytes, nil)
+
+					updated = recordSet.Subscribe()
+					subscriptionChan <- true
+				})
+
+				It("leaves health checking alone", func() {
+					Eventually(updated).Should(Receive())
+
+					Expect(fakeHealthWatcher.IsHealthyCallCount()).To(Equal(2))
+					Expect(fakeHealthWatcher.UntrackCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when a tracked name which resolved to no IPs gains instances", func() {
+				BeforeEach(func() {
+					ips, err := recordSet.Resolve("q-s0.other-group.my-network.my-deployment.my-domain.")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ips).To(BeEmpty())
+
+					jsonBytes := []byte(`{
+					"record_keys":
+						["id", "num_id", "instance_group", "group_ids", "az", "az_id", "network", "network_id", "deployment", "ip", "domain", "instance_index"],
+					"record_infos": [
+						["instance0", "0", "my-group", ["1"], "az1", "1", "my-network", "1", "my-deployment", "123.123.123.123", "my-domain", 1],
+						["instance1", "1", "my-group", ["1"], "az2", "2", "my-network", "1", "my-deployment", "123.123.123.246", "my-domain", 2],
+						["instance2", "2", "other-group", ["2"], "az1", "1", "my-network", "1", "my-deployment", "123.123.123.7", "my-domain", 0]
+					]
+				}`)
+
+					fileReader.GetReturns(jsonBytes, nil)
+
+					subscriptionChan <- true
+				})
+
+				It("checks the health of the new instances", func() {
+					Eventually(fakeHealthWatcher.IsHealthyCallCount).Should(Equal(1))
+					Expect(fakeHealthWatcher.IsHealthyArgsForCall(0)).To(Equal("123.123.123.7"))
+				})
+			})
+
+			Context("when the ips behind a tracked alias change", func() {
+				BeforeEach(func() {
+					_, err := recordSet.Resolve("alias1.")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeHealthWatcher.IsHealthyCallCount()).To(Equal(2))
+
+					jsonBytes := []byte(`{
+					"record_keys":
+						["id", "num_id", "instance_group", "group_ids", "az", "az_id", "network", "network_id", "deployment", "ip", "domain", "instance_index"],
+					"record_infos": [
+						["instance0", "0", "my-group", ["1"], "az1", "1", "my-network", "1", "my-deployment", "123.123.123.123", "my-domain", 1],
+						["instance1", "1", "my-group", ["1"], "az2", "2", "my-network", "1", "my-deployment", "123.123.123.246", "my-domain", 2],
+						["instance1", "1", "my-group", ["1"], "az2", "2", "my-network", "1", "my-deployment", "246.246.246.1", "a1_domain1", 1],
+						["instance1", "1", "my-group", ["1"], "az2", "2", "my-network", "1", "my-deployment", "246.246.246.247", "a1_domain2", 2]
+					]
+				}`)
+
+					fileReader.GetReturns(jsonBytes, nil)
+
+					subscriptionChan <- true
+				})
+
+				It("checks the new ones and stops tracking the old ones", func() {
+					Eventually(fakeHealthWatcher.IsHealthyCallCount).Should(Equal(3))
+					Expect(fakeHealthWatcher.IsHealthyArgsForCall(2)).To(Equal("246.246.246.1"))
+
+					Eventually(fakeHealthWatcher.UntrackCallCount).Should(Equal(1))
+					Expect(fakeHealthWatcher.UntrackArgsForCall(0)).To(Equal("246.246.246.246"))
+				})
+			})
+
 			Context("when the ips not under a tracked domain change", func() {
 				Describe("limiting tracked domains", func() {
+					var (
+						limitedFileReader       *recordsfakes.FakeFileReader
+						limitedSubscriptionChan chan bool
+					)
+
 					BeforeEach(func() {
 						fakeHealthWatcher.IsHealthyReturns(true)
+						limitedSubscriptionChan = make(chan bool, 1)
+						limitedFileReader = &recordsfakes.FakeFileReader{}
+						limitedFileReader.SubscribeReturns(limitedSubscriptionChan)
 
 						jsonBytes := []byte(`{
 					"record_keys":
@@ -1255,10 +1633,10 @@ var _ = Describe("RecordSet", func() {
 					]
 				}`)
 
-						fileReader.GetReturns(jsonBytes, nil)
+						limitedFileReader.GetReturns(jsonBytes, nil)
 
 						var err error
-						recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger)
+						recordSet, err = records.NewRecordSet(limitedFileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger)
 
 						Expect(err).ToNot(HaveOccurred())
 					})
@@ -1278,8 +1656,67 @@ var _ = Describe("RecordSet", func() {
 							"123.123.123.124",
 						))
 					})
+
+					It("checks IPs it stopped tracking again once a tracked name resolves to them", func() {
+						for i := 1; i <= 7; i++ {
+							recordSet.Resolve(fmt.Sprintf("q-i%d.my-group.my-network.my-deployment.my-domain.", i))
+						}
+						Eventually(fakeHealthWatcher.UntrackCallCount).Should(Equal(2))
+						checks := fakeHealthWatcher.IsHealthyCallCount()
+
+						limitedFileReader.GetReturns([]byte(`{
+					"record_keys":
+						["id", "num_id", "instance_group", "group_ids", "az", "az_id", "network", "network_id", "deployment", "ip", "domain", "instance_index"],
+					"record_infos": [
+						["instance2", "2", "my-group", ["1"], "az1", "1", "my-network", "1", "my-deployment", "123.123.123.125", "my-domain", 3],
+						["instance3", "3", "my-group", ["1"], "az2", "2", "my-network", "1", "my-deployment", "123.123.123.126", "my-domain", 4],
+						["instance4", "4", "my-group", ["1"], "az1", "1", "my-network", "1", "my-deployment", "123.123.123.127", "my-domain", 5],
+						["instance5", "5", "my-group", ["1"], "az1", "1", "my-network", "1", "my-deployment", "123.123.123.128", "my-domain", 6],
+						["instance6", "6", "my-group", ["1"], "az1", "1", "my-network", "1", "my-deployment", "123.123.123.123", "my-domain", 7]
+					]
+				}`), nil)
+						limitedSubscriptionChan <- true
+
+						Eventually(fakeHealthWatcher.IsHealthyCallCount).Should(Equal(checks + 1))
+						Expect(fakeHealthWatcher.IsHealthyArgsForCall(checks)).To(Equal("123.123.123.123"))
+					})
 				})
 			})
 		})
 	})
+
+	Describe("health check targets", func() {
+		BeforeEach(func() {
+			aliasList = aliases.MustNewConfigFromMap(map[string][]string{
+				"legacy.internal": {"q-s0.my-group.my-network.my-deployment.my-domain.", "10.0.0.9"},
+			})
+
+			jsonBytes := []byte(`{
+				"record_keys": ["id", "num_id", "instance_group", "az", "az_id", "network", "network_id", "deployment", "ip", "domain"],
+				"record_infos": [
+					["instance0", "0", "my-group", "az1", "1", "my-network", "1", "my-deployment", "123.123.123.123", "my-domain"],
+					["instance1", "1", "my-group", "az2", "2", "my-network", "1", "my-deployment", "123.123.123.124", "my-domain"],
+					["instance2", "2", "other-group", "az1", "1", "my-network", "1", "other-deployment", "123.123.123.124", "my-domain"]
+				]
+			}`)
+			fileReader.GetReturns(jsonBytes, nil)
+
+			var err error
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the instance groups of an IP", func() {
+			Expect(recordSet.InstanceGroupsForIP("123.123.123.124")).To(ConsistOf("my-group", "other-group"))
+			Expect(recordSet.InstanceGroupsForIP("123.123.123.123")).To(ConsistOf("my-group"))
+			Expect(recordSet.InstanceGroupsForIP("1.1.1.1")).To(BeEmpty())
+		})
+
+		It("tells whether an alias resolves to an IP", func() {
+			Expect(recordSet.AliasResolvesToIP("legacy.internal.", "123.123.123.123")).To(BeTrue())
+			Expect(recordSet.AliasResolvesToIP("legacy.internal.", "10.0.0.9")).To(BeTrue())
+			Expect(recordSet.AliasResolvesToIP("legacy.internal.", "1.1.1.1")).To(BeFalse())
+			Expect(recordSet.AliasResolvesToIP("unknown.internal.", "123.123.123.123")).To(BeFalse())
+		})
+	})
 })
@@ -0,0 +1,109 @@
+package records
+
+import "reflect"
+
+// RecordChange is a record whose instance is still present but whose IP or
+// other attributes changed.
+type RecordChange struct {
+	Old Record
+	New Record
+}
+
+// RecordsDiff is published to change subscribers after every reload which
+// altered the record set.
+type RecordsDiff struct {
+	Added   []Record
+	Removed []Record
+	Changed []RecordChange
+}
+
+func (d RecordsDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Affects reports whether any added, removed or changed record, before or
+// after the change, satisfies matcher.
+func (d RecordsDiff) Affects(matcher Matcher) bool {
+	for i := range d.Added {
+		if matcher.Match(&d.Added[i]) {
+			return true
+		}
+	}
+
+	for i := range d.Removed {
+		if matcher.Match(&d.Removed[i]) {
+			return true
+		}
+	}
+
+	for i := range d.Changed {
+		if matcher.Match(&d.Changed[i].Old) || matcher.Match(&d.Changed[i].New) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recordKey identifies the same instance on the same network and domain
+// across reloads. occurrence tells apart rows which repeat all of these.
+type recordKey struct {
+	id         string
+	group      string
+	network    string
+	deployment string
+	domain     string
+	occurrence int
+}
+
+func keyRecords(records []Record) map[recordKey]Record {
+	keyed := make(map[recordKey]Record, len(records))
+
+	for _, record := range records {
+		key := recordKey{
+			id:         record.ID,
+			group:      record.Group,
+			network:    record.Network,
+			deployment: record.Deployment,
+			domain:     record.Domain,
+		}
+
+		for {
+			if _, ok := keyed[key]; !ok {
+				break
+			}
+			key.occurrence++
+		}
+
+		keyed[key] = record
+	}
+
+	return keyed
+}
+
+func diffRecords(old, new []Record) RecordsDiff {
+	diff := RecordsDiff{}
+
+	oldKeyed := keyRecords(old)
+	newKeyed := keyRecords(new)
+
+	for key, newRecord := range newKeyed {
+		oldRecord, ok := oldKeyed[key]
+		if !ok {
+			diff.Added = append(diff.Added, newRecord)
+			continue
+		}
+
+		if !reflect.DeepEqual(oldRecord, newRecord) {
+			diff.Changed = append(diff.Changed, RecordChange{Old: oldRecord, New: newRecord})
+		}
+	}
+
+	for key, oldRecord := range oldKeyed {
+		if _, ok := newKeyed[key]; !ok {
+			diff.Removed = append(diff.Removed, oldRecord)
+		}
+	}
+
+	return diff
+}
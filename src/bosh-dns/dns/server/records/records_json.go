@@ -0,0 +1,339 @@
+package records
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+// SchemaVersion is the newest records.json schema_version understood by this
+// release. Files without a schema_version are treated as version 1.
+const SchemaVersion = 1
+
+var requiredColumns = []string{"id", "instance_group", "network", "deployment", "ip", "domain"}
+
+// knownColumns are parsed into Record fields or deliberately ignored; any
+// other column is kept on Record.Attributes.
+var knownColumns = map[string]struct{}{
+	"id":             {},
+	"num_id":         {},
+	"instance_group": {},
+	"group_ids":      {},
+	"az":             {},
+	"az_id":          {},
+	"network":        {},
+	"network_id":     {},
+	"deployment":     {},
+	"ip":             {},
+	"domain":         {},
+	"instance_index": {},
+}
+
+type recordsJSON struct {
+	SchemaVersion int             `json:"schema_version,omitempty"`
+	Keys          []string        `json:"record_keys"`
+	Infos         [][]interface{} `json:"record_infos"`
+}
+
+// ValidationReport describes every problem found in a records file. Rows are
+// numbered from 0 in the order of record_infos.
+type ValidationReport struct {
+	SchemaVersion  int          `json:"schema_version"`
+	Rows           int          `json:"rows"`
+	ValidRows      int          `json:"valid_rows"`
+	MissingColumns []string     `json:"missing_columns,omitempty"`
+	ExtraColumns   []string     `json:"extra_columns,omitempty"`
+	RowProblems    []RowProblem `json:"row_problems,omitempty"`
+}
+
+type RowProblem struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Problem string `json:"problem"`
+}
+
+func (p RowProblem) String() string {
+	if p.Column == "" {
+		return fmt.Sprintf("row %d: %s", p.Row, p.Problem)
+	}
+
+	return fmt.Sprintf("row %d, column %s: %s", p.Row, p.Column, p.Problem)
+}
+
+// Valid is false when any row was dropped or had a value of the wrong type.
+// Extra columns do not make a file invalid.
+func (r ValidationReport) Valid() bool {
+	return len(r.MissingColumns) == 0 && len(r.RowProblems) == 0
+}
+
+func (r ValidationReport) Summary() string {
+	summary := fmt.Sprintf("%d of %d rows valid", r.ValidRows, r.Rows)
+
+	if len(r.MissingColumns) > 0 {
+		summary += fmt.Sprintf(", missing required columns %s", strings.Join(r.MissingColumns, ", "))
+	}
+
+	if len(r.RowProblems) > 0 {
+		summary += fmt.Sprintf(", %d row problems, first %s", len(r.RowProblems), r.RowProblems[0])
+	}
+
+	return summary
+}
+
+func (r ValidationReport) String() string {
+	lines := []string{
+		fmt.Sprintf("schema version: %d", r.SchemaVersion),
+		fmt.Sprintf("rows: %d (%d valid)", r.Rows, r.ValidRows),
+	}
+
+	if len(r.MissingColumns) > 0 {
+		lines = append(lines, fmt.Sprintf("missing required columns: %s", strings.Join(r.MissingColumns, ", ")))
+	}
+
+	if len(r.ExtraColumns) > 0 {
+		lines = append(lines, fmt.Sprintf("extra columns kept as attributes: %s", strings.Join(r.ExtraColumns, ", ")))
+	}
+
+	for _, problem := range r.RowProblems {
+		lines = append(lines, problem.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ValidateRecords parses a records file the same way RecordSet does and
+// reports every problem found. An error is only returned for files which can
+// not be used at all.
+func ValidateRecords(contents []byte) (ValidationReport, error) {
+	_, report, err := createFromJSON(contents, boshlog.NewLogger(boshlog.LevelNone))
+	return report, err
+}
+
+type recordsParser struct {
+	keys   []string
+	logger boshlog.Logger
+	report *ValidationReport
+}
+
+func createFromJSON(j []byte, logger boshlog.Logger) ([]Record, ValidationReport, error) {
+	report := ValidationReport{}
+
+	var swap recordsJSON
+	err := json.Unmarshal(j, &swap)
+	if err != nil {
+		return nil, report, err
+	}
+
+	report.SchemaVersion = swap.SchemaVersion
+	if report.SchemaVersion == 0 {
+		report.SchemaVersion = 1
+	}
+
+	if report.SchemaVersion > SchemaVersion {
+		return nil, report, fmt.Errorf("unsupported records schema_version %d, newest supported is %d", swap.SchemaVersion, SchemaVersion)
+	}
+
+	report.Rows = len(swap.Infos)
+	p := recordsParser{keys: swap.Keys, logger: logger, report: &report}
+
+	records := make([]Record, 0, len(swap.Infos))
+
+	idIndex := -1
+	numIDIndex := -1
+	groupIndex := -1
+	networkIndex := -1
+	networkIDIndex := -1
+	deploymentIndex := -1
+	ipIndex := -1
+	domainIndex := -1
+	azIDIndex := -1
+	instanceIndexIndex := -1
+	groupIdsIndex := -1
+	attributeIndexes := map[string]int{}
+
+	for i, k := range swap.Keys {
+		switch k {
+		case "id":
+			idIndex = i
+		case "num_id":
+			numIDIndex = i
+		case "instance_group":
+			groupIndex = i
+		case "group_ids":
+			groupIdsIndex = i
+		case "network":
+			networkIndex = i
+		case "network_id":
+			networkIDIndex = i
+		case "deployment":
+			deploymentIndex = i
+		case "ip":
+			ipIndex = i
+		case "domain":
+			domainIndex = i
+		case "az_id":
+			azIDIndex = i
+		case "instance_index":
+			instanceIndexIndex = i
+		default:
+			if _, ok := knownColumns[k]; !ok {
+				attributeIndexes[k] = i
+				report.ExtraColumns = append(report.ExtraColumns, k)
+			}
+			continue
+		}
+	}
+
+	present := map[string]struct{}{}
+	for _, k := range swap.Keys {
+		present[k] = struct{}{}
+	}
+	for _, column := range requiredColumns {
+		if _, ok := present[column]; !ok {
+			report.MissingColumns = append(report.MissingColumns, column)
+		}
+	}
+
+	countKeys := len(swap.Keys)
+	mistyped := 0
+
+	for index, info := range swap.Infos {
+		countInfo := len(info)
+		if countInfo != countKeys {
+			logger.Warn("RecordSet", "Unbalanced records structure. Found %d fields of an expected %d at record #%d", countInfo, countKeys, index)
+			report.RowProblems = append(report.RowProblems, RowProblem{
+				Row:     index,
+				Problem: fmt.Sprintf("found %d fields, expected %d", countInfo, countKeys),
+			})
+			continue
+		}
+
+		var domainIndexStr string
+		if !p.requiredStringValue(&domainIndexStr, info, domainIndex, "domain", index) {
+			continue
+		}
+
+		domain := dns.Fqdn(domainIndexStr)
+
+		record := Record{Domain: domain}
+
+		if !p.requiredStringValue(&record.ID, info, idIndex, "id", index) {
+			continue
+		} else if !p.requiredStringValue(&record.Group, info, groupIndex, "group", index) {
+			continue
+		} else if !p.requiredStringValue(&record.Network, info, networkIndex, "network", index) {
+			continue
+		} else if !p.requiredStringValue(&record.Deployment, info, deploymentIndex, "deployment", index) {
+			continue
+		} else if !p.requiredStringValue(&record.IP, info, ipIndex, "ip", index) {
+			continue
+		} else if !p.optionalStringValue(&record.AZID, info, azIDIndex, "az_id", index) {
+			continue
+		} else if !p.optionalStringValue(&record.NetworkID, info, networkIDIndex, "network_id", index) {
+			continue
+		} else if !p.optionalStringValue(&record.NumId, info, numIDIndex, "num_id", index) {
+			continue
+		} else if groupIdsIndex >= 0 && !p.assertStringArrayOfStringValue(&record.GroupIDs, info, groupIdsIndex, "group_ids", index) {
+			continue
+		}
+
+		// records with a mistyped instance_index are still served, but the row
+		// does not count as valid
+		if instanceIndexIndex >= 0 && !p.assertStringIntegerValue(&record.InstanceIndex, info, instanceIndexIndex, "instance_index", index) {
+			mistyped++
+		}
+
+		if len(attributeIndexes) > 0 {
+			record.Attributes = make(map[string]interface{}, len(attributeIndexes))
+			for name, attributeIndex := range attributeIndexes {
+				record.Attributes[name] = info[attributeIndex]
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	report.ValidRows = len(records) - mistyped
+
+	return records, report, nil
+}
+
+func (p recordsParser) typeProblem(fieldIdx int, fieldName string, infoIdx int, expectedType string, value interface{}) {
+	p.logger.Warn("RecordSet", "Value %d (%s) of record %d is not expected type of %s: %#+v", fieldIdx, fieldName, infoIdx, expectedType, value)
+	p.report.RowProblems = append(p.report.RowProblems, RowProblem{
+		Row:     infoIdx,
+		Column:  p.keys[fieldIdx],
+		Problem: fmt.Sprintf("expected %s, got %#+v", expectedType, value),
+	})
+}
+
+func (p recordsParser) assertStringIntegerValue(field *string, info []interface{}, fieldIdx int, fieldName string, infoIdx int) bool {
+	if fieldIdx < 0 {
+		return false
+	}
+
+	float64Value, ok := info[fieldIdx].(float64) // golang default type for numeric fields
+	if !ok {
+		p.typeProblem(fieldIdx, fieldName, infoIdx, "numeric", info[fieldIdx])
+	}
+
+	*field = strconv.Itoa(int(float64Value))
+	return ok
+}
+
+func (p recordsParser) convertToStringValue(field *string, info []interface{}, fieldIdx int, fieldName string, infoIdx int) bool {
+	var ok bool
+	*field, ok = info[fieldIdx].(string)
+
+	if !ok {
+		p.typeProblem(fieldIdx, fieldName, infoIdx, "string", info[fieldIdx])
+	}
+
+	return ok
+}
+
+func (p recordsParser) optionalStringValue(field *string, info []interface{}, fieldIdx int, fieldName string, infoIdx int) bool {
+	if fieldIdx >= 0 {
+		if info[fieldIdx] == nil {
+			info[fieldIdx] = ""
+			return true
+		}
+		return p.convertToStringValue(field, info, fieldIdx, fieldName, infoIdx)
+	}
+
+	return true
+}
+
+func (p recordsParser) requiredStringValue(field *string, info []interface{}, fieldIdx int, fieldName string, infoIdx int) bool {
+	if fieldIdx < 0 {
+		return false
+	}
+
+	return p.convertToStringValue(field, info, fieldIdx, fieldName, infoIdx)
+}
+
+func (p recordsParser) assertStringArrayOfStringValue(field *[]string, info []interface{}, fieldIdx int, fieldName string, infoIdx int) bool {
+	var ok bool
+	var intermediateField []interface{}
+
+	intermediateField, ok = info[fieldIdx].([]interface{})
+	if !ok {
+		p.typeProblem(fieldIdx, fieldName, infoIdx, "array of string", info[fieldIdx])
+	}
+	out := make([]string, len(intermediateField))
+	for i, v := range intermediateField {
+		out[i], ok = v.(string)
+		if !ok {
+			p.typeProblem(fieldIdx, fieldName, infoIdx, "array of string", info[fieldIdx])
+			return ok
+		}
+	}
+
+	*field = out
+
+	return ok
+}
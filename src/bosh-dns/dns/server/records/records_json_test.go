@@ -0,0 +1,95 @@
+package records_test
+
+import (
+	"bosh-dns/dns/server/records"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateRecords", func() {
+	It("reports a valid file", func() {
+		report, err := records.ValidateRecords([]byte(`{
+			"schema_version": 1,
+			"record_keys": ["id", "instance_group", "az", "network", "deployment", "ip", "domain"],
+			"record_infos": [
+				["instance0", "my-group", "az1", "my-network", "my-deployment", "123.123.123.123", "bosh."]
+			]
+		}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Valid()).To(BeTrue())
+		Expect(report).To(Equal(records.ValidationReport{
+			SchemaVersion: 1,
+			Rows:          1,
+			ValidRows:     1,
+		}))
+	})
+
+	It("treats files without a schema version as version 1", func() {
+		report, err := records.ValidateRecords([]byte(`{"record_keys": [], "record_infos": []}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.SchemaVersion).To(Equal(1))
+	})
+
+	It("rejects newer schema versions", func() {
+		_, err := records.ValidateRecords([]byte(`{"schema_version": 2, "record_keys": [], "record_infos": []}`))
+		Expect(err).To(MatchError("unsupported records schema_version 2, newest supported is 1"))
+	})
+
+	It("returns an error for unparseable files", func() {
+		_, err := records.ValidateRecords([]byte(`{"record_keys": [`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reports missing required columns", func() {
+		report, err := records.ValidateRecords([]byte(`{
+			"record_keys": ["id", "instance_group", "network", "deployment"],
+			"record_infos": [["instance0", "my-group", "my-network", "my-deployment"]]
+		}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Valid()).To(BeFalse())
+		Expect(report.MissingColumns).To(Equal([]string{"ip", "domain"}))
+		Expect(report.ValidRows).To(Equal(0))
+	})
+
+	It("reports every bad row", func() {
+		report, err := records.ValidateRecords([]byte(`{
+			"record_keys": ["id", "instance_group", "group_ids", "network", "deployment", "ip", "domain", "instance_index"],
+			"record_infos": [
+				["instance0", "my-group", ["1"], "my-network", "my-deployment", "123.123.123.123", "bosh.", 0],
+				["instance1", "my-group", ["1"], "my-network", "my-deployment", "123.123.123.124"],
+				["instance2", 7, ["1"], "my-network", "my-deployment", "123.123.123.125", "bosh.", 0],
+				["instance3", "my-group", [1], "my-network", "my-deployment", "123.123.123.126", "bosh.", 0],
+				["instance4", "my-group", ["1"], "my-network", "my-deployment", "123.123.123.127", "bosh.", "0"]
+			]
+		}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Valid()).To(BeFalse())
+		Expect(report.Rows).To(Equal(5))
+		Expect(report.ValidRows).To(Equal(1))
+		Expect(report.RowProblems).To(Equal([]records.RowProblem{
+			{Row: 1, Problem: "found 6 fields, expected 8"},
+			{Row: 2, Column: "instance_group", Problem: "expected string, got 7"},
+			{Row: 3, Column: "group_ids", Problem: `expected array of string, got []interface {}{1}`},
+			{Row: 4, Column: "instance_index", Problem: `expected numeric, got "0"`},
+		}))
+		Expect(report.Summary()).To(Equal("1 of 5 rows valid, 4 row problems, first row 1: found 6 fields, expected 8"))
+	})
+
+	It("lists extra columns", func() {
+		report, err := records.ValidateRecords([]byte(`{
+			"record_keys": ["id", "instance_group", "network", "deployment", "ip", "domain", "weight", "port"],
+			"record_infos": [["instance0", "my-group", "my-network", "my-deployment", "123.123.123.123", "bosh.", 10, 8080]]
+		}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Valid()).To(BeTrue())
+		Expect(report.ExtraColumns).To(Equal([]string{"weight", "port"}))
+		Expect(report.String()).To(Equal(`schema version: 1
+rows: 1 (1 valid)
+extra columns kept as attributes: weight, port`))
+	})
+})
@@ -27,6 +27,15 @@ type FakeFileReader struct {
 	subscribeReturnsOnCall map[int]struct {
 		result1 <-chan bool
 	}
+	RejectionsStub        func() uint64
+	rejectionsMutex       sync.RWMutex
+	rejectionsArgsForCall []struct{}
+	rejectionsReturns     struct {
+		result1 uint64
+	}
+	rejectionsReturnsOnCall map[int]struct {
+		result1 uint64
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -114,6 +123,46 @@ func (fake *FakeFileReader) SubscribeReturnsOnCall(i int, result1 <-chan bool) {
 	}{result1}
 }
 
+func (fake *FakeFileReader) Rejections() uint64 {
+	fake.rejectionsMutex.Lock()
+	ret, specificReturn := fake.rejectionsReturnsOnCall[len(fake.rejectionsArgsForCall)]
+	fake.rejectionsArgsForCall = append(fake.rejectionsArgsForCall, struct{}{})
+	fake.recordInvocation("Rejections", []interface{}{})
+	fake.rejectionsMutex.Unlock()
+	if fake.RejectionsStub != nil {
+		return fake.RejectionsStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.rejectionsReturns.result1
+}
+
+func (fake *FakeFileReader) RejectionsCallCount() int {
+	fake.rejectionsMutex.RLock()
+	defer fake.rejectionsMutex.RUnlock()
+	return len(fake.rejectionsArgsForCall)
+}
+
+func (fake *FakeFileReader) RejectionsReturns(result1 uint64) {
+	fake.RejectionsStub = nil
+	fake.rejectionsReturns = struct {
+		result1 uint64
+	}{result1}
+}
+
+func (fake *FakeFileReader) RejectionsReturnsOnCall(i int, result1 uint64) {
+	fake.RejectionsStub = nil
+	if fake.rejectionsReturnsOnCall == nil {
+		fake.rejectionsReturnsOnCall = make(map[int]struct {
+			result1 uint64
+		})
+	}
+	fake.rejectionsReturnsOnCall[i] = struct {
+		result1 uint64
+	}{result1}
+}
+
 func (fake *FakeFileReader) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -121,6 +170,8 @@ func (fake *FakeFileReader) Invocations() map[string][][]interface{} {
 	defer fake.getMutex.RUnlock()
 	fake.subscribeMutex.RLock()
 	defer fake.subscribeMutex.RUnlock()
+	fake.rejectionsMutex.RLock()
+	defer fake.rejectionsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
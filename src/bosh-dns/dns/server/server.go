@@ -2,12 +2,22 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/cloudfoundry/bosh-utils/logger"
 )
 
+// maxUpcheckFailures is how many upchecks of a listener must fail in a row
+// before it is considered down.
+const maxUpcheckFailures = 5
+
+// recoveryUpchecks is how many upchecks of a rebound listener must succeed
+// with none failing before its recovery attempts are restored, so that a
+// flapping listener uses them up instead of being rebound forever.
+const recoveryUpchecks = 12
+
 //go:generate counterfeiter . DNSServer
 
 type DNSServer interface {
@@ -15,28 +25,85 @@ type DNSServer interface {
 	Shutdown() error
 }
 
+// Listener is a server on one network and address along with the upchecks
+// verifying that it answers.
+type Listener struct {
+	// Name identifies the network and address in logs, e.g. "udp 169.254.0.2:53".
+	Name     string
+	Server   DNSServer
+	Upchecks []Upcheck
+
+	// Rebind returns a fresh server for the same network and address, which
+	// replaces Server once its upchecks keep failing. Listeners without
+	// Rebind cannot recover.
+	Rebind func() DNSServer
+}
+
+type listener struct {
+	Listener
+
+	mutex           *sync.Mutex
+	attempts        int
+	healthyUpchecks int
+}
+
 type Server struct {
-	servers         []DNSServer
-	upchecks        []Upcheck
+	listeners       []*listener
 	timeout         time.Duration
 	upcheckInterval time.Duration
 	shutdownChan    chan struct{}
 	logger          logger.Logger
+
+	recoveryAttempts int
+	failed           chan error
+	failOnce         *sync.Once
+
+	handoff     Handoff
+	handoffWait time.Duration
 }
 
-func New(servers []DNSServer, upchecks []Upcheck, timeout, upcheckInterval time.Duration, shutdownChan chan struct{}, logger logger.Logger) Server {
-	return Server{
-		servers:         servers,
-		upchecks:        upchecks,
+func New(listeners []Listener, timeout, upcheckInterval time.Duration, shutdownChan chan struct{}, logger logger.Logger) Server {
+	s := Server{
 		timeout:         timeout,
 		shutdownChan:    shutdownChan,
 		upcheckInterval: upcheckInterval,
 		logger:          logger,
+
+		failed:   make(chan error, 1),
+		failOnce: &sync.Once{},
+	}
+
+	for _, l := range listeners {
+		s.listeners = append(s.listeners, &listener{Listener: l, mutex: &sync.Mutex{}})
 	}
+
+	return s
+}
+
+// WithHandoff returns a copy of s which hands its sockets over to a successor.
+// Once asked to shut down, it keeps serving for up to wait so that a
+// successor can take over.
+func (s Server) WithHandoff(handoff Handoff, wait time.Duration) Server {
+	s.handoff = handoff
+	s.handoffWait = wait
+
+	return s
+}
+
+// WithRecoveryAttempts returns a copy of s which rebinds a listener whose
+// upchecks keep failing up to attempts times before giving up. The attempts
+// are restored once the listener stays up for recoveryUpchecks upchecks.
+// Without recovery attempts, s gives up as soon as a listener is down.
+func (s Server) WithRecoveryAttempts(attempts int) Server {
+	s.recoveryAttempts = attempts
+
+	return s
 }
 
 func (s Server) Run() error {
-	err := make(chan error)
+	// buffered so that servers which stop after startup, e.g. when rebound,
+	// do not block forever on reporting it
+	err := make(chan error, len(s.listeners))
 	s.listenAndServe(err)
 
 	done := make(chan struct{})
@@ -51,41 +118,185 @@ func (s Server) Run() error {
 		s.logger.Debug("server", "done with upchecks")
 	}
 
-	s.monitorUpchecks()
+	stopMonitoring := make(chan struct{})
+	defer close(stopMonitoring)
+	s.monitorUpchecks(stopMonitoring)
+
+	stopHandoff := make(chan struct{})
+	defer close(stopHandoff)
+	handedOff := s.handOff(stopHandoff)
 
 	select {
+	case e := <-s.failed:
+		s.shutdown()
+		return e
 	case <-s.shutdownChan:
-		return s.shutdown()
+		if handedOff != nil && s.handoffWait > 0 {
+			s.logger.Info("server", "waiting up to %s for a successor to take over", s.handoffWait)
+
+			select {
+			case <-handedOff:
+			case <-time.After(s.handoffWait):
+			}
+		}
+	case <-handedOff:
+		s.logger.Info("server", "handed off sockets to successor, draining")
 	}
+
+	return s.shutdown()
 }
 
-func (s Server) monitorUpchecks() {
-	for _, upcheck := range s.upchecks {
-		go func(h Upcheck, limit int) {
-			danger := 0
-			for {
-				if err := h.IsUp(); err != nil {
-					danger += 1
-					if danger >= limit && s.shutdownChan != nil {
-						close(s.shutdownChan)
-						s.shutdownChan = nil
-						return
-					}
-				} else {
-					danger = 0
-				}
+// handOff releases the process the sockets were inherited from and waits for
+// a successor in the background. The returned channel is closed once a
+// successor has taken over; it is nil without a handoff.
+func (s Server) handOff(stop <-chan struct{}) <-chan struct{} {
+	if s.handoff == nil {
+		return nil
+	}
+
+	if err := s.handoff.Release(); err != nil {
+		s.logger.Error("server", "releasing predecessor: %s", err)
+	}
 
-				time.Sleep(s.upcheckInterval)
+	handedOff := make(chan struct{})
+	go func() {
+		if err := s.handoff.Accept(stop, s.servers); err != nil {
+			select {
+			case <-stop:
+			default:
+				s.logger.Error("server", "accepting successor: %s", err)
 			}
-		}(upcheck, 5)
+			return
+		}
+
+		close(handedOff)
+	}()
+
+	return handedOff
+}
+
+func (s Server) monitorUpchecks(stop <-chan struct{}) {
+	for _, l := range s.listeners {
+		for _, upcheck := range l.Upchecks {
+			go s.monitorUpcheck(l, upcheck, stop)
+		}
+	}
+}
+
+func (s Server) monitorUpcheck(l *listener, upcheck Upcheck, stop <-chan struct{}) {
+	failures := 0
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(s.upcheckInterval):
+		}
+
+		err := upcheck.IsUp()
+		if err == nil {
+			failures = 0
+			s.recovered(l)
+			continue
+		}
+
+		s.failing(l)
+		failures++
+		if failures < maxUpcheckFailures {
+			continue
+		}
+		failures = 0
+
+		if err := s.recover(l, err); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+}
+
+// recover replaces the server of l with a fresh one, unless l has used up
+// its recovery attempts.
+func (s Server) recover(l *listener, cause error) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.attempts >= s.recoveryAttempts || l.Rebind == nil {
+		return fmt.Errorf("%s is down after %d recovery attempts: upcheck failed %d times in a row: %s", l.Name, l.attempts, maxUpcheckFailures, cause)
+	}
+	l.attempts++
+	l.healthyUpchecks = 0
+
+	s.logger.Error("server", "%s: upcheck failed %d times in a row, rebinding (attempt %d of %d): %s", l.Name, maxUpcheckFailures, l.attempts, s.recoveryAttempts, cause)
+
+	if err := l.Server.Shutdown(); err != nil {
+		s.logger.Error("server", "%s: shutting down the failing server: %s", l.Name, err)
+	}
+
+	l.Server = l.Rebind()
+	go func(server DNSServer) {
+		if err := server.ListenAndServe(); err != nil {
+			s.logger.Error("server", "%s: binding the new server: %s", l.Name, err)
+		}
+	}(l.Server)
+
+	return nil
+}
+
+// recovered restores the recovery attempts of l once it has been up for
+// recoveryUpchecks upchecks since it last failed one.
+func (s Server) recovered(l *listener) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.attempts == 0 {
+		return
+	}
+
+	l.healthyUpchecks++
+	if l.healthyUpchecks < recoveryUpchecks {
+		return
+	}
+
+	s.logger.Info("server", "%s: recovered after %d attempts", l.Name, l.attempts)
+	l.attempts = 0
+	l.healthyUpchecks = 0
+}
+
+func (s Server) failing(l *listener) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.healthyUpchecks = 0
+}
+
+func (s Server) fail(err error) {
+	s.failOnce.Do(func() {
+		s.logger.Error("server", "giving up: %s", err)
+		s.failed <- err
+	})
+}
+
+// servers returns the servers currently serving the listeners.
+func (s Server) servers() []DNSServer {
+	servers := []DNSServer{}
+	for _, l := range s.listeners {
+		l.mutex.Lock()
+		servers = append(servers, l.Server)
+		l.mutex.Unlock()
 	}
+
+	return servers
 }
 
 func (s Server) doUpchecks(done chan struct{}) {
+	upchecks := []Upcheck{}
+	for _, l := range s.listeners {
+		upchecks = append(upchecks, l.Upchecks...)
+	}
+
 	wg := &sync.WaitGroup{}
-	wg.Add(len(s.upchecks))
+	wg.Add(len(upchecks))
 
-	if len(s.upchecks) == 0 {
+	if len(upchecks) == 0 {
 		s.logger.Warn("server", "proceeding immediately: no upchecks configured")
 		close(done)
 		return
@@ -96,7 +307,7 @@ func (s Server) doUpchecks(done chan struct{}) {
 		close(done)
 	}()
 
-	for _, upcheck := range s.upchecks {
+	for _, upcheck := range upchecks {
 		go func(upcheck Upcheck) {
 			for {
 				var err error
@@ -114,7 +325,7 @@ func (s Server) doUpchecks(done chan struct{}) {
 }
 
 func (s Server) listenAndServe(err chan error) {
-	for _, server := range s.servers {
+	for _, server := range s.servers() {
 		go func(server DNSServer) {
 			err <- server.ListenAndServe()
 		}(server)
@@ -122,12 +333,13 @@ func (s Server) listenAndServe(err chan error) {
 }
 
 func (s Server) shutdown() error {
-	err := make(chan error, len(s.servers))
+	servers := s.servers()
+	err := make(chan error, len(servers))
 
 	wg := &sync.WaitGroup{}
-	wg.Add(len(s.servers))
+	wg.Add(len(servers))
 
-	for _, server := range s.servers {
+	for _, server := range servers {
 		go func(server DNSServer) {
 			err <- server.Shutdown()
 
@@ -10,6 +10,7 @@ import (
 	"errors"
 
 	"net"
+	"strings"
 	"time"
 
 	"bosh-dns/dns/server/serverfakes"
@@ -150,8 +151,10 @@ var _ = Describe("Server", func() {
 
 	JustBeforeEach(func() {
 		dnsServer = server.New(
-			[]server.DNSServer{fakeTCPServer, fakeUDPServer},
-			[]server.Upcheck{tcpUpcheck, udpUpcheck},
+			[]server.Listener{
+				{Name: "tcp " + bindAddress, Server: fakeTCPServer, Upchecks: []server.Upcheck{tcpUpcheck}},
+				{Name: "udp " + bindAddress, Server: fakeUDPServer, Upchecks: []server.Upcheck{udpUpcheck}},
+			},
 			timeout,
 			pollingInterval,
 			shutdownChannel,
@@ -260,7 +263,7 @@ var _ = Describe("Server", func() {
 						tcpUpcheck = passthroughCheck(upChan)
 					})
 
-					It("kills itself after five failures in a row", func() {
+					It("gives up after five failures in a row", func() {
 						go triggerNFailures(upChan, startFailing, numFailuresSent)
 
 						dnsServerFinished := make(chan error)
@@ -270,9 +273,11 @@ var _ = Describe("Server", func() {
 
 						startFailing <- 5
 						Eventually(numFailuresSent).Should(Receive(Equal(5)))
-						Eventually(dnsServerFinished).Should(Receive())
-						Eventually(shutdownChannel).Should(BeClosed())
-						shutdownChannel = nil
+						Eventually(dnsServerFinished).Should(Receive(MatchError(ContainSubstring("tcp %s is down after 0 recovery attempts", bindAddress))))
+
+						Expect(fakeTCPServer.ShutdownCallCount()).To(Equal(1))
+						Expect(fakeUDPServer.ShutdownCallCount()).To(Equal(1))
+						Expect(shutdownChannel).NotTo(BeClosed())
 					})
 
 					It("recovers if the failures are not consistent", func() {
@@ -301,7 +306,7 @@ var _ = Describe("Server", func() {
 						tcpUpcheck = upCheck()
 					})
 
-					It("kills itself after five failures in a row", func() {
+					It("gives up after five failures in a row", func() {
 						go triggerNFailures(upChan, startFailing, numFailuresSent)
 
 						dnsServerFinished := make(chan error)
@@ -311,9 +316,8 @@ var _ = Describe("Server", func() {
 
 						startFailing <- 5
 						Eventually(numFailuresSent).Should(Receive(Equal(5)))
-						Eventually(dnsServerFinished).Should(Receive())
-						Eventually(shutdownChannel).Should(BeClosed())
-						shutdownChannel = nil
+						Eventually(dnsServerFinished).Should(Receive(MatchError(ContainSubstring("udp %s is down after 0 recovery attempts", bindAddress))))
+						Expect(shutdownChannel).NotTo(BeClosed())
 					})
 
 					It("recovers if the failures are not consistent", func() {
@@ -334,13 +338,149 @@ var _ = Describe("Server", func() {
 						Consistently(dnsServerFinished, 3*pollingInterval).ShouldNot(Receive())
 					})
 				})
+
+				Context("when recovery attempts are configured", func() {
+					var reboundServer *serverfakes.FakeDNSServer
+
+					BeforeEach(func() {
+						udpUpcheck = upCheck()
+						tcpUpcheck = passthroughCheck(upChan)
+						reboundServer = &serverfakes.FakeDNSServer{}
+						reboundServer.ListenAndServeStub = notListeningStub(stopFakeServer)
+					})
+
+					JustBeforeEach(func() {
+						dnsServer = server.New(
+							[]server.Listener{
+								{
+									Name:     "tcp " + bindAddress,
+									Server:   fakeTCPServer,
+									Upchecks: []server.Upcheck{tcpUpcheck},
+									Rebind:   func() server.DNSServer { return reboundServer },
+								},
+								{Name: "udp " + bindAddress, Server: fakeUDPServer, Upchecks: []server.Upcheck{udpUpcheck}},
+							},
+							timeout,
+							pollingInterval,
+							shutdownChannel,
+							logger,
+						).WithRecoveryAttempts(2)
+					})
+
+					errorLogs := func() []string {
+						logs := []string{}
+						for i := 0; i < logger.ErrorCallCount(); i++ {
+							_, msg, args := logger.ErrorArgsForCall(i)
+							logs = append(logs, fmt.Sprintf(msg, args...))
+						}
+						return logs
+					}
+
+					It("rebinds the failing listener instead of giving up", func() {
+						go triggerNFailures(upChan, startFailing, numFailuresSent)
+
+						dnsServerFinished := make(chan error)
+						go func() {
+							dnsServerFinished <- dnsServer.Run()
+						}()
+
+						startFailing <- 5
+						Eventually(numFailuresSent).Should(Receive(Equal(5)))
+
+						Eventually(reboundServer.ListenAndServeCallCount).Should(Equal(1))
+						Expect(fakeTCPServer.ShutdownCallCount()).To(Equal(1))
+						Expect(fakeUDPServer.ShutdownCallCount()).To(Equal(0))
+						Expect(errorLogs()).To(ContainElement(ContainSubstring("tcp %s: upcheck failed 5 times in a row, rebinding (attempt 1 of 2): deadbeef", bindAddress)))
+
+						Consistently(dnsServerFinished, 3*pollingInterval).ShouldNot(Receive())
+
+						By("shutting down the rebound server")
+						close(shutdownChannel)
+						shutdownChannel = nil
+						Eventually(dnsServerFinished).Should(Receive(nil))
+						Expect(reboundServer.ShutdownCallCount()).To(Equal(1))
+						Expect(fakeTCPServer.ShutdownCallCount()).To(Equal(1))
+					})
+
+					It("gives up once the recovery attempts are used up", func() {
+						go triggerNFailures(upChan, startFailing, numFailuresSent)
+
+						dnsServerFinished := make(chan error)
+						go func() {
+							dnsServerFinished <- dnsServer.Run()
+						}()
+
+						startFailing <- 15
+						Eventually(numFailuresSent).Should(Receive(Equal(15)))
+
+						Eventually(dnsServerFinished).Should(Receive(MatchError(ContainSubstring("tcp %s is down after 2 recovery attempts", bindAddress))))
+						Expect(reboundServer.ListenAndServeCallCount()).To(Equal(2))
+					})
+
+					recoveries := func() int {
+						count := 0
+						for i := 0; i < logger.InfoCallCount(); i++ {
+							_, msg, args := logger.InfoArgsForCall(i)
+							if strings.Contains(fmt.Sprintf(msg, args...), "recovered after 1 attempts") {
+								count++
+							}
+						}
+						return count
+					}
+
+					It("restores the recovery attempts once the listener stayed up", func() {
+						go triggerNFailures(upChan, startFailing, numFailuresSent)
+
+						dnsServerFinished := make(chan error)
+						go func() {
+							dnsServerFinished <- dnsServer.Run()
+						}()
+
+						for i := 0; i < 3; i++ {
+							startFailing <- 5
+							Eventually(numFailuresSent).Should(Receive(Equal(5)))
+							Eventually(reboundServer.ListenAndServeCallCount).Should(Equal(i + 1))
+
+							Eventually(recoveries).Should(Equal(i + 1))
+						}
+
+						Consistently(dnsServerFinished, 3*pollingInterval).ShouldNot(Receive())
+						Expect(errorLogs()).NotTo(ContainElement(ContainSubstring("attempt 2 of 2")))
+					})
+
+					It("gives up on a flapping listener although upchecks succeed in between", func() {
+						go triggerNFailures(upChan, startFailing, numFailuresSent)
+
+						dnsServerFinished := make(chan error)
+						go func() {
+							dnsServerFinished <- dnsServer.Run()
+						}()
+
+						for i := 0; i < 2; i++ {
+							startFailing <- 5
+							Eventually(numFailuresSent).Should(Receive(Equal(5)))
+
+							upchecksSoFar := tcpUpcheck.IsUpCallCount()
+							Eventually(tcpUpcheck.IsUpCallCount).Should(BeNumerically(">", upchecksSoFar))
+						}
+
+						startFailing <- 5
+						Eventually(numFailuresSent).Should(Receive(Equal(5)))
+
+						Eventually(dnsServerFinished).Should(Receive(MatchError(ContainSubstring("tcp %s is down after 2 recovery attempts", bindAddress))))
+						Expect(reboundServer.ListenAndServeCallCount()).To(Equal(2))
+						Expect(recoveries()).To(Equal(0))
+					})
+				})
 			})
 
 			Context("when no upchecks are configured", func() {
 				JustBeforeEach(func() {
 					dnsServer = server.New(
-						[]server.DNSServer{fakeTCPServer, fakeUDPServer},
-						[]server.Upcheck{},
+						[]server.Listener{
+							{Name: "tcp " + bindAddress, Server: fakeTCPServer},
+							{Name: "udp " + bindAddress, Server: fakeUDPServer},
+						},
 						timeout,
 						pollingInterval,
 						shutdownChannel,
@@ -433,5 +573,115 @@ var _ = Describe("Server", func() {
 				})
 			})
 		})
+
+		Context("handoff", func() {
+			var (
+				fakeHandoff       *serverfakes.FakeHandoff
+				successor         chan struct{}
+				dnsServerFinished chan error
+				runReturned       chan struct{}
+			)
+
+			run := func() {
+				go func() {
+					defer close(runReturned)
+					dnsServerFinished <- dnsServer.Run()
+				}()
+			}
+
+			BeforeEach(func() {
+				successor = make(chan struct{})
+				dnsServerFinished = make(chan error, 1)
+				runReturned = make(chan struct{})
+				fakeHandoff = &serverfakes.FakeHandoff{}
+
+				successor := successor
+				fakeHandoff.AcceptStub = func(signal <-chan struct{}, servers func() []server.DNSServer) error {
+					select {
+					case <-successor:
+						return nil
+					case <-signal:
+						return errors.New("stopped")
+					}
+				}
+			})
+
+			JustBeforeEach(func() {
+				dnsServer = dnsServer.WithHandoff(fakeHandoff, time.Second)
+			})
+
+			AfterEach(func() {
+				if shutdownChannel != nil {
+					close(shutdownChannel)
+					shutdownChannel = nil
+				}
+
+				Eventually(runReturned, 3*time.Second).Should(BeClosed())
+			})
+
+			It("releases the predecessor once the servers are up", func() {
+				run()
+
+				Eventually(fakeHandoff.ReleaseCallCount).Should(Equal(1))
+				Eventually(fakeHandoff.AcceptCallCount).Should(Equal(1))
+				_, servers := fakeHandoff.AcceptArgsForCall(0)
+				Expect(servers()).To(Equal([]server.DNSServer{fakeTCPServer, fakeUDPServer}))
+				Expect(tcpUpcheck.IsUpCallCount()).To(BeNumerically(">", 0))
+				Expect(udpUpcheck.IsUpCallCount()).To(BeNumerically(">", 0))
+			})
+
+			It("drains the servers once a successor took over", func() {
+				run()
+
+				Eventually(fakeHandoff.AcceptCallCount).Should(Equal(1))
+				Consistently(dnsServerFinished, 100*time.Millisecond).ShouldNot(Receive())
+
+				close(successor)
+				Eventually(dnsServerFinished).Should(Receive(nil))
+
+				Expect(fakeTCPServer.ShutdownCallCount()).To(Equal(1))
+				Expect(fakeUDPServer.ShutdownCallCount()).To(Equal(1))
+			})
+
+			Context("when the shutdown signal has been fired", func() {
+				It("keeps serving until a successor takes over", func() {
+					run()
+
+					Eventually(fakeHandoff.AcceptCallCount).Should(Equal(1))
+					close(shutdownChannel)
+					shutdownChannel = nil
+
+					Consistently(dnsServerFinished, 100*time.Millisecond).ShouldNot(Receive())
+					Expect(fakeTCPServer.ShutdownCallCount()).To(Equal(0))
+
+					close(successor)
+					Eventually(dnsServerFinished).Should(Receive(nil))
+					Expect(fakeTCPServer.ShutdownCallCount()).To(Equal(1))
+				})
+
+				It("shuts down when no successor takes over in time", func() {
+					run()
+
+					Eventually(fakeHandoff.AcceptCallCount).Should(Equal(1))
+					close(shutdownChannel)
+					shutdownChannel = nil
+
+					Eventually(dnsServerFinished, 3*time.Second).Should(Receive(nil))
+					Expect(fakeTCPServer.ShutdownCallCount()).To(Equal(1))
+					Expect(fakeUDPServer.ShutdownCallCount()).To(Equal(1))
+					Expect(logger.ErrorCallCount()).To(Equal(0))
+				})
+			})
+
+			It("keeps serving when it cannot wait for successors", func() {
+				fakeHandoff.AcceptReturns(errors.New("fake-accept-error"))
+				fakeHandoff.AcceptStub = nil
+
+				run()
+
+				Eventually(logger.ErrorCallCount).Should(Equal(1))
+				Consistently(dnsServerFinished, 100*time.Millisecond).ShouldNot(Receive())
+			})
+		})
 	})
 })
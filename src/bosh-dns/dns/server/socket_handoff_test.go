@@ -0,0 +1,189 @@
+//+build !windows
+
+package server_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"bosh-dns/dns/server"
+
+	"github.com/cloudfoundry/bosh-utils/logger/fakes"
+	"github.com/miekg/dns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SocketHandoff", func() {
+	var (
+		tmpDir      string
+		socketPath  string
+		bindAddress string
+		logger      *fakes.FakeLogger
+
+		predecessorServers []*dns.Server
+		predecessorServing []server.DNSServer
+		predecessor        *server.SocketHandoff
+		successorServers   []*dns.Server
+		successor          *server.SocketHandoff
+	)
+
+	answering := func(ip string) dns.Handler {
+		return dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			m := &dns.Msg{}
+			m.SetReply(r)
+			m.Answer = []dns.RR{&dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET},
+				A:   net.ParseIP(ip),
+			}}
+			Expect(w.WriteMsg(m)).To(Succeed())
+		})
+	}
+
+	newServers := func(handler dns.Handler) []*dns.Server {
+		return []*dns.Server{
+			{Addr: bindAddress, Net: "tcp", Handler: handler},
+			{Addr: bindAddress, Net: "udp", Handler: handler},
+		}
+	}
+
+	start := func(servers []*dns.Server, dnsServers []server.DNSServer) {
+		for i, s := range servers {
+			started := make(chan struct{})
+			s.NotifyStartedFunc = func() { close(started) }
+
+			go dnsServers[i].ListenAndServe()
+			Eventually(started).Should(BeClosed())
+		}
+	}
+
+	serving := func(servers []server.DNSServer) func() []server.DNSServer {
+		return func() []server.DNSServer { return servers }
+	}
+
+	shutdown := func(servers []*dns.Server) {
+		for _, s := range servers {
+			s.Shutdown()
+		}
+	}
+
+	resolve := func(network string) string {
+		c := &dns.Client{Net: network, Timeout: time.Second}
+		m := &dns.Msg{}
+		m.SetQuestion("handoff.bosh.", dns.TypeA)
+
+		r, _, err := c.Exchange(m, bindAddress)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Answer).To(HaveLen(1))
+
+		return r.Answer[0].(*dns.A).A.String()
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "handoff")
+		Expect(err).NotTo(HaveOccurred())
+		socketPath = filepath.Join(tmpDir, "handoff.sock")
+
+		port, err := getFreePort()
+		Expect(err).NotTo(HaveOccurred())
+		bindAddress = fmt.Sprintf("127.0.0.1:%d", port)
+
+		logger = &fakes.FakeLogger{}
+
+		predecessorServers = newServers(answering("10.0.0.1"))
+		predecessor = server.NewSocketHandoff(socketPath, predecessorServers, time.Second, logger)
+
+		predecessorServing, err = predecessor.Inherit()
+		Expect(err).NotTo(HaveOccurred())
+		start(predecessorServers, predecessorServing)
+
+		successorServers = newServers(answering("10.0.0.2"))
+		successor = server.NewSocketHandoff(socketPath, successorServers, time.Second, logger)
+	})
+
+	AfterEach(func() {
+		shutdown(predecessorServers)
+		shutdown(successorServers)
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("listens as usual without a predecessor", func() {
+		Expect(resolve("udp")).To(Equal("10.0.0.1"))
+		Expect(resolve("tcp")).To(Equal("10.0.0.1"))
+	})
+
+	It("hands the sockets over to a successor which serves them", func() {
+		accepted := make(chan error, 1)
+		signal := make(chan struct{})
+		defer close(signal)
+		go func() {
+			accepted <- predecessor.Accept(signal, serving(predecessorServing))
+		}()
+		Eventually(func() error {
+			_, err := os.Stat(socketPath)
+			return err
+		}).Should(Succeed())
+
+		dnsServers, err := successor.Inherit()
+		Expect(err).NotTo(HaveOccurred())
+		start(successorServers, dnsServers)
+
+		By("keeping the predecessor serving until the successor is ready")
+		Consistently(accepted, 100*time.Millisecond).ShouldNot(Receive())
+
+		Expect(successor.Release()).To(Succeed())
+		Eventually(accepted).Should(Receive(BeNil()))
+
+		By("serving from the successor once the predecessor drained")
+		shutdown(predecessorServers)
+		Expect(resolve("udp")).To(Equal("10.0.0.2"))
+		Expect(resolve("tcp")).To(Equal("10.0.0.2"))
+
+		By("letting the successor accept its own successor on the same path")
+		go successor.Accept(signal, serving(dnsServers))
+		Eventually(func() error {
+			conn, err := net.Dial("unix", socketPath)
+			if err == nil {
+				conn.Close()
+			}
+			return err
+		}).Should(Succeed())
+	})
+
+	It("carries on when the successor never serves", func() {
+		accepted := make(chan error, 1)
+		signal := make(chan struct{})
+		go func() {
+			accepted <- predecessor.Accept(signal, serving(predecessorServing))
+		}()
+		Eventually(func() error {
+			_, err := os.Stat(socketPath)
+			return err
+		}).Should(Succeed())
+
+		_, err := successor.Inherit()
+		Expect(err).NotTo(HaveOccurred())
+		for _, s := range successorServers {
+			if s.Listener != nil {
+				s.Listener.Close()
+			}
+			if s.PacketConn != nil {
+				s.PacketConn.Close()
+			}
+		}
+
+		Eventually(logger.ErrorCallCount, 3*time.Second).Should(Equal(1))
+		Expect(accepted).NotTo(Receive())
+		Expect(resolve("udp")).To(Equal("10.0.0.1"))
+		Expect(resolve("tcp")).To(Equal("10.0.0.1"))
+
+		close(signal)
+		Eventually(accepted).Should(Receive(BeNil()))
+	})
+})
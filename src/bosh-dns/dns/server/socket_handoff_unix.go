@@ -0,0 +1,298 @@
+//+build !windows
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+const (
+	handoffLogTag = "handoff"
+
+	// maxHandoffSockets bounds the sockets a predecessor may pass.
+	maxHandoffSockets = 64
+)
+
+// SocketHandoff passes the sockets of servers over the unix socket at path.
+// The predecessor sends the network and address of each socket along with
+// its file descriptor, and drains once the successor reports it serves.
+type SocketHandoff struct {
+	path    string
+	servers []*dns.Server
+	timeout time.Duration
+	logger  logger.Logger
+
+	predecessor *net.UnixConn
+}
+
+// NewSocketHandoff returns a handoff for servers. A successor has timeout to
+// start serving on the sockets it inherited before the predecessor carries
+// on.
+func NewSocketHandoff(path string, servers []*dns.Server, timeout time.Duration, logger logger.Logger) *SocketHandoff {
+	return &SocketHandoff{
+		path:    path,
+		servers: servers,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+// Inherit takes over the sockets of the process currently serving on path,
+// if any. Servers for which it passed a socket of the same network and
+// address serve on that socket; the others listen as usual.
+func (h *SocketHandoff) Inherit() ([]DNSServer, error) {
+	servers := []DNSServer{}
+	for _, server := range h.servers {
+		servers = append(servers, server)
+	}
+
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: h.path, Net: "unix"})
+	if err != nil {
+		h.logger.Debug(handoffLogTag, "no predecessor to inherit sockets from: %s", err)
+		return servers, nil
+	}
+
+	err = conn.SetReadDeadline(time.Now().Add(h.timeout))
+	if err != nil {
+		conn.Close()
+		return nil, bosherr.WrapError(err, "Setting deadline")
+	}
+
+	files, err := receiveSockets(conn)
+	if err != nil {
+		conn.Close()
+		return nil, bosherr.WrapError(err, "Receiving sockets")
+	}
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	h.predecessor = conn
+
+	for i, server := range h.servers {
+		file, ok := files[socketKey(server)]
+		if !ok {
+			continue
+		}
+
+		switch server.Net {
+		case "udp", "udp4", "udp6":
+			server.PacketConn, err = net.FilePacketConn(file)
+		default:
+			server.Listener, err = net.FileListener(file)
+		}
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Inheriting %s socket on %s", server.Net, server.Addr)
+		}
+
+		h.logger.Info(handoffLogTag, "inherited %s socket on %s", server.Net, server.Addr)
+		servers[i] = inheritedServer{server}
+	}
+
+	return servers, nil
+}
+
+// Release tells the predecessor that the inherited sockets are served.
+func (h *SocketHandoff) Release() error {
+	if h.predecessor == nil {
+		return nil
+	}
+	defer h.predecessor.Close()
+
+	_, err := h.predecessor.Write([]byte{1})
+	return err
+}
+
+// Accept listens on path and hands the sockets of servers to the first
+// successor which reports that it serves them. Stale sockets left at path
+// are replaced.
+func (h *SocketHandoff) Accept(signal <-chan struct{}, servers func() []DNSServer) error {
+	err := os.Remove(h.path)
+	if err != nil && !os.IsNotExist(err) {
+		return bosherr.WrapError(err, "Removing stale socket")
+	}
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: h.path, Net: "unix"})
+	if err != nil {
+		return bosherr.WrapError(err, "Listening for successors")
+	}
+	// the successor listens on the same path before this process exits
+	listener.SetUnlinkOnClose(false)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-signal:
+		case <-done:
+		}
+
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.AcceptUnix()
+		if err != nil {
+			select {
+			case <-signal:
+				return nil
+			default:
+				return bosherr.WrapError(err, "Accepting successor")
+			}
+		}
+
+		err = h.handOver(conn, servers())
+		conn.Close()
+		if err == nil {
+			return nil
+		}
+
+		h.logger.Error(handoffLogTag, "handing off sockets: %s", err)
+	}
+}
+
+func (h *SocketHandoff) handOver(conn *net.UnixConn, servers []DNSServer) error {
+	keys := []string{}
+	files := []*os.File{}
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	for _, dnsServer := range servers {
+		var server *dns.Server
+		switch s := dnsServer.(type) {
+		case *dns.Server:
+			server = s
+		case inheritedServer:
+			server = s.Server
+		default:
+			return fmt.Errorf("cannot hand over sockets of %T", dnsServer)
+		}
+
+		file, err := socketFile(server)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Duplicating %s socket on %s", server.Net, server.Addr)
+		}
+
+		keys = append(keys, socketKey(server))
+		files = append(files, file)
+	}
+
+	if err := sendSockets(conn, keys, files); err != nil {
+		return err
+	}
+
+	err := conn.SetReadDeadline(time.Now().Add(h.timeout))
+	if err != nil {
+		return err
+	}
+
+	ready := make([]byte, 1)
+	if _, err := conn.Read(ready); err != nil {
+		return bosherr.WrapError(err, "Waiting for successor to serve")
+	}
+
+	return nil
+}
+
+type inheritedServer struct {
+	*dns.Server
+}
+
+func (s inheritedServer) ListenAndServe() error {
+	return s.ActivateAndServe()
+}
+
+func socketKey(server *dns.Server) string {
+	return fmt.Sprintf("%s %s", server.Net, server.Addr)
+}
+
+func socketFile(server *dns.Server) (*os.File, error) {
+	var socket interface{}
+	switch server.Net {
+	case "udp", "udp4", "udp6":
+		socket = server.PacketConn
+	default:
+		socket = server.Listener
+	}
+
+	filer, ok := socket.(interface {
+		File() (*os.File, error)
+	})
+	if !ok {
+		return nil, errors.New("socket is not listening")
+	}
+
+	return filer.File()
+}
+
+func sendSockets(conn *net.UnixConn, keys []string, files []*os.File) error {
+	payload, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	// files are duplicates returned by File, so passing their descriptors
+	// leaves the sockets of the servers of this process alone
+	fds := []int{}
+	for _, file := range files {
+		fds = append(fds, int(file.Fd()))
+	}
+
+	_, _, err = conn.WriteMsgUnix(payload, syscall.UnixRights(fds...), nil)
+	return err
+}
+
+func receiveSockets(conn *net.UnixConn) (map[string]*os.File, error) {
+	payload := make([]byte, 64*1024)
+	oob := make([]byte, syscall.CmsgSpace(maxHandoffSockets*4))
+
+	n, oobn, _, _, err := conn.ReadMsgUnix(payload, oob)
+	if err != nil {
+		return nil, err
+	}
+
+	fds := []int{}
+	messages, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return nil, err
+	}
+	for i := range messages {
+		rights, err := syscall.ParseUnixRights(&messages[i])
+		if err != nil {
+			return nil, err
+		}
+
+		fds = append(fds, rights...)
+	}
+
+	keys := []string{}
+	if err := json.Unmarshal(payload[:n], &keys); err != nil || len(keys) != len(fds) {
+		for _, fd := range fds {
+			syscall.Close(fd)
+		}
+
+		return nil, errors.New("malformed handoff message")
+	}
+
+	files := map[string]*os.File{}
+	for i, key := range keys {
+		files[key] = os.NewFile(uintptr(fds[i]), key)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+// SocketHandoff is not supported on windows, which cannot pass sockets over
+// unix sockets. Enabling handoff is rejected when loading the config, so it
+// is never used.
+type SocketHandoff struct{}
+
+func NewSocketHandoff(path string, servers []*dns.Server, timeout time.Duration, logger logger.Logger) *SocketHandoff {
+	return &SocketHandoff{}
+}
+
+func (h *SocketHandoff) Inherit() ([]DNSServer, error) {
+	return nil, errors.New("socket handoff is not supported on windows")
+}
+
+func (h *SocketHandoff) Release() error {
+	return nil
+}
+
+func (h *SocketHandoff) Accept(signal <-chan struct{}, servers func() []DNSServer) error {
+	return errors.New("socket handoff is not supported on windows")
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"net"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -31,25 +32,12 @@ func NewDNSAnswerValidatingUpcheck(target string, upcheckDomain string, network
 }
 
 func (uc DNSAnswerValidatingUpcheck) IsUp() error {
-	var err error
-	uc.target, err = determineHost(uc.target)
-	if err != nil {
-		return uc.wrapError(err)
-	}
-
-	dnsClient := dns.Client{Net: uc.network}
-	request := dns.Msg{
-		Question: []dns.Question{
-			{Name: uc.upCheckDomain, Qtype: dns.TypeA},
-		},
-	}
-	msg, _, err := dnsClient.Exchange(&request, uc.target)
-
+	msg, err := exchange(uc.target, uc.upCheckDomain, uc.network)
 	if err != nil {
 		return uc.wrapError(err)
 	}
 	if msg.Rcode != dns.RcodeSuccess {
-		return uc.wrapError(errors.New("DNS resolve failed"))
+		return uc.wrapError(fmt.Errorf("DNS resolve failed: %s", dns.RcodeToString[msg.Rcode]))
 	}
 
 	if len(msg.Answer) == 0 {
@@ -68,17 +56,67 @@ func (uc DNSAnswerValidatingUpcheck) IsUp() error {
 	return nil
 }
 
+// RecursionUpcheck verifies end-to-end that the server resolves a domain
+// through its recursors. Non-existent domains pass, as the recursors answered.
+type RecursionUpcheck struct {
+	target  string
+	domain  string
+	network string
+}
+
+func NewRecursionUpcheck(target string, domain string, network string) Upcheck {
+	return RecursionUpcheck{
+		target:  target,
+		domain:  domain,
+		network: network,
+	}
+}
+
+func (uc RecursionUpcheck) IsUp() error {
+	msg, err := exchange(uc.target, uc.domain, uc.network)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "recursion on %s", uc.network)
+	}
+
+	if msg.Rcode != dns.RcodeSuccess && msg.Rcode != dns.RcodeNameError {
+		return fmt.Errorf("recursion on %s: resolving %s failed: %s", uc.network, uc.domain, dns.RcodeToString[msg.Rcode])
+	}
+
+	return nil
+}
+
+func exchange(target, domain, network string) (*dns.Msg, error) {
+	host, err := determineHost(target)
+	if err != nil {
+		return nil, err
+	}
+
+	dnsClient := dns.Client{Net: network}
+	request := &dns.Msg{}
+	request.SetQuestion(domain, dns.TypeA)
+
+	msg, _, err := dnsClient.Exchange(request, host)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "querying %s for %s", host, domain)
+	}
+
+	return msg, nil
+}
+
 func determineHost(target string) (string, error) {
 	host, port, err := net.SplitHostPort(target)
 	if err != nil {
 		return "", err
 	}
 
-	if host == "0.0.0.0" {
-		return net.JoinHostPort("127.0.0.1", port), nil
+	switch host {
+	case "0.0.0.0", "":
+		host = "127.0.0.1"
+	case "::":
+		host = "::1"
 	}
 
-	return target, nil
+	return net.JoinHostPort(host, port), nil
 }
 
 func (h DNSAnswerValidatingUpcheck) wrapError(err error) error {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"sync/atomic"
 
 	"bosh-dns/dns/server"
 	"bosh-dns/dns/server/handlers"
@@ -53,8 +54,8 @@ var _ = Describe("Upcheck", func() {
 		Expect(err).NotTo(HaveOccurred())
 		ports["tcp"], err = getFreePort()
 		Expect(err).NotTo(HaveOccurred())
-		addresses["udp"] = fmt.Sprintf("%s:%d", listenDomain, ports["udp"])
-		addresses["tcp"] = fmt.Sprintf("%s:%d", listenDomain, ports["tcp"])
+		addresses["udp"] = net.JoinHostPort(listenDomain, strconv.Itoa(ports["udp"]))
+		addresses["tcp"] = net.JoinHostPort(listenDomain, strconv.Itoa(ports["tcp"]))
 
 		udpServer = startServer("udp", addresses["udp"], dnsHandler)
 		tcpServer = startServer("tcp", addresses["tcp"], dnsHandler)
@@ -113,6 +114,34 @@ var _ = Describe("Upcheck", func() {
 				Entry("when networking is tcp", "tcp"),
 			)
 		})
+
+		Context("when the server listens on IPv6", func() {
+			BeforeEach(func() {
+				listenDomain = "::1"
+			})
+
+			DescribeTable("it checks on ::1", func(network string) {
+				subject = server.NewDNSAnswerValidatingUpcheck(fmt.Sprintf("[::1]:%d", ports[network]), upcheckDomain, network)
+
+				err := subject.IsUp()
+				Expect(err).NotTo(HaveOccurred())
+			},
+				Entry("when networking is udp", "udp"),
+				Entry("when networking is tcp", "tcp"),
+			)
+
+			Context("when the target address is ::", func() {
+				DescribeTable("it checks on ::1", func(network string) {
+					subject = server.NewDNSAnswerValidatingUpcheck(fmt.Sprintf("[::]:%d", ports[network]), upcheckDomain, network)
+
+					err := subject.IsUp()
+					Expect(err).NotTo(HaveOccurred())
+				},
+					Entry("when networking is udp", "udp"),
+					Entry("when networking is tcp", "tcp"),
+				)
+			})
+		})
 	})
 
 	Context("when the upcheck takes a long time", func() {
@@ -159,7 +188,42 @@ var _ = Describe("Upcheck", func() {
 			subject = server.NewDNSAnswerValidatingUpcheck(addresses[network], upcheckDomain, network)
 
 			err := subject.IsUp()
-			Expect(err).To(HaveOccurred())
+			Expect(err).To(MatchError(fmt.Sprintf("on %s: DNS resolve failed: SERVFAIL", network)))
+		},
+			Entry("when networking is udp", "udp"),
+			Entry("when networking is tcp", "tcp"),
+		)
+	})
+
+	Describe("RecursionUpcheck", func() {
+		var rcode int32
+
+		BeforeEach(func() {
+			atomic.StoreInt32(&rcode, dns.RcodeSuccess)
+			dnsHandler = dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+				m := &dns.Msg{}
+				m.SetRcode(r, int(atomic.LoadInt32(&rcode)))
+				w.WriteMsg(m)
+			})
+		})
+
+		DescribeTable("passes when the recursors answered", func(network string, answer int) {
+			atomic.StoreInt32(&rcode, int32(answer))
+			subject = server.NewRecursionUpcheck(addresses[network], "example.com.", network)
+
+			Expect(subject.IsUp()).To(Succeed())
+		},
+			Entry("when networking is udp", "udp", dns.RcodeSuccess),
+			Entry("when networking is tcp", "tcp", dns.RcodeSuccess),
+			Entry("when the domain does not exist", "udp", dns.RcodeNameError),
+		)
+
+		DescribeTable("returns an error when recursion failed", func(network string) {
+			atomic.StoreInt32(&rcode, dns.RcodeServerFailure)
+			subject = server.NewRecursionUpcheck(addresses[network], "example.com.", network)
+
+			err := subject.IsUp()
+			Expect(err).To(MatchError(fmt.Sprintf("recursion on %s: resolving example.com. failed: SERVFAIL", network)))
 		},
 			Entry("when networking is udp", "udp"),
 			Entry("when networking is tcp", "tcp"),
@@ -0,0 +1,83 @@
+package zone
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	"bosh-dns/dns/config"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+const (
+	notifierLogTag = "ZoneNotifier"
+
+	notifyAttempts = 3
+)
+
+type dnsNotifier struct {
+	targets []string
+	key     *config.TSIGKey
+	client  *dns.Client
+	clock   clock.Clock
+	logger  boshlog.Logger
+}
+
+// NewNotifier sends a NOTIFY, signed with the first of keys, to every target.
+// Each target is tried a few times as NOTIFY travels over UDP.
+func NewNotifier(targets []string, keys []config.TSIGKey, timeout time.Duration, clock clock.Clock, logger boshlog.Logger) Notifier {
+	n := dnsNotifier{
+		targets: targets,
+		client:  &dns.Client{Net: "udp", Timeout: timeout},
+		clock:   clock,
+		logger:  logger,
+	}
+
+	if len(keys) > 0 {
+		n.key = &keys[0]
+		n.client.TsigSecret = map[string]string{dns.Fqdn(keys[0].Name): keys[0].Secret}
+	}
+
+	return n
+}
+
+func (n dnsNotifier) Notify(origin string, serial uint32) {
+	for _, target := range n.targets {
+		go n.notify(target, origin, serial)
+	}
+}
+
+func (n dnsNotifier) notify(target, origin string, serial uint32) {
+	var err error
+
+	for attempt := 1; attempt <= notifyAttempts; attempt++ {
+		msg := &dns.Msg{}
+		msg.SetNotify(origin)
+		msg.Answer = []dns.RR{&dns.SOA{
+			Hdr:    dns.RR_Header{Name: origin, Rrtype: dns.TypeSOA, Class: dns.ClassINET},
+			Ns:     ".",
+			Mbox:   ".",
+			Serial: serial,
+		}}
+
+		if n.key != nil {
+			msg.SetTsig(dns.Fqdn(n.key.Name), n.key.Algorithm, 300, n.clock.Now().Unix())
+		}
+
+		var resp *dns.Msg
+		resp, _, err = n.client.Exchange(msg, target)
+		if err == nil && resp.Rcode != dns.RcodeSuccess {
+			n.logger.Error(notifierLogTag, "Secondary %s refused NOTIFY for zone %s serial %d: %s", target, origin, serial, dns.RcodeToString[resp.Rcode])
+			return
+		}
+
+		if err == nil {
+			n.logger.Debug(notifierLogTag, "Notified %s of zone %s serial %d", target, origin, serial)
+			return
+		}
+	}
+
+	n.logger.Error(notifierLogTag, "Failed to notify %s of zone %s serial %d after %d attempts: %s", target, origin, serial, notifyAttempts, err)
+}
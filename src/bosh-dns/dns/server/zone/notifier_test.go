@@ -0,0 +1,112 @@
+package zone_test
+
+import (
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/zone"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type notifyRecorder struct {
+	received chan *dns.Msg
+	rcode    int
+}
+
+func (n *notifyRecorder) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := &dns.Msg{}
+	m.SetRcode(r, n.rcode)
+	if w.TsigStatus() != nil {
+		m.SetRcode(r, dns.RcodeNotAuth)
+	} else if t := r.IsTsig(); t != nil {
+		m.SetTsig(t.Hdr.Name, t.Algorithm, 300, time.Now().Unix())
+	}
+
+	w.WriteMsg(m)
+	n.received <- r
+}
+
+var _ = Describe("Notifier", func() {
+	var (
+		recorder   *notifyRecorder
+		server     *dns.Server
+		target     string
+		fakeLogger *loggerfakes.FakeLogger
+		keys       []config.TSIGKey
+	)
+
+	BeforeEach(func() {
+		keys = []config.TSIGKey{{Name: "transfer", Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0LXNlY3JldC1zZWNyZXQ="}}
+		recorder = &notifyRecorder{received: make(chan *dns.Msg, 10), rcode: dns.RcodeSuccess}
+		fakeLogger = &loggerfakes.FakeLogger{}
+	})
+
+	JustBeforeEach(func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		target = conn.LocalAddr().String()
+
+		started := make(chan struct{})
+		server = &dns.Server{
+			PacketConn:        conn,
+			Handler:           recorder,
+			TsigSecret:        map[string]string{"transfer.": keys[0].Secret},
+			NotifyStartedFunc: func() { close(started) },
+		}
+		go server.ActivateAndServe()
+		Eventually(started).Should(BeClosed())
+	})
+
+	AfterEach(func() {
+		server.Shutdown()
+	})
+
+	It("sends a signed NOTIFY carrying the new serial", func() {
+		notifier := zone.NewNotifier([]string{target}, keys, time.Second, clock.NewClock(), fakeLogger)
+		notifier.Notify("bosh.", 1234)
+
+		var msg *dns.Msg
+		Eventually(recorder.received).Should(Receive(&msg))
+
+		Expect(msg.Opcode).To(Equal(dns.OpcodeNotify))
+		Expect(msg.Question[0].Name).To(Equal("bosh."))
+		Expect(msg.Question[0].Qtype).To(Equal(dns.TypeSOA))
+		Expect(msg.Answer[0].(*dns.SOA).Serial).To(Equal(uint32(1234)))
+		Expect(msg.IsTsig()).NotTo(BeNil())
+
+		Eventually(fakeLogger.DebugCallCount).Should(Equal(1))
+		Expect(fakeLogger.ErrorCallCount()).To(Equal(0))
+	})
+
+	Context("when the secondary refuses the NOTIFY", func() {
+		BeforeEach(func() {
+			recorder.rcode = dns.RcodeRefused
+		})
+
+		It("logs the refusal", func() {
+			notifier := zone.NewNotifier([]string{target}, keys, time.Second, clock.NewClock(), fakeLogger)
+			notifier.Notify("bosh.", 1234)
+
+			Eventually(fakeLogger.ErrorCallCount).Should(Equal(1))
+			_, message, args := fakeLogger.ErrorArgsForCall(0)
+			Expect(message).To(ContainSubstring("refused NOTIFY"))
+			Expect(args[3]).To(Equal("REFUSED"))
+		})
+	})
+
+	It("gives up after a few attempts", func() {
+		notifier := zone.NewNotifier([]string{"127.0.0.1:1"}, keys, 50*time.Millisecond, clock.NewClock(), fakeLogger)
+		notifier.Notify("bosh.", 1234)
+
+		Eventually(fakeLogger.ErrorCallCount).Should(Equal(1))
+		_, message, _ := fakeLogger.ErrorArgsForCall(0)
+		Expect(message).To(ContainSubstring("after %d attempts"))
+	})
+})
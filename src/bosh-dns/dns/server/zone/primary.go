@@ -0,0 +1,244 @@
+package zone
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/clock"
+
+	"bosh-dns/dns/config"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+const (
+	primaryLogTag = "ZonePrimary"
+
+	// journalSize is how many changes per zone are kept to answer IXFR
+	// requests incrementally; older serials are sent the full zone.
+	journalSize = 20
+)
+
+//go:generate counterfeiter . Notifier
+
+type Notifier interface {
+	Notify(origin string, serial uint32)
+}
+
+type delta struct {
+	from    *dns.SOA
+	to      *dns.SOA
+	removed []dns.RR
+	added   []dns.RR
+}
+
+type versionedZone struct {
+	zone    Zone
+	journal []delta
+}
+
+// Primary keeps the synthesized zones current as records change, bumping
+// each changed zone's serial and notifying secondaries.
+type Primary struct {
+	source   RecordSource
+	cfg      config.ZoneTransfer
+	notifier Notifier
+	clock    clock.Clock
+	logger   boshlog.Logger
+
+	mutex *sync.RWMutex
+	zones map[string]*versionedZone
+}
+
+func NewPrimary(source RecordSource, cfg config.ZoneTransfer, notifier Notifier, clock clock.Clock, logger boshlog.Logger) *Primary {
+	p := &Primary{
+		source:   source,
+		cfg:      cfg,
+		notifier: notifier,
+		clock:    clock,
+		logger:   logger,
+
+		mutex: &sync.RWMutex{},
+		zones: map[string]*versionedZone{},
+	}
+
+	p.rebuild()
+
+	return p
+}
+
+// Run notifies secondaries of every zone, then rebuilds the zones whenever
+// the records change until signal is closed.
+func (p *Primary) Run(signal <-chan struct{}) {
+	changes := p.source.SubscribeChanges()
+
+	// records may have changed before subscribing
+	p.rebuild()
+	p.notifyAll(p.serials())
+
+	for {
+		select {
+		case <-signal:
+			return
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+
+			p.notifyAll(p.rebuild())
+		}
+	}
+}
+
+func (p *Primary) Zone(origin string) (Zone, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	versioned, ok := p.zones[origin]
+	if !ok {
+		return Zone{}, false
+	}
+
+	return versioned.zone, true
+}
+
+// IXFR returns the records answering an IXFR for origin from serial: the
+// current SOA alone when serial is current, the journaled changes when they
+// reach back to serial, and otherwise the full zone.
+func (p *Primary) IXFR(origin string, serial uint32) ([]dns.RR, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	versioned, ok := p.zones[origin]
+	if !ok {
+		return nil, false
+	}
+
+	current := versioned.zone.SOA
+	if serial == current.Serial {
+		return []dns.RR{current}, true
+	}
+
+	for i, d := range versioned.journal {
+		if d.from.Serial != serial {
+			continue
+		}
+
+		rrs := []dns.RR{current}
+		for _, d := range versioned.journal[i:] {
+			rrs = append(rrs, d.from)
+			rrs = append(rrs, d.removed...)
+			rrs = append(rrs, d.to)
+			rrs = append(rrs, d.added...)
+		}
+
+		return append(rrs, current), true
+	}
+
+	return versioned.zone.AXFR(), true
+}
+
+func (p *Primary) serials() map[string]uint32 {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	serials := map[string]uint32{}
+	for origin, versioned := range p.zones {
+		serials[origin] = versioned.zone.SOA.Serial
+	}
+
+	return serials
+}
+
+func (p *Primary) notifyAll(changed map[string]uint32) {
+	for origin, serial := range changed {
+		p.notifier.Notify(origin, serial)
+	}
+}
+
+// rebuild returns the new serial of every zone which changed
+func (p *Primary) rebuild() map[string]uint32 {
+	built := Build(p.source, Origins(p.source), p.cfg, 0)
+	changed := map[string]uint32{}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for origin := range p.zones {
+		if _, ok := built[origin]; !ok {
+			delete(p.zones, origin)
+			p.logger.Info(primaryLogTag, "Stopped serving zone %s", origin)
+		}
+	}
+
+	for origin, zone := range built {
+		versioned, ok := p.zones[origin]
+		if !ok {
+			zone.SOA.Serial = p.nextSerial(0)
+			p.zones[origin] = &versionedZone{zone: zone}
+			p.logger.Info(primaryLogTag, "Serving zone %s with serial %d and %d records", origin, zone.SOA.Serial, len(zone.Records))
+			continue
+		}
+
+		if zone.sameRecords(versioned.zone) {
+			continue
+		}
+
+		zone.SOA.Serial = p.nextSerial(versioned.zone.SOA.Serial)
+		removed, added := diffZones(versioned.zone, zone)
+
+		versioned.journal = append(versioned.journal, delta{
+			from:    versioned.zone.SOA,
+			to:      zone.SOA,
+			removed: removed,
+			added:   added,
+		})
+		if len(versioned.journal) > journalSize {
+			versioned.journal = versioned.journal[len(versioned.journal)-journalSize:]
+		}
+		versioned.zone = zone
+
+		changed[origin] = zone.SOA.Serial
+		p.logger.Info(primaryLogTag, "Zone %s changed to serial %d: %d records removed, %d added", origin, zone.SOA.Serial, len(removed), len(added))
+	}
+
+	return changed
+}
+
+// nextSerial uses the current unix time so serials survive restarts, and
+// falls back to incrementing when several changes happen within a second.
+func (p *Primary) nextSerial(previous uint32) uint32 {
+	now := uint32(p.clock.Now().Unix())
+	if previous == 0 || int32(now-previous) > 0 {
+		return now
+	}
+
+	return previous + 1
+}
+
+func diffZones(old, new Zone) ([]dns.RR, []dns.RR) {
+	oldRecords := map[string]struct{}{}
+	for _, rr := range old.Records {
+		oldRecords[rr.String()] = struct{}{}
+	}
+
+	newRecords := map[string]struct{}{}
+	for _, rr := range new.Records {
+		newRecords[rr.String()] = struct{}{}
+	}
+
+	var removed, added []dns.RR
+	for _, rr := range old.Records {
+		if _, ok := newRecords[rr.String()]; !ok {
+			removed = append(removed, rr)
+		}
+	}
+
+	for _, rr := range new.Records {
+		if _, ok := oldRecords[rr.String()]; !ok {
+			added = append(added, rr)
+		}
+	}
+
+	return removed, added
+}
@@ -0,0 +1,163 @@
+package zone_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/records"
+	"bosh-dns/dns/server/zone"
+	"bosh-dns/dns/server/zone/zonefakes"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Primary", func() {
+	var (
+		source       *zonefakes.FakeRecordSource
+		notifier     *zonefakes.FakeNotifier
+		fakeClock    *fakeclock.FakeClock
+		fakeLogger   *loggerfakes.FakeLogger
+		changes      chan records.RecordsDiff
+		shutdownChan chan struct{}
+		primary      *zone.Primary
+		startSerial  uint32
+	)
+
+	setRecords := func(ips ...string) {
+		recs := []records.Record{}
+		for i, ip := range ips {
+			recs = append(recs, records.Record{ID: string(rune('a' + i)), Group: "web", Network: "default", Deployment: "cf", IP: ip, Domain: "bosh."})
+		}
+		source.AllRecordsReturns(recs)
+	}
+
+	reload := func() {
+		changes <- records.RecordsDiff{}
+		// the next send only happens once the previous change was handled
+		changes <- records.RecordsDiff{}
+	}
+
+	BeforeEach(func() {
+		source = &zonefakes.FakeRecordSource{}
+		notifier = &zonefakes.FakeNotifier{}
+		fakeClock = fakeclock.NewFakeClock(time.Unix(1500000000, 0))
+		fakeLogger = &loggerfakes.FakeLogger{}
+		changes = make(chan records.RecordsDiff)
+		shutdownChan = make(chan struct{})
+		startSerial = 1500000000
+
+		source.SubscribeChangesReturns(changes)
+		setRecords("10.0.0.1")
+
+		primary = zone.NewPrimary(source, config.ZoneTransfer{Nameservers: []string{"ns1.example.com."}}, notifier, fakeClock, fakeLogger)
+		go primary.Run(shutdownChan)
+	})
+
+	AfterEach(func() {
+		close(shutdownChan)
+	})
+
+	It("serves the zone with a serial from the current time", func() {
+		z, ok := primary.Zone("bosh.")
+		Expect(ok).To(BeTrue())
+		Expect(z.SOA.Serial).To(Equal(startSerial))
+
+		_, ok = primary.Zone("unknown.")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("notifies secondaries of every zone on start", func() {
+		Eventually(notifier.NotifyCallCount).Should(Equal(1))
+
+		origin, serial := notifier.NotifyArgsForCall(0)
+		Expect(origin).To(Equal("bosh."))
+		Expect(serial).To(Equal(startSerial))
+	})
+
+	Context("when the records change", func() {
+		BeforeEach(func() {
+			Eventually(notifier.NotifyCallCount).Should(Equal(1))
+
+			setRecords("10.0.0.2")
+			reload()
+		})
+
+		It("bumps the serial and notifies secondaries", func() {
+			z, _ := primary.Zone("bosh.")
+			Expect(z.SOA.Serial).To(Equal(startSerial + 1))
+
+			Expect(notifier.NotifyCallCount()).To(Equal(2))
+			origin, serial := notifier.NotifyArgsForCall(1)
+			Expect(origin).To(Equal("bosh."))
+			Expect(serial).To(Equal(startSerial + 1))
+		})
+
+		It("uses the current time as the serial once it is ahead", func() {
+			fakeClock.Increment(time.Minute)
+			setRecords("10.0.0.3")
+			reload()
+
+			z, _ := primary.Zone("bosh.")
+			Expect(z.SOA.Serial).To(Equal(startSerial + 60))
+		})
+
+		It("answers IXFR from the previous serial with the changes", func() {
+			rrs, ok := primary.IXFR("bosh.", startSerial)
+			Expect(ok).To(BeTrue())
+
+			Expect(recordStrings(rrs)).To(Equal([]string{
+				"bosh.\t0\tIN\tSOA\tns1.example.com. hostmaster.bosh. 1500000001 0 0 0 0",
+				"bosh.\t0\tIN\tSOA\tns1.example.com. hostmaster.bosh. 1500000000 0 0 0 0",
+				"a.web.default.cf.bosh.\t0\tIN\tA\t10.0.0.1",
+				"q-s0.web.default.cf.bosh.\t0\tIN\tA\t10.0.0.1",
+				"bosh.\t0\tIN\tSOA\tns1.example.com. hostmaster.bosh. 1500000001 0 0 0 0",
+				"a.web.default.cf.bosh.\t0\tIN\tA\t10.0.0.2",
+				"q-s0.web.default.cf.bosh.\t0\tIN\tA\t10.0.0.2",
+				"bosh.\t0\tIN\tSOA\tns1.example.com. hostmaster.bosh. 1500000001 0 0 0 0",
+			}))
+		})
+
+		It("answers IXFR from the current serial with the SOA alone", func() {
+			rrs, _ := primary.IXFR("bosh.", startSerial+1)
+
+			Expect(rrs).To(HaveLen(1))
+			Expect(rrs[0].(*dns.SOA).Serial).To(Equal(startSerial + 1))
+		})
+
+		It("answers IXFR from an unknown serial with the full zone", func() {
+			rrs, _ := primary.IXFR("bosh.", 7)
+
+			z, _ := primary.Zone("bosh.")
+			Expect(rrs).To(Equal(z.AXFR()))
+		})
+	})
+
+	Context("when a reload does not change the zone", func() {
+		It("keeps the serial and does not notify", func() {
+			Eventually(notifier.NotifyCallCount).Should(Equal(1))
+
+			reload()
+
+			z, _ := primary.Zone("bosh.")
+			Expect(z.SOA.Serial).To(Equal(startSerial))
+			Expect(notifier.NotifyCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when a domain disappears", func() {
+		It("stops serving its zone", func() {
+			Eventually(notifier.NotifyCallCount).Should(Equal(1))
+
+			source.AllRecordsReturns([]records.Record{})
+			reload()
+
+			_, ok := primary.Zone("bosh.")
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,234 @@
+package zone
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/records"
+
+	"github.com/miekg/dns"
+)
+
+//go:generate counterfeiter . RecordSource
+
+type RecordSource interface {
+	AllRecords() []records.Record
+	Aliases() []string
+	AliasIPs(alias string) []string
+	SubscribeChanges() <-chan records.RecordsDiff
+}
+
+// Zone is a synthesized BOSH zone. Records holds every record except the
+// SOA, sorted so that two zones with the same contents compare equal.
+type Zone struct {
+	Origin  string
+	SOA     *dns.SOA
+	Records []dns.RR
+}
+
+// AXFR returns the zone in transfer order, enclosed by its SOA.
+func (z Zone) AXFR() []dns.RR {
+	rrs := make([]dns.RR, 0, len(z.Records)+2)
+	rrs = append(rrs, z.SOA)
+	rrs = append(rrs, z.Records...)
+	return append(rrs, z.SOA)
+}
+
+// NS returns the zone's apex NS records.
+func (z Zone) NS() []dns.RR {
+	ns := []dns.RR{}
+	for _, rr := range z.Records {
+		if rr.Header().Rrtype == dns.TypeNS {
+			ns = append(ns, rr)
+		}
+	}
+
+	return ns
+}
+
+func (z Zone) sameRecords(other Zone) bool {
+	if len(z.Records) != len(other.Records) {
+		return false
+	}
+
+	for i := range z.Records {
+		if z.Records[i].String() != other.Records[i].String() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Origins returns the zones bosh-dns is primary for: every records domain,
+// plus the topmost alias names which are not below one of them.
+func Origins(source RecordSource) []string {
+	seen := map[string]struct{}{}
+	origins := []string{}
+
+	for _, record := range source.AllRecords() {
+		if _, ok := seen[record.Domain]; !ok {
+			seen[record.Domain] = struct{}{}
+			origins = append(origins, record.Domain)
+		}
+	}
+
+	aliases := []string{}
+	for _, alias := range source.Aliases() {
+		if originFor(alias, origins) == "" {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	for _, alias := range aliases {
+		topmost := true
+		for _, other := range aliases {
+			if other != alias && dns.IsSubDomain(other, alias) {
+				topmost = false
+				break
+			}
+		}
+
+		if topmost {
+			origins = append(origins, alias)
+		}
+	}
+
+	sort.Strings(origins)
+
+	return origins
+}
+
+// Build synthesizes the zones for origins. Each record yields an address
+// record for its instance name, id.group.network.deployment.domain, and one
+// for its group, q-s0.group.network.deployment.domain. Aliases yield address
+// records for every IP they resolve to, ignoring health.
+func Build(source RecordSource, origins []string, cfg config.ZoneTransfer, serial uint32) map[string]Zone {
+	ttl := uint32(time.Duration(cfg.TTL) / time.Second)
+	names := map[string]map[string][]string{}
+	for _, origin := range origins {
+		names[origin] = map[string][]string{}
+	}
+
+	for _, record := range source.AllRecords() {
+		zoneNames, ok := names[record.Domain]
+		if !ok {
+			continue
+		}
+
+		instance := fmt.Sprintf("%s.%s.%s.%s.%s", record.ID, record.Group, record.Network, record.Deployment, record.Domain)
+		group := fmt.Sprintf("q-s0.%s.%s.%s.%s", record.Group, record.Network, record.Deployment, record.Domain)
+
+		zoneNames[dns.Fqdn(instance)] = append(zoneNames[dns.Fqdn(instance)], record.IP)
+		zoneNames[dns.Fqdn(group)] = append(zoneNames[dns.Fqdn(group)], record.IP)
+	}
+
+	for _, alias := range source.Aliases() {
+		origin := originFor(alias, origins)
+		if origin == "" {
+			continue
+		}
+
+		names[origin][alias] = append(names[origin][alias], source.AliasIPs(alias)...)
+	}
+
+	zones := map[string]Zone{}
+	for _, origin := range origins {
+		zone := Zone{
+			Origin: origin,
+			SOA:    soa(origin, cfg, serial, ttl),
+		}
+
+		for _, nameserver := range cfg.Nameservers {
+			zone.Records = append(zone.Records, &dns.NS{
+				Hdr: dns.RR_Header{Name: origin, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: ttl},
+				Ns:  dns.Fqdn(nameserver),
+			})
+		}
+
+		for name, ips := range names[origin] {
+			zone.Records = append(zone.Records, addressRecords(name, ips, ttl)...)
+		}
+
+		sortRecords(zone.Records)
+		zones[origin] = zone
+	}
+
+	return zones
+}
+
+func soa(origin string, cfg config.ZoneTransfer, serial, ttl uint32) *dns.SOA {
+	hostmaster := cfg.Hostmaster
+	if hostmaster == "" {
+		hostmaster = "hostmaster." + origin
+	}
+
+	// zones are also built for DNSSEC alone, which needs no nameservers
+	primaryNS := origin
+	if len(cfg.Nameservers) > 0 {
+		primaryNS = dns.Fqdn(cfg.Nameservers[0])
+	}
+
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: origin, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: ttl},
+		Ns:      primaryNS,
+		Mbox:    dns.Fqdn(hostmaster),
+		Serial:  serial,
+		Refresh: uint32(time.Duration(cfg.Refresh) / time.Second),
+		Retry:   uint32(time.Duration(cfg.Retry) / time.Second),
+		Expire:  uint32(time.Duration(cfg.Expire) / time.Second),
+		Minttl:  ttl,
+	}
+}
+
+func addressRecords(name string, ips []string, ttl uint32) []dns.RR {
+	rrs := []dns.RR{}
+	seen := map[string]struct{}{}
+
+	for _, ipStr := range ips {
+		if _, ok := seen[ipStr]; ok {
+			continue
+		}
+		seen[ipStr] = struct{}{}
+
+		ip := net.ParseIP(ipStr)
+		if ip == nil {
+			continue
+		}
+
+		if ip.To4() != nil {
+			rrs = append(rrs, &dns.A{
+				Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+				A:   ip.To4(),
+			})
+		} else {
+			rrs = append(rrs, &dns.AAAA{
+				Hdr:  dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: ttl},
+				AAAA: ip,
+			})
+		}
+	}
+
+	return rrs
+}
+
+func sortRecords(rrs []dns.RR) {
+	sort.Slice(rrs, func(i, j int) bool {
+		return rrs[i].String() < rrs[j].String()
+	})
+}
+
+// originFor returns the longest of origins containing name.
+func originFor(name string, origins []string) string {
+	found := ""
+	for _, origin := range origins {
+		if dns.IsSubDomain(origin, name) && len(origin) > len(found) {
+			found = origin
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,13 @@
+package zone_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestZone(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "dns/server/zone")
+}
@@ -0,0 +1,129 @@
+package zone_test
+
+import (
+	"time"
+
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/records"
+	"bosh-dns/dns/server/zone"
+	"bosh-dns/dns/server/zone/zonefakes"
+
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func recordStrings(rrs []dns.RR) []string {
+	strs := []string{}
+	for _, rr := range rrs {
+		strs = append(strs, rr.String())
+	}
+
+	return strs
+}
+
+var _ = Describe("Zone", func() {
+	var (
+		source *zonefakes.FakeRecordSource
+		cfg    config.ZoneTransfer
+	)
+
+	BeforeEach(func() {
+		source = &zonefakes.FakeRecordSource{}
+		source.AllRecordsReturns([]records.Record{
+			{ID: "instance0", Group: "web", Network: "default", Deployment: "cf", IP: "10.0.0.1", Domain: "bosh."},
+			{ID: "instance1", Group: "web", Network: "default", Deployment: "cf", IP: "10.0.0.2", Domain: "bosh."},
+			{ID: "instance2", Group: "db", Network: "default", Deployment: "cf", IP: "fd00::1", Domain: "other.tld."},
+		})
+		source.AliasesReturns([]string{"db.internal.", "primary.db.internal.", "web.bosh."})
+		source.AliasIPsStub = func(alias string) []string {
+			switch alias {
+			case "db.internal.":
+				return []string{"10.0.1.1"}
+			case "primary.db.internal.":
+				return []string{"10.0.1.2"}
+			case "web.bosh.":
+				return []string{"10.0.0.1", "10.0.0.2"}
+			}
+			return nil
+		}
+
+		cfg = config.ZoneTransfer{
+			Nameservers: []string{"ns1.example.com", "ns2.example.com."},
+			TTL:         config.DurationJSON(5 * time.Second),
+			Refresh:     config.DurationJSON(time.Minute),
+			Retry:       config.DurationJSON(10 * time.Second),
+			Expire:      config.DurationJSON(time.Hour),
+		}
+	})
+
+	Describe("Origins", func() {
+		It("returns the records domains and the topmost alias names outside of them", func() {
+			Expect(zone.Origins(source)).To(Equal([]string{"bosh.", "db.internal.", "other.tld."}))
+		})
+	})
+
+	Describe("Build", func() {
+		var zones map[string]zone.Zone
+
+		BeforeEach(func() {
+			zones = zone.Build(source, zone.Origins(source), cfg, 42)
+		})
+
+		It("synthesizes the SOA", func() {
+			Expect(zones["bosh."].SOA.String()).To(Equal("bosh.\t5\tIN\tSOA\tns1.example.com. hostmaster.bosh. 42 60 10 3600 5"))
+		})
+
+		It("uses the configured hostmaster", func() {
+			cfg.Hostmaster = "dns-admin.example.com"
+			zones = zone.Build(source, zone.Origins(source), cfg, 42)
+
+			Expect(zones["bosh."].SOA.Mbox).To(Equal("dns-admin.example.com."))
+		})
+
+		It("names the origin as primary nameserver without nameservers", func() {
+			cfg.Nameservers = nil
+			zones = zone.Build(source, zone.Origins(source), cfg, 42)
+
+			Expect(zones["bosh."].SOA.Ns).To(Equal("bosh."))
+			Expect(zones["bosh."].NS()).To(BeEmpty())
+		})
+
+		It("synthesizes NS, instance, group and alias records", func() {
+			Expect(recordStrings(zones["bosh."].Records)).To(Equal([]string{
+				"bosh.\t5\tIN\tNS\tns1.example.com.",
+				"bosh.\t5\tIN\tNS\tns2.example.com.",
+				"instance0.web.default.cf.bosh.\t5\tIN\tA\t10.0.0.1",
+				"instance1.web.default.cf.bosh.\t5\tIN\tA\t10.0.0.2",
+				"q-s0.web.default.cf.bosh.\t5\tIN\tA\t10.0.0.1",
+				"q-s0.web.default.cf.bosh.\t5\tIN\tA\t10.0.0.2",
+				"web.bosh.\t5\tIN\tA\t10.0.0.1",
+				"web.bosh.\t5\tIN\tA\t10.0.0.2",
+			}))
+		})
+
+		It("synthesizes AAAA records for IPv6 addresses", func() {
+			Expect(recordStrings(zones["other.tld."].Records)).To(ContainElement("instance2.db.default.cf.other.tld.\t5\tIN\tAAAA\tfd00::1"))
+		})
+
+		It("puts aliases below an alias zone into it", func() {
+			Expect(recordStrings(zones["db.internal."].Records)).To(ContainElement("primary.db.internal.\t5\tIN\tA\t10.0.1.2"))
+			Expect(recordStrings(zones["db.internal."].Records)).To(ContainElement("db.internal.\t5\tIN\tA\t10.0.1.1"))
+		})
+
+		It("encloses the zone in its SOA for transfers", func() {
+			axfr := zones["bosh."].AXFR()
+
+			Expect(axfr).To(HaveLen(10))
+			Expect(axfr[0]).To(Equal(zones["bosh."].SOA))
+			Expect(axfr[9]).To(Equal(zones["bosh."].SOA))
+		})
+
+		It("returns the apex NS records", func() {
+			Expect(recordStrings(zones["bosh."].NS())).To(Equal([]string{
+				"bosh.\t5\tIN\tNS\tns1.example.com.",
+				"bosh.\t5\tIN\tNS\tns2.example.com.",
+			}))
+		})
+	})
+})
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"bosh-dns/dns/server/records"
+)
+
+// validateRecords implements `bosh-dns validate-records [--json] <file>`. It
+// exits 0 only when every row of the records file is usable. The report goes
+// to out, usage and errors to errOut.
+func validateRecords(args []string, out, errOut io.Writer) int {
+	flags := flag.NewFlagSet("validate-records", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(errOut, "usage: bosh-dns validate-records [--json] <file>")
+		return 2
+	}
+
+	path := flags.Arg(0)
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(errOut, "%s: %s\n", path, err)
+		return 1
+	}
+
+	report, err := records.ValidateRecords(contents)
+	if err != nil {
+		fmt.Fprintf(errOut, "%s: %s\n", path, err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(errOut, "encoding report: %s\n", err)
+			return 1
+		}
+	} else {
+		fmt.Fprintln(out, report)
+	}
+
+	if !report.Valid() {
+		return 1
+	}
+
+	return 0
+}
@@ -10,6 +10,8 @@ import (
 
 	"crypto/x509"
 
+	"bosh-dns/healthcheck/healthtls"
+
 	"github.com/cloudfoundry/bosh-utils/httpclient"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
@@ -34,10 +36,33 @@ func NewHealthClient(caCert []byte, cert tls.Certificate, logger boshlog.Logger)
 	caCertPool := x509.NewCertPool()
 	caCertPool.AppendCertsFromPEM(caCert)
 
-	client := httpclient.NewMutualTLSClient(cert, caCertPool, "")
+	return newHealthClient(
+		func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return &cert, nil },
+		func() *x509.CertPool { return caCertPool },
+		logger,
+	)
+}
+
+// NewReloadingHealthClient presents whatever client certificate the reloader
+// currently holds and verifies servers against all of its CA bundles, so
+// certificate rotation does not require restarting the DNS server.
+func NewReloadingHealthClient(certs *healthtls.CertReloader, logger boshlog.Logger) *httpclient.HTTPClient {
+	return newHealthClient(certs.GetClientCertificate, certs.CAPool, logger)
+}
+
+func newHealthClient(
+	getClientCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error),
+	rootCAs func() *x509.CertPool,
+	logger boshlog.Logger,
+) *httpclient.HTTPClient {
+	initialCert, _ := getClientCertificate(nil)
+
+	client := httpclient.NewMutualTLSClient(*initialCert, rootCAs(), "")
 	client.Timeout = 5 * time.Second
 
 	if tr, ok := client.Transport.(*http.Transport); ok {
+		tr.TLSClientConfig.Certificates = nil
+		tr.TLSClientConfig.GetClientCertificate = getClientCertificate
 		tr.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(10000)
 		tr.TLSClientConfig.InsecureSkipVerify = true
 		tr.TLSClientConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
@@ -51,7 +76,7 @@ func NewHealthClient(caCert []byte, cert tls.Certificate, logger boshlog.Logger)
 			}
 
 			opts := x509.VerifyOptions{
-				Roots:         tr.TLSClientConfig.RootCAs,
+				Roots:         rootCAs(),
 				CurrentTime:   time.Now(),
 				DNSName:       "health.bosh-dns",
 				Intermediates: x509.NewCertPool(),
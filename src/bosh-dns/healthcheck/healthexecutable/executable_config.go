@@ -0,0 +1,53 @@
+package healthexecutable
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"bosh-dns/dns/config"
+
+	"github.com/cloudfoundry/bosh-utils/system"
+)
+
+// ExecutableConfig holds optional per-executable settings read from a sidecar
+// file next to the executable, e.g. bin/dns/healthy.json for bin/dns/healthy
+// or bin/dns/healthy.ps1.
+type ExecutableConfig struct {
+	Interval config.DurationJSON `json:"interval,omitempty"`
+	Timeout  config.DurationJSON `json:"timeout,omitempty"`
+}
+
+func sidecarConfigPath(executablePath string) string {
+	return strings.TrimSuffix(executablePath, filepath.Ext(executablePath)) + ".json"
+}
+
+func loadExecutableConfig(fs system.FileSystem, executablePath string, defaultInterval, defaultTimeout time.Duration) (time.Duration, time.Duration, error) {
+	interval, timeout := defaultInterval, defaultTimeout
+
+	configPath := sidecarConfigPath(executablePath)
+	if !fs.FileExists(configPath) {
+		return interval, timeout, nil
+	}
+
+	contents, err := fs.ReadFile(configPath)
+	if err != nil {
+		return interval, timeout, err
+	}
+
+	var executableConfig ExecutableConfig
+	if err := json.Unmarshal(contents, &executableConfig); err != nil {
+		return interval, timeout, err
+	}
+
+	if executableConfig.Interval > 0 {
+		interval = time.Duration(executableConfig.Interval)
+	}
+
+	if executableConfig.Timeout > 0 {
+		timeout = time.Duration(executableConfig.Timeout)
+	}
+
+	return interval, timeout, nil
+}
@@ -1,7 +1,6 @@
 package healthexecutable
 
 import (
-	"strings"
 	"time"
 
 	"sync"
@@ -11,32 +10,79 @@ import (
 	"github.com/cloudfoundry/bosh-utils/system"
 )
 
+const (
+	logTag = "HealthExecutableMonitor"
+
+	// maxOutputBytes bounds how much of each stream is kept for diagnostics
+	maxOutputBytes = 4096
+
+	killGracePeriod = 5 * time.Second
+
+	defaultTimeout = time.Minute
+)
+
+type executableResult struct {
+	Path       string
+	Healthy    bool
+	ExitStatus int
+	Stdout     string
+	Stderr     string
+	Error      string
+	TimedOut   bool
+	Duration   time.Duration
+}
+
+type executableState struct {
+	interval time.Duration
+	nextRun  time.Time
+	running  bool
+	result   executableResult
+	finished bool
+}
+
 type HealthExecutableMonitor struct {
-	healthExecutablePaths []string
+	healthExecutablesGlob string
+	fs                    system.FileSystem
 	cmdRunner             system.CmdRunner
 	clock                 clock.Clock
 	interval              time.Duration
+	timeout               time.Duration
+	workers               chan struct{}
 	shutdown              chan struct{}
-	status                bool
+	executables           map[string]*executableState
 	mutex                 *sync.Mutex
 	logger                logger.Logger
 }
 
 func NewHealthExecutableMonitor(
-	healthExecutablePaths []string,
+	healthExecutablesGlob string,
+	fs system.FileSystem,
 	cmdRunner system.CmdRunner,
 	clock clock.Clock,
 	interval time.Duration,
+	timeout time.Duration,
+	maxConcurrency int,
 	shutdown chan struct{},
 	logger logger.Logger,
 ) *HealthExecutableMonitor {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
 	monitor := &HealthExecutableMonitor{
-		healthExecutablePaths: healthExecutablePaths,
+		healthExecutablesGlob: healthExecutablesGlob,
+		fs:                    fs,
 		cmdRunner:             cmdRunner,
 		clock:                 clock,
 		interval:              interval,
+		timeout:               timeout,
+		workers:               make(chan struct{}, maxConcurrency),
 		shutdown:              shutdown,
-		status:                true,
+		executables:           map[string]*executableState{},
 		mutex:                 &sync.Mutex{},
 		logger:                logger,
 	}
@@ -49,32 +95,178 @@ func NewHealthExecutableMonitor(
 func (m *HealthExecutableMonitor) Status() bool {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
-	return m.status
+
+	for _, state := range m.executables {
+		if state.finished && !state.result.Healthy {
+			return false
+		}
+	}
+
+	return true
 }
 
 func (m *HealthExecutableMonitor) run() {
 	ticker := m.clock.NewTicker(m.interval)
-	m.logger.Debug("HealthExecutableMonitor", "starting monitor for [%s] with interval %v", strings.Join(m.healthExecutablePaths, ", "), m.interval)
+	m.logger.Debug(logTag, "starting monitor for '%s' with interval %v, timeout %v and concurrency %d", m.healthExecutablesGlob, m.interval, m.timeout, cap(m.workers))
 	for {
 		select {
 		case <-m.shutdown:
-			m.logger.Debug("HealthExecutableMonitor", "stopping")
+			m.logger.Debug(logTag, "stopping")
 			ticker.Stop()
 			return
 		case <-ticker.C():
-			var allSucceeded = true
-			for _, executable := range m.healthExecutablePaths {
-				_, _, exitStatus, err := m.cmdRunner.RunCommand(executable)
-				if err != nil {
-					allSucceeded = false
-					m.logger.Error("HealthExecutableMonitor", "Error occurred executing '%s': %v", executable, err)
-				} else if exitStatus != 0 {
-					allSucceeded = false
-				}
-			}
-			m.mutex.Lock()
-			m.status = allSucceeded
-			m.mutex.Unlock()
+			m.refreshExecutables()
+			m.scheduleDue()
 		}
 	}
 }
+
+func (m *HealthExecutableMonitor) refreshExecutables() {
+	paths, err := m.fs.Glob(m.healthExecutablesGlob)
+	if err != nil {
+		m.logger.Error(logTag, "Error globbing for executables '%s': %v", m.healthExecutablesGlob, err)
+		return
+	}
+
+	found := map[string]struct{}{}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, path := range paths {
+		found[path] = struct{}{}
+		if _, ok := m.executables[path]; !ok {
+			m.logger.Info(logTag, "discovered health executable '%s'", path)
+			m.executables[path] = &executableState{interval: m.interval}
+		}
+	}
+
+	for path := range m.executables {
+		if _, ok := found[path]; !ok {
+			m.logger.Info(logTag, "health executable '%s' is gone, no longer monitoring it", path)
+			delete(m.executables, path)
+		}
+	}
+}
+
+func (m *HealthExecutableMonitor) scheduleDue() {
+	now := m.clock.Now()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for path, state := range m.executables {
+		if state.running || now.Before(state.nextRun) {
+			continue
+		}
+
+		interval, timeout, err := loadExecutableConfig(m.fs, path, m.interval, m.timeout)
+		if err != nil {
+			m.logger.Error(logTag, "Error loading config for '%s', using defaults: %v", path, err)
+		}
+
+		state.running = true
+		state.interval = interval
+
+		go m.runExecutable(path, timeout)
+	}
+}
+
+func (m *HealthExecutableMonitor) runExecutable(path string, timeout time.Duration) {
+	select {
+	case m.workers <- struct{}{}:
+	case <-m.shutdown:
+		return
+	}
+	defer func() { <-m.workers }()
+
+	startedAt := m.clock.Now()
+	result := m.execute(path, timeout)
+	result.Duration = m.clock.Since(startedAt)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	state, ok := m.executables[path]
+	if !ok {
+		return
+	}
+
+	m.logger.Debug(logTag, "'%s' exited with status %d after %v", path, result.ExitStatus, result.Duration)
+
+	// Only the run that turns an executable unhealthy is logged at error
+	// level so that a persistently failing check does not flood the log.
+	// Executables which could not be run were already logged by execute.
+	wasHealthy := !state.finished || state.result.Healthy
+	if !result.Healthy && result.Error != "" {
+		m.logger.Debug(logTag, "'%s' is unhealthy as it could not be executed", path)
+	} else if !result.Healthy && wasHealthy {
+		m.logger.Error(logTag, "'%s' reported unhealthy (exit status %d, timed out: %t); stdout: %s; stderr: %s", path, result.ExitStatus, result.TimedOut, result.Stdout, result.Stderr)
+	} else if !result.Healthy {
+		m.logger.Debug(logTag, "'%s' is still unhealthy (exit status %d, timed out: %t); stdout: %s; stderr: %s", path, result.ExitStatus, result.TimedOut, result.Stdout, result.Stderr)
+	} else if !wasHealthy {
+		m.logger.Info(logTag, "'%s' reported healthy again", path)
+	}
+
+	state.running = false
+	state.finished = true
+	state.result = result
+	state.nextRun = startedAt.Add(state.interval)
+}
+
+func (m *HealthExecutableMonitor) execute(path string, timeout time.Duration) executableResult {
+	result := executableResult{Path: path, ExitStatus: -1}
+
+	process, err := m.cmdRunner.RunComplexCommandAsync(system.Command{
+		Name:  path,
+		Quiet: true,
+	})
+	if err != nil {
+		m.logger.Error(logTag, "Error occurred executing '%s': %v", path, err)
+		result.Error = err.Error()
+		return result
+	}
+
+	timer := m.clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	waitCh := process.Wait()
+
+	var processResult system.Result
+
+	select {
+	case processResult = <-waitCh:
+	case <-timer.C():
+		m.logger.Error(logTag, "Executing '%s' timed out after %v, killing its process group", path, timeout)
+		result.TimedOut = true
+
+		if err := process.TerminateNicely(killGracePeriod); err != nil {
+			m.logger.Error(logTag, "Error terminating '%s': %v", path, err)
+		}
+
+		processResult = <-waitCh
+	}
+
+	result.ExitStatus = processResult.ExitStatus
+	result.Stdout = truncateOutput(processResult.Stdout)
+	result.Stderr = truncateOutput(processResult.Stderr)
+
+	if processResult.Error != nil {
+		result.Error = processResult.Error.Error()
+		if !result.TimedOut {
+			m.logger.Error(logTag, "Error occurred executing '%s': %v", path, processResult.Error)
+		}
+	}
+
+	result.Healthy = !result.TimedOut && processResult.Error == nil && processResult.ExitStatus == 0
+
+	return result
+}
+
+func truncateOutput(output string) string {
+	if len(output) <= maxOutputBytes {
+		return output
+	}
+
+	return output[len(output)-maxOutputBytes:]
+}
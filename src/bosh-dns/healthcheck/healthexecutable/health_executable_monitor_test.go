@@ -9,38 +9,96 @@ import (
 
 	"errors"
 	"fmt"
+	"sync"
 
 	"code.cloudfoundry.org/clock/fakeclock"
 	loggerfakes "github.com/cloudfoundry/bosh-utils/logger/fakes"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	sysfakes "github.com/cloudfoundry/bosh-utils/system/fakes"
 )
 
+// syncCmdRunner records the commands the monitor's workers start under a lock,
+// as FakeCmdRunner does not guard RunComplexCommands against concurrent reads.
+type syncCmdRunner struct {
+	*sysfakes.FakeCmdRunner
+
+	mutex    sync.Mutex
+	commands []boshsys.Command
+}
+
+func (r *syncCmdRunner) RunComplexCommandAsync(cmd boshsys.Command) (boshsys.Process, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.commands = append(r.commands, cmd)
+	return r.FakeCmdRunner.RunComplexCommandAsync(cmd)
+}
+
+func (r *syncCmdRunner) Commands() []boshsys.Command {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]boshsys.Command{}, r.commands...)
+}
+
 var _ = Describe("HealthExecutableMonitor", func() {
 	var (
 		monitor         *healthexecutable.HealthExecutableMonitor
 		logger          *loggerfakes.FakeLogger
-		cmdRunner       *sysfakes.FakeCmdRunner
+		fs              *sysfakes.FakeFileSystem
+		cmdRunner       *syncCmdRunner
 		clock           *fakeclock.FakeClock
 		interval        time.Duration
+		timeout         time.Duration
+		maxConcurrency  int
+		glob            string
 		executablePaths []string
 		signal          chan struct{}
 	)
 
+	addProcess := func(path string, result boshsys.Result) {
+		cmdRunner.AddProcess(path, &sysfakes.FakeProcess{WaitResult: result})
+	}
+
+	runCount := func() int {
+		return len(cmdRunner.Commands())
+	}
+
+	finishedCount := func() int {
+		finished := 0
+		for i := 0; i < logger.DebugCallCount(); i++ {
+			_, template, _ := logger.DebugArgsForCall(i)
+			if template == "'%s' exited with status %d after %v" {
+				finished++
+			}
+		}
+		return finished
+	}
+
 	BeforeEach(func() {
 		logger = &loggerfakes.FakeLogger{}
+		fs = sysfakes.NewFakeFileSystem()
 		clock = fakeclock.NewFakeClock(time.Now())
-		cmdRunner = sysfakes.NewFakeCmdRunner()
+		cmdRunner = &syncCmdRunner{FakeCmdRunner: sysfakes.NewFakeCmdRunner()}
 		interval = time.Millisecond
+		timeout = time.Minute
+		maxConcurrency = 3
+		glob = "/var/vcap/jobs/*/bin/dns/healthy"
 		executablePaths = []string{"e1", "e2", "e3"}
 		signal = make(chan struct{})
 	})
 
 	JustBeforeEach(func() {
+		fs.SetGlob(glob, executablePaths)
+
 		monitor = healthexecutable.NewHealthExecutableMonitor(
-			executablePaths,
+			glob,
+			fs,
 			cmdRunner,
 			clock,
 			interval,
+			timeout,
+			maxConcurrency,
 			signal,
 			logger,
 		)
@@ -54,17 +112,17 @@ var _ = Describe("HealthExecutableMonitor", func() {
 
 	Context("when some executables go unhealthy and they become healthy again", func() {
 		BeforeEach(func() {
-			cmdRunner.AddCmdResult(executablePaths[0], sysfakes.FakeCmdResult{ExitStatus: 0})
-			cmdRunner.AddCmdResult(executablePaths[1], sysfakes.FakeCmdResult{ExitStatus: 0})
-			cmdRunner.AddCmdResult(executablePaths[2], sysfakes.FakeCmdResult{ExitStatus: 0})
+			addProcess(executablePaths[0], boshsys.Result{ExitStatus: 0})
+			addProcess(executablePaths[1], boshsys.Result{ExitStatus: 0})
+			addProcess(executablePaths[2], boshsys.Result{ExitStatus: 0})
 
-			cmdRunner.AddCmdResult(executablePaths[0], sysfakes.FakeCmdResult{ExitStatus: 0})
-			cmdRunner.AddCmdResult(executablePaths[1], sysfakes.FakeCmdResult{ExitStatus: 1})
-			cmdRunner.AddCmdResult(executablePaths[2], sysfakes.FakeCmdResult{ExitStatus: 0})
+			addProcess(executablePaths[0], boshsys.Result{ExitStatus: 0})
+			addProcess(executablePaths[1], boshsys.Result{ExitStatus: 1, Stdout: "not ready", Stderr: "oops"})
+			addProcess(executablePaths[2], boshsys.Result{ExitStatus: 0})
 
-			cmdRunner.AddCmdResult(executablePaths[0], sysfakes.FakeCmdResult{ExitStatus: 0})
-			cmdRunner.AddCmdResult(executablePaths[1], sysfakes.FakeCmdResult{ExitStatus: 0})
-			cmdRunner.AddCmdResult(executablePaths[2], sysfakes.FakeCmdResult{ExitStatus: 0})
+			addProcess(executablePaths[0], boshsys.Result{ExitStatus: 0})
+			addProcess(executablePaths[1], boshsys.Result{ExitStatus: 0})
+			addProcess(executablePaths[2], boshsys.Result{ExitStatus: 0})
 		})
 
 		It("starts with status true", func() {
@@ -73,19 +131,55 @@ var _ = Describe("HealthExecutableMonitor", func() {
 
 		It("returns status accordingly", func() {
 			clock.WaitForWatcherAndIncrement(interval)
-			Eventually(monitor.Status).Should(BeTrue())
+			Eventually(runCount).Should(Equal(3))
+			Eventually(finishedCount).Should(Equal(3))
+			Expect(monitor.Status()).To(BeTrue())
+
 			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(runCount).Should(Equal(6))
 			Eventually(monitor.Status).Should(BeFalse())
+
 			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(runCount).Should(Equal(9))
 			Eventually(monitor.Status).Should(BeTrue())
 		})
+
+		It("logs the output of runs which turn an executable unhealthy", func() {
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(runCount).Should(Equal(3))
+			Eventually(finishedCount).Should(Equal(3))
+
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(monitor.Status).Should(BeFalse())
+
+			Expect(logger.ErrorCallCount()).To(Equal(1))
+			logTag, template, interpols := logger.ErrorArgsForCall(0)
+			Expect(logTag).To(Equal("HealthExecutableMonitor"))
+			Expect(fmt.Sprintf(template, interpols...)).To(Equal("'e2' reported unhealthy (exit status 1, timed out: false); stdout: not ready; stderr: oops"))
+
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(func() string {
+				_, template, interpols := logger.InfoArgsForCall(logger.InfoCallCount() - 1)
+				return fmt.Sprintf(template, interpols...)
+			}).Should(Equal("'e2' reported healthy again"))
+		})
+
+		It("runs executables quietly in their own process group", func() {
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(runCount).Should(Equal(3))
+
+			for _, cmd := range cmdRunner.Commands() {
+				Expect(cmd.Quiet).To(BeTrue())
+				Expect(cmd.KeepAttached).To(BeFalse())
+			}
+		})
 	})
 
 	Context("when executing an executable returns an error", func() {
 		BeforeEach(func() {
-			cmdRunner.AddCmdResult(executablePaths[0], sysfakes.FakeCmdResult{ExitStatus: 0})
-			cmdRunner.AddCmdResult(executablePaths[1], sysfakes.FakeCmdResult{ExitStatus: 0, Error: errors.New("can't do that")})
-			cmdRunner.AddCmdResult(executablePaths[2], sysfakes.FakeCmdResult{ExitStatus: 0})
+			addProcess(executablePaths[0], boshsys.Result{ExitStatus: 0})
+			addProcess(executablePaths[1], boshsys.Result{ExitStatus: 0, Error: errors.New("can't do that")})
+			addProcess(executablePaths[2], boshsys.Result{ExitStatus: 0})
 		})
 
 		It("logs an error", func() {
@@ -97,6 +191,150 @@ var _ = Describe("HealthExecutableMonitor", func() {
 			Expect(logTag).To(Equal("HealthExecutableMonitor"))
 			Expect(fmt.Sprintf(template, interpols...)).To(Equal("Error occurred executing 'e2': can't do that"))
 		})
+
+		It("does not log the failed run again as unhealthy output", func() {
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(finishedCount).Should(Equal(3))
+
+			Expect(logger.ErrorCallCount()).To(Equal(1))
+			for i := 0; i < logger.DebugCallCount(); i++ {
+				_, template, interpols := logger.DebugArgsForCall(i)
+				if template == "'%s' is unhealthy as it could not be executed" {
+					Expect(interpols).To(Equal([]interface{}{"e2"}))
+					return
+				}
+			}
+			Fail("expected the failed run to be logged at debug level")
+		})
+	})
+
+	Context("when an executable does not finish within the timeout", func() {
+		var hangingProcess *sysfakes.FakeProcess
+
+		BeforeEach(func() {
+			executablePaths = []string{"e1"}
+			interval = time.Minute
+			timeout = 10 * time.Second
+
+			hangingProcess = &sysfakes.FakeProcess{
+				TerminatedNicelyCallBack: func(p *sysfakes.FakeProcess) {
+					p.WaitCh <- boshsys.Result{ExitStatus: 143, Stdout: "still working"}
+				},
+			}
+			cmdRunner.AddProcess("e1", hangingProcess)
+		})
+
+		It("kills the process group and reports unhealthy", func() {
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(runCount).Should(Equal(1))
+			Eventually(clock.WatcherCount).Should(Equal(2))
+
+			clock.Increment(timeout)
+
+			Eventually(monitor.Status).Should(BeFalse())
+			Expect(hangingProcess.TerminatedNicely).To(BeTrue())
+
+			Expect(logger.ErrorCallCount()).To(Equal(2))
+			_, template, interpols := logger.ErrorArgsForCall(1)
+			Expect(fmt.Sprintf(template, interpols...)).To(Equal("'e1' reported unhealthy (exit status 143, timed out: true); stdout: still working; stderr: "))
+		})
+	})
+
+	Context("when no timeout is configured", func() {
+		var hangingProcess *sysfakes.FakeProcess
+
+		BeforeEach(func() {
+			executablePaths = []string{"e1"}
+			interval = time.Hour
+			timeout = 0
+
+			hangingProcess = &sysfakes.FakeProcess{
+				TerminatedNicelyCallBack: func(p *sysfakes.FakeProcess) {
+					p.WaitCh <- boshsys.Result{ExitStatus: 143}
+				},
+			}
+			cmdRunner.AddProcess("e1", hangingProcess)
+		})
+
+		It("times executables out after a minute", func() {
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(runCount).Should(Equal(1))
+			Eventually(clock.WatcherCount).Should(Equal(2))
+
+			clock.Increment(time.Minute - time.Second)
+			Consistently(monitor.Status).Should(BeTrue())
+
+			clock.Increment(time.Second)
+			Eventually(monitor.Status).Should(BeFalse())
+			Expect(hangingProcess.TerminatedNicely).To(BeTrue())
+		})
+	})
+
+	Context("when more executables exist than workers", func() {
+		var hangingProcess *sysfakes.FakeProcess
+
+		BeforeEach(func() {
+			maxConcurrency = 1
+			executablePaths = []string{"e1", "e2"}
+
+			hangingProcess = &sysfakes.FakeProcess{
+				TerminatedNicelyCallBack: func(p *sysfakes.FakeProcess) {
+					p.WaitCh <- boshsys.Result{ExitStatus: 143}
+				},
+			}
+			cmdRunner.AddProcess("e1", hangingProcess)
+			cmdRunner.AddProcess("e2", hangingProcess)
+		})
+
+		It("does not run more than the configured number at once", func() {
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(runCount).Should(Equal(1))
+			Consistently(runCount).Should(Equal(1))
+		})
+	})
+
+	Context("when an executable has a sidecar config", func() {
+		BeforeEach(func() {
+			executablePaths = []string{"/jobs/a/bin/dns/healthy"}
+			interval = time.Second
+
+			err := fs.WriteFileString("/jobs/a/bin/dns/healthy.json", `{"interval":"3s"}`)
+			Expect(err).NotTo(HaveOccurred())
+
+			for i := 0; i < 2; i++ {
+				addProcess(executablePaths[0], boshsys.Result{ExitStatus: 0})
+			}
+		})
+
+		It("uses the configured interval", func() {
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(runCount).Should(Equal(1))
+			Eventually(finishedCount).Should(Equal(1))
+
+			clock.WaitForWatcherAndIncrement(interval)
+			clock.WaitForWatcherAndIncrement(interval)
+			Consistently(runCount).Should(Equal(1))
+
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(runCount).Should(Equal(2))
+		})
+	})
+
+	Context("when executables are added after startup", func() {
+		BeforeEach(func() {
+			executablePaths = []string{}
+			addProcess("e1", boshsys.Result{ExitStatus: 1})
+		})
+
+		It("picks them up", func() {
+			clock.WaitForWatcherAndIncrement(interval)
+			Consistently(monitor.Status).Should(BeTrue())
+
+			fs.SetGlob(glob, []string{"e1"})
+
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(monitor.Status).Should(BeFalse())
+		})
 	})
 
 	Context("when no executables are defined", func() {
@@ -118,8 +356,8 @@ var _ = Describe("HealthExecutableMonitor", func() {
 			signal = nil
 
 			Eventually(clock.WatcherCount).Should(Equal(0))
-			cmdRunner.AddCmdResult(executablePaths[1], sysfakes.FakeCmdResult{ExitStatus: 1})
 			clock.Increment(interval * 2)
+			Consistently(runCount).Should(Equal(0))
 			Consistently(monitor.Status).Should(Equal(true))
 		})
 	})
@@ -8,9 +8,13 @@ type HealthCheckConfig struct {
 	CertificateFile          string              `json:"certificate_file"`
 	PrivateKeyFile           string              `json:"private_key_file"`
 	CAFile                   string              `json:"ca_file"`
+	CertReloadInterval       config.DurationJSON `json:"cert_reload_interval,omitempty"`
 	HealthFileName           string              `json:"health_file_name"`
 	HealthExecutablesGlob    string              `json:"health_executables_glob"`
 	HealthExecutableInterval config.DurationJSON `json:"health_executable_interval"`
+	HealthExecutableTimeout  config.DurationJSON `json:"health_executable_timeout,omitempty"`
+
+	HealthExecutableMaxConcurrency int `json:"health_executable_max_concurrency,omitempty"`
 }
 
 const CN = "health.bosh-dns"
@@ -2,13 +2,13 @@ package healthserver
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 
 	"crypto/tls"
-	"crypto/x509"
 	"io/ioutil"
 
+	"bosh-dns/healthcheck/healthtls"
+
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/cloudfoundry/bosh-utils/system"
 	"github.com/pivotal-cf/paraphernalia/secure/tlsconfig"
@@ -27,43 +27,34 @@ type concreteHealthServer struct {
 	fs                 system.FileSystem
 	healthJsonFileName string
 	healthExecutable   HealthExecutable
+	certs              *healthtls.CertReloader
 }
 
 const logTag = "healthServer"
 
-func NewHealthServer(logger boshlog.Logger, fs system.FileSystem, healthFileName string, healthExecutable HealthExecutable) HealthServer {
+func NewHealthServer(logger boshlog.Logger, fs system.FileSystem, healthFileName string, healthExecutable HealthExecutable, certs *healthtls.CertReloader) HealthServer {
 	return &concreteHealthServer{
 		logger:             logger,
 		fs:                 fs,
 		healthJsonFileName: healthFileName,
 		healthExecutable:   healthExecutable,
+		certs:              certs,
 	}
 }
 
 func (c *concreteHealthServer) Serve(config *HealthCheckConfig) {
 	http.HandleFunc("/health", c.healthEntryPoint)
 
-	caCert, err := ioutil.ReadFile(config.CAFile)
-	if err != nil {
-		log.Fatal(err)
-		return
+	serverConfig := tlsconfig.Build(tlsconfig.WithInternalServiceDefaults()).Server()
+	serverConfig.GetCertificate = c.certs.GetCertificate
+	// Build the config per handshake so that rotated certificates and CA
+	// bundles are picked up without restarting the server.
+	serverConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		return tlsconfig.Build(
+			tlsconfig.WithIdentity(*c.certs.Certificate()),
+			tlsconfig.WithInternalServiceDefaults(),
+		).Server(tlsconfig.WithClientAuthentication(c.certs.CAPool())), nil
 	}
-	caCertPool := x509.NewCertPool()
-	caCertPool.AppendCertsFromPEM(caCert)
-
-	cert, err := tls.LoadX509KeyPair(config.CertificateFile, config.PrivateKeyFile)
-	if err != nil {
-		log.Fatal(err)
-		return
-	}
-
-	tlsConfig := tlsconfig.Build(
-		tlsconfig.WithIdentity(cert),
-		tlsconfig.WithInternalServiceDefaults(),
-	)
-
-	serverConfig := tlsConfig.Server(tlsconfig.WithClientAuthentication(caCertPool))
-	serverConfig.BuildNameToCertificate()
 
 	server := &http.Server{
 		Addr:      fmt.Sprintf("%s:%d", config.Address, config.Port),
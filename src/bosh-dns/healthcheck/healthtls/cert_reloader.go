@@ -0,0 +1,236 @@
+package healthtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	"bosh-dns/dns/filewatcher"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	logTag = "CertReloader"
+
+	// ExpiryWarningThreshold is how long before the certificate expires that
+	// every reload starts logging warnings instead of informational messages.
+	ExpiryWarningThreshold = 30 * 24 * time.Hour
+
+	// watchedPollInterval is how often watched files are checked in case a
+	// change notification was missed
+	watchedPollInterval = time.Minute
+)
+
+// CertificateNotAfter publishes the expiry of every loaded certificate as
+// unix seconds keyed by certificate file. The health server serves it at
+// /debug/vars on its port, bosh-dns on its metrics endpoint when enabled.
+var CertificateNotAfter = expvar.NewMap("health_tls_certificate_not_after")
+
+// CertReloader keeps a certificate/key pair and a pool of trusted CAs in
+// memory and reloads them whenever one of the files changes on disk, so that
+// rotated BOSH credentials take effect without restarting the process. The CA
+// file may hold several PEM certificates so that old and new CAs are both
+// trusted while a rotation is rolling through the deployment.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	clock    clock.Clock
+	logger   boshlog.Logger
+
+	mutex    *sync.RWMutex
+	cert     *tls.Certificate
+	caPool   *x509.CertPool
+	notAfter time.Time
+	modTimes map[string]time.Time
+}
+
+func NewCertReloader(certFile, keyFile, caFile string, clock clock.Clock, logger boshlog.Logger) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		clock:    clock,
+		logger:   logger,
+		mutex:    &sync.RWMutex{},
+		modTimes: map[string]time.Time{},
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Run reloads the files whenever they change until signal is closed. Where
+// the files cannot be watched they are checked every interval instead. A
+// failed reload keeps the previously loaded material.
+func (r *CertReloader) Run(interval time.Duration, signal <-chan struct{}) {
+	pollInterval := interval
+	var events <-chan string
+
+	watcher, err := filewatcher.New(r.files())
+	if err != nil {
+		r.logger.Info(logTag, "Polling certificates every %s, unable to watch them: %s", interval, err)
+	} else {
+		defer watcher.Close()
+		events = watcher.Events()
+
+		if pollInterval < watchedPollInterval {
+			pollInterval = watchedPollInterval
+		}
+	}
+
+	for {
+		timer := r.clock.NewTimer(pollInterval)
+
+		select {
+		case <-signal:
+			timer.Stop()
+			return
+		case _, ok := <-events:
+			if !ok {
+				r.logger.Error(logTag, "Stopped watching certificates, polling every %s", interval)
+				events = nil
+				pollInterval = interval
+			}
+		case <-timer.C():
+		}
+
+		timer.Stop()
+
+		if !r.changed() {
+			continue
+		}
+
+		if err := r.reload(); err != nil {
+			r.logger.Error(logTag, "Failed to reload certificates, keeping previous ones: %s", err)
+		}
+	}
+}
+
+func (r *CertReloader) Certificate() *tls.Certificate {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.cert
+}
+
+func (r *CertReloader) CAPool() *x509.CertPool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.caPool
+}
+
+// NotAfter returns the expiry time of the currently loaded certificate.
+func (r *CertReloader) NotAfter() time.Time {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.notAfter
+}
+
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+func (r *CertReloader) files() []string {
+	return []string{r.certFile, r.keyFile, r.caFile}
+}
+
+func (r *CertReloader) changed() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			r.logger.Error(logTag, "Failed to stat '%s': %s", file, err)
+			continue
+		}
+
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *CertReloader) reload() error {
+	modTimes := map[string]time.Time{}
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	// files which failed to load are only retried once they change again
+	defer func() {
+		r.mutex.Lock()
+		r.modTimes = modTimes
+		r.mutex.Unlock()
+	}()
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	cert.Leaf = leaf
+
+	caCert, err := ioutil.ReadFile(r.caFile)
+	if err != nil {
+		return err
+	}
+
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCert) {
+		return fmt.Errorf("no certificates found in CA file '%s'", r.caFile)
+	}
+
+	r.mutex.Lock()
+	r.cert = &cert
+	r.caPool = caPool
+	r.notAfter = leaf.NotAfter
+	r.mutex.Unlock()
+
+	notAfter := new(expvar.Int)
+	notAfter.Set(leaf.NotAfter.Unix())
+	CertificateNotAfter.Set(r.certFile, notAfter)
+
+	r.logExpiry(leaf)
+
+	return nil
+}
+
+func (r *CertReloader) logExpiry(leaf *x509.Certificate) {
+	remaining := leaf.NotAfter.Sub(r.clock.Now())
+
+	if remaining < ExpiryWarningThreshold {
+		r.logger.Warn(logTag, "Loaded certificate '%s' (CN=%s) expires at %s, in %s", r.certFile, leaf.Subject.CommonName, leaf.NotAfter.UTC().Format(time.RFC3339), remaining)
+		return
+	}
+
+	r.logger.Info(logTag, "Loaded certificate '%s' (CN=%s) expires at %s, in %s", r.certFile, leaf.Subject.CommonName, leaf.NotAfter.UTC().Format(time.RFC3339), remaining)
+}
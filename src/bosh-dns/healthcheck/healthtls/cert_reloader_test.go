@@ -0,0 +1,219 @@
+package healthtls_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"bosh-dns/healthcheck/healthtls"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	loggerfakes "github.com/cloudfoundry/bosh-utils/logger/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type certPair struct {
+	certPEM []byte
+	keyPEM  []byte
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+}
+
+func generateCert(cn string, notAfter time.Time, parent *certPair) certPair {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		DNSNames:              []string{cn},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+
+	signerCert, signerKey := template, key
+	if parent != nil {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	Expect(err).NotTo(HaveOccurred())
+
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	return certPair{
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		cert:    cert,
+		key:     key,
+	}
+}
+
+var _ = Describe("CertReloader", func() {
+	var (
+		dir      string
+		certFile string
+		keyFile  string
+		caFile   string
+		clock    *fakeclock.FakeClock
+		logger   *loggerfakes.FakeLogger
+		ca       certPair
+		leaf     certPair
+		signal   chan struct{}
+	)
+
+	writeFile := func(path string, contents []byte, modTime time.Time) {
+		Expect(ioutil.WriteFile(path, contents, 0600)).To(Succeed())
+		Expect(os.Chtimes(path, modTime, modTime)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "healthtls")
+		Expect(err).NotTo(HaveOccurred())
+
+		certFile = filepath.Join(dir, "cert.pem")
+		keyFile = filepath.Join(dir, "key.pem")
+		caFile = filepath.Join(dir, "ca.pem")
+
+		clock = fakeclock.NewFakeClock(time.Now())
+		logger = &loggerfakes.FakeLogger{}
+		signal = make(chan struct{})
+
+		ca = generateCert("ca", time.Now().Add(365*24*time.Hour), nil)
+		leaf = generateCert("health.bosh-dns", time.Now().Add(90*24*time.Hour), &ca)
+
+		past := time.Now().Add(-time.Hour)
+		writeFile(certFile, leaf.certPEM, past)
+		writeFile(keyFile, leaf.keyPEM, past)
+		writeFile(caFile, ca.certPEM, past)
+	})
+
+	AfterEach(func() {
+		close(signal)
+		os.RemoveAll(dir)
+	})
+
+	It("loads the certificate, CA pool and expiry", func() {
+		reloader, err := healthtls.NewCertReloader(certFile, keyFile, caFile, clock, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(reloader.Certificate().Leaf.Subject.CommonName).To(Equal("health.bosh-dns"))
+		Expect(reloader.NotAfter()).To(Equal(leaf.cert.NotAfter))
+
+		_, err = leaf.cert.Verify(x509.VerifyOptions{Roots: reloader.CAPool()})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(logger.InfoCallCount()).To(Equal(1))
+		_, template, args := logger.InfoArgsForCall(0)
+		Expect(fmt.Sprintf(template, args...)).To(ContainSubstring("expires at"))
+	})
+
+	It("publishes the expiry of the certificate", func() {
+		_, err := healthtls.NewCertReloader(certFile, keyFile, caFile, clock, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(healthtls.CertificateNotAfter.Get(certFile).String()).To(Equal(fmt.Sprintf("%d", leaf.cert.NotAfter.Unix())))
+		Expect(expvar.Get("health_tls_certificate_not_after")).To(Equal(healthtls.CertificateNotAfter))
+	})
+
+	It("warns when the certificate is close to expiry", func() {
+		leaf = generateCert("health.bosh-dns", time.Now().Add(24*time.Hour), &ca)
+		writeFile(certFile, leaf.certPEM, time.Now())
+		writeFile(keyFile, leaf.keyPEM, time.Now())
+
+		_, err := healthtls.NewCertReloader(certFile, keyFile, caFile, clock, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(logger.WarnCallCount()).To(Equal(1))
+	})
+
+	It("errors when a file cannot be loaded", func() {
+		_, err := healthtls.NewCertReloader(certFile, keyFile, filepath.Join(dir, "missing.pem"), clock, logger)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("trusts every CA in the CA file", func() {
+		otherCA := generateCert("other-ca", time.Now().Add(365*24*time.Hour), nil)
+		otherLeaf := generateCert("health.bosh-dns", time.Now().Add(90*24*time.Hour), &otherCA)
+		writeFile(caFile, append(ca.certPEM, otherCA.certPEM...), time.Now())
+
+		reloader, err := healthtls.NewCertReloader(certFile, keyFile, caFile, clock, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, cert := range []*x509.Certificate{leaf.cert, otherLeaf.cert} {
+			_, err = cert.Verify(x509.VerifyOptions{Roots: reloader.CAPool()})
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	Context("when running", func() {
+		var reloader *healthtls.CertReloader
+
+		BeforeEach(func() {
+			var err error
+			reloader, err = healthtls.NewCertReloader(certFile, keyFile, caFile, clock, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			go reloader.Run(time.Second, signal)
+		})
+
+		It("picks up rotated certificates", func() {
+			rotatedCA := generateCert("rotated-ca", time.Now().Add(365*24*time.Hour), nil)
+			rotated := generateCert("health.bosh-dns", time.Now().Add(180*24*time.Hour), &rotatedCA)
+			writeFile(certFile, rotated.certPEM, time.Now())
+			writeFile(keyFile, rotated.keyPEM, time.Now())
+			writeFile(caFile, append(ca.certPEM, rotatedCA.certPEM...), time.Now())
+
+			clock.WaitForWatcherAndIncrement(time.Minute)
+
+			Eventually(reloader.NotAfter).Should(Equal(rotated.cert.NotAfter))
+
+			for _, cert := range []*x509.Certificate{leaf.cert, rotated.cert} {
+				_, err := cert.Verify(x509.VerifyOptions{Roots: reloader.CAPool()})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("reloads changed files without waiting for the interval", func() {
+			renewed := generateCert("health.bosh-dns", time.Now().Add(120*24*time.Hour), &ca)
+			Eventually(clock.WatcherCount).Should(Equal(1))
+
+			writeFile(keyFile, renewed.keyPEM, time.Now())
+			writeFile(certFile, renewed.certPEM, time.Now())
+
+			Eventually(reloader.NotAfter).Should(Equal(renewed.cert.NotAfter))
+		})
+
+		It("keeps the previous certificate when the new one is invalid", func() {
+			writeFile(certFile, []byte("half written"), time.Now())
+
+			clock.WaitForWatcherAndIncrement(time.Minute)
+
+			Eventually(logger.ErrorCallCount).Should(Equal(1))
+			Expect(reloader.NotAfter()).To(Equal(leaf.cert.NotAfter))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package healthtls_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHealthTLS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "healthcheck/healthtls")
+}
@@ -8,8 +8,10 @@ import (
 	"os"
 	"time"
 
+	dnsconfig "bosh-dns/dns/config"
 	"bosh-dns/healthcheck/healthexecutable"
 	"bosh-dns/healthcheck/healthserver"
+	"bosh-dns/healthcheck/healthtls"
 
 	"os/signal"
 	"syscall"
@@ -42,22 +44,34 @@ func mainExitCode() int {
 	fs := boshsys.NewOsFileSystem(logger)
 	cmdRunner := boshsys.NewExecCmdRunner(logger)
 	interval := time.Duration(config.HealthExecutableInterval)
-	executablePaths, err := fs.Glob(config.HealthExecutablesGlob)
-	if err != nil {
-		logger.Error(logTag, fmt.Sprintf("Error: %v", err.Error()))
-		return 1
-	}
+	timeout := time.Duration(config.HealthExecutableTimeout)
 
 	healthExecutableMonitor := healthexecutable.NewHealthExecutableMonitor(
-		executablePaths,
+		config.HealthExecutablesGlob,
+		fs,
 		cmdRunner,
 		clock.NewClock(),
 		interval,
+		timeout,
+		config.HealthExecutableMaxConcurrency,
 		shutdown,
 		logger,
 	)
 
-	healthServer = healthserver.NewHealthServer(logger, fs, config.HealthFileName, healthExecutableMonitor)
+	certs, err := healthtls.NewCertReloader(
+		config.CertificateFile,
+		config.PrivateKeyFile,
+		config.CAFile,
+		clock.NewClock(),
+		logger,
+	)
+	if err != nil {
+		logger.Error(logTag, fmt.Sprintf("Error: %v", err.Error()))
+		return 1
+	}
+	go certs.Run(time.Duration(config.CertReloadInterval), shutdown)
+
+	healthServer = healthserver.NewHealthServer(logger, fs, config.HealthFileName, healthExecutableMonitor, certs)
 	healthServer.Serve(config)
 
 	sigterm := make(chan os.Signal, 1)
@@ -86,7 +100,12 @@ func getConfig() (*healthserver.HealthCheckConfig, error) {
 		return nil, fmt.Errorf("Couldn't open config file for health. error: %s", err)
 	}
 
-	config = &healthserver.HealthCheckConfig{}
+	config = &healthserver.HealthCheckConfig{
+		HealthExecutableInterval:       dnsconfig.DurationJSON(5 * time.Second),
+		HealthExecutableTimeout:        dnsconfig.DurationJSON(time.Minute),
+		HealthExecutableMaxConcurrency: 4,
+		CertReloadInterval:             dnsconfig.DurationJSON(10 * time.Second),
+	}
 	err = json.Unmarshal(configRaw, config)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't decode config file for health. error: %s", err)
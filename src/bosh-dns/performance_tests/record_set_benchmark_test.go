@@ -0,0 +1,109 @@
+package performance_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"bosh-dns/dns/server/aliases"
+	"bosh-dns/dns/server/healthiness"
+	"bosh-dns/dns/server/records"
+	"bosh-dns/dns/server/records/recordsfakes"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// The record set benchmarks spread records over many deployments the way a
+// large director does. Run them with
+//
+//	go test -run XXX -bench RecordSet ./performance_tests
+//
+// ns/op should stay roughly flat as the record count grows.
+
+var recordSetSizes = []int{1000, 10000, 50000, 100000}
+
+func newBenchmarkRecordSet(b *testing.B, size int, shutdown chan struct{}) *records.RecordSet {
+	infos := make([][]interface{}, 0, size)
+	for i := 0; i < size; i++ {
+		infos = append(infos, []interface{}{
+			fmt.Sprintf("instance%d", i),
+			fmt.Sprintf("%d", i),
+			fmt.Sprintf("group%d", i%50),
+			[]string{fmt.Sprintf("%d", i%50)},
+			fmt.Sprintf("%d", i%3),
+			"network",
+			"1",
+			fmt.Sprintf("deployment%d", i/500),
+			fmt.Sprintf("10.%d.%d.%d", i/65536, (i/256)%256, i%256),
+			"bosh.",
+			i % 500,
+		})
+	}
+
+	infosJSON, err := json.Marshal(infos)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	fileReader := &recordsfakes.FakeFileReader{}
+	fileReader.GetReturns([]byte(fmt.Sprintf(`{
+		"record_keys": ["id", "num_id", "instance_group", "group_ids", "az_id", "network", "network_id", "deployment", "ip", "domain", "instance_index"],
+		"record_infos": %s
+	}`, infosJSON)), nil)
+
+	recordSet, err := records.NewRecordSet(
+		fileReader,
+		aliases.MustNewConfigFromMap(map[string][]string{}),
+		healthiness.NewNopHealthWatcher(),
+		2000,
+		shutdown,
+		boshlog.NewLogger(boshlog.LevelNone),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return recordSet
+}
+
+func benchmarkResolve(b *testing.B, query func(size int) string, expected int) {
+	for _, size := range recordSetSizes {
+		b.Run(fmt.Sprintf("%d records", size), func(b *testing.B) {
+			shutdown := make(chan struct{})
+			defer close(shutdown)
+
+			recordSet := newBenchmarkRecordSet(b, size, shutdown)
+			fqdn := query(size)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ips, err := recordSet.Resolve(fqdn)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(ips) != expected {
+					b.Fatalf("expected %d ips for %s, got %d", expected, fqdn, len(ips))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRecordSetResolveInstance(b *testing.B) {
+	benchmarkResolve(b, func(size int) string {
+		last := size - 1
+		return fmt.Sprintf("instance%d.group%d.network.deployment%d.bosh.", last, last%50, last/500)
+	}, 1)
+}
+
+func BenchmarkRecordSetResolveNumID(b *testing.B) {
+	benchmarkResolve(b, func(size int) string {
+		return fmt.Sprintf("q-m%ds0.q-g%d.bosh.", size-1, (size-1)%50)
+	}, 1)
+}
+
+func BenchmarkRecordSetResolveGroupInDeployment(b *testing.B) {
+	benchmarkResolve(b, func(int) string {
+		return "q-s0.group7.network.deployment1.bosh."
+	}, 10)
+}